@@ -0,0 +1,75 @@
+package ospf3
+
+import "fmt"
+
+// instanceIDsPerAF is the number of Instance IDs assigned to each
+// AddressFamily.
+const instanceIDsPerAF = 32
+
+// An AddressFamily is an address family supported by an OSPFv3 instance, as
+// identified by the Instance ID ranges described in RFC5838, section 2.1.
+type AddressFamily uint8
+
+// Possible AddressFamily values.
+const (
+	IPv6Unicast AddressFamily = iota
+	IPv6Multicast
+	IPv4Unicast
+	IPv4Multicast
+)
+
+// String returns the string representation of an AddressFamily.
+func (f AddressFamily) String() string {
+	switch f {
+	case IPv6Unicast:
+		return "IPv6Unicast"
+	case IPv6Multicast:
+		return "IPv6Multicast"
+	case IPv4Unicast:
+		return "IPv4Unicast"
+	case IPv4Multicast:
+		return "IPv4Multicast"
+	default:
+		return fmt.Sprintf("AddressFamily(%d)", f)
+	}
+}
+
+// InstanceIDs returns the first and last Instance IDs of the range assigned to
+// the AddressFamily. The AddressFamily must be one of the defined constants.
+func (f AddressFamily) InstanceIDs() (first, last uint8) {
+	first = uint8(f) * instanceIDsPerAF
+	return first, first + instanceIDsPerAF - 1
+}
+
+// IPv4 reports whether the AddressFamily is an IPv4 address family. Prefixes
+// in the LSAs of an IPv4 instance should be converted using
+// Prefix.IPv4Prefix.
+func (f AddressFamily) IPv4() bool { return f == IPv4Unicast || f == IPv4Multicast }
+
+// AddressFamily returns the AddressFamily of the instance identified by the
+// Header's InstanceID. It reports false if InstanceID is in the unassigned
+// range 128-255.
+func (h Header) AddressFamily() (AddressFamily, bool) {
+	if h.InstanceID >= 4*instanceIDsPerAF {
+		return 0, false
+	}
+
+	return AddressFamily(h.InstanceID / instanceIDsPerAF), true
+}
+
+// CheckAddressFamily applies the Hello processing rules of RFC5838. It returns
+// an error if the Hello's InstanceID is in the unassigned range, or if the
+// Hello belongs to an instance outside of the IPv6 unicast range but does not
+// set AFBit. Such Hellos must be discarded.
+func (h *Hello) CheckAddressFamily() error {
+	f, ok := h.Header.AddressFamily()
+	if !ok {
+		return fmt.Errorf("ospf3: Hello Instance ID %d is not assigned to an address family", h.Header.InstanceID)
+	}
+
+	if f != IPv6Unicast && h.Options&AFBit == 0 {
+		return fmt.Errorf("ospf3: Hello for %s Instance ID %d must set the AF-bit", f, h.Header.InstanceID)
+	}
+
+	return nil
+}
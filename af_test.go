@@ -0,0 +1,90 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHeaderAddressFamily(t *testing.T) {
+	tests := []struct {
+		id uint8
+		f  AddressFamily
+		ok bool
+	}{
+		{id: 0, f: IPv6Unicast, ok: true},
+		{id: 31, f: IPv6Unicast, ok: true},
+		{id: 32, f: IPv6Multicast, ok: true},
+		{id: 64, f: IPv4Unicast, ok: true},
+		{id: 127, f: IPv4Multicast, ok: true},
+		{id: 128},
+		{id: 255},
+	}
+
+	for _, tt := range tests {
+		f, ok := Header{InstanceID: tt.id}.AddressFamily()
+		if diff := cmp.Diff(tt.ok, ok); diff != "" {
+			t.Fatalf("unexpected ok for Instance ID %d (-want +got):\n%s", tt.id, diff)
+		}
+		if !ok {
+			continue
+		}
+
+		if diff := cmp.Diff(tt.f, f); diff != "" {
+			t.Fatalf("unexpected AddressFamily for Instance ID %d (-want +got):\n%s", tt.id, diff)
+		}
+
+		if first, last := f.InstanceIDs(); tt.id < first || tt.id > last {
+			t.Fatalf("Instance ID %d is not in %s range %d-%d", tt.id, f, first, last)
+		}
+	}
+}
+
+func TestHelloCheckAddressFamily(t *testing.T) {
+	tests := []struct {
+		name string
+		id   uint8
+		opts Options
+		ok   bool
+	}{
+		{
+			name: "IPv6 unicast",
+			id:   0,
+			opts: V6Bit,
+			ok:   true,
+		},
+		{
+			name: "IPv4 unicast",
+			id:   64,
+			opts: AFBit,
+			ok:   true,
+		},
+		{
+			name: "IPv4 unicast no AF-bit",
+			id:   64,
+			opts: V6Bit,
+		},
+		{
+			name: "unassigned",
+			id:   200,
+			opts: AFBit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Hello{
+				Header:  Header{InstanceID: tt.id},
+				Options: tt.opts,
+			}
+
+			err := h.CheckAddressFamily()
+			if tt.ok && err != nil {
+				t.Fatalf("failed to check address family: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
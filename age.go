@@ -0,0 +1,115 @@
+package ospf3
+
+import "time"
+
+// Architectural constants for LSA aging as described in RFC2328, appendix B.
+const (
+	// LSRefreshTime is the maximum age of a self-originated LSA before it is
+	// reoriginated. It is the default for Timers.LSRefreshTime.
+	LSRefreshTime = 30 * time.Minute
+
+	// CheckAge is the interval at which the checksum of an LSA in the link
+	// state database is verified.
+	CheckAge = 5 * time.Minute
+
+	// MaxAge is the maximum age of an LSA. An LSA which reaches MaxAge is
+	// flushed from the routing domain.
+	MaxAge = 1 * time.Hour
+
+	// MaxAgeDiff is the maximum time an LSA can take to flood across the
+	// routing domain, used to distinguish LSA instances by age.
+	MaxAgeDiff = 15 * time.Minute
+)
+
+// DoNotAge is the DoNotAge bit of the LS age field as described in RFC1793,
+// section 2.2, expressed as a time.Duration so it can be combined with an
+// LSAHeader's Age. LSAs with the DoNotAge bit set are not aged while held in
+// the link state database.
+const DoNotAge = 0x8000 * time.Second
+
+// AddAge adds d to the LS age age, saturating at MaxAge. If age has the
+// DoNotAge bit set, the bit is preserved and only the remaining age is
+// incremented, as described in RFC1793, section 2.2.
+func AddAge(age, d time.Duration) time.Duration {
+	var dna time.Duration
+	if doNotAge(age) {
+		dna = DoNotAge
+	}
+
+	age = lsAge(age) + d
+	switch {
+	case age > MaxAge:
+		age = MaxAge
+	case age < 0:
+		age = 0
+	}
+
+	return dna + age
+}
+
+// DoNotAge reports whether the LSAHeader's Age has the DoNotAge bit set.
+func (h LSAHeader) DoNotAge() bool {
+	return doNotAge(h.Age)
+}
+
+// doNotAge reports whether age has the DoNotAge bit set. Any age of at least
+// DoNotAge has the bit set because the LS age field is 16 bits and MaxAge is
+// much smaller than DoNotAge.
+func doNotAge(age time.Duration) bool {
+	return age >= DoNotAge
+}
+
+// lsAge returns the LS age of age with the DoNotAge bit cleared.
+func lsAge(age time.Duration) time.Duration {
+	if doNotAge(age) {
+		return age - DoNotAge
+	}
+
+	return age
+}
+
+// An LSAAge tracks the LS age of an LSA held in a link state database without
+// modifying the LSA itself. The age is computed from the LS age carried by the
+// LSA when it was received plus the time elapsed since then, measured using
+// the monotonic clock reading of the time.Time values passed to its methods,
+// so that wall clock adjustments do not cause drift.
+type LSAAge struct {
+	age      time.Duration
+	received time.Time
+}
+
+// NewLSAAge begins tracking the age of an LSA with LSAHeader h, which was
+// received or originated at time now.
+func NewLSAAge(h LSAHeader, now time.Time) LSAAge {
+	return LSAAge{
+		age:      h.Age,
+		received: now,
+	}
+}
+
+// Age returns the LS age of the LSA at time now, saturating at MaxAge and
+// truncated to whole seconds as carried by the LS age field. LSAs with the
+// DoNotAge bit set are not aged while held in the link state database, so
+// their LS age is always returned unchanged, as described in RFC1793,
+// section 2.2.
+func (a LSAAge) Age(now time.Time) time.Duration {
+	if doNotAge(a.age) {
+		return a.age
+	}
+
+	// An LSA never becomes younger than it was when received, even if now is
+	// earlier than the time of receipt.
+	d := now.Sub(a.received)
+	if d < 0 {
+		d = 0
+	}
+
+	return AddAge(a.age, d).Truncate(time.Second)
+}
+
+// Header returns a copy of h with its Age set to the LS age of the LSA at time
+// now, such as for reporting or flooding the LSA.
+func (a LSAAge) Header(h LSAHeader, now time.Time) LSAHeader {
+	h.Age = a.Age(now)
+	return h
+}
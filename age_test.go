@@ -0,0 +1,142 @@
+package ospf3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAddAge(t *testing.T) {
+	tests := []struct {
+		name     string
+		age, d   time.Duration
+		want     time.Duration
+		doNotAge bool
+	}{
+		{
+			name: "zero",
+		},
+		{
+			name: "add",
+			age:  10 * time.Second,
+			d:    1 * time.Second,
+			want: 11 * time.Second,
+		},
+		{
+			name: "MaxAge",
+			age:  MaxAge - 1*time.Second,
+			d:    1 * time.Second,
+			want: MaxAge,
+		},
+		{
+			name: "saturating",
+			age:  MaxAge - 1*time.Second,
+			d:    10 * time.Second,
+			want: MaxAge,
+		},
+		{
+			name: "negative",
+			age:  1 * time.Second,
+			d:    -10 * time.Second,
+		},
+		{
+			name:     "DoNotAge",
+			age:      DoNotAge + 10*time.Second,
+			d:        1 * time.Second,
+			want:     DoNotAge + 11*time.Second,
+			doNotAge: true,
+		},
+		{
+			name:     "DoNotAge saturating",
+			age:      DoNotAge + MaxAge - 1*time.Second,
+			d:        10 * time.Second,
+			want:     DoNotAge + MaxAge,
+			doNotAge: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AddAge(tt.age, tt.d)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("unexpected age (-want +got):\n%s", diff)
+			}
+
+			h := LSAHeader{Age: got}
+			if diff := cmp.Diff(tt.doNotAge, h.DoNotAge()); diff != "" {
+				t.Fatalf("unexpected DoNotAge (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDoNotAgeRoundTrip(t *testing.T) {
+	// The DoNotAge bit must survive a trip through the 16-bit LS age field.
+	want := LSAHeader{Age: AddAge(DoNotAge, 5*time.Second)}
+
+	b := make([]byte, lsaHeaderLen)
+	want.marshal(b)
+
+	if diff := cmp.Diff([]byte{0x80, 0x05}, b[0:2]); diff != "" {
+		t.Fatalf("unexpected LS age bytes (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(want, parseLSAHeader(b)); diff != "" {
+		t.Fatalf("unexpected LSAHeader (-want +got):\n%s", diff)
+	}
+}
+
+func TestLSAAge(t *testing.T) {
+	start := time.Unix(0, 0)
+
+	tests := []struct {
+		name    string
+		age     time.Duration
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{
+			name:    "elapsed",
+			age:     10 * time.Second,
+			elapsed: 5500 * time.Millisecond,
+			want:    15 * time.Second,
+		},
+		{
+			name:    "MaxAge",
+			age:     MaxAge - 1*time.Second,
+			elapsed: 1 * time.Hour,
+			want:    MaxAge,
+		},
+		{
+			name:    "before receipt",
+			age:     10 * time.Second,
+			elapsed: -1 * time.Minute,
+			want:    10 * time.Second,
+		},
+		{
+			name:    "DoNotAge",
+			age:     DoNotAge + 10*time.Second,
+			elapsed: 1 * time.Hour,
+			want:    DoNotAge + 10*time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := LSAHeader{Age: tt.age, SequenceNumber: 0x80000001}
+			a := NewLSAAge(h, start)
+
+			now := start.Add(tt.elapsed)
+			if diff := cmp.Diff(tt.want, a.Age(now)); diff != "" {
+				t.Fatalf("unexpected age (-want +got):\n%s", diff)
+			}
+
+			want := h
+			want.Age = tt.want
+			if diff := cmp.Diff(want, a.Header(h, now)); diff != "" {
+				t.Fatalf("unexpected LSAHeader (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
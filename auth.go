@@ -0,0 +1,302 @@
+package ospf3
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+)
+
+// Authentication Trailer constants as described in RFC7166, section 4.
+const (
+	authTrailerLen = 16 // No trailing authentication data.
+	authTypeHMAC   = 1
+)
+
+// apad is repeated to fill the authentication data following the IPv6 source
+// address when computing a digest, as described in RFC7166, section 4.5.
+var apad = [4]byte{0x87, 0x8f, 0xe1, 0xf3}
+
+// An AuthTrailer is an OSPFv3 Authentication Trailer using HMAC
+// cryptographic authentication, as described in RFC7166, section 4. The
+// Authentication Trailer follows a packet and any LLS data block, and is not
+// counted in the OSPFv3 packet length or checksum.
+type AuthTrailer struct {
+	SAID           uint16
+	SequenceNumber uint64
+	Data           []byte
+}
+
+// An Authenticator computes the authentication data of Authentication
+// Trailers for one or more security associations. Authenticators may be used
+// with MarshalOptions and ParseOptions.
+type Authenticator interface {
+	// Size returns the length in bytes of the authentication data for the
+	// security association said, or an error if said is not known.
+	Size(said uint16) (int, error)
+
+	// Sum computes the authentication data for the security association
+	// said over b, which contains the OSPFv3 packet, any LLS data block, and
+	// the Authentication Trailer with its authentication data set to Apad as
+	// described in RFC7166, section 4.5.
+	Sum(said uint16, b []byte) ([]byte, error)
+}
+
+// An acceptor is an Authenticator which uses different security associations
+// to verify received packets than to send them, such as a Keychain with
+// separate send and accept lifetimes.
+type acceptor interface {
+	// accepting returns the Authenticator used to verify received packets.
+	accepting() Authenticator
+}
+
+// An HMACKey is the key and hash function of a security association for HMAC
+// cryptographic authentication, such as sha256.New for HMAC-SHA-256. Keys
+// longer than the hash's output length are hashed before use, as described in
+// RFC7166, section 4.5.
+type HMACKey struct {
+	Hash func() hash.Hash
+	Key  []byte
+}
+
+// HMACAuthenticator is an Authenticator which maps security association IDs
+// to HMACKeys.
+type HMACAuthenticator map[uint16]HMACKey
+
+var _ Authenticator = HMACAuthenticator(nil)
+
+// Size implements Authenticator.
+func (a HMACAuthenticator) Size(said uint16) (int, error) {
+	k, ok := a[said]
+	if !ok {
+		return 0, fmt.Errorf("ospf3: unknown security association ID %d", said)
+	}
+
+	return k.Hash().Size(), nil
+}
+
+// Sum implements Authenticator.
+func (a HMACAuthenticator) Sum(said uint16, b []byte) ([]byte, error) {
+	k, ok := a[said]
+	if !ok {
+		return nil, fmt.Errorf("ospf3: unknown security association ID %d", said)
+	}
+
+	return k.sum(b), nil
+}
+
+// sum computes the HMAC of b. The key is first prepared as described in
+// RFC7166, section 4.5: a key longer than the hash's output length is replaced
+// by its hash. Unlike HMAC itself, this applies even to keys which fit within
+// the hash's block size. Shorter keys are padded with zeros, as HMAC does.
+func (k HMACKey) sum(b []byte) []byte {
+	key := k.Key
+	if h := k.Hash(); len(key) > h.Size() {
+		_, _ = h.Write(key)
+		key = h.Sum(nil)
+	}
+
+	mac := hmac.New(k.Hash, key)
+	_, _ = mac.Write(b)
+	return mac.Sum(nil)
+}
+
+// errAuth is a sentinel for authentication failures.
+var errAuth = errors.New("failed to authenticate packet")
+
+// ParseAuthTrailer parses the Authentication Trailer which follows
+// the OSPFv3 packet and any LLS data block in b. It does not verify the
+// authentication data. Use ParseOptions.Authenticator to do so.
+func ParseAuthTrailer(b []byte) (*AuthTrailer, error) {
+	off, err := authTrailerOffset(b)
+	if err != nil {
+		return nil, fmt.Errorf("ospf3: %w", err)
+	}
+
+	t, _, err := parseAuthTrailer(b[off:])
+	if err != nil {
+		return nil, fmt.Errorf("ospf3: %w", err)
+	}
+
+	return t, nil
+}
+
+// appendAuthTrailer appends an Authentication Trailer to the packet bytes b,
+// using a to compute its authentication data.
+func appendAuthTrailer(b []byte, a Authenticator, said uint16, seq uint64, src net.IP) ([]byte, error) {
+	if err := checkATBit(b); err != nil {
+		return nil, fmt.Errorf("ospf3: %v: %w", err, errMarshal)
+	}
+
+	n, err := a.Size(said)
+	if err != nil {
+		return nil, err
+	}
+	if n < net.IPv6len || authTrailerLen+n > 0xffff {
+		return nil, fmt.Errorf("ospf3: invalid authentication data length %d: %w", n, errMarshal)
+	}
+
+	off := len(b)
+	b = append(b, make([]byte, authTrailerLen+n)...)
+	t := b[off:]
+	binary.BigEndian.PutUint16(t[0:2], authTypeHMAC)
+	binary.BigEndian.PutUint16(t[2:4], uint16(authTrailerLen+n))
+	// t[4:6] are reserved.
+	binary.BigEndian.PutUint16(t[6:8], said)
+	binary.BigEndian.PutUint64(t[8:16], seq)
+
+	sum, err := authSum(b, t[authTrailerLen:], a, said, src)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(t[authTrailerLen:], sum)
+	return b, nil
+}
+
+// verifyAuthTrailer verifies the Authentication Trailer which follows the
+// packet and any LLS data block in b using a.
+func verifyAuthTrailer(b []byte, a Authenticator, src net.IP) error {
+	if aa, ok := a.(acceptor); ok {
+		a = aa.accepting()
+	}
+
+	if err := checkATBit(b); err != nil {
+		return fmt.Errorf("%v: %w", err, errAuth)
+	}
+
+	off, err := authTrailerOffset(b)
+	if err != nil {
+		return err
+	}
+
+	t, n, err := parseAuthTrailer(b[off:])
+	if err != nil {
+		return err
+	}
+
+	size, err := a.Size(t.SAID)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, errAuth)
+	}
+	if size != len(t.Data) {
+		return fmt.Errorf("authentication data length %d does not match security association ID %d: %w",
+			len(t.Data), t.SAID, errAuth)
+	}
+
+	// Compute the digest over a copy of the packet so that the caller's
+	// authentication data is not overwritten by Apad.
+	bb := append([]byte(nil), b[:off+n]...)
+	sum, err := authSum(bb, bb[off+authTrailerLen:], a, t.SAID, src)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(sum, t.Data) {
+		return errAuth
+	}
+
+	return nil
+}
+
+// authSum fills data, the authentication data of the trailer at the end of
+// b, with Apad and computes the authentication data over b.
+func authSum(b, data []byte, a Authenticator, said uint16, src net.IP) ([]byte, error) {
+	if src.To16() == nil || src.To4() != nil {
+		return nil, fmt.Errorf("ospf3: authentication requires an IPv6 source address, but got: %q", src)
+	}
+
+	copy(data, src.To16())
+	for i := net.IPv6len; i < len(data); i += len(apad) {
+		copy(data[i:], apad[:])
+	}
+
+	return a.Sum(said, b)
+}
+
+// parseAuthTrailer parses an Authentication Trailer from the beginning of b
+// and returns the number of bytes it occupies.
+func parseAuthTrailer(b []byte) (*AuthTrailer, int, error) {
+	if l := len(b); l < authTrailerLen {
+		return nil, 0, fmt.Errorf("not enough bytes for Authentication Trailer: %d: %w", l, errAuth)
+	}
+
+	if typ := binary.BigEndian.Uint16(b[0:2]); typ != authTypeHMAC {
+		return nil, 0, fmt.Errorf("unknown authentication type: %d: %w", typ, errAuth)
+	}
+
+	n := int(binary.BigEndian.Uint16(b[2:4]))
+	if n < authTrailerLen || n > len(b) {
+		return nil, 0, fmt.Errorf("invalid Authentication Trailer length %d for %d bytes: %w", n, len(b), errAuth)
+	}
+
+	return &AuthTrailer{
+		// b[4:6] are reserved.
+		SAID:           binary.BigEndian.Uint16(b[6:8]),
+		SequenceNumber: binary.BigEndian.Uint64(b[8:16]),
+		Data:           append([]byte(nil), b[authTrailerLen:n]...),
+	}, n, nil
+}
+
+// authTrailerOffset returns the offset of the Authentication Trailer in the
+// packet bytes b, following the packet and any LLS data block.
+func authTrailerOffset(b []byte) (int, error) {
+	_, ptyp, plen, err := parseHeader(b)
+	if err != nil {
+		return 0, err
+	}
+
+	// The L-bit indicates an LLS data block whose length must be skipped.
+	if packetOptions(b, ptyp)&LBit == 0 {
+		return plen, nil
+	}
+
+	if l := len(b[plen:]); l < llsHeaderLen {
+		return 0, fmt.Errorf("not enough bytes for LLS data block: %d: %w", l, errAuth)
+	}
+
+	n := 4 * int(binary.BigEndian.Uint16(b[plen+2:plen+4]))
+	if n < llsHeaderLen || n > len(b[plen:]) {
+		return 0, fmt.Errorf("invalid LLS data block length %d: %w", n, errAuth)
+	}
+
+	return plen + n, nil
+}
+
+// checkATBit verifies that the Options of a Hello or DatabaseDescription in
+// the packet bytes b set the AT-bit, as required by RFC7166, section 3.
+func checkATBit(b []byte) error {
+	_, ptyp, _, err := parseHeader(b)
+	if err != nil {
+		return err
+	}
+
+	if ptyp != hello && ptyp != databaseDescription {
+		return nil
+	}
+
+	if packetOptions(b, ptyp)&ATBit == 0 {
+		return errors.New("Hello and DatabaseDescription packets with an Authentication Trailer must set the AT-bit")
+	}
+
+	return nil
+}
+
+// packetOptions returns the Options of a Hello or DatabaseDescription in the
+// packet bytes b, or 0 for other packet types or if b is too short.
+func packetOptions(b []byte, ptyp packetType) Options {
+	switch ptyp {
+	case hello:
+		if len(b) >= headerLen+8 {
+			return options(b[headerLen+4 : headerLen+8])
+		}
+	case databaseDescription:
+		if len(b) >= headerLen+4 {
+			return options(b[headerLen : headerLen+4])
+		}
+	}
+
+	return 0
+}
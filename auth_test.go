@@ -0,0 +1,378 @@
+package ospf3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAuthTrailerRoundTrip(t *testing.T) {
+	var (
+		src = net.ParseIP("fe80::1")
+		dst = net.ParseIP("ff02::5")
+
+		key = []byte("ospf3")
+		a   = HMACAuthenticator{
+			1: {Hash: sha1.New, Key: key},
+			2: {Hash: sha256.New, Key: key},
+		}
+	)
+
+	tests := []struct {
+		name string
+		said uint16
+		p    Packet
+	}{
+		{
+			name: "Hello HMAC-SHA-1",
+			said: 1,
+			p: &Hello{
+				Header:      Header{RouterID: ID{192, 0, 2, 1}},
+				Options:     V6Bit | ATBit,
+				NeighborIDs: []ID{{192, 0, 2, 2}},
+			},
+		},
+		{
+			name: "Hello LLS HMAC-SHA-256",
+			said: 2,
+			p: &Hello{
+				Header:      Header{RouterID: ID{192, 0, 2, 1}},
+				Options:     V6Bit | ATBit | LBit,
+				NeighborIDs: []ID{},
+				LLS:         &LLS{ExtendedOptions: LRBit},
+			},
+		},
+		{
+			name: "LinkStateUpdate HMAC-SHA-256",
+			said: 2,
+			p:    pktLinkStateUpdate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mo := MarshalOptions{
+				Source:         src,
+				Destination:    dst,
+				Authenticator:  a,
+				SAID:           tt.said,
+				SequenceNumber: 0x0102030405060708,
+			}
+
+			b, err := mo.MarshalPacket(tt.p)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			at, err := ParseAuthTrailer(b)
+			if err != nil {
+				t.Fatalf("failed to parse trailer: %v", err)
+			}
+
+			// Independently compute the expected authentication data with Apad
+			// in place of the digest.
+			size, _ := a.Size(tt.said)
+			text := append([]byte(nil), b...)
+			data := text[len(text)-size:]
+			copy(data, src)
+			for i := net.IPv6len; i < len(data); i += 4 {
+				copy(data[i:], []byte{0x87, 0x8f, 0xe1, 0xf3})
+			}
+			mac := hmac.New(a[tt.said].Hash, key)
+			mac.Write(text)
+
+			want := &AuthTrailer{
+				SAID:           tt.said,
+				SequenceNumber: 0x0102030405060708,
+				Data:           mac.Sum(nil),
+			}
+			if diff := cmp.Diff(want, at); diff != "" {
+				t.Fatalf("unexpected trailer (-want +got):\n%s", diff)
+			}
+
+			po := ParseOptions{
+				Source:        src,
+				Destination:   dst,
+				Authenticator: a,
+			}
+
+			p, err := po.ParsePacket(b)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+
+			// The checksum is computed when marshaling.
+			opt := cmpopts.IgnoreFields(Header{}, "Checksum")
+			if diff := cmp.Diff(tt.p, p, opt); diff != "" {
+				t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+			}
+
+			// Tampering with the trailer must be detected.
+			b[len(b)-1] ^= 0xff
+			if _, err := po.ParsePacket(b); !errors.Is(err, errAuth) {
+				t.Fatalf("expected authentication error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestHMACAuthenticatorVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		hash func() hash.Hash
+		key  []byte
+		data string
+		want string
+	}{
+		// Published HMAC test vectors whose keys are prepared identically by
+		// HMAC and RFC7166, section 4.5.
+		{
+			name: "RFC2202 HMAC-SHA-1 1",
+			hash: sha1.New,
+			key:  bytes.Repeat([]byte{0x0b}, 20),
+			data: "Hi There",
+			want: "b617318655057264e28bc0b6fb378c8ef146be00",
+		},
+		{
+			name: "RFC2202 HMAC-SHA-1 2",
+			hash: sha1.New,
+			key:  []byte("Jefe"),
+			data: "what do ya want for nothing?",
+			want: "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79",
+		},
+		{
+			name: "RFC2202 HMAC-SHA-1 6",
+			hash: sha1.New,
+			key:  bytes.Repeat([]byte{0xaa}, 80),
+			data: "Test Using Larger Than Block-Size Key - Hash Key First",
+			want: "aa4ae5e15272d00e95705637ce8a3b55ed402112",
+		},
+		{
+			name: "RFC4231 HMAC-SHA-256 1",
+			hash: sha256.New,
+			key:  bytes.Repeat([]byte{0x0b}, 20),
+			data: "Hi There",
+			want: "b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7",
+		},
+		{
+			name: "RFC4231 HMAC-SHA-256 2",
+			hash: sha256.New,
+			key:  []byte("Jefe"),
+			data: "what do ya want for nothing?",
+			want: "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		},
+		{
+			name: "RFC4231 HMAC-SHA-256 6",
+			hash: sha256.New,
+			key:  bytes.Repeat([]byte{0xaa}, 131),
+			data: "Test Using Larger Than Block-Size Key - Hash Key First",
+			want: "60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54",
+		},
+		{
+			name: "RFC4231 HMAC-SHA-384 1",
+			hash: sha512.New384,
+			key:  bytes.Repeat([]byte{0x0b}, 20),
+			data: "Hi There",
+			want: "afd03944d84895626b0825f4ab46907f15f9dadbe4101ec682aa034c7cebc59cfaea9ea9076ede7f4af152e8b2fa9cb6",
+		},
+		{
+			name: "RFC4231 HMAC-SHA-512 1",
+			hash: sha512.New,
+			key:  bytes.Repeat([]byte{0x0b}, 20),
+			data: "Hi There",
+			want: "87aa7cdea5ef619d4ff0b4241a1d6cb02379f4e2ce4ec2787ad0b30545e17cdedaa833b7d6b8a702038b274eaea3f4e4be9d914eeb61f1702e696c203a126854",
+		},
+		{
+			// The 25 byte key is longer than the SHA-1 output but fits
+			// within its block size, so RFC7166 hashes it where HMAC does
+			// not. Plain HMAC produces 4c9007f4026250c6bc8414f9bf50c86c2d7235da.
+			name: "RFC2202 HMAC-SHA-1 4 RFC7166 key",
+			hash: sha1.New,
+			key: []byte{
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a,
+				0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14,
+				0x15, 0x16, 0x17, 0x18, 0x19,
+			},
+			data: string(bytes.Repeat([]byte{0xcd}, 50)),
+			want: "4b3ba757830a7eaf63496c7d7378e6259e98dea4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := HMACAuthenticator{1: {Hash: tt.hash, Key: tt.key}}
+			sum, err := a.Sum(1, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("failed to compute sum: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, hex.EncodeToString(sum)); diff != "" {
+				t.Fatalf("unexpected sum (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuthTrailerVectors(t *testing.T) {
+	// Each packet is a Hello from 192.0.2.1 with an Authentication Trailer,
+	// computed independently of this package following RFC7166, section 4.5
+	// with the source address fe80::1.
+	var (
+		src = net.ParseIP("fe80::1")
+		h   = &Hello{
+			Header:             Header{RouterID: ID{192, 0, 2, 1}},
+			InterfaceID:        1,
+			RouterPriority:     1,
+			Options:            V6Bit | EBit | RBit | ATBit,
+			HelloInterval:      10 * time.Second,
+			RouterDeadInterval: 40 * time.Second,
+			NeighborIDs:        []ID{{192, 0, 2, 2}},
+		}
+	)
+
+	tests := []struct {
+		name string
+		said uint16
+		key  HMACKey
+		want string
+	}{
+		{
+			name: "HMAC-SHA-1 long key",
+			said: 1,
+			key: HMACKey{Hash: sha1.New, Key: []byte{
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a,
+				0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14,
+				0x15, 0x16, 0x17, 0x18, 0x19,
+			}},
+			want: "03010028c000020100000000000000000000000101000413000a00280000000000000000c0000202" +
+				"00010024000000010102030405060708" +
+				"e8af8eaecf5ddf754ab41a8f4176d9838b06b9c1",
+		},
+		{
+			name: "HMAC-SHA-256 short key",
+			said: 2,
+			key:  HMACKey{Hash: sha256.New, Key: []byte("ospf3")},
+			want: "03010028c000020100000000000000000000000101000413000a00280000000000000000c0000202" +
+				"00010030000000020102030405060708" +
+				"7ded342503e86fd0a6a99738dad271d527a171b946f235cd273bae8131179158",
+		},
+		{
+			name: "HMAC-SHA-512 long key",
+			said: 3,
+			key:  HMACKey{Hash: sha512.New, Key: bytes.Repeat([]byte{0xaa}, 100)},
+			want: "03010028c000020100000000000000000000000101000413000a00280000000000000000c0000202" +
+				"00010050000000030102030405060708" +
+				"752bb80f6dfd649f4238c98aacb298219688dfef991c7cc965434c93bbb1dcc8" +
+				"7a73a48eb47c2df039827b27d912c2aecf6bd46bcfbaf2d94b195deaa2b78067",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := HMACAuthenticator{tt.said: tt.key}
+			b, err := MarshalOptions{
+				Source:         src,
+				Authenticator:  a,
+				SAID:           tt.said,
+				SequenceNumber: 0x0102030405060708,
+			}.MarshalPacket(h)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, hex.EncodeToString(b)); diff != "" {
+				t.Fatalf("unexpected packet bytes (-want +got):\n%s", diff)
+			}
+
+			want, err := hex.DecodeString(tt.want)
+			if err != nil {
+				t.Fatalf("failed to decode vector: %v", err)
+			}
+
+			if _, err := (ParseOptions{Source: src, Authenticator: a}).ParsePacket(want); err != nil {
+				t.Fatalf("failed to verify vector: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthTrailerErrors(t *testing.T) {
+	var (
+		src = net.ParseIP("fe80::1")
+		a   = HMACAuthenticator{1: {Hash: sha256.New, Key: []byte("ospf3")}}
+	)
+
+	t.Run("marshal", func(t *testing.T) {
+		tests := []struct {
+			name string
+			o    MarshalOptions
+			p    Packet
+		}{
+			{
+				name: "no AT-bit",
+				o:    MarshalOptions{Source: src, Authenticator: a, SAID: 1},
+				p:    &Hello{Options: V6Bit},
+			},
+			{
+				name: "unknown SA",
+				o:    MarshalOptions{Source: src, Authenticator: a, SAID: 2},
+				p:    pktLinkStateAcknowledgement,
+			},
+			{
+				name: "no source",
+				o:    MarshalOptions{Authenticator: a, SAID: 1},
+				p:    pktLinkStateAcknowledgement,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := tt.o.MarshalPacket(tt.p); err == nil {
+					t.Fatal("expected an error, but none occurred")
+				}
+			})
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		o := ParseOptions{Source: src, Authenticator: a}
+
+		b, err := MarshalOptions{Source: src, Authenticator: a, SAID: 1}.
+			MarshalPacket(pktLinkStateAcknowledgement)
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+
+		_, _, plen, err := parseHeader(b)
+		if err != nil {
+			t.Fatalf("failed to parse Header: %v", err)
+		}
+
+		unknown := append([]byte(nil), b...)
+		unknown[plen+7] = 2
+
+		for _, b := range [][]byte{
+			// No trailer.
+			b[:plen],
+			// Truncated trailer.
+			b[:len(b)-1],
+			// Unknown SA.
+			unknown,
+		} {
+			if _, err := o.ParsePacket(b); !errors.Is(err, errAuth) {
+				t.Fatalf("expected authentication error, but got: %v", err)
+			}
+		}
+	})
+}
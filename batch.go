@@ -0,0 +1,185 @@
+package ospf3
+
+import (
+	"context"
+	"io"
+	"net"
+	"runtime/trace"
+
+	"golang.org/x/net/ipv6"
+)
+
+// A Message is an OSPFv3 packet received by Conn.ReadBatch or sent by
+// Conn.WriteBatch.
+type Message struct {
+	// Packet is the OSPFv3 packet.
+	Packet Packet
+
+	// Control is the IPv6 control message of a received packet. It is
+	// ignored by WriteBatch.
+	Control *ipv6.ControlMessage
+
+	// Addr is the source address of a received packet, or the destination
+	// address or multicast group of a sent packet.
+	Addr *net.IPAddr
+}
+
+// ReadBatch reads multiple OSPFv3 packets into ms using as few system calls as
+// possible, such as recvmmsg on Linux, and returns the number of Messages
+// read. ReadBatch blocks until a timeout occurs or at least one valid OSPFv3
+// packet is read. Packets are discarded as described by ReadFrom.
+//
+// On platforms without batch system calls, ReadBatch reads one packet per
+// system call.
+func (c *Conn) ReadBatch(ms []Message) (int, error) {
+	if len(ms) == 0 {
+		return 0, nil
+	}
+
+	// As with ReadFrom, allocate one extra byte per buffer so that oversized
+	// packets can be detected.
+	raw := make([]ipv6.Message, len(ms))
+	for i := range raw {
+		raw[i] = ipv6.Message{
+			Buffers: [][]byte{make([]byte, c.BufferSize())},
+			OOB:     ipv6.NewControlMessage(^ipv6.ControlFlags(0)),
+		}
+	}
+
+	for {
+		n, err := c.c.ReadBatch(raw, 0)
+		if err != nil {
+			return 0, err
+		}
+
+		var k int
+		for _, m := range raw[:n] {
+			var cm *ipv6.ControlMessage
+			if m.NN > 0 {
+				cm = new(ipv6.ControlMessage)
+				if err := cm.Parse(m.OOB[:m.NN]); err != nil {
+					cm = nil
+				}
+			}
+
+			// The maximum size may have grown since the buffers were
+			// allocated, so check for truncation explicitly.
+			if m.N == len(m.Buffers[0]) {
+				c.discardTruncated()
+				continue
+			}
+
+			ip := m.Addr.(*net.IPAddr)
+			p, ok := c.receive(m.Buffers[0][:m.N], cm, ip)
+			if !ok {
+				continue
+			}
+
+			ms[k] = Message{Packet: p, Control: cm, Addr: ip}
+			k++
+		}
+
+		if k > 0 {
+			return k, nil
+		}
+
+		// Replace the buffers if a truncated read raised the maximum size.
+		if size := c.BufferSize(); size > len(raw[0].Buffers[0]) {
+			for i := range raw {
+				raw[i].Buffers[0] = make([]byte, size)
+			}
+		}
+	}
+}
+
+// WriteBatch writes the Packet in each of ms to its Addr using as few system
+// calls as possible, such as sendmmsg on Linux, and returns the number of
+// Messages written. Each Packet is validated and marshaled as described by
+// WriteTo before any send rate limit is applied, and rate limits are applied
+// to every Message before any are sent. So if any Packet cannot be marshaled
+// or would be dropped by a send rate limit, no Messages are written and no
+// rate limit tokens are taken. If the rate limits delay any Message, the
+// whole batch is sent after the longest delay.
+//
+// On platforms without batch system calls, WriteBatch writes one packet per
+// system call.
+func (c *Conn) WriteBatch(ms []Message) (int, error) {
+	// A pending message tracks the index in ms and control message of each
+	// element of raw, since packets may be dropped or delayed by c.policy.
+	type pending struct {
+		i  int
+		cm *ipv6.ControlMessage
+	}
+
+	var (
+		bs   = make([][]byte, 0, len(ms))
+		cms  = make([]*ipv6.ControlMessage, 0, len(ms))
+		dsts = make([]*net.IPAddr, 0, len(ms))
+	)
+
+	for _, m := range ms {
+		r := trace.StartRegion(context.Background(), traceMarshal)
+		b, err := c.marshal(m.Packet, m.Addr)
+		r.End()
+		if err != nil {
+			return 0, err
+		}
+
+		cm, err := c.controlMessage(m.Packet)
+		if err != nil {
+			return 0, err
+		}
+
+		bs = append(bs, b)
+		cms = append(cms, cm)
+		dsts = append(dsts, m.Addr)
+	}
+
+	if c.limit != nil {
+		if err := c.limit.wait(dsts...); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		raw  = make([]ipv6.Message, 0, len(ms))
+		sent = make([]pending, 0, len(ms))
+	)
+
+	for i, m := range ms {
+		if c.applyPolicy(m.Packet, bs[i], cms[i], m.Addr) {
+			continue
+		}
+
+		raw = append(raw, ipv6.Message{
+			Buffers: [][]byte{bs[i]},
+			OOB:     cms[i].Marshal(),
+			Addr:    m.Addr,
+		})
+		sent = append(sent, pending{i: i, cm: cms[i]})
+	}
+
+	// The kernel may send fewer messages than requested, so continue until
+	// all have been sent.
+	for off := 0; off < len(raw); {
+		n, err := c.c.WriteBatch(raw[off:], 0)
+		if n == 0 && err == nil {
+			err = io.ErrShortWrite
+		}
+		for i, m := range raw[off : off+n] {
+			s := sent[off+i]
+			c.captureWrite(m.Buffers[0], s.cm, ms[s.i].Addr.IP)
+		}
+		off += n
+
+		if err != nil {
+			if off == len(raw) {
+				return len(ms), err
+			}
+
+			return sent[off].i, err
+		}
+	}
+
+	return len(ms), nil
+}
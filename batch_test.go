@@ -0,0 +1,122 @@
+package ospf3
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConnBatch(t *testing.T) {
+	c1, c2 := testConns(t, nil)
+
+	const n = 4
+	ms := make([]Message, 0, n)
+	for i := uint32(1); i <= n; i++ {
+		ms = append(ms, Message{
+			Packet: &Hello{
+				Header:      Header{RouterID: ID{192, 0, 2, 1}},
+				InterfaceID: i,
+				NeighborIDs: []ID{},
+			},
+			Addr: AllSPFRouters,
+		})
+	}
+
+	written, err := c1.WriteBatch(ms)
+	if err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if diff := cmp.Diff(n, written); diff != "" {
+		t.Fatalf("unexpected number of written messages (-want +got):\n%s", diff)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	// The packets may arrive across several batches.
+	var ids []uint32
+	for len(ids) < n {
+		got := make([]Message, n)
+		k, err := c2.ReadBatch(got)
+		if err != nil {
+			t.Fatalf("failed to read batch: %v", err)
+		}
+
+		for _, m := range got[:k] {
+			if m.Control == nil || !m.Control.Dst.Equal(AllSPFRouters.IP) {
+				t.Fatalf("unexpected control message: %+v", m.Control)
+			}
+
+			ids = append(ids, m.Packet.(*Hello).InterfaceID)
+		}
+	}
+
+	if diff := cmp.Diff([]uint32{1, 2, 3, 4}, ids); diff != "" {
+		t.Fatalf("unexpected interface IDs (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnWriteBatchMarshalError(t *testing.T) {
+	c1, _ := testConns(t, nil)
+
+	// The second Packet cannot be marshaled, so none are sent.
+	n, err := c1.WriteBatch([]Message{
+		{Packet: &Hello{}, Addr: AllSPFRouters},
+		{Packet: &Hello{Options: 0xff000000}, Addr: AllSPFRouters},
+	})
+	if err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+	if diff := cmp.Diff(0, n); diff != "" {
+		t.Fatalf("unexpected number of written messages (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnWriteBatchRateLimited(t *testing.T) {
+	// Rate is low enough that no tokens are refilled during the test.
+	c1, _ := testConns(t, &Config{
+		SendLimit: &RateLimit{Rate: 0.001, Burst: 2},
+	})
+
+	hellos := func(n int) []Message {
+		ms := make([]Message, 0, n)
+		for i := 0; i < n; i++ {
+			ms = append(ms, Message{Packet: &Hello{}, Addr: AllSPFRouters})
+		}
+		return ms
+	}
+
+	// A Packet which cannot be marshaled takes no tokens.
+	_, err := c1.WriteBatch(append(hellos(1), Message{
+		Packet: &Hello{Options: 0xff000000},
+		Addr:   AllSPFRouters,
+	}))
+	if err == nil {
+		t.Fatal("expected a marshal error, but none occurred")
+	}
+
+	// Only 2 tokens are available, so none of 3 Messages are sent and no
+	// tokens are taken.
+	n, err := c1.WriteBatch(hellos(3))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected rate limit error, but got: %v", err)
+	}
+	if diff := cmp.Diff(0, n); diff != "" {
+		t.Fatalf("unexpected number of written messages (-want +got):\n%s", diff)
+	}
+
+	n, err = c1.WriteBatch(hellos(2))
+	if err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if diff := cmp.Diff(2, n); diff != "" {
+		t.Fatalf("unexpected number of written messages (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(RateLimitStats{Dropped: 3}, c1.RateLimited()); diff != "" {
+		t.Fatalf("unexpected rate limit stats (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,44 @@
+package ospf3
+
+// A Capability is a bitmask of optional OSPFv3 features which may or may not
+// be supported by this package. Capabilities reports the features which are
+// supported, so callers can validate their configuration against the package's
+// feature set at runtime.
+type Capability uint32
+
+// Possible Capability values.
+const (
+	// AuthenticationTrailer indicates support for the RFC7166 OSPFv3
+	// Authentication Trailer.
+	AuthenticationTrailer Capability = 1 << iota
+
+	// ExtendedLSAs indicates support for the RFC8362 OSPFv3 extended LSA
+	// formats.
+	ExtendedLSAs
+
+	// GracefulRestart indicates support for RFC5187 OSPFv3 graceful restart.
+	GracefulRestart
+
+	// SegmentRouting indicates support for the RFC8666 OSPFv3 Segment Routing
+	// TLVs.
+	SegmentRouting
+)
+
+// Capabilities returns the optional features supported by this package.
+func Capabilities() Capability {
+	// TODO(mdlayher): report more capabilities as they are implemented.
+	return AuthenticationTrailer
+}
+
+// Has reports whether c contains all of the Capability bits in want.
+func (c Capability) Has(want Capability) bool { return c&want == want }
+
+// String returns the string representation of a Capability bitmask.
+func (c Capability) String() string {
+	return flagsString(uint(c), []string{
+		"AuthenticationTrailer",
+		"ExtendedLSAs",
+		"GracefulRestart",
+		"SegmentRouting",
+	})
+}
@@ -0,0 +1,53 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCapability(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Capability
+		want Capability
+		has  bool
+		s    string
+	}{
+		{
+			name: "none",
+			has:  true,
+			s:    "0",
+		},
+		{
+			name: "missing",
+			want: AuthenticationTrailer,
+			s:    "0",
+		},
+		{
+			name: "partial",
+			c:    AuthenticationTrailer | SegmentRouting,
+			want: AuthenticationTrailer | GracefulRestart,
+			s:    "AuthenticationTrailer|SegmentRouting",
+		},
+		{
+			name: "all",
+			c:    AuthenticationTrailer | ExtendedLSAs | GracefulRestart | SegmentRouting,
+			want: ExtendedLSAs | GracefulRestart,
+			has:  true,
+			s:    "AuthenticationTrailer|ExtendedLSAs|GracefulRestart|SegmentRouting",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.has, tt.c.Has(tt.want)); diff != "" {
+				t.Fatalf("unexpected Has result (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.s, tt.c.String()); diff != "" {
+				t.Fatalf("unexpected string (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
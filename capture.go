@@ -0,0 +1,135 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv6"
+)
+
+// A Capturer records the raw OSPFv3 packets sent and received by a Conn, such
+// as to write them to a capture file for later analysis. The pcap package
+// provides a Capturer which writes pcapng files.
+//
+// Capture is called synchronously from Conn.ReadFrom and Conn.WriteTo, and
+// may be called concurrently. Capturers must handle their own errors, since
+// a failure to capture must not disrupt the protocol.
+type Capturer interface {
+	Capture(p *CapturedPacket)
+}
+
+// A CapturedPacket is a raw OSPFv3 packet sent or received by a Conn, along
+// with the IPv6 header parameters needed to synthesize its IPv6 header.
+type CapturedPacket struct {
+	// Time is the time at which the packet was sent or received.
+	Time time.Time
+
+	// Outbound reports whether the packet was sent, rather than received.
+	Outbound bool
+
+	// Source and Destination are the IPv6 addresses of the packet. Source
+	// is nil for sent packets if the interface has no IPv6 link-local
+	// address.
+	Source, Destination net.IP
+
+	// TrafficClass and HopLimit are the IPv6 header parameters of the
+	// packet, or -1 if unknown.
+	TrafficClass, HopLimit int
+
+	// Interface is the name of the network interface used by the Conn.
+	Interface string
+
+	// Data is the OSPFv3 packet, beginning with its Header. It must not be
+	// retained after Capture returns.
+	Data []byte
+}
+
+// A capturer holds a Conn's Capturer, which may be replaced at any time.
+type capturer struct {
+	mu sync.RWMutex
+	c  Capturer
+}
+
+// set replaces the Capturer.
+func (c *capturer) set(cp Capturer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.c = cp
+}
+
+// get returns the current Capturer, or nil if none is set.
+func (c *capturer) get() Capturer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.c
+}
+
+// SetCapturer replaces the Conn's Capturer, as described by Config.Capturer.
+// A nil Capturer stops capturing packets.
+func (c *Conn) SetCapturer(cp Capturer) { c.capture.set(cp) }
+
+// captureRead passes a packet b received from src to the Capturer, if one is
+// set.
+func (c *Conn) captureRead(b []byte, cm *ipv6.ControlMessage, src net.IP) {
+	cp := c.capture.get()
+	if cp == nil {
+		return
+	}
+
+	p := &CapturedPacket{
+		Time:         time.Now(),
+		Source:       src,
+		TrafficClass: -1,
+		HopLimit:     -1,
+		Interface:    c.ifi.Name,
+		Data:         b,
+	}
+	if cm != nil {
+		p.Destination = cm.Dst
+		p.TrafficClass = cm.TrafficClass
+		p.HopLimit = cm.HopLimit
+	}
+
+	cp.Capture(p)
+}
+
+// captureWrite passes a packet b sent to dst to the Capturer, if one is set.
+func (c *Conn) captureWrite(b []byte, cm *ipv6.ControlMessage, dst net.IP) {
+	cp := c.capture.get()
+	if cp == nil {
+		return
+	}
+
+	src := c.src
+	if src == nil {
+		// Best effort: the kernel chooses the source address.
+		src, _ = linkLocal(c.ifi)
+	}
+
+	if !c.userCk && src != nil {
+		// The checksum is computed by the kernel, so compute it on a copy
+		// as well.
+		b = append([]byte(nil), b...)
+		if sum, err := PacketChecksum(b, src, dst); err == nil {
+			binary.BigEndian.PutUint16(b[12:14], sum)
+		}
+	}
+
+	tc := tclass
+	if cm != nil && cm.TrafficClass > 0 {
+		tc = cm.TrafficClass
+	}
+
+	cp.Capture(&CapturedPacket{
+		Time:         time.Now(),
+		Outbound:     true,
+		Source:       src,
+		Destination:  dst,
+		TrafficClass: tc,
+		HopLimit:     hopLimit,
+		Interface:    c.ifi.Name,
+		Data:         b,
+	})
+}
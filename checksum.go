@@ -0,0 +1,170 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// lsaChecksumOffset is the offset of the checksum within an LSA, not including
+// the 2 byte LS age field which is excluded from the checksum.
+const lsaChecksumOffset = 14
+
+// Checksum computes the Fletcher checksum of a LinkStateAdvertisement as
+// described in RFC2328, section 12.1.7. The LSAHeader's Age and Checksum
+// fields are not included in the computation, so the result may be assigned
+// directly to the LSAHeader's Checksum field when originating an LSA.
+func (l *LinkStateAdvertisement) Checksum() (uint16, error) {
+	b := make([]byte, l.len())
+	if err := l.marshal(b); err != nil {
+		return 0, fmt.Errorf("ospf3: failed to marshal LSA: %w", err)
+	}
+
+	// Skip LS age and zero the existing checksum before computing.
+	b = b[2:]
+	b[lsaChecksumOffset] = 0
+	b[lsaChecksumOffset+1] = 0
+
+	return fletcher16(b, lsaChecksumOffset), nil
+}
+
+// verifyLSAChecksums verifies the checksum of each LSA in lsu using the raw
+// LSA bytes in b, which must have been used to parse lsu.
+func verifyLSAChecksums(lsu *LinkStateUpdate, b []byte) error {
+	off := 0
+	for _, l := range lsu.LSAs {
+		n := int(l.Header.Length)
+		if !fletcher16Valid(b[off+2 : off+n]) {
+			return fmt.Errorf("LSA %s from %s has invalid checksum %#04x: %w",
+				l.Header.LSA.Type, l.Header.LSA.AdvertisingRouter, l.Header.Checksum, errParse)
+		}
+
+		off += n
+	}
+
+	return nil
+}
+
+// fletcher16 computes the ISO 8473 Fletcher checksum of b, where the two
+// checksum bytes at offset off in b are set to zero.
+func fletcher16(b []byte, off int) uint16 {
+	c0, c1 := fletcherSums(b)
+
+	// Compute the checksum bytes such that the sums over the entire input,
+	// including the checksum, are zero.
+	x := ((len(b)-off-1)*c0 - c1) % 255
+	if x <= 0 {
+		x += 255
+	}
+
+	y := 510 - c0 - x
+	if y > 255 {
+		y -= 255
+	}
+
+	return uint16(x)<<8 | uint16(y)
+}
+
+// fletcher16Valid reports whether b, including its embedded checksum, has a
+// valid ISO 8473 Fletcher checksum.
+func fletcher16Valid(b []byte) bool {
+	c0, c1 := fletcherSums(b)
+	return c0 == 0 && c1 == 0
+}
+
+// fletcherSums computes the two running sums of the Fletcher checksum.
+func fletcherSums(b []byte) (c0, c1 int) {
+	for _, v := range b {
+		c0 = (c0 + int(v)) % 255
+		c1 = (c1 + c0) % 255
+	}
+
+	return c0, c1
+}
+
+// ipProtocolOSPF is the IP protocol number for OSPF.
+const ipProtocolOSPF = 89
+
+// PacketChecksum computes the OSPFv3 packet checksum of the OSPFv3 packet bytes
+// in b using the IPv6 pseudo-header with the source and destination addresses
+// src and dst, as described in RFC5340, appendix A.3.1. The existing Header
+// checksum in b is treated as zero, so the result may be assigned directly to
+// the Header's Checksum field.
+//
+// Most operating systems compute the checksum in the kernel, but
+// PacketChecksum may be used when packets are sent or received by other means.
+func PacketChecksum(b []byte, src, dst net.IP) (uint16, error) {
+	_, _, plen, err := parseHeader(b)
+	if err != nil {
+		return 0, fmt.Errorf("ospf3: failed to parse Header: %w", err)
+	}
+
+	sum, err := pseudoHeaderSum(src, dst, plen)
+	if err != nil {
+		return 0, fmt.Errorf("ospf3: %w", err)
+	}
+
+	// Skip the existing checksum.
+	sum = onesSum(sum, b[:12])
+	sum = onesSum(sum, b[14:plen])
+
+	return ^fold(sum), nil
+}
+
+// verifyPacketChecksum verifies the OSPFv3 packet checksum of b, which has
+// packet length plen, using the IPv6 pseudo-header.
+func verifyPacketChecksum(b []byte, src, dst net.IP, plen int) error {
+	sum, err := pseudoHeaderSum(src, dst, plen)
+	if err != nil {
+		return err
+	}
+
+	if fold(onesSum(sum, b[:plen])) != 0xffff {
+		return fmt.Errorf("invalid packet checksum %#04x: %w",
+			binary.BigEndian.Uint16(b[12:14]), errParse)
+	}
+
+	return nil
+}
+
+// pseudoHeaderSum computes the ones' complement sum of the IPv6 pseudo-header
+// for an OSPFv3 packet of length plen, as described in RFC8200, section 8.1.
+func pseudoHeaderSum(src, dst net.IP, plen int) (uint32, error) {
+	for _, ip := range []net.IP{src, dst} {
+		if ip.To16() == nil || ip.To4() != nil {
+			return 0, fmt.Errorf("checksum requires IPv6 addresses, but got: %q", ip)
+		}
+	}
+
+	var b [40]byte
+	copy(b[0:16], src.To16())
+	copy(b[16:32], dst.To16())
+	binary.BigEndian.PutUint32(b[32:36], uint32(plen))
+	// b[36:39] are zero.
+	b[39] = ipProtocolOSPF
+
+	return onesSum(0, b[:]), nil
+}
+
+// onesSum adds the 16-bit big endian words of b to sum, padding an odd length
+// b with a trailing zero byte.
+func onesSum(sum uint32, b []byte) uint32 {
+	for len(b) >= 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[:2]))
+		b = b[2:]
+	}
+	if len(b) == 1 {
+		sum += uint32(b[0]) << 8
+	}
+
+	return sum
+}
+
+// fold folds the carries of a 32-bit ones' complement sum into 16 bits.
+func fold(sum uint32) uint16 {
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+
+	return uint16(sum)
+}
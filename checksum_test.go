@@ -0,0 +1,165 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLinkStateAdvertisementChecksum(t *testing.T) {
+	l := LinkStateAdvertisement{
+		Header: LSAHeader{
+			Age: 1 * time.Second,
+			LSA: LSA{
+				Type:              RouterLSA,
+				AdvertisingRouter: ID{192, 0, 2, 1},
+			},
+			SequenceNumber: 0x80000001,
+		},
+		Body: lsaRouterLSABody,
+	}
+
+	c1, err := l.Checksum()
+	if err != nil {
+		t.Fatalf("failed to compute checksum: %v", err)
+	}
+
+	// Neither LS age nor the existing checksum are covered by the checksum.
+	l.Header.Age = MaxAge
+	l.Header.Checksum = 0xffff
+
+	c2, err := l.Checksum()
+	if err != nil {
+		t.Fatalf("failed to compute second checksum: %v", err)
+	}
+
+	if diff := cmp.Diff(c1, c2); diff != "" {
+		t.Fatalf("unexpected checksum (-want +got):\n%s", diff)
+	}
+
+	// The checksummed LSA must pass verification.
+	l.Header.Checksum = c1
+	b := make([]byte, l.len())
+	if err := l.marshal(b); err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if !fletcher16Valid(b[2:]) {
+		t.Fatalf("checksum %#04x did not verify", c1)
+	}
+
+	// Any change to the contents must invalidate the checksum.
+	b[len(b)-1]++
+	if fletcher16Valid(b[2:]) {
+		t.Fatal("corrupted LSA passed checksum verification")
+	}
+}
+
+func TestParseOptionsVerifyLSAChecksums(t *testing.T) {
+	lsu := &LinkStateUpdate{
+		Header: Header{RouterID: ID{192, 0, 2, 1}},
+		LSAs: []LinkStateAdvertisement{
+			{
+				Header: LSAHeader{
+					LSA: LSA{
+						Type:              RouterLSA,
+						AdvertisingRouter: ID{192, 0, 2, 1},
+					},
+					SequenceNumber: 0x80000001,
+				},
+				Body: lsaRouterLSABody,
+			},
+			{
+				Header: LSAHeader{
+					LSA: LSA{
+						Type:              LinkLSA,
+						LinkStateID:       ID{0, 0, 0, 1},
+						AdvertisingRouter: ID{192, 0, 2, 1},
+					},
+					SequenceNumber: 0x80000002,
+				},
+				Body: lsaLinkLSABody,
+			},
+		},
+	}
+
+	for i := range lsu.LSAs {
+		c, err := lsu.LSAs[i].Checksum()
+		if err != nil {
+			t.Fatalf("failed to compute checksum: %v", err)
+		}
+		lsu.LSAs[i].Header.Checksum = c
+	}
+
+	b, err := MarshalPacket(lsu)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	opts := ParseOptions{VerifyLSAChecksums: true}
+	if _, err := opts.ParsePacket(b); err != nil {
+		t.Fatalf("failed to parse with valid checksums: %v", err)
+	}
+
+	// Corrupt the final byte of the last LSA, which only verification will
+	// detect.
+	b[len(b)-1]++
+
+	if _, err := ParsePacket(b); err != nil {
+		t.Fatalf("failed to parse without verification: %v", err)
+	}
+
+	_, err = opts.ParsePacket(b)
+	if diff := cmp.Diff(errParse, err, cmpopts.EquateErrors()); diff != "" {
+		t.Fatalf("unexpected error (-want +got):\n%s", diff)
+	}
+
+	t.Logf("err: %v", err)
+}
+
+func TestPacketChecksum(t *testing.T) {
+	var (
+		src = net.ParseIP("fe80::1")
+		dst = AllSPFRouters.IP
+	)
+
+	b, err := MarshalOptions{Source: src, Destination: dst}.MarshalPacket(pktHello)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	// The Packet itself must not be modified, but the checksum is inserted in
+	// the output bytes.
+	if diff := cmp.Diff(pktHello.Header.Checksum, binary.BigEndian.Uint16(b[12:14])); diff == "" {
+		t.Fatal("checksum was not computed")
+	}
+
+	c, err := PacketChecksum(b, src, dst)
+	if err != nil {
+		t.Fatalf("failed to compute checksum: %v", err)
+	}
+	if diff := cmp.Diff(binary.BigEndian.Uint16(b[12:14]), c); diff != "" {
+		t.Fatalf("unexpected checksum (-want +got):\n%s", diff)
+	}
+
+	opts := ParseOptions{Source: src, Destination: dst}
+	if _, err := opts.ParsePacket(b); err != nil {
+		t.Fatalf("failed to parse with valid checksum: %v", err)
+	}
+
+	// The checksum covers the pseudo-header, so a different source address
+	// must fail verification.
+	opts.Source = net.ParseIP("fe80::2")
+	_, err = opts.ParsePacket(b)
+	if diff := cmp.Diff(errParse, err, cmpopts.EquateErrors()); diff != "" {
+		t.Fatalf("unexpected error (-want +got):\n%s", diff)
+	}
+
+	if _, err := PacketChecksum(b, net.IPv4(192, 0, 2, 1), dst); err == nil {
+		t.Fatal("expected an error for an IPv4 address")
+	}
+}
@@ -1,9 +1,17 @@
 package ospf3
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"net"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/net/bpf"
 	"golang.org/x/net/ipv6"
 )
 
@@ -26,119 +34,1134 @@ var (
 // A Conn can send and receive OSPFv3 packets which implement the Packet
 // interface.
 type Conn struct {
+	// Counters are accessed atomically and must be the first fields for
+	// 64-bit alignment.
+	reserved, truncated, seq, unauthenticated, wrongArea uint64
+
+	rejected ReceiveCheckErrors
+
 	c      *ipv6.PacketConn
+	icmp   *ipv6.PacketConn
 	ifi    *net.Interface
+	keys   *Keychain
+	replay *replayGuard
+	src    net.IP
+	pinSrc bool
+	userCk bool
 	groups []*net.IPAddr
+	dscp   func(p Packet) uint8
+	delay  time.Duration
+	dups   *dedup
+	areas  map[ID]struct{}
+	insts  map[uint8]struct{}
+	limit  *rateLimiter
+	checks bool
+	parse  ParseOptions
+	size   uint32
+
+	// unicast disables multicast sends and receives. neighbors, if set,
+	// replaces multicast sends on NBMA interfaces.
+	unicast   bool
+	neighbors []*net.IPAddr
+
+	onParseError func(b []byte, src *net.IPAddr, err error)
+
+	capture capturer
+
+	// expvar reports whether the Conn's counters are published.
+	expvar bool
+
+	// followMTU allows size to grow with the interface MTU after Listen.
+	// checked is the last time the MTU was queried, guarded by mu.
+	followMTU bool
+	mu        sync.Mutex
+	checked   time.Time
+
+	// policy, if set, is consulted before each write. It is only set by
+	// tests to inject latency or loss on real interfaces.
+	policy writePolicy
 }
 
-// Listen creates a *Conn using the specified network interface.
-func Listen(ifi *net.Interface) (*Conn, error) {
-	// IP protocol number 89 is OSPF.
-	conn, err := net.ListenPacket("ip6:89", "::")
+// A writePolicy decides the fate of a Packet about to be written to dst. It
+// returns a delay before the Packet is sent, or true to silently drop it.
+type writePolicy func(p Packet, dst *net.IPAddr) (delay time.Duration, drop bool)
+
+// Config contains optional parameters for a Conn. A nil *Config applies the
+// default values for each field.
+type Config struct {
+	// DSCP optionally selects the IPv6 Differentiated Services Code Point used
+	// for each outgoing Packet, such as to mark Hellos differently from bulk
+	// flooding. Return values must fit in 6 bits. A return value of 0 or a nil
+	// function selects the default of CS6, per appendix A.1.
+	DSCP func(p Packet) uint8
+
+	// Timers optionally sets the protocol timers for the interface, which
+	// must satisfy Timers.Validate. Its InfTransDelay is added to the age of
+	// each LSA sent in a LinkStateUpdate, saturating at MaxAge. Pass the same
+	// Timers to NewHelloProtocol to run the Hello Protocol on the Conn. If
+	// nil, DefaultTimers is used.
+	Timers *Timers
+
+	// DuplicateWindow optionally enables suppression of received packets
+	// which are byte-for-byte duplicates of a packet received from the same
+	// source address within the window, such as those produced by bridged
+	// topologies or mirrored capture setups. Suppressed packets are counted
+	// by Conn.Duplicates. If zero, duplicate suppression is disabled.
+	DuplicateWindow time.Duration
+
+	// Strict enables discarding of received packets which contain nonzero
+	// values in reserved fields or unexpected trailing bytes, as described by
+	// ParseOptions.Strict. Discarded packets are counted by
+	// Conn.ReservedFieldErrors.
+	Strict bool
+
+	// MaxPacketSize optionally sets the size in bytes of the largest packet
+	// accepted by ReadFrom, independent of the interface MTU, such as for
+	// interfaces with jumbo frames or a misreported MTU. Larger packets are
+	// discarded rather than parsed partially, and are counted by
+	// Conn.Truncated. If zero, the interface MTU is used, and is raised if
+	// an oversized packet arrives after the MTU is increased. Packets
+	// reassembled from fragments may exceed the MTU, so set MaxPacketSize to
+	// accept them.
+	MaxPacketSize int
+
+	// ICMPErrors enables receiving ICMPv6 error messages triggered by
+	// unicast OSPFv3 packets sent on the interface, such as for virtual links
+	// or unicast flooding to a misconfigured neighbor. Errors are read using
+	// Conn.ReadICMPError.
+	ICMPErrors bool
+
+	// Keychain optionally enables the Authentication Trailer described in
+	// RFC7166. Each outgoing packet is authenticated using the Keychain's
+	// current send Key, and received packets which are not authenticated by
+	// a Key are discarded. Hellos and DatabaseDescriptions must set ATBit in
+	// their Options. The interface must have an IPv6 link-local address.
+	//
+	// Received packets must also carry a cryptographic sequence number
+	// greater than that of the last packet authenticated from the same
+	// neighbor, or they are discarded as replays. Discarded packets are
+	// counted by Conn.Unauthenticated and Conn.Replays.
+	Keychain *Keychain
+
+	// Store optionally persists protocol state across restarts. If set with
+	// Keychain, a boot count for the interface is incremented in the Store on
+	// each successful ListenConfig and used as the high 32 bits of the
+	// cryptographic sequence number, as recommended by RFC7166, section 4.1.
+	// Otherwise the clock is used in its place. The boot count is never lower
+	// than the clock, so a Store may be enabled without neighbors discarding
+	// packets as replays. ListenConfig returns an error if the boot count
+	// would wrap, in which case the Keychain's keys must be changed and the
+	// boot count reset by deleting the interface name key from the
+	// "boot-count" bucket.
+	Store Store
+
+	// Capturer optionally receives a copy of every OSPFv3 packet sent or
+	// received by the Conn, such as to write a capture file using the pcap
+	// package. Received packets are captured before they are parsed, so
+	// packets which are later discarded are also captured. The Capturer may
+	// be replaced or removed using Conn.SetCapturer.
+	Capturer Capturer
+
+	// Areas optionally restricts received packets to those with an Area ID
+	// in the list, as described in RFC2328, section 8.2. Packets from other
+	// areas are discarded before they are parsed, and are counted by
+	// Conn.WrongArea. If empty, packets from all areas are accepted.
+	Areas []ID
+
+	// Unicast restricts the Conn to unicast OSPFv3 packets, such as for
+	// virtual links or tunnels without multicast support. No multicast groups
+	// are joined, received multicast packets are discarded, and writes to a
+	// multicast address return an error. Packets are sent with the system's
+	// default hop limit rather than 1, as virtual link neighbors may be
+	// several hops away per RFC5340, appendix A.1.
+	Unicast bool
+
+	// NBMANeighbors optionally configures the addresses of the neighbors on
+	// an NBMA interface, as described in RFC2328, section 9.5.1. It implies
+	// Unicast, except that packets written to AllSPFRouters or AllDRouters
+	// are sent to each neighbor in turn. Use an NBMAPoller to send Hellos to
+	// neighbors which are down at the PollInterval.
+	NBMANeighbors []*net.IPAddr
+
+	// NetworkType optionally sets the type of network the interface is
+	// attached to. AllDRouters is only joined on a BroadcastNetwork, and
+	// NBMANetwork requires NBMANeighbors. If zero, the interface is treated
+	// as a PointToPointNetwork if it has the point-to-point flag and as a
+	// BroadcastNetwork otherwise.
+	NetworkType NetworkType
+
+	// Source optionally pins the IPv6 source address of outgoing packets,
+	// such as on interfaces with multiple link-local addresses, since
+	// neighbors identify each other by source address. It must be assigned
+	// to the interface. If nil, the kernel chooses the source address.
+	Source net.IP
+
+	// VRF optionally binds the Conn's sockets to a VRF, such as to run one
+	// OSPFv3 speaker per VRF. On Linux, VRF names a VRF device which is set
+	// with SO_BINDTODEVICE, and ifi must be enslaved to it. On FreeBSD and
+	// OpenBSD, VRF is the number of a FIB or routing table which is set with
+	// SO_SETFIB or SO_RTABLE, respectively. Not supported on other platforms.
+	VRF string
+
+	// Namespace optionally sets the path of a network namespace, such as
+	// "/run/netns/blue", in which the Conn's sockets are opened. ifi must
+	// describe an interface in that namespace, and its addresses are also
+	// looked up within it. Only supported on Linux.
+	Namespace string
+
+	// OnParseError is optionally called by ReadFrom for each received packet
+	// which is discarded because it could not be parsed or authenticated,
+	// such as to log malformed packets from a misbehaving neighbor. b is a
+	// copy of the packet which the function may retain. It is called
+	// synchronously and should not block.
+	OnParseError func(b []byte, src *net.IPAddr, err error)
+
+	// InstanceIDs optionally restricts received packets to those with an
+	// Instance ID in the list, as described in RFC5340, section 4.2.2.
+	// Packets from other instances are discarded and counted by
+	// Conn.ReceiveCheckErrors. If empty, packets from all instances are
+	// accepted.
+	InstanceIDs []uint8
+
+	// SkipReceiveChecks disables the checks applied to the IPv6 header of
+	// each received packet, as described in RFC5340, section 4.2.2, such as
+	// for passive monitoring of misbehaving routers. By default, packets must
+	// be sent from a link-local address with a hop limit of 1, to a unicast
+	// address or to AllSPFRouters or AllDRouters. In Unicast mode, the source
+	// address and hop limit are not checked, as virtual link neighbors may be
+	// several hops away. Discarded packets are counted by
+	// Conn.ReceiveCheckErrors.
+	SkipReceiveChecks bool
+
+	// SendLimit and SendLimitPerDestination optionally limit the rate of
+	// outgoing packets overall and to each destination address, such as to
+	// keep retransmissions or large floods from saturating a slow link. A
+	// write which exceeds a limit waits for up to the limit's MaxDelay, or
+	// is dropped with ErrRateLimited. Affected packets are counted by
+	// Conn.RateLimited.
+	SendLimit, SendLimitPerDestination *RateLimit
+
+	// Filter optionally attaches a classic BPF program to the Conn's socket
+	// to discard unwanted packets in the kernel, such as to reduce wakeups
+	// on busy links. Use NewFilter to create a program which accepts only
+	// OSPFv3 packets from specific areas or instances. Only supported on
+	// Linux.
+	Filter []bpf.RawInstruction
+
+	// Expvar optionally publishes the Conn's counters through package expvar
+	// until the Conn is closed, for programs which do not collect them
+	// otherwise. Each counter is published as a variable such as
+	// "ospf3.truncated" or "ospf3.rejected_hop_limit", which maps interface
+	// names to the sum of the counter for all Conns on that interface with
+	// Expvar set. No variables are published unless a Conn sets Expvar.
+	Expvar bool
+}
+
+// Listen creates a *Conn using the specified network interface and a default
+// configuration. Use ListenConfig to configure the Conn.
+func Listen(ifi *net.Interface) (*Conn, error) { return ListenConfig(ifi, nil) }
+
+// ListenConfig creates a *Conn using the specified network interface and cfg.
+// If cfg is nil, a default configuration is used.
+func ListenConfig(ifi *net.Interface, cfg *Config) (*Conn, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	if cfg.Namespace == "" {
+		return listen(ifi, cfg)
+	}
+
+	var c *Conn
+	err := inNamespace(cfg.Namespace, func() error {
+		var err error
+		c, err = listen(ifi, cfg)
+		return err
+	})
+
+	return c, err
+}
+
+// listen implements ListenConfig within the current network namespace.
+func listen(ifi *net.Interface, cfg *Config) (_ *Conn, err error) {
+	timers := DefaultTimers()
+	if cfg.Timers != nil {
+		if err := cfg.Timers.Validate(); err != nil {
+			return nil, err
+		}
+		timers = *cfg.Timers
+	}
+
+	size, followMTU := cfg.MaxPacketSize, cfg.MaxPacketSize == 0
+	switch {
+	case size < 0:
+		return nil, fmt.Errorf("ospf3: invalid MaxPacketSize: %d", size)
+	case size == 0:
+		size = ifi.MTU
+	}
+	if size <= 0 || size > math.MaxUint16 {
+		// The OSPFv3 packet length field is 16 bits.
+		size = math.MaxUint16
+	}
+
+	limit, err := newRateLimiter(cfg.SendLimit, cfg.SendLimitPerDestination, time.Now)
 	if err != nil {
 		return nil, err
 	}
-	c := ipv6.NewPacketConn(conn)
 
-	// Return all possible control message information to the caller so they
-	// can make more informed choices.
-	if err := c.SetControlMessage(^ipv6.ControlFlags(0), true); err != nil {
-		return nil, err
+	// NBMA interfaces send copies of multicast packets to each neighbor.
+	var neighbors []*net.IPAddr
+	for _, n := range cfg.NBMANeighbors {
+		if n == nil || n.IP.To16() == nil || n.IP.To4() != nil || n.IP.IsMulticast() {
+			return nil, fmt.Errorf("ospf3: invalid NBMA neighbor address: %v", n)
+		}
+
+		nn := *n
+		if nn.Zone == "" && nn.IP.IsLinkLocalUnicast() {
+			nn.Zone = ifi.Name
+		}
+		neighbors = append(neighbors, &nn)
 	}
+	unicast := cfg.Unicast || len(neighbors) > 0
 
-	// Process checksums in the OSPFv3 header.
-	if err := c.SetChecksum(true, 12); err != nil {
-		return nil, err
+	nt := cfg.NetworkType
+	switch {
+	case nt == 0 && ifi.Flags&net.FlagPointToPoint != 0:
+		nt = PointToPointNetwork
+	case nt == 0:
+		nt = BroadcastNetwork
+	case nt > PointToMultipointNetwork:
+		return nil, fmt.Errorf("ospf3: invalid NetworkType: %d", nt)
+	case nt == NBMANetwork && len(neighbors) == 0:
+		return nil, errors.New("ospf3: NBMANetwork requires NBMANeighbors")
 	}
 
-	// Set IPv6 header parameters per the RFC.
-	if err := c.SetHopLimit(hopLimit); err != nil {
-		return nil, err
+	// The Authentication Trailer is covered by the packet's authentication
+	// data but not by its checksum, so the source address is needed to
+	// compute both in userspace.
+	var src net.IP
+	switch {
+	case cfg.Source != nil:
+		if err := checkSource(ifi, cfg.Source); err != nil {
+			return nil, err
+		}
+		src = cfg.Source
+	case cfg.Keychain != nil:
+		var err error
+		if src, err = linkLocal(ifi); err != nil {
+			return nil, err
+		}
 	}
-	if err := c.SetMulticastHopLimit(hopLimit); err != nil {
+
+	// IP protocol number 89 is OSPF.
+	conn, err := listenPacket("ip6:89", cfg.VRF)
+	if err != nil {
 		return nil, err
 	}
-	if err := c.SetTrafficClass(tclass); err != nil {
+	c := ipv6.NewPacketConn(conn)
+
+	// Close the sockets opened so far if any later step fails.
+	var icmp *ipv6.PacketConn
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		_ = c.Close()
+		if icmp != nil {
+			_ = icmp.Close()
+		}
+	}()
+
+	groups, userCk, err := configureSocket(c, socketConfig{
+		ifi:     ifi,
+		nt:      nt,
+		unicast: unicast,
+		auth:    cfg.Keychain != nil,
+		filter:  cfg.Filter,
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Join the appropriate multicast groups. Note that point-to-point links
-	// don't use DR/BDR and can skip joining that group.
-	if err := c.SetMulticastInterface(ifi); err != nil {
-		return nil, err
+	// The source address is pinned when checksums are computed in userspace
+	// so that it matches the checksum's pseudo-header.
+	if userCk && src == nil {
+		if src, err = linkLocal(ifi); err != nil {
+			return nil, err
+		}
+	}
+
+	var areas map[ID]struct{}
+	if len(cfg.Areas) > 0 {
+		areas = make(map[ID]struct{}, len(cfg.Areas))
+		for _, a := range cfg.Areas {
+			areas[a] = struct{}{}
+		}
 	}
 
-	groups := []*net.IPAddr{AllSPFRouters}
-	if ifi.Flags&net.FlagPointToPoint == 0 {
-		groups = append(groups, AllDRouters)
+	var insts map[uint8]struct{}
+	if len(cfg.InstanceIDs) > 0 {
+		insts = make(map[uint8]struct{}, len(cfg.InstanceIDs))
+		for _, id := range cfg.InstanceIDs {
+			insts[id] = struct{}{}
+		}
 	}
 
-	for _, g := range groups {
-		if err := c.JoinGroup(ifi, g); err != nil {
+	var dups *dedup
+	if cfg.DuplicateWindow > 0 {
+		dups = newDedup(cfg.DuplicateWindow, time.Now)
+	}
+
+	var replay *replayGuard
+	if cfg.Keychain != nil {
+		replay = newReplayGuard()
+	}
+
+	if cfg.ICMPErrors {
+		if icmp, err = listenICMP(ifi, cfg.VRF); err != nil {
 			return nil, err
 		}
 	}
 
-	// Don't read our own multicast packets during concurrent read/write.
-	if err := c.SetMulticastLoopback(false); err != nil {
-		return nil, err
+	// The high 32 bits of the cryptographic sequence number must increase
+	// across restarts. The boot count is incremented last so that a failed
+	// ListenConfig does not consume one.
+	now := time.Now()
+	seq := uint64(now.Unix()) << 32
+	if cfg.Keychain != nil && cfg.Store != nil {
+		boot, err := nextBootCount(cfg.Store, ifi.Name, now)
+		if err != nil {
+			return nil, err
+		}
+		seq = uint64(boot) << 32
 	}
 
-	return &Conn{
+	oc := &Conn{
+		seq:    seq,
 		c:      c,
+		icmp:   icmp,
+		keys:   cfg.Keychain,
+		replay: replay,
+		src:    src,
+		pinSrc: cfg.Source != nil || (userCk && cfg.Keychain == nil),
+		userCk: userCk,
 		ifi:    ifi,
 		groups: groups,
-	}, nil
+		dscp:   cfg.DSCP,
+		delay:  timers.InfTransDelay,
+		dups:   dups,
+		areas:  areas,
+		insts:  insts,
+		limit:  limit,
+		checks: !cfg.SkipReceiveChecks,
+		parse:  ParseOptions{Strict: cfg.Strict},
+		size:   uint32(size),
+
+		unicast:   unicast,
+		neighbors: neighbors,
+		capture:   capturer{c: cfg.Capturer},
+
+		onParseError: cfg.OnParseError,
+
+		expvar: cfg.Expvar,
+
+		followMTU: followMTU,
+	}
+	if oc.expvar {
+		publishExpvar(oc)
+	}
+
+	return oc, nil
 }
 
 // Close closes the Conn's underlying network connection.
 func (c *Conn) Close() error {
+	if c.expvar {
+		unpublishExpvar(c)
+	}
+
 	for _, g := range c.groups {
 		if err := c.c.LeaveGroup(c.ifi, g); err != nil {
 			return err
 		}
 	}
 
+	if c.icmp != nil {
+		if err := c.icmp.Close(); err != nil {
+			return err
+		}
+	}
+
 	return c.c.Close()
 }
 
-// SetReadDeadline sets the read deadline associated with the Conn.
+// SetReadDeadline sets the read deadline associated with the Conn, which
+// applies to both ReadFrom and ReadICMPError.
 func (c *Conn) SetReadDeadline(t time.Time) error {
+	if c.icmp != nil {
+		if err := c.icmp.SetReadDeadline(t); err != nil {
+			return err
+		}
+	}
+
 	return c.c.SetReadDeadline(t)
 }
 
+// Region names used to annotate Conn operations in runtime/trace output.
+const (
+	traceParse   = "ospf3.parse"
+	traceMarshal = "ospf3.marshal"
+)
+
 // ReadFrom reads a single OSPFv3 packet and returns a Packet along with its
 // associated IPv6 control message and source address. ReadFrom will block until
-// a timeout occurs or a valid OSPFv3 packet is read.
+// a timeout occurs or a valid OSPFv3 packet is read. Packets which exceed the
+// maximum packet size are discarded. If Config.DuplicateWindow is set,
+// duplicate packets are also discarded. Packets which cannot be parsed are
+// discarded and reported to Config.OnParseError, if set.
+//
+// When runtime/trace is enabled, packet parsing is annotated with the
+// "ospf3.parse" region so its CPU cost can be attributed in execution traces.
 func (c *Conn) ReadFrom() (Packet, *ipv6.ControlMessage, *net.IPAddr, error) {
-	b := make([]byte, c.ifi.MTU)
+	return c.readFrom(make([]byte, c.BufferSize()), true)
+}
+
+// BufferSize returns the size in bytes of a buffer for ReadFromBuf which can
+// hold a packet of the maximum packet size. One extra byte is included so that
+// a packet which exceeds the maximum size can be detected, rather than
+// silently truncated by the kernel.
+//
+// The maximum packet size may grow if the interface MTU is raised after
+// Listen, so callers which reuse buffers should check BufferSize again when
+// Truncated increases.
+func (c *Conn) BufferSize() int { return c.maxSize() + 1 }
+
+// maxSize returns the maximum packet size.
+func (c *Conn) maxSize() int { return int(atomic.LoadUint32(&c.size)) }
+
+// ReadFromBuf is like ReadFrom, but reads packets into b rather than
+// allocating a new buffer on each call, so that long-running readers can
+// reuse a single buffer. The returned Packet does not reference b, so b may be
+// reused as soon as ReadFromBuf returns.
+//
+// b should be at least BufferSize bytes. Packets which fill b entirely may
+// have been truncated, and are discarded and counted by Truncated.
+func (c *Conn) ReadFromBuf(b []byte) (Packet, *ipv6.ControlMessage, *net.IPAddr, error) {
+	if len(b) <= headerLen {
+		return nil, nil, nil, fmt.Errorf("ospf3: read buffer of %d bytes is too small", len(b))
+	}
+
+	return c.readFrom(b, false)
+}
+
+// readFrom implements ReadFromBuf. If grow is set, b is replaced by a larger
+// buffer when the maximum packet size grows.
+func (c *Conn) readFrom(b []byte, grow bool) (Packet, *ipv6.ControlMessage, *net.IPAddr, error) {
 	for {
 		n, cm, src, err := c.c.ReadFrom(b)
 		if err != nil {
 			return nil, nil, nil, err
 		}
 
-		p, err := ParsePacket(b[:n])
-		if err != nil {
-			// Assume invalid OSPFv3 data, keep reading.
+		if n == len(b) {
+			c.discardTruncated()
+			if size := c.BufferSize(); grow && size > len(b) {
+				b = make([]byte, size)
+			}
+			continue
+		}
+
+		ip := src.(*net.IPAddr)
+		p, ok := c.receive(b[:n], cm, ip)
+		if !ok {
 			continue
 		}
 
-		return p, cm, src.(*net.IPAddr), nil
+		return p, cm, ip, nil
+	}
+}
+
+// receive processes a packet b received from src, returning the parsed Packet
+// or false if the packet was discarded. b must not have filled its buffer, so
+// that oversized packets are not mistaken for valid ones.
+func (c *Conn) receive(b []byte, cm *ipv6.ControlMessage, src *net.IPAddr) (Packet, bool) {
+	if len(b) > c.maxSize() {
+		c.discardTruncated()
+		return nil, false
+	}
+
+	c.captureRead(b, cm, src.IP)
+
+	if c.unicast && cm != nil && cm.Dst.IsMulticast() {
+		return nil, false
+	}
+
+	if c.dups != nil && c.dups.seen(src, b) {
+		return nil, false
+	}
+
+	if c.areas != nil && len(b) >= headerLen {
+		// Check the Area ID before doing the work of parsing.
+		var area ID
+		copy(area[:], b[8:12])
+		if _, ok := c.areas[area]; !ok {
+			atomic.AddUint64(&c.wrongArea, 1)
+			return nil, false
+		}
+	}
+
+	if !c.check(b, cm, src) {
+		return nil, false
+	}
+
+	o := c.parse
+	if c.userCk {
+		o.Source = src.IP
+		if cm != nil {
+			o.Destination = cm.Dst
+		}
+	}
+	switch {
+	case c.keys != nil:
+		o.Authenticator = c.keys
+	case c.userCk && o.Destination == nil:
+		// The checksum can't be verified without the destination address.
+		return nil, false
+	}
+
+	r := trace.StartRegion(context.Background(), traceParse)
+	p, err := o.ParsePacket(b)
+	r.End()
+	if err != nil {
+		var (
+			rerr *ReservedFieldError
+			lerr *LengthError
+		)
+		switch {
+		case errors.As(err, &rerr), errors.As(err, &lerr):
+			atomic.AddUint64(&c.reserved, 1)
+		case errors.Is(err, errAuth):
+			atomic.AddUint64(&c.unauthenticated, 1)
+		}
+
+		if c.onParseError != nil {
+			c.onParseError(append([]byte(nil), b...), src, err)
+		}
+
+		// Assume invalid OSPFv3 data.
+		return nil, false
+	}
+
+	if c.replay != nil {
+		// The trailer was already verified while parsing.
+		t, err := ParseAuthTrailer(b)
+		if err != nil || !c.replay.accept(src, t.SequenceNumber) {
+			return nil, false
+		}
+	}
+
+	return p, true
+}
+
+// check applies the receive checks for packet b from src, and reports whether
+// the packet should be accepted.
+func (c *Conn) check(b []byte, cm *ipv6.ControlMessage, src *net.IPAddr) bool {
+	if c.insts != nil && len(b) >= headerLen {
+		if _, ok := c.insts[b[14]]; !ok {
+			atomic.AddUint64(&c.rejected.WrongInstance, 1)
+			return false
+		}
+	}
+
+	if !c.checks {
+		return true
+	}
+
+	if !c.unicast {
+		if !src.IP.IsLinkLocalUnicast() {
+			atomic.AddUint64(&c.rejected.Source, 1)
+			return false
+		}
+
+		// The hop limit is only known if reported by the kernel.
+		if cm != nil && cm.HopLimit > 0 && cm.HopLimit != hopLimit {
+			atomic.AddUint64(&c.rejected.HopLimit, 1)
+			return false
+		}
+	}
+
+	if cm != nil && cm.Dst.IsMulticast() &&
+		!cm.Dst.Equal(AllSPFRouters.IP) && !cm.Dst.Equal(AllDRouters.IP) {
+		atomic.AddUint64(&c.rejected.Destination, 1)
+		return false
+	}
+
+	return true
+}
+
+// ReceiveCheckErrors contains the number of received packets which have been
+// discarded by each of the receive checks described by
+// Config.SkipReceiveChecks and Config.InstanceIDs.
+type ReceiveCheckErrors struct {
+	// Source counts packets which were not sent from a link-local address.
+	Source uint64
+
+	// HopLimit counts packets which were not received with a hop limit of 1.
+	HopLimit uint64
+
+	// Destination counts packets which were sent to a multicast group other
+	// than AllSPFRouters or AllDRouters.
+	Destination uint64
+
+	// WrongInstance counts packets with an Instance ID which is not in
+	// Config.InstanceIDs.
+	WrongInstance uint64
+}
+
+// ReceiveCheckErrors returns the number of received packets which have been
+// discarded by each receive check.
+func (c *Conn) ReceiveCheckErrors() ReceiveCheckErrors {
+	return ReceiveCheckErrors{
+		Source:        atomic.LoadUint64(&c.rejected.Source),
+		HopLimit:      atomic.LoadUint64(&c.rejected.HopLimit),
+		Destination:   atomic.LoadUint64(&c.rejected.Destination),
+		WrongInstance: atomic.LoadUint64(&c.rejected.WrongInstance),
+	}
+}
+
+// RateLimited returns the number of packets which have been delayed or dropped
+// by Config.SendLimit and Config.SendLimitPerDestination.
+func (c *Conn) RateLimited() RateLimitStats {
+	if c.limit == nil {
+		return RateLimitStats{}
+	}
+
+	return c.limit.snapshot()
+}
+
+// Duplicates returns the number of received packets which have been discarded
+// as duplicates. It always returns 0 if Config.DuplicateWindow is not set.
+func (c *Conn) Duplicates() uint64 {
+	if c.dups == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&c.dups.count)
+}
+
+// mtuCheckInterval limits how often a truncated read queries the interface
+// MTU, so that a flood of oversized packets cannot cause a flood of queries.
+const mtuCheckInterval = 1 * time.Second
+
+// discardTruncated counts a packet discarded for exceeding the maximum packet
+// size. If the size follows the interface MTU, the MTU is checked in case it
+// has been raised since Listen so that later packets are accepted.
+func (c *Conn) discardTruncated() {
+	atomic.AddUint64(&c.truncated, 1)
+	if !c.followMTU {
+		return
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.checked) < mtuCheckInterval {
+		return
+	}
+	c.checked = now
+
+	ifi, err := net.InterfaceByIndex(c.ifi.Index)
+	if err != nil || ifi.MTU <= c.maxSize() || ifi.MTU > math.MaxUint16 {
+		return
+	}
+
+	atomic.StoreUint32(&c.size, uint32(ifi.MTU))
+}
+
+// Truncated returns the number of received packets which have been discarded
+// because they exceeded the maximum packet size.
+func (c *Conn) Truncated() uint64 {
+	return atomic.LoadUint64(&c.truncated)
+}
+
+// Unauthenticated returns the number of received packets which have been
+// discarded because their Authentication Trailer was missing or invalid. It
+// always returns 0 if Config.Keychain is not set.
+func (c *Conn) Unauthenticated() uint64 {
+	return atomic.LoadUint64(&c.unauthenticated)
+}
+
+// Replays returns the number of received packets which have been discarded
+// because their cryptographic sequence number was not greater than that of the
+// last packet from the same neighbor. It always returns 0 if Config.Keychain is
+// not set.
+func (c *Conn) Replays() uint64 {
+	if c.replay == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&c.replay.count)
+}
+
+// WrongArea returns the number of received packets which have been discarded
+// because their Area ID was not in Config.Areas. It always returns 0 if
+// Config.Areas is not set.
+func (c *Conn) WrongArea() uint64 {
+	return atomic.LoadUint64(&c.wrongArea)
+}
+
+// ReservedFieldErrors returns the number of received packets which have been
+// discarded due to nonzero reserved fields or unexpected trailing bytes. It
+// always returns 0 if Config.Strict is not set.
+func (c *Conn) ReservedFieldErrors() uint64 {
+	return atomic.LoadUint64(&c.reserved)
 }
 
 // WriteTo writes a single OSPFv3 Packet to the specified destination address
-// or multicast group.
+// or multicast group. If p is a *Hello with too many neighbor IDs to fit within
+// the interface MTU, a *NeighborOverflowError is returned.
+//
+// If Config.NBMANeighbors is set, a Packet written to a multicast group is sent
+// to each NBMA neighbor instead.
+//
+// When runtime/trace is enabled, packet validation and marshaling is annotated
+// with the "ospf3.marshal" region.
 func (c *Conn) WriteTo(p Packet, dst *net.IPAddr) error {
-	b, err := MarshalPacket(p)
+	if c.neighbors == nil || !dst.IP.IsMulticast() {
+		return c.writeTo(p, dst)
+	}
+
+	for _, n := range c.neighbors {
+		if err := c.writeTo(p, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTo implements WriteTo for a single destination.
+func (c *Conn) writeTo(p Packet, dst *net.IPAddr) error {
+	// Wait before marshaling so that cryptographic sequence numbers are
+	// sent in order.
+	if c.limit != nil {
+		if err := c.limit.wait(dst); err != nil {
+			return err
+		}
+	}
+
+	r := trace.StartRegion(context.Background(), traceMarshal)
+	b, err := c.marshal(p, dst)
+	r.End()
 	if err != nil {
 		return err
 	}
 
-	// TODO(mdlayher): consider parameterizing control message if necessary but
-	// it seems that x/net/ipv6 lets us configure the kernel to do a lot of the
-	// work for us.
-	_, err = c.c.WriteTo(b, nil, dst)
-	return err
+	// Most IPv6 header parameters are configured on the socket, but the
+	// traffic class may be overridden per packet.
+	cm, err := c.controlMessage(p)
+	if err != nil {
+		return err
+	}
+
+	if c.applyPolicy(p, b, cm, dst) {
+		return nil
+	}
+
+	if _, err := c.c.WriteTo(b, cm, dst); err != nil {
+		return err
+	}
+
+	c.captureWrite(b, cm, dst.IP)
+	return nil
+}
+
+// applyPolicy consults c.policy, if set, for a Packet p marshaled as b. It
+// reports true if the policy dropped the packet or will send it after a delay,
+// in which case the caller must not send it.
+func (c *Conn) applyPolicy(p Packet, b []byte, cm *ipv6.ControlMessage, dst *net.IPAddr) bool {
+	if c.policy == nil {
+		return false
+	}
+
+	delay, drop := c.policy(p, dst)
+	switch {
+	case drop:
+		return true
+	case delay > 0:
+		// Send the packet later without blocking the caller, as a network
+		// with latency would. Errors cannot be reported.
+		time.AfterFunc(delay, func() {
+			if _, err := c.c.WriteTo(b, cm, dst); err == nil {
+				c.captureWrite(b, cm, dst.IP)
+			}
+		})
+		return true
+	default:
+		return false
+	}
+}
+
+// marshal validates and marshals p for transmission on c's interface to dst.
+func (c *Conn) marshal(p Packet, dst *net.IPAddr) ([]byte, error) {
+	if c.unicast && dst.IP.IsMulticast() {
+		return nil, fmt.Errorf("ospf3: cannot write to multicast address %s in unicast mode", dst.IP)
+	}
+
+	// Checksums and authentication are computed in userspace if requested.
+	// The zero MarshalOptions is equivalent to MarshalPacket.
+	var (
+		o       MarshalOptions
+		trailer int
+	)
+	if c.userCk {
+		o.Source, o.Destination = c.src, dst.IP
+	}
+	if c.userCk && c.keys != nil {
+		said, ok := c.keys.SendKey()
+		if !ok {
+			return nil, errors.New("ospf3: Keychain has no Key valid for sending")
+		}
+
+		n, err := c.keys.Size(said)
+		if err != nil {
+			return nil, err
+		}
+
+		o.Authenticator, o.SAID = c.keys, said
+		trailer = authTrailerLen + n
+	}
+
+	switch pp := p.(type) {
+	case *Hello:
+		if err := checkHelloMTU(pp, c.ifi.MTU, trailer); err != nil {
+			return nil, err
+		}
+	case *LinkStateUpdate:
+		// Per RFC2328, section 13.3, each LSA's age is incremented by
+		// InfTransDelay when it is copied into a Link State Update.
+		p = ageLSAs(pp, c.delay)
+	}
+
+	if o.Authenticator != nil {
+		o.SequenceNumber = atomic.AddUint64(&c.seq, 1)
+	}
+
+	return o.MarshalPacket(p)
+}
+
+// linkLocal returns the IPv6 link-local address of ifi.
+func linkLocal(ifi *net.Interface) (net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if ok && ipn.IP.To4() == nil && ipn.IP.IsLinkLocalUnicast() {
+			return ipn.IP, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ospf3: interface %q has no IPv6 link-local address", ifi.Name)
+}
+
+// checkSource verifies that ip is an IPv6 unicast address assigned to ifi.
+func checkSource(ifi *net.Interface, ip net.IP) error {
+	if ip.To16() == nil || ip.To4() != nil || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("ospf3: invalid source address: %v", ip)
+	}
+
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range addrs {
+		if ipn, ok := a.(*net.IPNet); ok && ipn.IP.Equal(ip) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("ospf3: source address %s is not assigned to interface %q", ip, ifi.Name)
+}
+
+// ageLSAs returns a copy of lsu with delay added to the age of each LSA,
+// saturating at MaxAge. The caller's LinkStateUpdate is not modified.
+func ageLSAs(lsu *LinkStateUpdate, delay time.Duration) *LinkStateUpdate {
+	out := &LinkStateUpdate{
+		Header: lsu.Header,
+		LSAs:   make([]LinkStateAdvertisement, len(lsu.LSAs)),
+	}
+
+	for i, l := range lsu.LSAs {
+		l.Header.Age = AddAge(l.Header.Age, delay)
+		out.LSAs[i] = l
+	}
+
+	return out
+}
+
+// controlMessage produces an IPv6 control message for an outgoing Packet, or
+// nil if the socket defaults should be used.
+func (c *Conn) controlMessage(p Packet) (*ipv6.ControlMessage, error) {
+	var tc int
+	if c.dscp != nil {
+		dscp := c.dscp(p)
+		if dscp > 0x3f {
+			return nil, fmt.Errorf("ospf3: DSCP value %#x does not fit in 6 bits", dscp)
+		}
+
+		// DSCP occupies the upper 6 bits of the traffic class, leaving ECN
+		// unset. A value of 0 selects the socket default.
+		tc = int(dscp) << 2
+	}
+
+	if tc == 0 && !c.pinSrc {
+		return nil, nil
+	}
+
+	cm := &ipv6.ControlMessage{TrafficClass: tc}
+	if c.pinSrc {
+		cm.Src, cm.IfIndex = c.src, c.ifi.Index
+	}
+
+	return cm, nil
+}
+
+// A NeighborOverflowError is returned by Conn.WriteTo when a Hello contains
+// more neighbor IDs than can fit in a single IPv6 packet on an interface.
+type NeighborOverflowError struct {
+	// MTU is the MTU of the interface the Hello was to be sent on.
+	MTU int
+
+	// Overflow is the number of trailing neighbor IDs which do not fit within
+	// MTU.
+	Overflow int
+}
+
+// Error implements error.
+func (e *NeighborOverflowError) Error() string {
+	return fmt.Sprintf("ospf3: Hello exceeds MTU %d by %d neighbor IDs", e.MTU, e.Overflow)
+}
+
+// checkHelloMTU verifies that h can be sent in a single IPv6 packet on an
+// interface with the specified MTU, followed by an Authentication Trailer of
+// trailer bytes, if any.
+func checkHelloMTU(h *Hello, mtu, trailer int) error {
+	// RFC5340, section 4.2.1.1 does not specify a way to split neighbor IDs
+	// across multiple Hellos, so refuse to send an oversized packet which would
+	// be fragmented or dropped.
+	over := ipv6.HeaderLen + h.len() + h.LLS.len() + trailer - mtu
+	if over <= 0 {
+		return nil
+	}
+
+	// Round up to the number of 4 byte neighbor IDs which must be removed.
+	return &NeighborOverflowError{
+		MTU:      mtu,
+		Overflow: (over + 3) / 4,
+	}
+}
+
+// A dedup detects duplicate packets received within a time window.
+type dedup struct {
+	// count is accessed atomically and must be the first field for 64-bit
+	// alignment.
+	count uint64
+
+	window time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+	// last maps a source address and packet contents to the time the packet
+	// was most recently received, and queue records each reception in order
+	// so expired entries can be removed.
+	last  map[string]time.Time
+	queue []dedupEntry
+}
+
+// A dedupEntry is a single received packet tracked by a dedup.
+type dedupEntry struct {
+	key string
+	t   time.Time
+}
+
+// newDedup creates a dedup with the specified window and time source.
+func newDedup(window time.Duration, now func() time.Time) *dedup {
+	return &dedup{
+		window: window,
+		now:    now,
+		last:   make(map[string]time.Time),
+	}
+}
+
+// seen reports whether the packet b from src is a duplicate of a packet which
+// was received within the window, and records the packet for future checks.
+func (d *dedup) seen(src *net.IPAddr, b []byte) bool {
+	now := d.now()
+	key := src.String() + "\x00" + string(b)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Remove expired entries, oldest first. An entry may have been refreshed
+	// by a later reception, in which case only the later queue entry removes
+	// it.
+	var i int
+	for ; i < len(d.queue) && now.Sub(d.queue[i].t) >= d.window; i++ {
+		e := d.queue[i]
+		if d.last[e.key].Equal(e.t) {
+			delete(d.last, e.key)
+		}
+	}
+	d.queue = d.queue[i:]
+
+	_, dup := d.last[key]
+	d.last[key] = now
+	d.queue = append(d.queue, dedupEntry{key: key, t: now})
+
+	if dup {
+		atomic.AddUint64(&d.count, 1)
+	}
+
+	return dup
+}
+
+// A replayGuard rejects authenticated packets whose cryptographic sequence
+// numbers do not increase, as described in RFC7166, section 4.6.
+type replayGuard struct {
+	// count is accessed atomically and must be the first field for 64-bit
+	// alignment.
+	count uint64
+
+	mu sync.Mutex
+	// last maps a neighbor's source address to the cryptographic sequence
+	// number of its most recently accepted packet.
+	last map[string]uint64
+}
+
+// newReplayGuard creates an empty replayGuard.
+func newReplayGuard() *replayGuard {
+	return &replayGuard{last: make(map[string]uint64)}
+}
+
+// accept reports whether a packet with cryptographic sequence number seq from
+// src should be accepted, and records seq for future checks if so.
+func (r *replayGuard) accept(src *net.IPAddr, seq uint64) bool {
+	key := src.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if last, ok := r.last[key]; ok && seq <= last {
+		atomic.AddUint64(&r.count, 1)
+		return false
+	}
+
+	r.last[key] = seq
+	return true
 }
@@ -1,6 +1,7 @@
 package ospf3
 
 import (
+	"crypto/sha256"
 	"errors"
 	"net"
 	"os"
@@ -9,10 +10,13 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/net/bpf"
+	"golang.org/x/net/ipv6"
 )
 
 func TestConn(t *testing.T) {
-	c1, c2 := testConns(t)
+	c1, c2 := testConns(t, nil)
 
 	// Pass a series of fixed packets from a sender to a receiver and then
 	// verify that information at the end of the test.
@@ -64,14 +68,26 @@ func TestConn(t *testing.T) {
 				panicf("invalid IPv6 control message: %+v", cm)
 			}
 
-			// Kernel checksumming must be on.
-			// TODO(mdlayher): compute the checksum for validity? Probably not
-			// worth it.
+			// Kernel checksumming must be on and must agree with the
+			// userspace computation.
 			h := p.(*Hello).Header
 			if h.Checksum == 0 {
 				panicf("no Header checksum set: %#04x", h.Checksum)
 			}
 
+			b, err := MarshalPacket(p)
+			if err != nil {
+				panicf("failed to marshal Packet: %v", err)
+			}
+
+			c, err := PacketChecksum(b, cm.Src, cm.Dst)
+			if err != nil {
+				panicf("failed to compute checksum: %v", err)
+			}
+			if c != h.Checksum {
+				panicf("kernel checksum %#04x does not match computed %#04x", h.Checksum, c)
+			}
+
 			msgC <- msg{
 				// TODO(mdlayher): consider adding a Header method to the
 				// Packet interface.
@@ -89,9 +105,1113 @@ func TestConn(t *testing.T) {
 	}
 }
 
+func TestConnMaxPacketSize(t *testing.T) {
+	c1, c2 := testConns(t, &Config{MaxPacketSize: helloLen + headerLen + 4})
+
+	// The first Hello is too large because of its extra neighbor ID, and the
+	// second fits exactly.
+	id := ID{192, 0, 2, 1}
+	for _, h := range []*Hello{
+		{Header: Header{RouterID: id}, NeighborIDs: []ID{{192, 0, 2, 2}, {192, 0, 2, 3}}},
+		{Header: Header{RouterID: id}, NeighborIDs: []ID{{192, 0, 2, 2}}},
+	} {
+		if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+			t.Fatalf("failed to write Hello: %v", err)
+		}
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(1, len(p.(*Hello).NeighborIDs)); diff != "" {
+		t.Fatalf("unexpected number of neighbor IDs (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(uint64(1), c2.Truncated()); diff != "" {
+		t.Fatalf("unexpected truncated count (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnReadFromBuf(t *testing.T) {
+	c1, c2 := testConns(t, nil)
+
+	if _, _, _, err := c2.ReadFromBuf(make([]byte, headerLen)); err == nil {
+		t.Fatal("expected an error for a small buffer, but none occurred")
+	}
+
+	// The first Hello fills a buffer sized for the second exactly, and is
+	// discarded as possibly truncated.
+	id := ID{192, 0, 2, 1}
+	for _, h := range []*Hello{
+		{Header: Header{RouterID: id}, InterfaceID: 1, NeighborIDs: []ID{{192, 0, 2, 2}}},
+		{Header: Header{RouterID: id}, InterfaceID: 2, NeighborIDs: []ID{}},
+		{Header: Header{RouterID: id}, InterfaceID: 3, NeighborIDs: []ID{}},
+	} {
+		if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+			t.Fatalf("failed to write Hello: %v", err)
+		}
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	b := make([]byte, headerLen+helloLen+1)
+	var ps []Packet
+	for i := 0; i < 2; i++ {
+		p, _, _, err := c2.ReadFromBuf(b)
+		if err != nil {
+			t.Fatalf("failed to read Packet: %v", err)
+		}
+
+		ps = append(ps, p)
+	}
+
+	// Packets must not be modified by reuse of the buffer.
+	want := []Packet{
+		&Hello{Header: Header{RouterID: id}, InterfaceID: 2, NeighborIDs: []ID{}},
+		&Hello{Header: Header{RouterID: id}, InterfaceID: 3, NeighborIDs: []ID{}},
+	}
+	if diff := cmp.Diff(want, ps, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packets (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(uint64(1), c2.Truncated()); diff != "" {
+		t.Fatalf("unexpected truncated count (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnMTUIncrease(t *testing.T) {
+	c1, c2 := testConns(t, nil)
+
+	// Simulate an interface MTU which has been raised since Listen, so that
+	// the first Hello is too large and the second may be received after the
+	// new MTU is detected.
+	c2.size = headerLen + helloLen
+
+	id := ID{192, 0, 2, 1}
+	for i := 1; i <= 2; i++ {
+		h := &Hello{
+			Header:      Header{RouterID: id},
+			InterfaceID: uint32(i),
+			NeighborIDs: []ID{{192, 0, 2, 2}},
+		}
+
+		if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+			t.Fatalf("failed to write Hello: %v", err)
+		}
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(uint32(2), p.(*Hello).InterfaceID); diff != "" {
+		t.Fatalf("unexpected Interface ID (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(uint64(1), c2.Truncated()); diff != "" {
+		t.Fatalf("unexpected truncated count (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(c2.ifi.MTU+1, c2.BufferSize()); diff != "" {
+		t.Fatalf("unexpected buffer size (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnAreas(t *testing.T) {
+	area := ID{0, 0, 0, 1}
+	c1, c2 := testConns(t, &Config{Areas: []ID{area}})
+
+	// Only the Hello for the configured area is accepted.
+	id := ID{192, 0, 2, 1}
+	for i, a := range []ID{{}, area} {
+		h := &Hello{
+			Header:      Header{RouterID: id, AreaID: a},
+			InterfaceID: uint32(i),
+			NeighborIDs: []ID{},
+		}
+
+		if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+			t.Fatalf("failed to write Hello: %v", err)
+		}
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	want := &Hello{
+		Header:      Header{RouterID: id, AreaID: area},
+		InterfaceID: 1,
+		NeighborIDs: []ID{},
+	}
+	if diff := cmp.Diff(want, p, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(uint64(1), c2.WrongArea()); diff != "" {
+		t.Fatalf("unexpected wrong area count (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnUnicast(t *testing.T) {
+	c1, c2 := testConns(t, &Config{Unicast: true})
+
+	h := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		NeighborIDs: []ID{},
+	}
+
+	if err := c1.WriteTo(h, AllSPFRouters); err == nil {
+		t.Fatal("expected an error writing to a multicast group, but none occurred")
+	}
+
+	ip, err := linkLocal(c2.ifi)
+	if err != nil {
+		t.Fatalf("failed to get link-local address: %v", err)
+	}
+
+	if err := c1.WriteTo(h, &net.IPAddr{IP: ip, Zone: c1.ifi.Name}); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, cm, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(h, p, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+	if !cm.Dst.Equal(ip) {
+		t.Fatalf("unexpected destination address: %s", cm.Dst)
+	}
+}
+
+func TestConnNBMA(t *testing.T) {
+	c1, c2 := testConns(t, &Config{Unicast: true})
+
+	ip, err := linkLocal(c2.ifi)
+	if err != nil {
+		t.Fatalf("failed to get link-local address: %v", err)
+	}
+
+	// Configure the NBMA neighbor after the fact as the address isn't known
+	// until the interface is ready.
+	c1.neighbors = []*net.IPAddr{{IP: ip, Zone: c1.ifi.Name}}
+
+	h := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		NeighborIDs: []ID{},
+	}
+
+	if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, cm, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(h, p, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+	if !cm.Dst.Equal(ip) {
+		t.Fatalf("unexpected destination address: %s", cm.Dst)
+	}
+}
+
+func TestListenConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{
+			name: "nil NBMA neighbor",
+			cfg:  &Config{NBMANeighbors: []*net.IPAddr{nil}},
+		},
+		{
+			name: "IPv4 NBMA neighbor",
+			cfg:  &Config{NBMANeighbors: []*net.IPAddr{{IP: net.IPv4(192, 0, 2, 1)}}},
+		},
+		{
+			name: "multicast NBMA neighbor",
+			cfg:  &Config{NBMANeighbors: []*net.IPAddr{AllSPFRouters}},
+		},
+		{
+			name: "NBMA without neighbors",
+			cfg:  &Config{NetworkType: NBMANetwork},
+		},
+		{
+			name: "invalid network type",
+			cfg:  &Config{NetworkType: 5},
+		},
+		{
+			name: "IPv4 source",
+			cfg:  &Config{Source: net.IPv4(192, 0, 2, 1)},
+		},
+		{
+			name: "multicast source",
+			cfg:  &Config{Source: AllSPFRouters.IP},
+		},
+		{
+			name: "negative InfTransDelay",
+			cfg: &Config{Timers: func() *Timers {
+				t := DefaultTimers()
+				t.InfTransDelay = -1 * time.Second
+				return &t
+			}()},
+		},
+		{
+			name: "InfTransDelay exceeds MaxAge",
+			cfg: &Config{Timers: func() *Timers {
+				t := DefaultTimers()
+				t.InfTransDelay = MaxAge + time.Second
+				return &t
+			}()},
+		},
+	}
+
+	// Configuration is checked before any socket is opened.
+	ifi := &net.Interface{Name: "eth0", MTU: 1500}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ListenConfig(ifi, tt.cfg); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+func TestListenClosesSocketsOnError(t *testing.T) {
+	ifi, err := net.InterfaceByName("vethospf0")
+	if err != nil {
+		t.Skipf("skipping, interface vethospf0 does not exist: %v", err)
+	}
+
+	fds := func() int {
+		t.Helper()
+
+		des, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skipf("skipping, cannot count open files: %v", err)
+		}
+
+		return len(des)
+	}
+
+	k, err := NewKeychain([]Key{{
+		SAID:    1,
+		HMACKey: HMACKey{Hash: sha256.New, Key: []byte("ospf3")},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create Keychain: %v", err)
+	}
+
+	// The Store fails after the socket has been opened and configured.
+	errStore := errors.New("store failed")
+	cfg := &Config{
+		Keychain: k,
+		Store:    failStore{err: errStore},
+	}
+
+	before := fds()
+	for i := 0; i < 3; i++ {
+		_, err := ListenConfig(ifi, cfg)
+		if errors.Is(err, os.ErrPermission) {
+			t.Skipf("skipping, permission denied while trying to listen OSPFv3 on %q", ifi.Name)
+		}
+		if !errors.Is(err, errStore) {
+			t.Fatalf("expected Store error, but got: %v", err)
+		}
+	}
+
+	if diff := cmp.Diff(before, fds()); diff != "" {
+		t.Fatalf("unexpected number of open files (-want +got):\n%s", diff)
+	}
+}
+
+func TestListenBootCount(t *testing.T) {
+	ifi, err := net.InterfaceByName("vethospf0")
+	if err != nil {
+		t.Skipf("skipping, interface vethospf0 does not exist: %v", err)
+	}
+
+	k, err := NewKeychain([]Key{{
+		SAID:    1,
+		HMACKey: HMACKey{Hash: sha256.New, Key: []byte("ospf3")},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create Keychain: %v", err)
+	}
+
+	s := NewMemoryStore()
+
+	// The kernel rejects the invalid filter after the socket is opened, which
+	// must not consume a boot count.
+	_, err = ListenConfig(ifi, &Config{
+		Keychain: k,
+		Store:    s,
+		Filter:   []bpf.RawInstruction{{Op: 0xffff}},
+	})
+	if errors.Is(err, os.ErrPermission) {
+		t.Skipf("skipping, permission denied while trying to listen OSPFv3 on %q", ifi.Name)
+	}
+	if err == nil {
+		t.Fatal("expected an error for an invalid filter, but none occurred")
+	}
+	if _, ok, _ := s.Get(bootCountBucket, ifi.Name); ok {
+		t.Fatal("failed Listen consumed a boot count")
+	}
+
+	start := time.Now()
+	c, err := ListenConfig(ifi, &Config{Keychain: k, Store: s})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer c.Close()
+
+	boot, ok, err := getUint32(s, bootCountBucket, ifi.Name)
+	if err != nil || !ok {
+		t.Fatalf("failed to get boot count: %v, %v", ok, err)
+	}
+
+	// The sequence number must not be lower than it would be without a
+	// Store.
+	if boot < uint32(start.Unix()) {
+		t.Fatalf("boot count %d is lower than the clock %d", boot, start.Unix())
+	}
+	if diff := cmp.Diff(uint64(boot)<<32, c.seq); diff != "" {
+		t.Fatalf("unexpected sequence number (-want +got):\n%s", diff)
+	}
+}
+
+// A failStore is a Store which always returns err.
+type failStore struct{ err error }
+
+func (s failStore) Get(_, _ string) ([]byte, bool, error) { return nil, false, s.err }
+func (s failStore) Put(_, _ string, _ []byte) error       { return s.err }
+func (s failStore) Delete(_, _ string) error              { return s.err }
+
+func TestConnPointToMultipoint(t *testing.T) {
+	c1, c2 := testConns(t, &Config{NetworkType: PointToMultipointNetwork})
+
+	for _, c := range []*Conn{c1, c2} {
+		if diff := cmp.Diff([]*net.IPAddr{AllSPFRouters}, c.groups); diff != "" {
+			t.Fatalf("unexpected multicast groups (-want +got):\n%s", diff)
+		}
+	}
+
+	h := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		NeighborIDs: []ID{},
+	}
+	if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(h, p, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnSource(t *testing.T) {
+	c1, c2 := testConns(t, nil)
+
+	ip, err := linkLocal(c1.ifi)
+	if err != nil {
+		t.Fatalf("failed to get link-local address: %v", err)
+	}
+
+	// Pin the source address after the fact as the address isn't known until
+	// the interface is ready.
+	if err := checkSource(c1.ifi, ip); err != nil {
+		t.Fatalf("failed to check source address: %v", err)
+	}
+	if err := checkSource(c2.ifi, ip); err == nil {
+		t.Fatal("expected an error for an address on another interface, but none occurred")
+	}
+	c1.src, c1.pinSrc = ip, true
+
+	h := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		NeighborIDs: []ID{},
+	}
+	if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, cm, src, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(h, p, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+	if !src.IP.Equal(ip) {
+		t.Fatalf("unexpected source address: %s", src.IP)
+	}
+	if cm.TrafficClass != tclass {
+		t.Fatalf("unexpected traffic class: %#x", cm.TrafficClass)
+	}
+}
+
+func TestConnOnParseError(t *testing.T) {
+	type parseError struct {
+		b   []byte
+		src *net.IPAddr
+		err error
+	}
+
+	// The callback is invoked synchronously by ReadFrom.
+	var errs []parseError
+	c1, c2 := testConns(t, &Config{
+		OnParseError: func(b []byte, src *net.IPAddr, err error) {
+			errs = append(errs, parseError{b: b, src: src, err: err})
+		},
+	})
+
+	// An OSPFv2 header followed by a valid Hello.
+	bad := []byte{
+		0x02, 0x01, 0x00, 0x10,
+		192, 0, 2, 1,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	if _, err := c1.c.WriteTo(bad, nil, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write bad packet: %v", err)
+	}
+
+	h := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		NeighborIDs: []ID{},
+	}
+	if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, src, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+	if diff := cmp.Diff(h, p, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 parse error, but got %d", len(errs))
+	}
+
+	// The checksum is filled in by the kernel.
+	got := errs[0]
+	if diff := cmp.Diff(bad[:12], got.b[:12]); diff != "" {
+		t.Fatalf("unexpected packet bytes (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(src.String(), got.src.String()); diff != "" {
+		t.Fatalf("unexpected source address (-want +got):\n%s", diff)
+	}
+	if got.err == nil {
+		t.Fatal("expected a parse error, but none was reported")
+	}
+}
+
+func TestConnUserspaceChecksum(t *testing.T) {
+	c1, c2 := testConns(t, nil)
+
+	// Simulate a platform without checksum offload as Listen would configure
+	// it after SetChecksum fails.
+	for _, c := range []*Conn{c1, c2} {
+		if err := c.c.SetChecksum(false, 0); err != nil {
+			t.Fatalf("failed to disable checksum offload: %v", err)
+		}
+
+		ip, err := linkLocal(c.ifi)
+		if err != nil {
+			t.Fatalf("failed to get link-local address: %v", err)
+		}
+
+		c.src, c.pinSrc, c.userCk = ip, true, true
+	}
+
+	// A Hello with an invalid checksum must be discarded, followed by a
+	// valid Hello.
+	bad, err := MarshalPacket(&Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}, Checksum: 0xffff},
+		InterfaceID: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal Hello: %v", err)
+	}
+	if _, err := c1.c.WriteTo(bad, nil, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write bad Hello: %v", err)
+	}
+
+	h := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		InterfaceID: 2,
+		NeighborIDs: []ID{},
+	}
+	if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(h, p, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnFilter(t *testing.T) {
+	area := ID{0, 0, 0, 1}
+	filter, err := NewFilter([]ID{area}, nil)
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+
+	c1, c2 := testConns(t, &Config{Filter: filter})
+
+	// Only the Hello for the filtered area reaches userspace.
+	id := ID{192, 0, 2, 1}
+	for i, a := range []ID{{}, area} {
+		h := &Hello{
+			Header:      Header{RouterID: id, AreaID: a},
+			InterfaceID: uint32(i),
+			NeighborIDs: []ID{},
+		}
+
+		if err := c1.WriteTo(h, AllSPFRouters); err != nil {
+			t.Fatalf("failed to write Hello: %v", err)
+		}
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	want := &Hello{
+		Header:      Header{RouterID: id, AreaID: area},
+		InterfaceID: 1,
+		NeighborIDs: []ID{},
+	}
+	if diff := cmp.Diff(want, p, cmpopts.IgnoreFields(Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnWritePolicy(t *testing.T) {
+	c1, c2 := testConns(t, nil)
+
+	// Drop the first Hello and delay the second.
+	const delay = 250 * time.Millisecond
+	c1.policy = func(p Packet, _ *net.IPAddr) (time.Duration, bool) {
+		if p.(*Hello).InterfaceID == 1 {
+			return 0, true
+		}
+
+		return delay, false
+	}
+
+	start := time.Now()
+	for i := uint32(1); i <= 2; i++ {
+		if err := c1.WriteTo(&Hello{InterfaceID: i}, AllSPFRouters); err != nil {
+			t.Fatalf("failed to write Hello: %v", err)
+		}
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, _, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(uint32(2), p.(*Hello).InterfaceID); diff != "" {
+		t.Fatalf("unexpected interface ID (-want +got):\n%s", diff)
+	}
+	if d := time.Since(start); d < delay {
+		t.Fatalf("packet arrived after %s, before the %s delay", d, delay)
+	}
+}
+
+func TestConnCapturer(t *testing.T) {
+	var rc recordCapturer
+	c1, c2 := testConns(t, &Config{Capturer: &rc})
+
+	hello := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		InterfaceID: 1,
+		NeighborIDs: []ID{},
+	}
+	if err := c1.WriteTo(hello, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+	if _, _, _, err := c2.ReadFrom(); err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	// Packets are no longer captured once the Capturer is removed.
+	c1.SetCapturer(nil)
+	if err := c1.WriteTo(hello, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	ps := rc.packets()
+	if diff := cmp.Diff(2, len(ps)); diff != "" {
+		t.Fatalf("unexpected number of captured packets (-want +got):\n%s", diff)
+	}
+
+	for i, p := range ps {
+		want := [2]bool{true, false}[i]
+		ifi := [2]string{"vethospf0", "vethospf1"}[i]
+		if p.Outbound != want || p.Interface != ifi {
+			t.Fatalf("unexpected packet %d direction or interface: %v, %q", i, p.Outbound, p.Interface)
+		}
+
+		// The checksum must be valid for both directions, even though the
+		// kernel computes it for sent packets.
+		o := ParseOptions{Source: p.Source, Destination: p.Destination}
+		got, err := o.ParsePacket(p.Data)
+		if err != nil {
+			t.Fatalf("failed to parse captured packet %d: %v", i, err)
+		}
+
+		opt := cmpopts.IgnoreFields(Header{}, "Checksum")
+		if diff := cmp.Diff(Packet(hello), got, opt); diff != "" {
+			t.Fatalf("unexpected captured packet %d (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// A recordCapturer is a Capturer which records copies of captured packets.
+type recordCapturer struct {
+	mu sync.Mutex
+	ps []CapturedPacket
+}
+
+func (rc *recordCapturer) Capture(p *CapturedPacket) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	cp := *p
+	cp.Data = append([]byte(nil), p.Data...)
+	rc.ps = append(rc.ps, cp)
+}
+
+func (rc *recordCapturer) packets() []CapturedPacket {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]CapturedPacket(nil), rc.ps...)
+}
+
+func Test_checkHelloMTU(t *testing.T) {
+	// A Hello with no neighbors fits in exactly 76 bytes with an IPv6 header.
+	const base = 40 + headerLen + helloLen
+
+	// An HMAC-SHA-256 Authentication Trailer.
+	const trailer = authTrailerLen + 32
+
+	tests := []struct {
+		name    string
+		n       int
+		mtu     int
+		trailer int
+		err     *NeighborOverflowError
+	}{
+		{
+			name: "OK empty",
+			mtu:  base,
+		},
+		{
+			name: "OK full",
+			n:    2,
+			mtu:  base + 8,
+		},
+		{
+			name: "overflow one",
+			n:    3,
+			mtu:  base + 8,
+			err:  &NeighborOverflowError{MTU: base + 8, Overflow: 1},
+		},
+		{
+			name: "overflow partial",
+			n:    3,
+			mtu:  base + 7,
+			err:  &NeighborOverflowError{MTU: base + 7, Overflow: 2},
+		},
+		{
+			name:    "OK authenticated",
+			n:       2,
+			mtu:     base + 8 + trailer,
+			trailer: trailer,
+		},
+		{
+			name:    "overflow authenticated",
+			n:       2,
+			mtu:     base + 8,
+			trailer: trailer,
+			err:     &NeighborOverflowError{MTU: base + 8, Overflow: 12},
+		},
+		{
+			name: "overflow 1280",
+			n:    400,
+			mtu:  1280,
+			err:  &NeighborOverflowError{MTU: 1280, Overflow: 99},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHelloMTU(&Hello{NeighborIDs: make([]ID, tt.n)}, tt.mtu, tt.trailer)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("failed to check Hello MTU: %v", err)
+				}
+				return
+			}
+
+			var nerr *NeighborOverflowError
+			if !errors.As(err, &nerr) {
+				t.Fatalf("expected *NeighborOverflowError, but got: %#v", err)
+			}
+
+			if diff := cmp.Diff(tt.err, nerr); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnControlMessage(t *testing.T) {
+	// Mark Hellos with CS6 and everything else with AF41, or an invalid value
+	// for LinkStateRequests.
+	dscp := func(p Packet) uint8 {
+		switch p.(type) {
+		case *Hello:
+			return 0
+		case *LinkStateRequest:
+			return 0xff
+		default:
+			return 34
+		}
+	}
+
+	tests := []struct {
+		name string
+		dscp func(p Packet) uint8
+		src  net.IP
+		p    Packet
+		cm   *ipv6.ControlMessage
+		ok   bool
+	}{
+		{
+			name: "default",
+			p:    &Hello{},
+			ok:   true,
+		},
+		{
+			name: "hello",
+			dscp: dscp,
+			p:    &Hello{},
+			ok:   true,
+		},
+		{
+			name: "link state update",
+			dscp: dscp,
+			p:    &LinkStateUpdate{},
+			cm:   &ipv6.ControlMessage{TrafficClass: 0x88},
+			ok:   true,
+		},
+		{
+			name: "link state request",
+			dscp: dscp,
+			p:    &LinkStateRequest{},
+		},
+		{
+			name: "source",
+			src:  net.ParseIP("fe80::1"),
+			p:    &Hello{},
+			cm: &ipv6.ControlMessage{
+				Src:     net.ParseIP("fe80::1"),
+				IfIndex: 1,
+			},
+			ok: true,
+		},
+		{
+			name: "source and DSCP",
+			dscp: dscp,
+			src:  net.ParseIP("fe80::1"),
+			p:    &LinkStateUpdate{},
+			cm: &ipv6.ControlMessage{
+				TrafficClass: 0x88,
+				Src:          net.ParseIP("fe80::1"),
+				IfIndex:      1,
+			},
+			ok: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Conn{
+				ifi:    &net.Interface{Index: 1},
+				dscp:   tt.dscp,
+				src:    tt.src,
+				pinSrc: tt.src != nil,
+			}
+			cm, err := c.controlMessage(tt.p)
+			if tt.ok && err != nil {
+				t.Fatalf("failed to create control message: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+
+			if diff := cmp.Diff(tt.cm, cm); diff != "" {
+				t.Fatalf("unexpected control message (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnReceiveChecks(t *testing.T) {
+	var (
+		ll     = &net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}
+		global = &net.IPAddr{IP: net.ParseIP("2001:db8::1")}
+
+		b = make([]byte, headerLen)
+
+		cmOK = &ipv6.ControlMessage{HopLimit: 1, Dst: AllSPFRouters.IP}
+	)
+
+	// instance returns a header with the specified Instance ID.
+	instance := func(id uint8) []byte {
+		b := make([]byte, headerLen)
+		b[14] = id
+		return b
+	}
+
+	tests := []struct {
+		name string
+		c    *Conn
+		b    []byte
+		cm   *ipv6.ControlMessage
+		src  *net.IPAddr
+		ok   bool
+		errs ReceiveCheckErrors
+	}{
+		{
+			name: "OK multicast",
+			c:    &Conn{checks: true},
+			cm:   cmOK,
+			src:  ll,
+			ok:   true,
+		},
+		{
+			name: "OK AllDRouters",
+			c:    &Conn{checks: true},
+			cm:   &ipv6.ControlMessage{HopLimit: 1, Dst: AllDRouters.IP},
+			src:  ll,
+			ok:   true,
+		},
+		{
+			name: "OK no control message",
+			c:    &Conn{checks: true},
+			src:  ll,
+			ok:   true,
+		},
+		{
+			name: "OK unicast mode",
+			c:    &Conn{checks: true, unicast: true},
+			cm:   &ipv6.ControlMessage{HopLimit: 60, Dst: net.ParseIP("2001:db8::2")},
+			src:  global,
+			ok:   true,
+		},
+		{
+			name: "OK skip checks",
+			c:    &Conn{},
+			cm:   &ipv6.ControlMessage{HopLimit: 64, Dst: net.ParseIP("ff02::1")},
+			src:  global,
+			ok:   true,
+		},
+		{
+			name: "global source",
+			c:    &Conn{checks: true},
+			cm:   cmOK,
+			src:  global,
+			errs: ReceiveCheckErrors{Source: 1},
+		},
+		{
+			name: "hop limit",
+			c:    &Conn{checks: true},
+			cm:   &ipv6.ControlMessage{HopLimit: 2, Dst: AllSPFRouters.IP},
+			src:  ll,
+			errs: ReceiveCheckErrors{HopLimit: 1},
+		},
+		{
+			name: "destination",
+			c:    &Conn{checks: true},
+			cm:   &ipv6.ControlMessage{HopLimit: 1, Dst: net.ParseIP("ff02::1")},
+			src:  ll,
+			errs: ReceiveCheckErrors{Destination: 1},
+		},
+		{
+			name: "OK instance",
+			c:    &Conn{checks: true, insts: map[uint8]struct{}{64: {}}},
+			b:    instance(64),
+			cm:   cmOK,
+			src:  ll,
+			ok:   true,
+		},
+		{
+			name: "wrong instance",
+			c:    &Conn{insts: map[uint8]struct{}{64: {}}},
+			b:    instance(0),
+			cm:   cmOK,
+			src:  ll,
+			errs: ReceiveCheckErrors{WrongInstance: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.b == nil {
+				tt.b = b
+			}
+
+			if diff := cmp.Diff(tt.ok, tt.c.check(tt.b, tt.cm, tt.src)); diff != "" {
+				t.Fatalf("unexpected check result (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.errs, tt.c.ReceiveCheckErrors()); diff != "" {
+				t.Fatalf("unexpected errors (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ageLSAs(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+		in    []time.Duration
+		out   []time.Duration
+	}{
+		{
+			name:  "empty",
+			delay: 1 * time.Second,
+		},
+		{
+			name:  "default",
+			delay: 1 * time.Second,
+			in:    []time.Duration{0, 10 * time.Second},
+			out:   []time.Duration{1 * time.Second, 11 * time.Second},
+		},
+		{
+			name:  "MaxAge boundary",
+			delay: 1 * time.Second,
+			in:    []time.Duration{MaxAge - 2*time.Second, MaxAge - 1*time.Second, MaxAge},
+			out:   []time.Duration{MaxAge - 1*time.Second, MaxAge, MaxAge},
+		},
+		{
+			name:  "MaxAge saturating",
+			delay: 5 * time.Second,
+			in:    []time.Duration{MaxAge - 6*time.Second, MaxAge - 4*time.Second},
+			out:   []time.Duration{MaxAge - 1*time.Second, MaxAge},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lsu := &LinkStateUpdate{
+				Header: Header{RouterID: ID{192, 0, 2, 1}},
+				LSAs:   make([]LinkStateAdvertisement, len(tt.in)),
+			}
+			for i, age := range tt.in {
+				lsu.LSAs[i].Header.Age = age
+			}
+
+			out := ageLSAs(lsu, tt.delay)
+			if diff := cmp.Diff(lsu.Header, out.Header); diff != "" {
+				t.Fatalf("unexpected Header (-want +got):\n%s", diff)
+			}
+
+			var ages []time.Duration
+			for i, l := range out.LSAs {
+				ages = append(ages, l.Header.Age)
+
+				// The input must not be modified.
+				if got := lsu.LSAs[i].Header.Age; got != tt.in[i] {
+					t.Fatalf("input LSA %d age modified: %v", i, got)
+				}
+			}
+
+			if diff := cmp.Diff(tt.out, ages); diff != "" {
+				t.Fatalf("unexpected ages (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 // testConns sets up a pair of *Conns pointed at each other using a fixed
-// set of veth interfaces for integration testing purposes.
-func testConns(t *testing.T) (c1, c2 *Conn) {
+// set of veth interfaces for integration testing purposes. Both *Conns use cfg.
+func testConns(t *testing.T, cfg *Config) (c1, c2 *Conn) {
 	t.Helper()
 
 	var veths [2]*net.Interface
@@ -114,7 +1234,15 @@ func testConns(t *testing.T) (c1, c2 *Conn) {
 
 	var conns [2]*Conn
 	for i, v := range veths {
-		c, err := Listen(v)
+		var (
+			c   *Conn
+			err error
+		)
+		if cfg == nil {
+			c, err = Listen(v)
+		} else {
+			c, err = ListenConfig(v, cfg)
+		}
 		if err != nil {
 			if errors.Is(err, os.ErrPermission) {
 				t.Skipf("skipping, permission denied while trying to listen OSPFv3 on %q", v.Name)
@@ -200,3 +1328,90 @@ func linkLocalReady(t *testing.T, addrs []net.Addr, zone string) bool {
 
 	return false
 }
+
+func Test_dedup(t *testing.T) {
+	var (
+		now = time.Unix(0, 0)
+		d   = newDedup(1*time.Second, func() time.Time { return now })
+
+		src1 = &net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}
+		src2 = &net.IPAddr{IP: net.ParseIP("fe80::2"), Zone: "eth0"}
+
+		b1 = []byte{0x01}
+		b2 = []byte{0x02}
+	)
+
+	tests := []struct {
+		name string
+		step time.Duration
+		src  *net.IPAddr
+		b    []byte
+		dup  bool
+	}{
+		{name: "first", src: src1, b: b1},
+		{name: "duplicate", step: 500 * time.Millisecond, src: src1, b: b1, dup: true},
+		{name: "other contents", src: src1, b: b2},
+		{name: "other source", src: src2, b: b1},
+		// The duplicate refreshed the entry, so it is still within the window.
+		{name: "refreshed", step: 900 * time.Millisecond, src: src1, b: b1, dup: true},
+		{name: "expired", step: 1 * time.Second, src: src1, b: b1},
+	}
+
+	var dups uint64
+	for _, tt := range tests {
+		now = now.Add(tt.step)
+		if diff := cmp.Diff(tt.dup, d.seen(tt.src, tt.b)); diff != "" {
+			t.Fatalf("%s: unexpected duplicate result (-want +got):\n%s", tt.name, diff)
+		}
+		if tt.dup {
+			dups++
+		}
+	}
+
+	if diff := cmp.Diff(dups, d.count); diff != "" {
+		t.Fatalf("unexpected duplicate count (-want +got):\n%s", diff)
+	}
+
+	// Only the most recent reception remains after expiry of all others.
+	if diff := cmp.Diff(1, len(d.last)); diff != "" {
+		t.Fatalf("unexpected number of tracked packets (-want +got):\n%s", diff)
+	}
+}
+
+func Test_replayGuard(t *testing.T) {
+	var (
+		r = newReplayGuard()
+
+		src1 = &net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}
+		src2 = &net.IPAddr{IP: net.ParseIP("fe80::2"), Zone: "eth0"}
+	)
+
+	tests := []struct {
+		name   string
+		src    *net.IPAddr
+		seq    uint64
+		replay bool
+	}{
+		{name: "first", src: src1, seq: 10},
+		{name: "increasing", src: src1, seq: 11},
+		{name: "repeated", src: src1, seq: 11, replay: true},
+		{name: "stale", src: src1, seq: 5, replay: true},
+		{name: "other source", src: src2, seq: 1},
+		// Rejected packets must not move the sequence number backwards.
+		{name: "after stale", src: src1, seq: 12},
+	}
+
+	var replays uint64
+	for _, tt := range tests {
+		if diff := cmp.Diff(!tt.replay, r.accept(tt.src, tt.seq)); diff != "" {
+			t.Fatalf("%s: unexpected accept result (-want +got):\n%s", tt.name, diff)
+		}
+		if tt.replay {
+			replays++
+		}
+	}
+
+	if diff := cmp.Diff(replays, r.count); diff != "" {
+		t.Fatalf("unexpected replay count (-want +got):\n%s", diff)
+	}
+}
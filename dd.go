@@ -0,0 +1,114 @@
+package ospf3
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+)
+
+// A DDOption modifies a DatabaseDescription created by
+// NewInitialDatabaseDescription. It returns an error if its value is not valid.
+type DDOption func(dd *DatabaseDescription) error
+
+// NewInitialDatabaseDescription creates the first DatabaseDescription sent
+// when a neighbor enters the ExStart state, as described in RFC2328, section
+// 10.8. The I-, M-, and MS-bits are set, no LSA headers are carried, and the
+// DD sequence number is chosen at random unless set with
+// WithDDSequenceNumber.
+//
+// mtu is the interface MTU, or 0 on virtual links. The resulting
+// DatabaseDescription must satisfy DatabaseDescription.Validate, or an error is
+// returned.
+func NewInitialDatabaseDescription(h Header, mtu uint16, options Options, opts ...DDOption) (*DatabaseDescription, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, fmt.Errorf("ospf3: failed to generate DD sequence number: %w", err)
+	}
+
+	dd := &DatabaseDescription{
+		Header:         h,
+		Options:        options,
+		InterfaceMTU:   mtu,
+		Flags:          IBit | MBit | MSBit,
+		SequenceNumber: binary.BigEndian.Uint32(b[:]),
+		LSAs:           []LSAHeader{},
+	}
+
+	for _, o := range opts {
+		if err := o(dd); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := dd.Validate(); err != nil {
+		return nil, err
+	}
+
+	return dd, nil
+}
+
+// WithDDSequenceNumber sets the initial DD sequence number, such as to use
+// the time of day as suggested by RFC2328, section 10.8.
+func WithDDSequenceNumber(seq uint32) DDOption {
+	return func(dd *DatabaseDescription) error {
+		dd.SequenceNumber = seq
+		return nil
+	}
+}
+
+// WithDDLLS sets a DatabaseDescription's LLS data block and the L-bit in its
+// Options, such as to signal the LR-bit for out-of-band resynchronization.
+func WithDDLLS(lls *LLS) DDOption {
+	return func(dd *DatabaseDescription) error {
+		if lls == nil {
+			return fmt.Errorf("ospf3: DatabaseDescription LLS must not be nil")
+		}
+
+		dd.LLS = lls
+		dd.Options |= LBit
+		return nil
+	}
+}
+
+// NextMaster creates the DatabaseDescription sent by the master after dd,
+// which must be the master's previous DatabaseDescription. The DD sequence
+// number is incremented, the MS-bit is set, the I-bit is cleared, and the
+// M-bit is set if more is true, as described in RFC2328, section 10.8. The
+// Header, Options, InterfaceMTU, and LLS are copied from dd.
+func (dd *DatabaseDescription) NextMaster(lsas []LSAHeader, more bool) *DatabaseDescription {
+	next := dd.next(dd.SequenceNumber+1, lsas, more)
+	next.Flags |= MSBit
+	return next
+}
+
+// NextSlave creates the DatabaseDescription sent by the slave in response to
+// master, where dd is the slave's previous DatabaseDescription. The DD
+// sequence number is copied from master, the I- and MS-bits are cleared, and
+// the M-bit is set if more is true, as described in RFC2328, section 10.8. The
+// Header, Options, InterfaceMTU, and LLS are copied from dd.
+func (dd *DatabaseDescription) NextSlave(master *DatabaseDescription, lsas []LSAHeader, more bool) *DatabaseDescription {
+	return dd.next(master.SequenceNumber, lsas, more)
+}
+
+// next creates a DatabaseDescription following dd with the specified sequence
+// number and LSA headers, which sets only the M-bit if more is true.
+func (dd *DatabaseDescription) next(seq uint32, lsas []LSAHeader, more bool) *DatabaseDescription {
+	var flags DDFlags
+	if more {
+		flags = MBit
+	}
+
+	if lsas == nil {
+		lsas = []LSAHeader{}
+	}
+
+	return &DatabaseDescription{
+		Header:         dd.Header,
+		Options:        dd.Options,
+		InterfaceMTU:   dd.InterfaceMTU,
+		Flags:          flags,
+		SequenceNumber: seq,
+		LSAs:           lsas,
+		LLS:            dd.LLS,
+	}
+}
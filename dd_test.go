@@ -0,0 +1,112 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDatabaseDescriptionExchange(t *testing.T) {
+	var (
+		masterH = Header{RouterID: ID{192, 0, 2, 2}}
+		slaveH  = Header{RouterID: ID{192, 0, 2, 1}}
+		lsas    = []LSAHeader{{LSA: LSA{Type: RouterLSA, AdvertisingRouter: ID{192, 0, 2, 2}}}}
+		lls     = &LLS{ExtendedOptions: LRBit}
+	)
+
+	master, err := NewInitialDatabaseDescription(masterH, 1500, V6Bit|EBit|RBit,
+		WithDDSequenceNumber(100), WithDDLLS(lls))
+	if err != nil {
+		t.Fatalf("failed to create master DD: %v", err)
+	}
+
+	slave, err := NewInitialDatabaseDescription(slaveH, 1500, V6Bit|EBit|RBit)
+	if err != nil {
+		t.Fatalf("failed to create slave DD: %v", err)
+	}
+
+	// The slave with the lower Router ID acknowledges the master's sequence
+	// number, then the master continues the exchange.
+	slave = slave.NextSlave(master, lsas, true)
+	master = master.NextMaster(nil, false)
+	done := slave.NextSlave(master, nil, false)
+
+	want := []*DatabaseDescription{
+		{
+			Header:         slaveH,
+			Options:        V6Bit | EBit | RBit,
+			InterfaceMTU:   1500,
+			Flags:          MBit,
+			SequenceNumber: 100,
+			LSAs:           lsas,
+		},
+		{
+			Header:         masterH,
+			Options:        V6Bit | EBit | RBit | LBit,
+			InterfaceMTU:   1500,
+			Flags:          MSBit,
+			SequenceNumber: 101,
+			LSAs:           []LSAHeader{},
+			LLS:            lls,
+		},
+		{
+			Header:         slaveH,
+			Options:        V6Bit | EBit | RBit,
+			InterfaceMTU:   1500,
+			SequenceNumber: 101,
+			LSAs:           []LSAHeader{},
+		},
+	}
+
+	got := []*DatabaseDescription{slave, master, done}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected DatabaseDescriptions (-want +got):\n%s", diff)
+	}
+
+	for _, dd := range got {
+		if err := dd.Validate(); err != nil {
+			t.Fatalf("failed to validate: %v", err)
+		}
+		if _, err := MarshalPacket(dd); err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+	}
+}
+
+func TestNewInitialDatabaseDescription(t *testing.T) {
+	h := Header{RouterID: ID{192, 0, 2, 1}}
+
+	dd, err := NewInitialDatabaseDescription(h, 0, V6Bit)
+	if err != nil {
+		t.Fatalf("failed to create DD: %v", err)
+	}
+
+	want := &DatabaseDescription{
+		Header:  h,
+		Options: V6Bit,
+		Flags:   IBit | MBit | MSBit,
+		LSAs:    []LSAHeader{},
+		// Random.
+		SequenceNumber: dd.SequenceNumber,
+	}
+	if diff := cmp.Diff(want, dd); diff != "" {
+		t.Fatalf("unexpected DatabaseDescription (-want +got):\n%s", diff)
+	}
+
+	for _, tt := range []struct {
+		name string
+		h    Header
+		mtu  uint16
+		opts []DDOption
+	}{
+		{name: "no router ID", mtu: 1500},
+		{name: "MTU", h: h, mtu: 1000},
+		{name: "nil LLS", h: h, mtu: 1500, opts: []DDOption{WithDDLLS(nil)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewInitialDatabaseDescription(tt.h, tt.mtu, V6Bit, tt.opts...); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package ospf3
+
+// A Decoder parses OSPFv3 packets into Packets which it owns and reuses across
+// calls to Decode, including the slices of neighbor IDs, LSAs, and LSA
+// headers which they contain. Once warmed up, a Decoder does not allocate
+// when parsing Hello, DatabaseDescription, LinkStateRequest, and
+// LinkStateAcknowledgement packets. LinkStateUpdate packets reuse their LSA
+// slice, but each LSA body is still allocated.
+//
+// A Decoder is not safe for concurrent use. Its zero value is ready to use and
+// is equivalent to the ParsePacket function.
+type Decoder struct {
+	// Options modify the behavior of packet parsing as described in
+	// ParseOptions.
+	Options ParseOptions
+
+	hello Hello
+	dd    DatabaseDescription
+	lsr   LinkStateRequest
+	lsu   LinkStateUpdate
+	lsack LinkStateAcknowledgement
+}
+
+// Decode parses an OSPFv3 Header and trailing Packet from bytes. The returned
+// Packet and its contents are only valid until the next call to Decode, so the
+// caller must copy any data which it wishes to retain.
+func (d *Decoder) Decode(b []byte) (Packet, error) {
+	return d.Options.parsePacket(b, d.packet)
+}
+
+// packet resets and returns the Decoder's Packet of type ptyp with Header h,
+// keeping the capacity of its slices, or returns nil if ptyp is not a known
+// packet type.
+func (d *Decoder) packet(ptyp packetType, h Header) Packet {
+	switch ptyp {
+	case hello:
+		d.hello = Hello{Header: h, NeighborIDs: d.hello.NeighborIDs}
+		return &d.hello
+	case databaseDescription:
+		d.dd = DatabaseDescription{Header: h, LSAs: d.dd.LSAs}
+		return &d.dd
+	case linkStateRequest:
+		d.lsr = LinkStateRequest{Header: h, LSAs: d.lsr.LSAs}
+		return &d.lsr
+	case linkStateUpdate:
+		d.lsu = LinkStateUpdate{Header: h, LSAs: d.lsu.LSAs}
+		return &d.lsu
+	case linkStateAcknowledgement:
+		d.lsack = LinkStateAcknowledgement{Header: h, LSAs: d.lsack.LSAs}
+		return &d.lsack
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecoderRoundTrip(t *testing.T) {
+	var d Decoder
+
+	// Decode every packet twice in sequence so that the second pass reuses
+	// the slices left by the first, and compare against ParsePacket.
+	for i := 0; i < 2; i++ {
+		for _, tt := range roundTripTests {
+			want, err := ParsePacket(tt.b)
+			if err != nil {
+				t.Fatalf("%s: failed to parse: %v", tt.name, err)
+			}
+
+			got, err := d.Decode(tt.b)
+			if err != nil {
+				t.Fatalf("%s: failed to decode: %v", tt.name, err)
+			}
+
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("%s: unexpected Packet (-want +got):\n%s", tt.name, diff)
+			}
+		}
+	}
+}
+
+func TestDecoderShrink(t *testing.T) {
+	var d Decoder
+
+	big, err := MarshalPacket(&Hello{
+		Options:     LBit,
+		NeighborIDs: []ID{{192, 0, 2, 2}, {192, 0, 2, 3}},
+		LLS:         &LLS{ExtendedOptions: LRBit},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if _, err := d.Decode(big); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	// Fields of a previous Hello must not leak into the next one.
+	want := &Hello{NeighborIDs: []ID{{192, 0, 2, 4}}}
+	small, err := MarshalPacket(want)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	got, err := d.Decode(small)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecoderAllocs(t *testing.T) {
+	for _, b := range [][]byte{
+		bufHello,
+		bufDatabaseDescription,
+		bufLinkStateRequest,
+		bufLinkStateAcknowledgement,
+	} {
+		var d Decoder
+		if _, err := d.Decode(b); err != nil {
+			t.Fatalf("failed to decode: %v", err)
+		}
+
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, err := d.Decode(b); err != nil {
+				panic(err)
+			}
+		})
+		if allocs != 0 {
+			t.Fatalf("expected no allocations after warm up, but got %v", allocs)
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	var d Decoder
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := d.Decode(bufHello); err != nil {
+			b.Fatalf("failed to decode: %v", err)
+		}
+	}
+}
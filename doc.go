@@ -1,4 +1,4 @@
 // Package ospf3 implements OSPFv3 (OSPF for IPv6) as described in RFC5340.
 package ospf3
 
-//go:generate stringer -type=FloodingScope,LSType -output=string.go
+//go:generate stringer -type=FloodingScope,RouterLinkType,NetworkType,NeighborEvent -output=string.go
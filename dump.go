@@ -0,0 +1,176 @@
+package ospf3
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sdump returns a human-readable, multi-line description of a Packet, similar
+// to the packet details shown by Wireshark. Option bits, LS types, flooding
+// scopes, and LS ages are decoded, and the bodies of LSAs carried by a
+// LinkStateUpdate are described where their format is known. The output is
+// intended for debugging and logging, and its format may change.
+func Sdump(p Packet) string {
+	var d dumper
+	switch p := p.(type) {
+	case *Hello:
+		d.header("Hello", p.Header)
+		d.line(1, "Interface ID: %d", p.InterfaceID)
+		d.line(1, "Router Priority: %d", p.RouterPriority)
+		d.line(1, "Options: %s", p.Options)
+		d.line(1, "Hello Interval: %s", p.HelloInterval)
+		d.line(1, "Router Dead Interval: %s", p.RouterDeadInterval)
+		d.line(1, "Designated Router: %s", p.DesignatedRouterID)
+		d.line(1, "Backup Designated Router: %s", p.BackupDesignatedRouterID)
+		d.line(1, "Neighbors: %d", len(p.NeighborIDs))
+		for _, id := range p.NeighborIDs {
+			d.line(2, "%s", id)
+		}
+		d.lls(p.LLS)
+	case *DatabaseDescription:
+		d.header("Database Description", p.Header)
+		d.line(1, "Options: %s", p.Options)
+		d.line(1, "Interface MTU: %d", p.InterfaceMTU)
+		d.line(1, "Flags: %s", p.Flags)
+		d.line(1, "Sequence Number: %#08x", p.SequenceNumber)
+		d.line(1, "LSA Headers: %d", len(p.LSAs))
+		for _, h := range p.LSAs {
+			d.lsaHeader(2, h)
+		}
+		d.lls(p.LLS)
+	case *LinkStateRequest:
+		d.header("Link State Request", p.Header)
+		d.line(1, "LSAs: %d", len(p.LSAs))
+		for _, l := range p.LSAs {
+			d.lsa(2, l)
+		}
+	case *LinkStateUpdate:
+		d.header("Link State Update", p.Header)
+		d.line(1, "LSAs: %d", len(p.LSAs))
+		for _, l := range p.LSAs {
+			d.lsaHeader(2, l.Header)
+			d.lsaBody(3, l.Body)
+		}
+	case *LinkStateAcknowledgement:
+		d.header("Link State Acknowledgement", p.Header)
+		d.line(1, "LSA Headers: %d", len(p.LSAs))
+		for _, h := range p.LSAs {
+			d.lsaHeader(2, h)
+		}
+	case nil:
+		d.line(0, "OSPFv3 <nil>")
+	default:
+		d.line(0, "OSPFv3 %T", p)
+	}
+
+	return d.b.String()
+}
+
+// A dumper accumulates the output of Sdump.
+type dumper struct {
+	b strings.Builder
+}
+
+// line writes a formatted line indented by depth levels.
+func (d *dumper) line(depth int, format string, v ...interface{}) {
+	d.b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(&d.b, format, v...)
+	d.b.WriteByte('\n')
+}
+
+// header writes the packet type name and the fields of its Header.
+func (d *dumper) header(name string, h Header) {
+	d.line(0, "OSPFv3 %s", name)
+	d.line(1, "Router ID: %s", h.RouterID)
+	d.line(1, "Area ID: %s", h.AreaID)
+	d.line(1, "Checksum: %#04x", h.Checksum)
+	if af, ok := h.AddressFamily(); ok {
+		d.line(1, "Instance ID: %d (%s)", h.InstanceID, af)
+	} else {
+		d.line(1, "Instance ID: %d", h.InstanceID)
+	}
+}
+
+// lsa writes the fields which identify an LSA.
+func (d *dumper) lsa(depth int, l LSA) {
+	d.line(depth, "%s (%s), Link State ID: %s, Advertising Router: %s",
+		l.Type, l.Type.FloodingScope(), l.LinkStateID, l.AdvertisingRouter)
+}
+
+// lsaHeader writes the fields of an LSAHeader.
+func (d *dumper) lsaHeader(depth int, h LSAHeader) {
+	d.lsa(depth, h.LSA)
+
+	age := h.Age.String()
+	if h.DoNotAge() {
+		age = fmt.Sprintf("%s (DoNotAge)", lsAge(h.Age))
+	}
+	if lsAge(h.Age) >= MaxAge {
+		age += " (MaxAge)"
+	}
+
+	d.line(depth+1, "Age: %s, Sequence Number: %#08x, Checksum: %#04x, Length: %d",
+		age, h.SequenceNumber, h.Checksum, h.Length)
+}
+
+// lsaBody writes the contents of an LSABody.
+func (d *dumper) lsaBody(depth int, body LSABody) {
+	switch b := body.(type) {
+	case nil:
+	case *RouterLSABody:
+		d.line(depth, "Flags: %s, Options: %s", b.Flags, b.Options)
+		for _, l := range b.Links {
+			d.line(depth, "%s, Metric: %d, Interface ID: %d, Neighbor Interface ID: %d, Neighbor Router ID: %s",
+				l.Type, l.Metric, l.InterfaceID, l.NeighborInterfaceID, l.NeighborRouterID)
+			for _, m := range l.MTMetrics {
+				d.line(depth+1, "MT-ID: %d, Metric: %d", m.ID, m.Metric)
+			}
+		}
+	case *NetworkLSABody:
+		d.line(depth, "Options: %s", b.Options)
+		for _, id := range b.AttachedRouters {
+			d.line(depth, "Attached Router: %s", id)
+		}
+	case *InterAreaPrefixLSABody:
+		d.line(depth, "Metric: %d, Prefix: %s", b.Metric, prefixString(b.Prefix))
+	case *LinkLSABody:
+		d.line(depth, "Router Priority: %d, Options: %s, Link-Local Address: %s",
+			b.RouterPriority, b.Options, b.LinkLocalInterfaceAddress)
+		for _, p := range b.Prefixes {
+			d.line(depth, "Prefix: %s", prefixString(p))
+		}
+	case *IntraAreaPrefixLSABody:
+		d.line(depth, "Referenced LSA: %s, Link State ID: %s, Advertising Router: %s",
+			b.ReferencedLSType, b.ReferencedLinkStateID, b.ReferencedAdvertisingRouter)
+		for _, p := range b.Prefixes {
+			d.line(depth, "Metric: %d, Prefix: %s", p.Metric, prefixString(p.Prefix))
+		}
+	case *RawLSABody:
+		d.line(depth, "Body: %d bytes", len(b.Data))
+	default:
+		d.line(depth, "%+v", body)
+	}
+}
+
+// lls writes the contents of an LLS data block, if present.
+func (d *dumper) lls(l *LLS) {
+	if l == nil {
+		return
+	}
+
+	d.line(1, "LLS Extended Options: %s", l.ExtendedOptions)
+	for _, t := range l.TLVs {
+		d.line(2, "TLV Type: %d, Length: %d", t.Type, len(t.Value))
+	}
+}
+
+// prefixString returns the string representation of a Prefix and its
+// PrefixOptions, if any.
+func prefixString(p Prefix) string {
+	s := fmt.Sprintf("%s/%d", p.Address, p.Length)
+	if p.Options != 0 {
+		s += fmt.Sprintf(" (%s)", p.Options)
+	}
+
+	return s
+}
@@ -0,0 +1,95 @@
+package ospf3
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSdump(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Packet
+		s    string
+	}{
+		{
+			name: "hello",
+			p:    pktHello,
+			s: `
+OSPFv3 Hello
+  Router ID: 192.0.2.1
+  Area ID: 0.0.0.0
+  Checksum: 0x0000
+  Instance ID: 1 (IPv6Unicast)
+  Interface ID: 1
+  Router Priority: 1
+  Options: V6-bit|E-bit
+  Hello Interval: 5s
+  Router Dead Interval: 10s
+  Designated Router: 192.0.2.1
+  Backup Designated Router: 192.0.2.2
+  Neighbors: 2
+    192.0.2.2
+    192.0.2.3
+`,
+		},
+		{
+			name: "link state update",
+			p:    pktLinkStateUpdate,
+			s: `
+OSPFv3 Link State Update
+  Router ID: 192.0.2.1
+  Area ID: 0.0.0.0
+  Checksum: 0x0000
+  Instance ID: 1 (IPv6Unicast)
+  LSAs: 2
+    RouterLSA (AreaScoping), Link State ID: 0.0.0.0, Advertising Router: 192.0.2.1
+      Age: 1s, Sequence Number: 0x000000ff, Checksum: 0x0000, Length: 56
+      Flags: B-bit|E-bit, Options: V6-bit|R-bit
+      PointToPointLink, Metric: 10, Interface ID: 1, Neighbor Interface ID: 2, Neighbor Router ID: 192.0.2.2
+      TransitNetworkLink, Metric: 65535, Interface ID: 3, Neighbor Interface ID: 4, Neighbor Router ID: 192.0.2.3
+    LSType(12287) (AreaScoping), Link State ID: 0.0.0.5, Advertising Router: 192.0.2.1
+      Age: 2s, Sequence Number: 0x000001ff, Checksum: 0x0000, Length: 24
+      Body: 4 bytes
+`,
+		},
+		{
+			name: "ages",
+			p: &LinkStateAcknowledgement{
+				LSAs: []LSAHeader{
+					{Age: MaxAge, LSA: LSA{Type: LinkLSA}},
+					{Age: DoNotAge + 10*time.Second, LSA: LSA{Type: ASExternalLSA}},
+				},
+			},
+			s: `
+OSPFv3 Link State Acknowledgement
+  Router ID: 0.0.0.0
+  Area ID: 0.0.0.0
+  Checksum: 0x0000
+  Instance ID: 0 (IPv6Unicast)
+  LSA Headers: 2
+    LinkLSA (LinkLocalScoping), Link State ID: 0.0.0.0, Advertising Router: 0.0.0.0
+      Age: 1h0m0s (MaxAge), Sequence Number: 0x00000000, Checksum: 0x0000, Length: 0
+    ASExternalLSA (ASScoping), Link State ID: 0.0.0.0, Advertising Router: 0.0.0.0
+      Age: 10s (DoNotAge), Sequence Number: 0x00000000, Checksum: 0x0000, Length: 0
+`,
+		},
+		{
+			name: "nil",
+			s: `
+OSPFv3 <nil>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := strings.TrimPrefix(tt.s, "\n")
+			if diff := cmp.Diff(want, Sdump(tt.p)); diff != "" {
+				t.Fatalf("unexpected dump (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
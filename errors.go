@@ -0,0 +1,65 @@
+package ospf3
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A ParseError is returned when bytes cannot be parsed as an OSPFv3 packet or
+// LSA body. It identifies the structure which could not be parsed and its
+// offset, so that tools which consume malformed packets can report where
+// decoding failed. Use errors.As to retrieve a *ParseError.
+type ParseError struct {
+	// Offset is the offset in bytes of the structure described by Field,
+	// from the start of the bytes passed to the parsing function, such as
+	// the OSPFv3 packet passed to ParsePacket.
+	Offset int
+
+	// Field describes the structure which could not be parsed, from the
+	// outermost to the innermost, such as "LinkStateUpdate: LSA 1: RouterLSA
+	// body".
+	Field string
+
+	// Reason describes why parsing failed.
+	Reason string
+
+	err error
+}
+
+// Error implements error.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("ospf3: failed to parse %s at offset %d: %s", e.Field, e.Offset, e.Reason)
+}
+
+// Unwrap returns the underlying error which caused parsing to fail.
+func (e *ParseError) Unwrap() error { return e.err }
+
+// parseError wraps err, which occurred while parsing the structure field at
+// offset off, in a *ParseError. If err is already a *ParseError for a
+// structure nested within field, its offset is made relative to off and field
+// is prepended to its Field. An empty field only adjusts the offset.
+func parseError(off int, field string, err error) error {
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		return &ParseError{
+			Offset: off,
+			Field:  field,
+			Reason: err.Error(),
+			err:    err,
+		}
+	}
+
+	switch {
+	case field == "":
+		field = perr.Field
+	case perr.Field != "":
+		field += ": " + perr.Field
+	}
+
+	return &ParseError{
+		Offset: off + perr.Offset,
+		Field:  field,
+		Reason: perr.Reason,
+		err:    perr.err,
+	}
+}
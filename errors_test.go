@@ -0,0 +1,100 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParseError(t *testing.T) {
+	first := LinkStateAdvertisement{
+		Header: LSAHeader{LSA: LSA{Type: RouterLSA}},
+		Body:   lsaRouterLSABody,
+	}
+
+	lsu, err := MarshalPacket(&LinkStateUpdate{
+		LSAs: []LinkStateAdvertisement{
+			first,
+			{
+				Header: LSAHeader{LSA: LSA{Type: NetworkLSA}},
+				Body:   lsaNetworkLSABody,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	// Truncate the final Network-LSA body so that it no longer ends on a 4
+	// byte boundary, adjusting the packet and LSA lengths to match.
+	second := headerLen + lsuLen + first.len()
+	lsu = lsu[:len(lsu)-1]
+	binary.BigEndian.PutUint16(lsu[2:4], uint16(len(lsu)))
+	binary.BigEndian.PutUint16(lsu[second+18:second+20], uint16(len(lsu)-second))
+
+	tests := []struct {
+		name   string
+		b      []byte
+		strict bool
+		want   *ParseError
+	}{
+		{
+			name: "Header",
+			b:    bufHello[:headerLen-1],
+			want: &ParseError{Field: "Header"},
+		},
+		{
+			name: "Hello",
+			b: merge(
+				[]byte{version, uint8(hello), 0x00, headerLen + 4},
+				bufHeaderCommon,
+				[]byte{0x00, 0x00, 0x00, 0x00},
+			),
+			want: &ParseError{Offset: headerLen, Field: "Hello"},
+		},
+		{
+			name: "unknown packet type",
+			b:    merge([]byte{version, 0xff, 0x00, headerLen}, bufHeaderCommon),
+			want: &ParseError{Offset: 1, Field: "Header packet type"},
+		},
+		{
+			// Malformed LSA bodies are only rejected by strict parsing.
+			name:   "LSA body",
+			b:      lsu,
+			strict: true,
+			want: &ParseError{
+				Offset: second + lsaHeaderLen,
+				Field:  "LinkStateUpdate: LSA 1: NetworkLSA body",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOptions{Strict: tt.strict}.ParsePacket(tt.b)
+
+			// The sentinel must still match through the *ParseError.
+			if !errors.Is(err, errParse) {
+				t.Fatalf("expected parse error, but got: %v", err)
+			}
+
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected *ParseError, but got: %T", err)
+			}
+
+			opts := []cmp.Option{
+				cmpopts.IgnoreUnexported(ParseError{}),
+				cmpopts.IgnoreFields(ParseError{}, "Reason"),
+			}
+			if diff := cmp.Diff(tt.want, perr, opts...); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+
+			t.Logf("err: %v", err)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package ospf3
+
+import (
+	"expvar"
+	"sync"
+)
+
+// expvarCounters are the Conn counters published by Config.Expvar.
+var expvarCounters = []struct {
+	name string
+	fn   func(c *Conn) uint64
+}{
+	{"truncated", (*Conn).Truncated},
+	{"duplicates", (*Conn).Duplicates},
+	{"unauthenticated", (*Conn).Unauthenticated},
+	{"replays", (*Conn).Replays},
+	{"wrong_area", (*Conn).WrongArea},
+	{"reserved_field_errors", (*Conn).ReservedFieldErrors},
+	{"rejected_source", func(c *Conn) uint64 { return c.ReceiveCheckErrors().Source }},
+	{"rejected_hop_limit", func(c *Conn) uint64 { return c.ReceiveCheckErrors().HopLimit }},
+	{"rejected_destination", func(c *Conn) uint64 { return c.ReceiveCheckErrors().Destination }},
+	{"rejected_wrong_instance", func(c *Conn) uint64 { return c.ReceiveCheckErrors().WrongInstance }},
+	{"rate_limit_delayed", func(c *Conn) uint64 { return c.RateLimited().Delayed }},
+	{"rate_limit_dropped", func(c *Conn) uint64 { return c.RateLimited().Dropped }},
+}
+
+// expvars tracks the Conns whose counters are published by Config.Expvar.
+// The variables are only published once a Conn requests them, so that the
+// package is otherwise silent.
+var expvars struct {
+	once  sync.Once
+	mu    sync.Mutex
+	conns map[*Conn]struct{}
+}
+
+// publishExpvar adds c's counters to the published variables.
+func publishExpvar(c *Conn) {
+	expvars.once.Do(func() {
+		expvars.conns = make(map[*Conn]struct{})
+
+		for _, ec := range expvarCounters {
+			fn := ec.fn
+			expvar.Publish("ospf3."+ec.name, expvar.Func(func() interface{} {
+				return sumExpvar(fn)
+			}))
+		}
+	})
+
+	expvars.mu.Lock()
+	defer expvars.mu.Unlock()
+	expvars.conns[c] = struct{}{}
+}
+
+// unpublishExpvar removes c's counters from the published variables.
+func unpublishExpvar(c *Conn) {
+	expvars.mu.Lock()
+	defer expvars.mu.Unlock()
+	delete(expvars.conns, c)
+}
+
+// sumExpvar sums a counter for the published Conns on each interface.
+func sumExpvar(fn func(c *Conn) uint64) map[string]uint64 {
+	expvars.mu.Lock()
+	defer expvars.mu.Unlock()
+
+	m := make(map[string]uint64, len(expvars.conns))
+	for c := range expvars.conns {
+		m[c.ifi.Name] += fn(c)
+	}
+
+	return m
+}
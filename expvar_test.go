@@ -0,0 +1,57 @@
+package ospf3
+
+import (
+	"encoding/json"
+	"expvar"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExpvar(t *testing.T) {
+	var (
+		a1 = &Conn{ifi: &net.Interface{Name: "eth0"}, truncated: 1}
+		a2 = &Conn{ifi: &net.Interface{Name: "eth0"}, truncated: 2}
+		b  = &Conn{ifi: &net.Interface{Name: "eth1"}, rejected: ReceiveCheckErrors{HopLimit: 3}}
+	)
+
+	for _, c := range []*Conn{a1, a2, b} {
+		publishExpvar(c)
+		defer unpublishExpvar(c)
+	}
+
+	get := func(name string) map[string]uint64 {
+		t.Helper()
+
+		v := expvar.Get(name)
+		if v == nil {
+			t.Fatalf("variable %q was not published", name)
+		}
+
+		var m map[string]uint64
+		if err := json.Unmarshal([]byte(v.String()), &m); err != nil {
+			t.Fatalf("failed to unmarshal %q: %v", name, err)
+		}
+
+		return m
+	}
+
+	want := map[string]uint64{"eth0": 3, "eth1": 0}
+	if diff := cmp.Diff(want, get("ospf3.truncated")); diff != "" {
+		t.Fatalf("unexpected truncated counts (-want +got):\n%s", diff)
+	}
+
+	want = map[string]uint64{"eth0": 0, "eth1": 3}
+	if diff := cmp.Diff(want, get("ospf3.rejected_hop_limit")); diff != "" {
+		t.Fatalf("unexpected hop limit counts (-want +got):\n%s", diff)
+	}
+
+	unpublishExpvar(a1)
+	unpublishExpvar(b)
+
+	want = map[string]uint64{"eth0": 2}
+	if diff := cmp.Diff(want, get("ospf3.truncated")); diff != "" {
+		t.Fatalf("unexpected truncated counts after unpublish (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,114 @@
+package ospf3
+
+import (
+	"fmt"
+	"math"
+
+	"golang.org/x/net/bpf"
+)
+
+// NewFilter assembles a classic BPF program for Config.Filter which drops
+// received packets in the kernel unless they carry an OSPFv3 header with a
+// known packet type. If areas or instanceIDs are set, packets must also carry
+// one of the listed Area IDs or Instance IDs, respectively.
+//
+// An IPv6 raw socket does not pass the IPv6 header to the program, so the hop
+// limit cannot be checked in the kernel.
+func NewFilter(areas []ID, instanceIDs []uint8) ([]bpf.RawInstruction, error) {
+	// Jump offsets are 8 bits, which limits the length of the program.
+	errTooLong := fmt.Errorf("ospf3: too many areas or instance IDs for BPF filter: %d, %d",
+		len(areas), len(instanceIDs))
+	if len(areas) > math.MaxUint8 || len(instanceIDs) > math.MaxUint8 {
+		return nil, errTooLong
+	}
+
+	var f filter
+
+	// The packet must contain a complete OSPFv3 header.
+	f.load(bpf.LoadExtension{Num: bpf.ExtLen})
+	f.dropIf(bpf.JumpLessThan, headerLen)
+	f.load(bpf.LoadAbsolute{Off: 0, Size: 1})
+	f.dropIf(bpf.JumpNotEqual, version)
+	f.load(bpf.LoadAbsolute{Off: 1, Size: 1})
+	f.dropIf(bpf.JumpLessThan, uint32(hello))
+	f.dropIf(bpf.JumpGreaterThan, uint32(linkStateAcknowledgement))
+
+	if len(areas) > 0 {
+		vals := make([]uint32, 0, len(areas))
+		for _, a := range areas {
+			vals = append(vals, uint32(a[0])<<24|uint32(a[1])<<16|uint32(a[2])<<8|uint32(a[3]))
+		}
+
+		f.load(bpf.LoadAbsolute{Off: 8, Size: 4})
+		f.oneOf(vals)
+	}
+
+	if len(instanceIDs) > 0 {
+		vals := make([]uint32, 0, len(instanceIDs))
+		for _, id := range instanceIDs {
+			vals = append(vals, uint32(id))
+		}
+
+		f.load(bpf.LoadAbsolute{Off: 14, Size: 1})
+		f.oneOf(vals)
+	}
+
+	f.ins = append(f.ins,
+		bpf.RetConstant{Val: snapLen},
+		bpf.RetConstant{Val: 0},
+	)
+
+	// Point each drop jump at the final instruction.
+	last := len(f.ins) - 1
+	for _, i := range f.drops {
+		skip := last - i - 1
+		if skip > math.MaxUint8 {
+			return nil, errTooLong
+		}
+
+		j := f.ins[i].(bpf.JumpIf)
+		j.SkipTrue = uint8(skip)
+		f.ins[i] = j
+	}
+
+	return bpf.Assemble(f.ins)
+}
+
+// snapLen is the number of bytes of an accepted packet which the filter passes
+// to the socket. It is bounded rather than math.MaxUint32, which some BPF
+// implementations interpret as a negative length.
+const snapLen = 0x40000
+
+// A filter is a BPF program under construction by NewFilter.
+type filter struct {
+	ins []bpf.Instruction
+
+	// drops are the indices of jumps to the drop instruction, which are
+	// resolved once the program is complete.
+	drops []int
+}
+
+// load appends an instruction which loads a value into the accumulator.
+func (f *filter) load(ins bpf.Instruction) { f.ins = append(f.ins, ins) }
+
+// dropIf appends a jump to the drop instruction if the accumulator satisfies
+// cond with val.
+func (f *filter) dropIf(cond bpf.JumpTest, val uint32) {
+	f.drops = append(f.drops, len(f.ins))
+	f.ins = append(f.ins, bpf.JumpIf{Cond: cond, Val: val})
+}
+
+// oneOf appends instructions which drop the packet unless the accumulator is
+// equal to one of vals.
+func (f *filter) oneOf(vals []uint32) {
+	for i, v := range vals[:len(vals)-1] {
+		// Skip the remaining comparisons on a match.
+		f.ins = append(f.ins, bpf.JumpIf{
+			Cond:     bpf.JumpEqual,
+			Val:      v,
+			SkipTrue: uint8(len(vals) - 1 - i),
+		})
+	}
+
+	f.dropIf(bpf.JumpNotEqual, vals[len(vals)-1])
+}
@@ -0,0 +1,128 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/bpf"
+)
+
+func TestNewFilter(t *testing.T) {
+	var (
+		area1 = ID{0, 0, 0, 1}
+		area2 = ID{192, 0, 2, 1}
+	)
+
+	// packet returns a minimal OSPFv3 header with the specified fields.
+	packet := func(version, typ uint8, area ID, instance uint8) []byte {
+		b := make([]byte, headerLen)
+		b[0], b[1] = version, typ
+		copy(b[8:12], area[:])
+		b[14] = instance
+		return b
+	}
+
+	tests := []struct {
+		name      string
+		areas     []ID
+		instances []uint8
+		b         []byte
+		ok        bool
+	}{
+		{
+			name: "OK any",
+			b:    packet(3, 1, ID{}, 0),
+			ok:   true,
+		},
+		{
+			name: "OK trailing bytes",
+			b:    append(packet(3, 5, ID{}, 0), 0xff, 0xff),
+			ok:   true,
+		},
+		{
+			name: "short",
+			b:    packet(3, 1, ID{}, 0)[:headerLen-1],
+		},
+		{
+			name: "OSPFv2",
+			b:    packet(2, 1, ID{}, 0),
+		},
+		{
+			name: "type 0",
+			b:    packet(3, 0, ID{}, 0),
+		},
+		{
+			name: "type 6",
+			b:    packet(3, 6, ID{}, 0),
+		},
+		{
+			name:  "OK first area",
+			areas: []ID{area1, area2},
+			b:     packet(3, 1, area1, 0),
+			ok:    true,
+		},
+		{
+			name:  "OK last area",
+			areas: []ID{area1, area2},
+			b:     packet(3, 1, area2, 0),
+			ok:    true,
+		},
+		{
+			name:  "wrong area",
+			areas: []ID{area1, area2},
+			b:     packet(3, 1, ID{}, 0),
+		},
+		{
+			name:      "OK area and instance",
+			areas:     []ID{area1},
+			instances: []uint8{0, 64},
+			b:         packet(3, 2, area1, 64),
+			ok:        true,
+		},
+		{
+			name:      "wrong instance",
+			areas:     []ID{area1},
+			instances: []uint8{0, 64},
+			b:         packet(3, 2, area1, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := NewFilter(tt.areas, tt.instances)
+			if err != nil {
+				t.Fatalf("failed to create filter: %v", err)
+			}
+
+			ins, ok := bpf.Disassemble(raw)
+			if !ok {
+				t.Fatal("failed to disassemble filter")
+			}
+
+			vm, err := bpf.NewVM(ins)
+			if err != nil {
+				t.Fatalf("failed to create VM: %v", err)
+			}
+
+			n, err := vm.Run(tt.b)
+			if err != nil {
+				t.Fatalf("failed to run VM: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.ok, n != 0); diff != "" {
+				t.Fatalf("unexpected filter result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewFilterTooLong(t *testing.T) {
+	if _, err := NewFilter(make([]ID, 256), nil); err == nil {
+		t.Fatal("expected an error for too many areas, but none occurred")
+	}
+
+	// Each list fits, but the program doesn't.
+	if _, err := NewFilter(make([]ID, 200), make([]uint8, 200)); err == nil {
+		t.Fatal("expected an error for a long program, but none occurred")
+	}
+}
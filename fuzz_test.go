@@ -1,6 +1,13 @@
 package ospf3
 
-import "testing"
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
 
 func Test_fuzz(t *testing.T) {
 	tests := []struct {
@@ -14,3 +21,242 @@ func Test_fuzz(t *testing.T) {
 		})
 	}
 }
+
+func FuzzHello(f *testing.F)                    { fuzzPacket(f, hello) }
+func FuzzDatabaseDescription(f *testing.F)      { fuzzPacket(f, databaseDescription) }
+func FuzzLinkStateRequest(f *testing.F)         { fuzzPacket(f, linkStateRequest) }
+func FuzzLinkStateUpdate(f *testing.F)          { fuzzPacket(f, linkStateUpdate) }
+func FuzzLinkStateAcknowledgement(f *testing.F) { fuzzPacket(f, linkStateAcknowledgement) }
+
+func FuzzRouterLSABody(f *testing.F)            { fuzzLSABody(f, RouterLSA) }
+func FuzzNetworkLSABody(f *testing.F)           { fuzzLSABody(f, NetworkLSA) }
+func FuzzInterAreaPrefixLSABody(f *testing.F)   { fuzzLSABody(f, InterAreaPrefixLSA) }
+func FuzzInterAreaRouterLSABody(f *testing.F)   { fuzzLSABody(f, InterAreaRouterLSA) }
+func FuzzASExternalLSABody(f *testing.F)        { fuzzLSABody(f, ASExternalLSA) }
+func FuzzNSSALSABody(f *testing.F)              { fuzzLSABody(f, NSSALSA) }
+func FuzzLinkLSABody(f *testing.F)              { fuzzLSABody(f, LinkLSA) }
+func FuzzIntraAreaPrefixLSABody(f *testing.F)   { fuzzLSABody(f, IntraAreaPrefixLSA) }
+func FuzzRouterInformationLSABody(f *testing.F) { fuzzLSABody(f, AreaRouterInformationLSA) }
+func FuzzIntraAreaTELSABody(f *testing.F)       { fuzzLSABody(f, IntraAreaTELSA) }
+func FuzzSRv6LocatorLSABody(f *testing.F)       { fuzzLSABody(f, AreaSRv6LocatorLSA) }
+
+// fuzzPacket runs a fuzz target for packets of type ptyp, seeded with the
+// corpus for ptyp.
+func fuzzPacket(f *testing.F, ptyp packetType) {
+	for _, b := range seedCorpus().packets[ptyp] {
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Keep the fuzzer within the decoder for ptyp rather than spending
+		// its time on the version and packet type fields.
+		if len(b) >= 2 {
+			b[0], b[1] = version, uint8(ptyp)
+		}
+
+		_ = fuzz(b)
+	})
+}
+
+// fuzzLSABody runs a fuzz target for LSA bodies of type typ, seeded with the
+// corpus for typ.
+func fuzzLSABody(f *testing.F, typ LSType) {
+	for _, b := range seedCorpus().bodies[typ] {
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b1 []byte) {
+		body1, err := ParseLSABody(typ, b1)
+		if err != nil {
+			return
+		}
+
+		b2, err := body1.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+
+		body2, err := ParseLSABody(typ, b2)
+		if err != nil {
+			t.Fatalf("failed to parse: %v", err)
+		}
+
+		if diff := cmp.Diff(body1, body2); diff != "" {
+			t.Fatalf("unexpected LSABody (-want +got):\n%s", diff)
+		}
+
+		b3, err := body2.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to marshal again: %v", err)
+		}
+
+		if diff := cmp.Diff(b2, b3); diff != "" {
+			t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// A fuzzCorpus is a seed corpus for the fuzz targets, built from the buffers
+// and values used by the other tests.
+type fuzzCorpus struct {
+	packets map[packetType][][]byte
+	bodies  map[LSType][][]byte
+}
+
+var (
+	corpusOnce sync.Once
+	corpus     fuzzCorpus
+)
+
+// seedCorpus returns the seed corpus, building it on first use.
+func seedCorpus() fuzzCorpus {
+	corpusOnce.Do(func() { corpus = buildCorpus() })
+	return corpus
+}
+
+// buildCorpus builds the seed corpus from the test buffers. Each packet is
+// added under its packet type, and the body of each LSA it carries is added
+// under the LSA's type.
+func buildCorpus() fuzzCorpus {
+	c := fuzzCorpus{
+		packets: make(map[packetType][][]byte),
+		bodies:  make(map[LSType][][]byte),
+	}
+
+	addPacket := func(b []byte) {
+		p, err := ParsePacket(b)
+		if err != nil {
+			panicf("failed to parse seed packet: %v", err)
+		}
+
+		ptyp := packetType(b[1])
+		c.packets[ptyp] = append(c.packets[ptyp], b)
+
+		if lsu, ok := p.(*LinkStateUpdate); ok {
+			for _, l := range lsu.LSAs {
+				addBody(c, l.Header.LSA.Type, l.Body)
+			}
+		}
+	}
+
+	for _, b := range [][]byte{
+		bufHello,
+		bufDatabaseDescription,
+		bufLinkStateRequest,
+		bufLinkStateUpdate,
+		bufLinkStateAcknowledgement,
+	} {
+		addPacket(b)
+	}
+
+	// Packets which exercise optional trailing data.
+	for _, p := range []Packet{
+		&Hello{
+			Header:      Header{RouterID: ID{192, 0, 2, 1}},
+			Options:     V6Bit | LBit,
+			NeighborIDs: []ID{{192, 0, 2, 2}},
+			LLS:         &LLS{ExtendedOptions: LRBit},
+		},
+		&DatabaseDescription{
+			Header:  Header{RouterID: ID{192, 0, 2, 1}},
+			Options: V6Bit | LBit,
+			Flags:   IBit | MBit | MSBit,
+			LLS:     &LLS{ExtendedOptions: RSBit},
+		},
+	} {
+		b, err := MarshalPacket(p)
+		if err != nil {
+			panicf("failed to marshal seed packet: %v", err)
+		}
+
+		addPacket(b)
+	}
+
+	for t, b := range map[LSType][]byte{
+		RouterLSA:                bufRouterLSABody,
+		NetworkLSA:               bufNetworkLSABody,
+		InterAreaPrefixLSA:       bufInterAreaPrefixLSABody,
+		InterAreaRouterLSA:       bufInterAreaRouterLSABody,
+		ASExternalLSA:            bufASExternalLSABody,
+		NSSALSA:                  bufNSSALSABody,
+		LinkLSA:                  bufLinkLSABody,
+		IntraAreaPrefixLSA:       bufIntraAreaPrefixLSABody,
+		AreaRouterInformationLSA: bufRouterInformationLSABody,
+		IntraAreaTELSA:           bufIntraAreaTELSABody,
+	} {
+		c.bodies[t] = append(c.bodies[t], b)
+	}
+
+	addBody(c, AreaSRv6LocatorLSA, &SRv6LocatorLSABody{
+		Locators: []SRv6Locator{{
+			RouteType: SRv6IntraArea,
+			Algorithm: SPFAlgorithm,
+			Metric:    10,
+			Length:    48,
+			Locator:   net.ParseIP("2001:db8:1::"),
+		}},
+	})
+
+	return c
+}
+
+// addBody adds the marshaled body of an LSA of type t to c.
+func addBody(c fuzzCorpus, t LSType, body LSABody) {
+	b, err := body.MarshalBinary()
+	if err != nil {
+		panicf("failed to marshal seed LSA body: %v", err)
+	}
+
+	c.bodies[t] = append(c.bodies[t], b)
+}
+
+func FuzzParseOptionsLimits(f *testing.F) {
+	for _, b := range [][]byte{
+		bufDatabaseDescription,
+		bufLinkStateRequest,
+		bufLinkStateUpdate,
+		bufLinkStateAcknowledgement,
+	} {
+		f.Add(b, 2, 56, len(b)-headerLen)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte, maxLSAs, maxLength, maxBody int) {
+		o := ParseOptions{MaxLSAs: maxLSAs, MaxLSALength: maxLength, MaxBodyLength: maxBody}
+		p, err := o.ParsePacket(b)
+		if err != nil {
+			return
+		}
+
+		// Any packet which parses successfully must respect the limits.
+		if body := int(binary.BigEndian.Uint16(b[2:4])) - headerLen; maxBody > 0 && body > maxBody {
+			t.Fatalf("parsed body of length %d with limit %d", body, maxBody)
+		}
+
+		var (
+			n      int
+			length []uint16
+		)
+		switch p := p.(type) {
+		case *DatabaseDescription:
+			n = len(p.LSAs)
+		case *LinkStateRequest:
+			n = len(p.LSAs)
+		case *LinkStateUpdate:
+			n = len(p.LSAs)
+			for _, l := range p.LSAs {
+				length = append(length, l.Header.Length)
+			}
+		case *LinkStateAcknowledgement:
+			n = len(p.LSAs)
+		}
+
+		if maxLSAs > 0 && n > maxLSAs {
+			t.Fatalf("parsed %d LSAs with limit %d", n, maxLSAs)
+		}
+		for _, l := range length {
+			if maxLength > 0 && int(l) > maxLength {
+				t.Fatalf("parsed LSA of length %d with limit %d", l, maxLength)
+			}
+		}
+	})
+}
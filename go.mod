@@ -1,10 +1,10 @@
 module github.com/mdlayher/ospf3
 
-go 1.15
+go 1.18
 
 require (
 	github.com/google/go-cmp v0.5.4
 	golang.org/x/net v0.0.0-20210119194325-5f4716e94777
-	golang.org/x/sys v0.0.0-20210124154548-22da62e12c0c // indirect
+	golang.org/x/sys v0.0.0-20210124154548-22da62e12c0c
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
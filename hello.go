@@ -0,0 +1,189 @@
+package ospf3
+
+import (
+	"fmt"
+	"time"
+)
+
+// Default values used by NewHello, as suggested by RFC2328, appendix C.3.
+const (
+	defaultRouterPriority = 1
+	defaultHelloOptions   = V6Bit | EBit | RBit
+)
+
+// A HelloOption modifies a Hello created by NewHello. It returns an error if
+// its value is not valid.
+type HelloOption func(h *Hello) error
+
+// NewHello creates a Hello for the interface with the specified ID, applying
+// each HelloOption in order. Fields which are not set by an option use the
+// defaults suggested by RFC2328, appendix C.3 and RFC5340, appendix A.2:
+//   - HelloInterval: 10 seconds
+//   - RouterDeadInterval: 40 seconds
+//   - RouterPriority: 1
+//   - Options: V6Bit, EBit, and RBit
+//
+// A Router ID must be set using WithRouterID. The resulting Hello must satisfy
+// Hello.Validate, or an error is returned.
+func NewHello(interfaceID uint32, opts ...HelloOption) (*Hello, error) {
+	t := DefaultTimers()
+	h := &Hello{
+		InterfaceID:        interfaceID,
+		RouterPriority:     defaultRouterPriority,
+		Options:            defaultHelloOptions,
+		HelloInterval:      t.HelloInterval,
+		RouterDeadInterval: t.RouterDeadInterval,
+		NeighborIDs:        []ID{},
+	}
+
+	for _, o := range opts {
+		if err := o(h); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// WithRouterID sets the Router ID in a Hello's Header.
+func WithRouterID(id ID) HelloOption {
+	return func(h *Hello) error {
+		h.Header.RouterID = id
+		return nil
+	}
+}
+
+// WithAreaID sets the Area ID in a Hello's Header.
+func WithAreaID(id ID) HelloOption {
+	return func(h *Hello) error {
+		h.Header.AreaID = id
+		return nil
+	}
+}
+
+// WithInstanceID sets the Instance ID in a Hello's Header.
+func WithInstanceID(id uint8) HelloOption {
+	return func(h *Hello) error {
+		h.Header.InstanceID = id
+		return nil
+	}
+}
+
+// WithHelloInterval sets a Hello's HelloInterval, which must be a whole
+// number of seconds.
+func WithHelloInterval(d time.Duration) HelloOption {
+	return func(h *Hello) error {
+		if err := wholeSeconds("HelloInterval", d); err != nil {
+			return err
+		}
+
+		h.HelloInterval = d
+		return nil
+	}
+}
+
+// WithRouterDeadInterval sets a Hello's RouterDeadInterval, which must be a
+// whole number of seconds.
+func WithRouterDeadInterval(d time.Duration) HelloOption {
+	return func(h *Hello) error {
+		if err := wholeSeconds("RouterDeadInterval", d); err != nil {
+			return err
+		}
+
+		h.RouterDeadInterval = d
+		return nil
+	}
+}
+
+// WithTimers sets a Hello's HelloInterval and RouterDeadInterval from t, which
+// must satisfy Timers.Validate.
+func WithTimers(t Timers) HelloOption {
+	return func(h *Hello) error {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+
+		for _, o := range []HelloOption{
+			WithHelloInterval(t.HelloInterval),
+			WithRouterDeadInterval(t.RouterDeadInterval),
+		} {
+			if err := o(h); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// WithRouterPriority sets a Hello's RouterPriority. A priority of 0 indicates
+// that the router is not eligible to become Designated Router.
+func WithRouterPriority(p uint8) HelloOption {
+	return func(h *Hello) error {
+		h.RouterPriority = p
+		return nil
+	}
+}
+
+// WithOptions sets a Hello's Options, replacing the defaults. The L-bit is
+// managed by WithLLS and must not be set.
+func WithOptions(o Options) HelloOption {
+	return func(h *Hello) error {
+		if !o.valid() {
+			return fmt.Errorf("ospf3: Hello Options bitmask is not valid: %#x", uint32(o))
+		}
+		if o&LBit != 0 {
+			return fmt.Errorf("ospf3: Hello Options must not set the L-bit directly, use WithLLS")
+		}
+
+		// Preserve the L-bit if an LLS was already set.
+		h.Options = o | h.Options&LBit
+		return nil
+	}
+}
+
+// WithDesignatedRouters sets the Router IDs of the Designated Router and Backup
+// Designated Router seen on the interface.
+func WithDesignatedRouters(dr, bdr ID) HelloOption {
+	return func(h *Hello) error {
+		h.DesignatedRouterID = dr
+		h.BackupDesignatedRouterID = bdr
+		return nil
+	}
+}
+
+// WithNeighborIDs sets the Router IDs of the neighbors from which Hellos have
+// recently been received on the interface.
+func WithNeighborIDs(ids ...ID) HelloOption {
+	return func(h *Hello) error {
+		h.NeighborIDs = append([]ID{}, ids...)
+		return nil
+	}
+}
+
+// WithLLS sets a Hello's LLS data block and the L-bit in its Options.
+func WithLLS(lls *LLS) HelloOption {
+	return func(h *Hello) error {
+		if lls == nil {
+			return fmt.Errorf("ospf3: Hello LLS must not be nil")
+		}
+
+		h.LLS = lls
+		h.Options |= LBit
+		return nil
+	}
+}
+
+// wholeSeconds verifies that d is a whole number of seconds, since intervals
+// are transmitted on the wire as seconds.
+func wholeSeconds(name string, d time.Duration) error {
+	if d%time.Second != 0 {
+		return fmt.Errorf("ospf3: Hello %s must be a whole number of seconds: %v", name, d)
+	}
+
+	return nil
+}
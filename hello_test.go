@@ -0,0 +1,142 @@
+package ospf3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewHello(t *testing.T) {
+	var (
+		id  = ID{192, 0, 2, 1}
+		lls = &LLS{ExtendedOptions: LRBit}
+	)
+
+	tests := []struct {
+		name string
+		opts []HelloOption
+		h    *Hello
+	}{
+		{
+			name: "defaults",
+			opts: []HelloOption{WithRouterID(id)},
+			h: &Hello{
+				Header:             Header{RouterID: id},
+				InterfaceID:        1,
+				RouterPriority:     1,
+				Options:            V6Bit | EBit | RBit,
+				HelloInterval:      10 * time.Second,
+				RouterDeadInterval: 40 * time.Second,
+				NeighborIDs:        []ID{},
+			},
+		},
+		{
+			name: "overrides",
+			opts: []HelloOption{
+				WithLLS(lls),
+				WithRouterID(id),
+				WithAreaID(ID{0, 0, 0, 1}),
+				WithInstanceID(64),
+				WithTimers(Timers{
+					HelloInterval:      1 * time.Second,
+					RouterDeadInterval: 3 * time.Second,
+					RxmtInterval:       5 * time.Second,
+					InfTransDelay:      1 * time.Second,
+					Wait:               3 * time.Second,
+					LSRefreshTime:      LSRefreshTime,
+				}),
+				WithRouterPriority(0),
+				WithOptions(V6Bit | RBit | AFBit),
+				WithDesignatedRouters(ID{192, 0, 2, 2}, ID{192, 0, 2, 3}),
+				WithNeighborIDs(ID{192, 0, 2, 2}, ID{192, 0, 2, 3}),
+			},
+			h: &Hello{
+				Header: Header{
+					RouterID:   id,
+					AreaID:     ID{0, 0, 0, 1},
+					InstanceID: 64,
+				},
+				InterfaceID:              1,
+				Options:                  V6Bit | RBit | AFBit | LBit,
+				HelloInterval:            1 * time.Second,
+				RouterDeadInterval:       3 * time.Second,
+				DesignatedRouterID:       ID{192, 0, 2, 2},
+				BackupDesignatedRouterID: ID{192, 0, 2, 3},
+				NeighborIDs:              []ID{{192, 0, 2, 2}, {192, 0, 2, 3}},
+				LLS:                      lls,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHello(1, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create Hello: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.h, h); diff != "" {
+				t.Fatalf("unexpected Hello (-want +got):\n%s", diff)
+			}
+
+			// Every Hello created by NewHello must marshal.
+			if _, err := MarshalPacket(h); err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewHelloErrors(t *testing.T) {
+	id := WithRouterID(ID{192, 0, 2, 1})
+
+	tests := []struct {
+		name string
+		opts []HelloOption
+	}{
+		{
+			name: "no router ID",
+		},
+		{
+			name: "fractional HelloInterval",
+			opts: []HelloOption{id, WithHelloInterval(1500 * time.Millisecond)},
+		},
+		{
+			name: "fractional RouterDeadInterval",
+			opts: []HelloOption{id, WithRouterDeadInterval(40*time.Second + 1)},
+		},
+		{
+			name: "dead before hello",
+			opts: []HelloOption{id, WithHelloInterval(40 * time.Second), WithRouterDeadInterval(10 * time.Second)},
+		},
+		{
+			name: "invalid timers",
+			opts: []HelloOption{id, WithTimers(Timers{})},
+		},
+		{
+			name: "invalid options",
+			opts: []HelloOption{id, WithOptions(0xff000000)},
+		},
+		{
+			name: "L-bit options",
+			opts: []HelloOption{id, WithOptions(V6Bit | LBit)},
+		},
+		{
+			name: "nil LLS",
+			opts: []HelloOption{id, WithLLS(nil)},
+		},
+		{
+			name: "duplicate neighbors",
+			opts: []HelloOption{id, WithNeighborIDs(ID{192, 0, 2, 2}, ID{192, 0, 2, 2})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHello(1, tt.opts...); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package ospf3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A HelloProtocol runs the Hello Protocol described in RFC5340, section 4.2.2
+// and RFC2328, section 9.5 on a single interface. It sends a Hello every
+// HelloInterval which lists each neighbor heard from within
+// RouterDeadInterval, and processes received Hellos to track the liveness of
+// each neighbor and whether communication with it is bidirectional.
+//
+// A HelloProtocol does not elect a Designated Router, but advertises the
+// routers set by SetDesignatedRouters. It is safe for concurrent use, but Run
+// must not be called concurrently.
+type HelloProtocol struct {
+	// Atomics must come first.
+	sendErrors uint64
+
+	c   PacketConn
+	now func() time.Time
+
+	mu        sync.Mutex
+	h         Hello
+	neighbors map[ID]*Neighbor
+}
+
+// A NeighborEvent is an event in the neighbor state machine described in
+// RFC2328, section 10.2, which is reported by HelloProtocol.Run.
+type NeighborEvent uint8
+
+// Possible NeighborEvent values.
+const (
+	// HelloReceived indicates that a Hello has been received from the
+	// neighbor.
+	HelloReceived NeighborEvent = iota + 1
+
+	// TwoWayReceived indicates that the neighbor has begun listing this
+	// router in its Hellos, so communication is bidirectional.
+	TwoWayReceived
+
+	// OneWayReceived indicates that the neighbor has stopped listing this
+	// router in its Hellos.
+	OneWayReceived
+
+	// InactivityTimer indicates that no Hello has been received from the
+	// neighbor within RouterDeadInterval. The neighbor is down and has been
+	// removed from the HelloProtocol, and any adjacency with it should be
+	// torn down.
+	InactivityTimer
+)
+
+// A Neighbor is a router discovered by a HelloProtocol.
+type Neighbor struct {
+	// Address is the source address of the neighbor's Hellos.
+	Address *net.IPAddr
+
+	// Hello is the most recent Hello received from the neighbor, which
+	// carries its Router ID, priority, and choice of Designated Router.
+	Hello *Hello
+
+	// LastHello is the time at which Hello was received. The neighbor is
+	// declared down by the InactivityTimer event if no Hello is received
+	// within RouterDeadInterval.
+	LastHello time.Time
+
+	// TwoWay reports whether the neighbor lists this router in its Hellos,
+	// meaning that communication with it is bidirectional.
+	TwoWay bool
+}
+
+// NewHelloProtocol creates a HelloProtocol which sends Hellos using the
+// parameters in h over c, with the HelloInterval and RouterDeadInterval of t.
+// t must satisfy Timers.Validate, and h must then satisfy Hello.Validate.
+// h.NeighborIDs is ignored and replaced by the neighbors heard from on c.
+func NewHelloProtocol(c PacketConn, h *Hello, t Timers) (*HelloProtocol, error) {
+	if h == nil {
+		return nil, errors.New("ospf3: HelloProtocol Hello must not be nil")
+	}
+
+	// Copy h so the caller can't modify it while the protocol runs.
+	hh := *h
+	hh.NeighborIDs = nil
+	if err := WithTimers(t)(&hh); err != nil {
+		return nil, err
+	}
+	if err := hh.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &HelloProtocol{
+		c:         c,
+		now:       time.Now,
+		h:         hh,
+		neighbors: make(map[ID]*Neighbor),
+	}, nil
+}
+
+// SetDesignatedRouters sets the Router IDs of the Designated Router and Backup
+// Designated Router advertised in subsequent Hellos.
+func (p *HelloProtocol) SetDesignatedRouters(dr, bdr ID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.h.DesignatedRouterID = dr
+	p.h.BackupDesignatedRouterID = bdr
+}
+
+// Neighbors returns each neighbor which is currently up, sorted by Router ID.
+func (p *HelloProtocol) Neighbors() []Neighbor {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ns := make([]Neighbor, 0, len(p.neighbors))
+	for _, n := range p.neighbors {
+		ns = append(ns, *n)
+	}
+
+	sort.Slice(ns, func(i, j int) bool {
+		a, b := ns[i].Hello.Header.RouterID, ns[j].Hello.Header.RouterID
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+
+	return ns
+}
+
+// Run sends and receives Hellos until ctx is canceled. If fn is not nil, it is
+// called from Run's goroutine with each NeighborEvent and the affected
+// Neighbor. A HelloReceived event is reported for each accepted Hello, followed
+// by TwoWayReceived or OneWayReceived if the neighbor's TwoWay state changed.
+// fn must not block, so callers which need channel notifications should send
+// to a buffered channel or start a goroutine.
+//
+// Received Hellos are ignored if their HelloInterval, RouterDeadInterval, or
+// E-bit and N-bit Options do not match those of the HelloProtocol, per
+// RFC5340, section 4.2.2.1. Use Config.Areas and Config.InstanceIDs to discard
+// Hellos from other areas and instances. Other packets are discarded.
+//
+// A Hello which cannot be sent, such as due to ErrRateLimited or a transient
+// network error, is counted by SendErrors and sent again after HelloInterval,
+// so that neighbors are not torn down for a single missed Hello. Run returns an
+// error only if the PacketConn is closed or a read fails.
+func (p *HelloProtocol) Run(ctx context.Context, fn func(n Neighbor, e NeighborEvent)) error {
+	if fn == nil {
+		fn = func(Neighbor, NeighborEvent) {}
+	}
+
+	l := &helloLoop{
+		c:          p.c,
+		interval:   p.h.HelloInterval,
+		now:        p.now,
+		sendErrors: &p.sendErrors,
+		hello:      p.hello,
+		tick: func(now time.Time) time.Time {
+			for _, n := range p.expire(now) {
+				fn(n, InactivityTimer)
+			}
+
+			// Wake up to declare the next neighbor down.
+			return p.nextExpiry()
+		},
+		receive: func(h *Hello, src *net.IPAddr) {
+			n, events := p.receive(h, src, p.now())
+			for _, e := range events {
+				fn(n, e)
+			}
+		},
+	}
+
+	return l.run(ctx)
+}
+
+// SendErrors returns the number of Hellos which Run could not send.
+func (p *HelloProtocol) SendErrors() uint64 {
+	return atomic.LoadUint64(&p.sendErrors)
+}
+
+// hello returns the Hello to send, listing each neighbor which is up.
+func (p *HelloProtocol) hello() *Hello {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := p.h
+	h.NeighborIDs = make([]ID, 0, len(p.neighbors))
+	for id := range p.neighbors {
+		h.NeighborIDs = append(h.NeighborIDs, id)
+	}
+
+	sort.Slice(h.NeighborIDs, func(i, j int) bool {
+		return bytes.Compare(h.NeighborIDs[i][:], h.NeighborIDs[j][:]) < 0
+	})
+
+	return &h
+}
+
+// receive processes a Hello h received from src at time now. It returns the
+// updated Neighbor and the resulting events, or no events if h is ignored.
+func (p *HelloProtocol) receive(h *Hello, src *net.IPAddr, now time.Time) (Neighbor, []NeighborEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := h.Header.RouterID
+	if id == p.h.Header.RouterID {
+		return Neighbor{}, nil
+	}
+
+	// RFC5340, section 4.2.2.1: the timers and the E-bit and N-bit Options
+	// which describe the area type must match.
+	if h.HelloInterval != p.h.HelloInterval ||
+		h.RouterDeadInterval != p.h.RouterDeadInterval ||
+		(h.Options^p.h.Options)&(EBit|NBit) != 0 {
+		return Neighbor{}, nil
+	}
+
+	var twoWay bool
+	for _, nid := range h.NeighborIDs {
+		if nid == p.h.Header.RouterID {
+			twoWay = true
+			break
+		}
+	}
+
+	n, ok := p.neighbors[id]
+	if !ok {
+		n = &Neighbor{}
+		p.neighbors[id] = n
+	}
+
+	events := []NeighborEvent{HelloReceived}
+	switch {
+	case twoWay && !n.TwoWay:
+		events = append(events, TwoWayReceived)
+	case !twoWay && n.TwoWay:
+		events = append(events, OneWayReceived)
+	}
+
+	n.Address = src
+	n.Hello = h
+	n.LastHello = now
+	n.TwoWay = twoWay
+
+	return *n, events
+}
+
+// expire removes and returns each neighbor which has not sent a Hello within
+// RouterDeadInterval at time now, causing an InactivityTimer event.
+func (p *HelloProtocol) expire(now time.Time) []Neighbor {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var down []Neighbor
+	for id, n := range p.neighbors {
+		if now.Before(n.LastHello.Add(p.h.RouterDeadInterval)) {
+			continue
+		}
+
+		down = append(down, *n)
+		delete(p.neighbors, id)
+	}
+
+	return down
+}
+
+// nextExpiry returns the time at which the next neighbor will be declared
+// down, or the zero time if there are no neighbors.
+func (p *HelloProtocol) nextExpiry() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var next time.Time
+	for _, n := range p.neighbors {
+		if t := n.LastHello.Add(p.h.RouterDeadInterval); next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+
+	return next
+}
+
+// A helloLoop sends a Hello every interval over a PacketConn and passes each
+// received Hello to a handler. It implements the send and receive loop shared
+// by HelloProtocol and Prober.
+type helloLoop struct {
+	c        PacketConn
+	interval time.Duration
+	now      func() time.Time
+
+	// sendErrors counts Hellos which could not be sent.
+	sendErrors *uint64
+
+	// hello returns the Hello to send.
+	hello func() *Hello
+
+	// tick, if set, is called with the current time before each Hello is
+	// sent or received, and returns the time at which it must next be
+	// called, or the zero time if it need not be.
+	tick func(now time.Time) time.Time
+
+	// receive is called with each Hello received from src.
+	receive func(h *Hello, src *net.IPAddr)
+}
+
+// run runs the loop until ctx is canceled.
+func (l *helloLoop) run(ctx context.Context) error {
+	rc := newReadCanceler(ctx, l.c)
+	defer rc.close()
+
+	var next time.Time
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		now := l.now()
+		var wake time.Time
+		if l.tick != nil {
+			wake = l.tick(now)
+		}
+
+		if !now.Before(next) {
+			// A Hello which can't be sent is retried at the next interval,
+			// unless the PacketConn is closed.
+			if err := l.c.WriteTo(l.hello(), AllSPFRouters); err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return fmt.Errorf("ospf3: failed to send Hello: %w", err)
+				}
+
+				atomic.AddUint64(l.sendErrors, 1)
+			}
+
+			next = now.Add(l.interval)
+		}
+
+		// Wake up to send the next Hello, for tick, or to stop.
+		deadline := next
+		if !wake.IsZero() && wake.Before(deadline) {
+			deadline = wake
+		}
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		if err := rc.setReadDeadline(deadline); err != nil {
+			return err
+		}
+
+		pkt, _, src, err := l.c.ReadFrom()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+
+			return err
+		}
+
+		if h, ok := pkt.(*Hello); ok {
+			l.receive(h, src)
+		}
+	}
+}
+
+// A readCanceler interrupts a pending ReadFrom on a PacketConn when a context
+// is canceled, by setting a read deadline in the past. Read deadlines must be
+// set using setReadDeadline, which cannot replace the past deadline once the
+// context is canceled.
+type readCanceler struct {
+	c    PacketConn
+	done chan struct{}
+
+	mu       sync.Mutex
+	canceled bool
+}
+
+// newReadCanceler creates a readCanceler which watches ctx until close is
+// called.
+func newReadCanceler(ctx context.Context, c PacketConn) *readCanceler {
+	rc := &readCanceler{
+		c:    c,
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			rc.mu.Lock()
+			defer rc.mu.Unlock()
+
+			rc.canceled = true
+			_ = rc.c.SetReadDeadline(time.Now())
+		case <-rc.done:
+		}
+	}()
+
+	return rc
+}
+
+// setReadDeadline sets the read deadline of the PacketConn to t, unless the
+// context has been canceled.
+func (rc *readCanceler) setReadDeadline(t time.Time) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.canceled {
+		return nil
+	}
+
+	return rc.c.SetReadDeadline(t)
+}
+
+// close stops watching the context.
+func (rc *readCanceler) close() { close(rc.done) }
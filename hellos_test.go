@@ -0,0 +1,360 @@
+package ospf3
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestHelloProtocolPipe(t *testing.T) {
+	c1, c2 := Pipe()
+	defer c1.Close()
+
+	var (
+		id1 = ID{192, 0, 2, 1}
+		id2 = ID{192, 0, 2, 2}
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	// Run the protocol on each side of the link so that each discovers the
+	// other, and then sees itself listed in the other's Hellos.
+	type event struct {
+		ID    ID
+		Event NeighborEvent
+	}
+
+	var (
+		wg     sync.WaitGroup
+		ps     [2]*HelloProtocol
+		hellos [2]int
+		events [2][]event
+		errs   [2]error
+	)
+	for i, c := range []PacketConn{c1, c2} {
+		p, err := NewHelloProtocol(c, testHello([]ID{id1, id2}[i]), testTimers())
+		if err != nil {
+			t.Fatalf("failed to create HelloProtocol: %v", err)
+		}
+		ps[i] = p
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = ps[i].Run(ctx, func(n Neighbor, e NeighborEvent) {
+				// The number of Hellos depends on timing, so only count them.
+				if e == HelloReceived {
+					hellos[i]++
+					return
+				}
+
+				events[i] = append(events[i], event{
+					ID:    n.Hello.Header.RouterID,
+					Event: e,
+				})
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, want := range []ID{id2, id1} {
+		if errs[i] != nil {
+			t.Fatalf("failed to run HelloProtocol: %v", errs[i])
+		}
+
+		if hellos[i] < 2 {
+			t.Fatalf("expected at least 2 Hellos, but got: %d", hellos[i])
+		}
+
+		wantEvents := []event{{ID: want, Event: TwoWayReceived}}
+		if diff := cmp.Diff(wantEvents, events[i]); diff != "" {
+			t.Fatalf("unexpected events (-want +got):\n%s", diff)
+		}
+
+		ns := ps[i].Neighbors()
+		if len(ns) != 1 {
+			t.Fatalf("expected 1 neighbor, but got: %d", len(ns))
+		}
+		if diff := cmp.Diff(want, ns[0].Hello.Header.RouterID); diff != "" {
+			t.Fatalf("unexpected router ID (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestHelloProtocolInactivityTimer(t *testing.T) {
+	c1, c2 := Pipe()
+	defer c1.Close()
+
+	tm := testTimers()
+	tm.RouterDeadInterval = 2 * time.Second
+
+	h := testHello(ID{192, 0, 2, 1})
+	h.RouterDeadInterval = tm.RouterDeadInterval
+
+	p, err := NewHelloProtocol(c1, h, tm)
+	if err != nil {
+		t.Fatalf("failed to create HelloProtocol: %v", err)
+	}
+
+	// The neighbor sends a single Hello and then goes silent, so it must be
+	// declared down after RouterDeadInterval.
+	peer := *h
+	peer.Header.RouterID = ID{192, 0, 2, 2}
+	if err := c2.WriteTo(&peer, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		events []NeighborEvent
+		heard  time.Time
+		down   time.Duration
+	)
+	err = p.Run(ctx, func(n Neighbor, e NeighborEvent) {
+		events = append(events, e)
+
+		switch e {
+		case HelloReceived:
+			heard = n.LastHello
+		case InactivityTimer:
+			down = time.Since(heard)
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to run HelloProtocol: %v", err)
+	}
+
+	if diff := cmp.Diff([]NeighborEvent{HelloReceived, InactivityTimer}, events); diff != "" {
+		t.Fatalf("unexpected events (-want +got):\n%s", diff)
+	}
+	if down < h.RouterDeadInterval {
+		t.Fatalf("neighbor declared down after only %v", down)
+	}
+	if ns := p.Neighbors(); len(ns) != 0 {
+		t.Fatalf("expected no neighbors, but got: %d", len(ns))
+	}
+}
+
+func TestHelloProtocolNeighbors(t *testing.T) {
+	var (
+		self  = ID{192, 0, 2, 1}
+		id2   = ID{192, 0, 2, 2}
+		id3   = ID{192, 0, 2, 3}
+		addr2 = &net.IPAddr{IP: net.ParseIP("fe80::2")}
+		addr3 = &net.IPAddr{IP: net.ParseIP("fe80::3")}
+		start = time.Unix(0, 0)
+	)
+
+	p, err := NewHelloProtocol(nil, testHello(self), testTimers())
+	if err != nil {
+		t.Fatalf("failed to create HelloProtocol: %v", err)
+	}
+
+	p.SetDesignatedRouters(id3, id2)
+
+	hello := func(id ID, neighbors ...ID) *Hello {
+		h := testHello(id)
+		h.NeighborIDs = neighbors
+		return h
+	}
+
+	mismatched := []*Hello{
+		// Our own Hello looped back.
+		hello(self),
+		func() *Hello { h := hello(id2); h.HelloInterval = 2 * time.Second; return h }(),
+		func() *Hello { h := hello(id2); h.RouterDeadInterval = 8 * time.Second; return h }(),
+		func() *Hello { h := hello(id2); h.Options &^= EBit; return h }(),
+	}
+	for _, h := range mismatched {
+		if _, events := p.receive(h, addr2, start); len(events) != 0 {
+			t.Fatalf("expected Hello to be ignored: %+v", h)
+		}
+	}
+
+	for _, tt := range []struct {
+		h      *Hello
+		src    *net.IPAddr
+		at     time.Duration
+		events []NeighborEvent
+		twoWay bool
+	}{
+		{h: hello(id3), src: addr3, events: []NeighborEvent{HelloReceived}},
+		{h: hello(id3, self), src: addr3, at: 1 * time.Second, events: []NeighborEvent{HelloReceived, TwoWayReceived}, twoWay: true},
+		{h: hello(id2), src: addr2, at: 1 * time.Second, events: []NeighborEvent{HelloReceived}},
+		{h: hello(id3), src: addr3, at: 2 * time.Second, events: []NeighborEvent{HelloReceived, OneWayReceived}},
+		{h: hello(id2, self), src: addr2, at: 3 * time.Second, events: []NeighborEvent{HelloReceived, TwoWayReceived}, twoWay: true},
+	} {
+		n, events := p.receive(tt.h, tt.src, start.Add(tt.at))
+		if diff := cmp.Diff(tt.events, events); diff != "" {
+			t.Fatalf("unexpected events for %s (-want +got):\n%s", tt.h.Header.RouterID, diff)
+		}
+		if diff := cmp.Diff(tt.twoWay, n.TwoWay); diff != "" {
+			t.Fatalf("unexpected TwoWay for %s (-want +got):\n%s", tt.h.Header.RouterID, diff)
+		}
+	}
+
+	want := hello(self, id2, id3)
+	want.DesignatedRouterID = id3
+	want.BackupDesignatedRouterID = id2
+	if diff := cmp.Diff(want, p.hello()); diff != "" {
+		t.Fatalf("unexpected Hello (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(start.Add(6*time.Second), p.nextExpiry()); diff != "" {
+		t.Fatalf("unexpected next expiry (-want +got):\n%s", diff)
+	}
+
+	// id3 was last heard from at 2 seconds and expires first.
+	if ns := p.expire(start.Add(5 * time.Second)); len(ns) != 0 {
+		t.Fatalf("expected no neighbors to expire, but got: %d", len(ns))
+	}
+
+	down := p.expire(start.Add(6 * time.Second))
+	wantDown := []Neighbor{{Address: addr3, Hello: hello(id3), LastHello: start.Add(2 * time.Second)}}
+	if diff := cmp.Diff(wantDown, down); diff != "" {
+		t.Fatalf("unexpected expired neighbors (-want +got):\n%s", diff)
+	}
+
+	wantNeighbors := []Neighbor{{
+		Address:   addr2,
+		Hello:     hello(id2, self),
+		LastHello: start.Add(3 * time.Second),
+		TwoWay:    true,
+	}}
+	if diff := cmp.Diff(wantNeighbors, p.Neighbors(), cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("unexpected neighbors (-want +got):\n%s", diff)
+	}
+}
+
+func TestHelloProtocolSendErrors(t *testing.T) {
+	c1, c2 := Pipe()
+	defer c1.Close()
+
+	p, err := NewHelloProtocol(&failWriteConn{PacketConn: c1, n: 2}, testHello(ID{192, 0, 2, 1}), testTimers())
+	if err != nil {
+		t.Fatalf("failed to create HelloProtocol: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	// The first Hellos are dropped, but the protocol keeps running and sends
+	// a Hello to the neighbor once the send errors stop.
+	if err := p.Run(ctx, nil); err != nil {
+		t.Fatalf("failed to run HelloProtocol: %v", err)
+	}
+	if diff := cmp.Diff(uint64(2), p.SendErrors()); diff != "" {
+		t.Fatalf("unexpected send errors (-want +got):\n%s", diff)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, _, _, err := c2.ReadFrom(); err != nil {
+		t.Fatalf("failed to read Hello: %v", err)
+	}
+
+	// A closed PacketConn stops the protocol.
+	_ = c1.Close()
+	if err := p.Run(ctx, nil); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("expected closed error, but got: %v", err)
+	}
+}
+
+// A failWriteConn is a PacketConn whose first n writes fail with
+// ErrRateLimited.
+type failWriteConn struct {
+	PacketConn
+
+	mu sync.Mutex
+	n  int
+}
+
+func (c *failWriteConn) WriteTo(p Packet, dst *net.IPAddr) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.n > 0 {
+		c.n--
+		return ErrRateLimited
+	}
+
+	return c.PacketConn.WriteTo(p, dst)
+}
+
+func Test_readCancelerCanceled(t *testing.T) {
+	c1, _ := Pipe()
+	defer c1.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rc := newReadCanceler(ctx, c1)
+	defer rc.close()
+
+	cancel()
+	for {
+		rc.mu.Lock()
+		canceled := rc.canceled
+		rc.mu.Unlock()
+		if canceled {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	// A deadline set after cancellation, as by a loop which checked the
+	// context just before it was canceled, must not delay the read.
+	if err := rc.setReadDeadline(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+
+	start := time.Now()
+	if _, _, _, err := c1.ReadFrom(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, but got: %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("read was not interrupted for %v", d)
+	}
+}
+
+func TestNewHelloProtocolErrors(t *testing.T) {
+	for _, h := range []*Hello{nil, {}, testHello(ID{})} {
+		if _, err := NewHelloProtocol(nil, h, testTimers()); err == nil {
+			t.Fatalf("expected an error for Hello: %+v", h)
+		}
+	}
+
+	if _, err := NewHelloProtocol(nil, testHello(ID{192, 0, 2, 1}), Timers{}); err == nil {
+		t.Fatal("expected an error for invalid Timers, but none occurred")
+	}
+}
+
+// testTimers returns valid Timers with short intervals for HelloProtocol and
+// Prober tests, which match those of testHello.
+func testTimers() Timers {
+	t := DefaultTimers()
+	t.HelloInterval = 1 * time.Second
+	t.RouterDeadInterval = 4 * time.Second
+	return t
+}
+
+// testHello returns a valid Hello from router id for HelloProtocol tests.
+func testHello(id ID) *Hello {
+	return &Hello{
+		Header:             Header{RouterID: id},
+		Options:            V6Bit | EBit | RBit,
+		HelloInterval:      1 * time.Second,
+		RouterDeadInterval: 4 * time.Second,
+	}
+}
@@ -0,0 +1,140 @@
+package ospf3
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// ipProtocolICMPv6 is the IP protocol number for ICMPv6.
+const ipProtocolICMPv6 = 58
+
+// An ICMPError is an ICMPv6 error message received in response to a unicast
+// OSPFv3 packet sent by a Conn, such as a Destination Unreachable message for
+// a neighbor which no longer responds to Neighbor Discovery.
+type ICMPError struct {
+	// Source is the address of the node which reported the error.
+	Source *net.IPAddr
+
+	// Neighbor is the destination address of the OSPFv3 packet which
+	// triggered the error.
+	Neighbor net.IP
+
+	// Type and Code are the ICMPv6 message type and code.
+	Type ipv6.ICMPType
+	Code int
+}
+
+// Error implements error.
+func (e *ICMPError) Error() string {
+	return fmt.Sprintf("ospf3: ICMPv6 %s (code %d) from %s for OSPFv3 neighbor %s",
+		e.Type, e.Code, e.Source, e.Neighbor)
+}
+
+// listenICMP creates an ICMPv6 socket which only receives error messages on
+// ifi, bound to the VRF device vrf if set.
+func listenICMP(ifi *net.Interface, vrf string) (*ipv6.PacketConn, error) {
+	conn, err := listenPacket("ip6:ipv6-icmp", vrf)
+	if err != nil {
+		return nil, err
+	}
+	c := ipv6.NewPacketConn(conn)
+
+	var f ipv6.ICMPFilter
+	f.SetAll(true)
+	for _, typ := range []ipv6.ICMPType{
+		ipv6.ICMPTypeDestinationUnreachable,
+		ipv6.ICMPTypePacketTooBig,
+		ipv6.ICMPTypeTimeExceeded,
+		ipv6.ICMPTypeParameterProblem,
+	} {
+		f.Accept(typ)
+	}
+
+	if err := c.SetICMPFilter(&f); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	// The interface index is used to discard errors for other interfaces.
+	if err := c.SetControlMessage(ipv6.FlagInterface, true); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ReadICMPError reads a single ICMPv6 error message which was received on the
+// Conn's interface in response to a unicast OSPFv3 packet. ReadICMPError will
+// block until a timeout occurs or such an error is read. Config.ICMPErrors
+// must be set to use ReadICMPError.
+func (c *Conn) ReadICMPError() (*ICMPError, error) {
+	if c.icmp == nil {
+		return nil, errors.New("ospf3: ICMPv6 errors are not enabled by Config.ICMPErrors")
+	}
+
+	// ICMPv6 error messages never exceed the IPv6 minimum MTU.
+	b := make([]byte, ipv6.HeaderLen+1280)
+	for {
+		n, cm, src, err := c.icmp.ReadFrom(b)
+		if err != nil {
+			return nil, err
+		}
+		if cm != nil && cm.IfIndex != c.ifi.Index {
+			continue
+		}
+
+		e, ok := parseICMPError(b[:n])
+		if !ok {
+			continue
+		}
+
+		e.Source, _ = src.(*net.IPAddr)
+		return e, nil
+	}
+}
+
+// parseICMPError parses an ICMPv6 error message from b and reports whether it
+// was triggered by a unicast OSPFv3 packet.
+func parseICMPError(b []byte) (*ICMPError, bool) {
+	m, err := icmp.ParseMessage(ipProtocolICMPv6, b)
+	if err != nil {
+		return nil, false
+	}
+
+	// Each error message carries as much of the invoking packet as possible,
+	// beginning with its IPv6 header.
+	var data []byte
+	switch body := m.Body.(type) {
+	case *icmp.DstUnreach:
+		data = body.Data
+	case *icmp.PacketTooBig:
+		data = body.Data
+	case *icmp.TimeExceeded:
+		data = body.Data
+	case *icmp.ParamProb:
+		data = body.Data
+	default:
+		return nil, false
+	}
+
+	h, err := ipv6.ParseHeader(data)
+	if err != nil || h.NextHeader != ipProtocolOSPF || h.Dst.IsMulticast() {
+		return nil, false
+	}
+
+	typ, ok := m.Type.(ipv6.ICMPType)
+	if !ok {
+		return nil, false
+	}
+
+	return &ICMPError{
+		Neighbor: h.Dst,
+		Type:     typ,
+		Code:     m.Code,
+	}, true
+}
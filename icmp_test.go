@@ -0,0 +1,127 @@
+package ospf3
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+func Test_parseICMPError(t *testing.T) {
+	invoking := func(nh int, dst string) []byte {
+		b := make([]byte, ipv6.HeaderLen+headerLen)
+		b[0] = 6 << 4
+		b[6] = byte(nh)
+		copy(b[8:24], net.ParseIP("fe80::1"))
+		copy(b[24:40], net.ParseIP(dst))
+		return b
+	}
+
+	marshal := func(typ ipv6.ICMPType, code int, body icmp.MessageBody) []byte {
+		b, err := (&icmp.Message{Type: typ, Code: code, Body: body}).Marshal(nil)
+		if err != nil {
+			t.Fatalf("failed to marshal ICMPv6 message: %v", err)
+		}
+
+		return b
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+		e    *ICMPError
+	}{
+		{
+			name: "address unreachable",
+			b: marshal(ipv6.ICMPTypeDestinationUnreachable, 3, &icmp.DstUnreach{
+				Data: invoking(ipProtocolOSPF, "fe80::2"),
+			}),
+			e: &ICMPError{
+				Neighbor: net.ParseIP("fe80::2"),
+				Type:     ipv6.ICMPTypeDestinationUnreachable,
+				Code:     3,
+			},
+		},
+		{
+			name: "packet too big",
+			b: marshal(ipv6.ICMPTypePacketTooBig, 0, &icmp.PacketTooBig{
+				MTU:  1280,
+				Data: invoking(ipProtocolOSPF, "2001:db8::2"),
+			}),
+			e: &ICMPError{
+				Neighbor: net.ParseIP("2001:db8::2"),
+				Type:     ipv6.ICMPTypePacketTooBig,
+			},
+		},
+		{
+			name: "not OSPFv3",
+			b: marshal(ipv6.ICMPTypeDestinationUnreachable, 3, &icmp.DstUnreach{
+				Data: invoking(17, "fe80::2"),
+			}),
+		},
+		{
+			name: "multicast",
+			b: marshal(ipv6.ICMPTypeParameterProblem, 0, &icmp.ParamProb{
+				Data: invoking(ipProtocolOSPF, "ff02::5"),
+			}),
+		},
+		{
+			name: "not an error",
+			b: marshal(ipv6.ICMPTypeEchoRequest, 0, &icmp.Echo{
+				Data: invoking(ipProtocolOSPF, "fe80::2"),
+			}),
+		},
+		{
+			name: "truncated",
+			b:    []byte{0x01},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := parseICMPError(tt.b)
+			if diff := cmp.Diff(tt.e != nil, ok); diff != "" {
+				t.Fatalf("unexpected ok (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.e, e); diff != "" {
+				t.Fatalf("unexpected ICMPError (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnReadICMPError(t *testing.T) {
+	c1, _ := testConns(t, &Config{ICMPErrors: true})
+
+	// Unicast to a link-local address which does not exist on the link, so
+	// that neighbor discovery fails and the kernel reports the neighbor as
+	// unreachable.
+	dst := &net.IPAddr{IP: net.ParseIP("fe80::dead:beef"), Zone: c1.ifi.Name}
+	if err := c1.WriteTo(&Hello{Header: Header{RouterID: ID{192, 0, 2, 1}}}, dst); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if err := c1.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	e, err := c1.ReadICMPError()
+	if err != nil {
+		t.Fatalf("failed to read ICMPv6 error: %v", err)
+	}
+
+	if !e.Neighbor.Equal(dst.IP) || e.Type != ipv6.ICMPTypeDestinationUnreachable {
+		t.Fatalf("unexpected ICMPv6 error: %v", e)
+	}
+}
+
+func TestConnReadICMPErrorDisabled(t *testing.T) {
+	c1, _ := testConns(t, nil)
+	if _, err := c1.ReadICMPError(); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+}
@@ -0,0 +1,172 @@
+package ospf3
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Key is an HMAC key for Authentication Trailers in a Keychain, identified
+// by its security association ID. A Key may be used to send packets between
+// SendStart and SendEnd, and to accept packets between AcceptStart and
+// AcceptEnd. A zero start or end time leaves that end of the lifetime
+// unbounded.
+type Key struct {
+	SAID uint16
+	HMACKey
+
+	SendStart, SendEnd     time.Time
+	AcceptStart, AcceptEnd time.Time
+}
+
+// sending reports whether the Key may be used to send packets at now.
+func (k Key) sending(now time.Time) bool { return within(now, k.SendStart, k.SendEnd) }
+
+// accepting reports whether the Key may be used to accept packets at now.
+func (k Key) accepting(now time.Time) bool { return within(now, k.AcceptStart, k.AcceptEnd) }
+
+// within reports whether now is within the lifetime beginning at start and
+// ending at end, where zero values are unbounded.
+func within(now, start, end time.Time) bool {
+	return (start.IsZero() || !now.Before(start)) && (end.IsZero() || now.Before(end))
+}
+
+// A Keychain is an Authenticator which holds multiple Keys with send and
+// accept lifetimes, so that the security association used to send packets can
+// roll over from one Key to another without dropping adjacencies. A Keychain
+// is safe for concurrent use. Use NewKeychain to create a Keychain.
+type Keychain struct {
+	now func() time.Time
+
+	mu   sync.RWMutex
+	keys map[uint16]Key
+}
+
+var _ Authenticator = &Keychain{}
+
+// NewKeychain creates a Keychain from keys. See Keychain.SetKeys for details.
+func NewKeychain(keys []Key) (*Keychain, error) {
+	k := &Keychain{now: time.Now}
+	if err := k.SetKeys(keys); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// SetKeys replaces the Keys of the Keychain. Each Key must have a unique SAID
+// and a hash function.
+func (k *Keychain) SetKeys(keys []Key) error {
+	m := make(map[uint16]Key, len(keys))
+	for _, key := range keys {
+		if key.Hash == nil {
+			return fmt.Errorf("ospf3: Key with security association ID %d has no hash function", key.SAID)
+		}
+		if _, ok := m[key.SAID]; ok {
+			return fmt.Errorf("ospf3: duplicate Key with security association ID %d", key.SAID)
+		}
+
+		m[key.SAID] = key
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = m
+	return nil
+}
+
+// SendKey returns the security association ID of the Key which should be used
+// to send packets. If multiple Keys are within their send lifetimes, the Key
+// with the most recent SendStart is used, with ties broken by the highest
+// SAID. SendKey reports false if no Key may be used to send packets.
+func (k *Keychain) SendKey() (uint16, bool) {
+	now := k.now()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var (
+		best Key
+		ok   bool
+	)
+	for _, key := range k.keys {
+		if !key.sending(now) {
+			continue
+		}
+
+		if !ok || key.SendStart.After(best.SendStart) ||
+			(key.SendStart.Equal(best.SendStart) && key.SAID > best.SAID) {
+			best, ok = key, true
+		}
+	}
+
+	return best.SAID, ok
+}
+
+// Size implements Authenticator. The Key for said must be within its send
+// lifetime.
+func (k *Keychain) Size(said uint16) (int, error) {
+	key, err := k.key(said, Key.sending, "sending")
+	if err != nil {
+		return 0, err
+	}
+
+	return key.Hash().Size(), nil
+}
+
+// Sum implements Authenticator. The Key for said must be within its send
+// lifetime.
+func (k *Keychain) Sum(said uint16, b []byte) ([]byte, error) {
+	key, err := k.key(said, Key.sending, "sending")
+	if err != nil {
+		return nil, err
+	}
+
+	return key.sum(b), nil
+}
+
+// accepting implements acceptor.
+func (k *Keychain) accepting() Authenticator { return keychainAcceptor{k: k} }
+
+// A keychainAcceptor is an Authenticator which verifies received packets
+// using the Keys of a Keychain which are within their accept lifetimes.
+type keychainAcceptor struct{ k *Keychain }
+
+// Size implements Authenticator.
+func (a keychainAcceptor) Size(said uint16) (int, error) {
+	key, err := a.k.key(said, Key.accepting, "accepting")
+	if err != nil {
+		return 0, err
+	}
+
+	return key.Hash().Size(), nil
+}
+
+// Sum implements Authenticator.
+func (a keychainAcceptor) Sum(said uint16, b []byte) ([]byte, error) {
+	key, err := a.k.key(said, Key.accepting, "accepting")
+	if err != nil {
+		return nil, err
+	}
+
+	return key.sum(b), nil
+}
+
+// key returns the Key for said if valid reports that it is within the
+// lifetime used for op, such as sending or accepting.
+func (k *Keychain) key(said uint16, valid func(Key, time.Time) bool, op string) (Key, error) {
+	now := k.now()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[said]
+	if !ok {
+		return Key{}, fmt.Errorf("ospf3: unknown security association ID %d", said)
+	}
+	if !valid(key, now) {
+		return Key{}, fmt.Errorf("ospf3: Key with security association ID %d is not valid for %s", said, op)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,238 @@
+package ospf3
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/ipv6"
+)
+
+func TestKeychainRollover(t *testing.T) {
+	var (
+		t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		t1 = t0.Add(1 * time.Hour)
+		t2 = t0.Add(2 * time.Hour)
+		t3 = t0.Add(3 * time.Hour)
+	)
+
+	// Key 1 is replaced by key 2 for sending at t1, but is still accepted
+	// until t2 so that neighbors have time to roll over.
+	k, err := NewKeychain([]Key{
+		{
+			SAID:      1,
+			HMACKey:   HMACKey{Hash: sha256.New, Key: []byte("one")},
+			SendEnd:   t1,
+			AcceptEnd: t2,
+		},
+		{
+			SAID:        2,
+			HMACKey:     HMACKey{Hash: sha256.New, Key: []byte("two")},
+			SendStart:   t1,
+			AcceptStart: t0,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create Keychain: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		send   uint16
+		accept []uint16
+		reject []uint16
+	}{
+		{
+			name:   "before rollover",
+			now:    t0,
+			send:   1,
+			accept: []uint16{1, 2},
+		},
+		{
+			name:   "after rollover",
+			now:    t1,
+			send:   2,
+			accept: []uint16{1, 2},
+		},
+		{
+			name:   "key 1 expired",
+			now:    t3,
+			send:   2,
+			accept: []uint16{2},
+			reject: []uint16{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k.now = func() time.Time { return tt.now }
+
+			said, ok := k.SendKey()
+			if !ok {
+				t.Fatal("no send key")
+			}
+			if diff := cmp.Diff(tt.send, said); diff != "" {
+				t.Fatalf("unexpected send key (-want +got):\n%s", diff)
+			}
+
+			if _, err := k.Sum(tt.send, nil); err != nil {
+				t.Fatalf("failed to sum with send key %d: %v", tt.send, err)
+			}
+
+			accept := k.accepting()
+			for _, said := range tt.accept {
+				if _, err := accept.Sum(said, nil); err != nil {
+					t.Fatalf("failed to sum with key %d: %v", said, err)
+				}
+			}
+			for _, said := range tt.reject {
+				if _, err := accept.Size(said); err == nil {
+					t.Fatalf("expected key %d to be rejected", said)
+				}
+			}
+		})
+	}
+}
+
+func TestKeychainAcceptLifetime(t *testing.T) {
+	var (
+		now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		src = net.ParseIP("fe80::1")
+	)
+
+	// The Key may be used to send packets, but its accept lifetime has not
+	// yet begun, so received packets must not be verified with it.
+	k, err := NewKeychain([]Key{{
+		SAID:        1,
+		HMACKey:     HMACKey{Hash: sha256.New, Key: []byte("ospf3")},
+		AcceptStart: now.Add(1 * time.Hour),
+	}})
+	if err != nil {
+		t.Fatalf("failed to create Keychain: %v", err)
+	}
+	k.now = func() time.Time { return now }
+
+	h := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		Options:     V6Bit | ATBit,
+		NeighborIDs: []ID{},
+	}
+
+	b, err := MarshalOptions{Source: src, Authenticator: k, SAID: 1}.MarshalPacket(h)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	o := ParseOptions{Source: src, Authenticator: k}
+	if _, err := o.ParsePacket(b); !errors.Is(err, errAuth) {
+		t.Fatalf("expected authentication error, but got: %v", err)
+	}
+
+	// Once the accept lifetime begins, the packet is verified.
+	k.now = func() time.Time { return now.Add(1 * time.Hour) }
+	if _, err := o.ParsePacket(b); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+}
+
+func TestKeychainNoSendKey(t *testing.T) {
+	k, err := NewKeychain([]Key{{
+		SAID:      1,
+		HMACKey:   HMACKey{Hash: sha256.New},
+		SendStart: time.Now().Add(1 * time.Hour),
+	}})
+	if err != nil {
+		t.Fatalf("failed to create Keychain: %v", err)
+	}
+
+	if _, ok := k.SendKey(); ok {
+		t.Fatal("expected no send key")
+	}
+}
+
+func TestNewKeychainErrors(t *testing.T) {
+	for _, keys := range [][]Key{
+		{{SAID: 1}},
+		{
+			{SAID: 1, HMACKey: HMACKey{Hash: sha256.New}},
+			{SAID: 1, HMACKey: HMACKey{Hash: sha256.New}},
+		},
+	} {
+		if _, err := NewKeychain(keys); err == nil {
+			t.Fatalf("expected an error for %+v", keys)
+		}
+	}
+}
+
+func TestConnKeychain(t *testing.T) {
+	k, err := NewKeychain([]Key{{
+		SAID:    1,
+		HMACKey: HMACKey{Hash: sha256.New, Key: []byte("ospf3")},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create Keychain: %v", err)
+	}
+
+	c1, c2 := testConns(t, &Config{Keychain: k})
+
+	want := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		Options:     V6Bit | ATBit,
+		NeighborIDs: []ID{},
+	}
+
+	// Without the AT-bit, the Hello cannot be authenticated.
+	if err := c1.WriteTo(&Hello{}, AllSPFRouters); err == nil {
+		t.Fatal("expected an error writing Hello without AT-bit")
+	}
+
+	if err := c1.WriteTo(want, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	p, _, src, err := c2.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	h := p.(*Hello)
+	if h.Header.Checksum == 0 {
+		t.Fatal("no Header checksum set")
+	}
+	h.Header.Checksum = 0
+
+	if diff := cmp.Diff(want, h); diff != "" {
+		t.Fatalf("unexpected Hello (-want +got):\n%s", diff)
+	}
+
+	if !src.IP.Equal(c1.src) {
+		t.Fatalf("unexpected source address: %v", src)
+	}
+	// A Hello which fills the MTU on its own no longer fits once the
+	// Authentication Trailer is added.
+	full := *want
+	full.NeighborIDs = nil
+	for len(full.NeighborIDs) < (c1.ifi.MTU-ipv6.HeaderLen-headerLen-helloLen)/4 {
+		n := len(full.NeighborIDs)
+		full.NeighborIDs = append(full.NeighborIDs, ID{192, 0, byte(n >> 8), byte(n)})
+	}
+
+	err = c1.WriteTo(&full, AllSPFRouters)
+	var nerr *NeighborOverflowError
+	if !errors.As(err, &nerr) {
+		t.Fatalf("expected *NeighborOverflowError, but got: %v", err)
+	}
+
+	// An HMAC-SHA-256 trailer requires 48 bytes, or 12 neighbor IDs.
+	if diff := cmp.Diff(12, nerr.Overflow); diff != "" {
+		t.Fatalf("unexpected overflow (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,105 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// A LimitError is returned by ParseOptions.ParsePacket when a packet exceeds
+// one of the limits configured by ParseOptions.
+type LimitError struct {
+	// Limit describes the exceeded limit, such as "LSAs", "LSA length", or
+	// "body length".
+	Limit string
+
+	// Value is the value found in the packet, and Max is the configured
+	// maximum.
+	Value, Max int
+}
+
+// Error implements error.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("ospf3: packet exceeds %s limit: %d > %d", e.Limit, e.Value, e.Max)
+}
+
+// checkLimits verifies that the body b of a packet of type ptyp does not
+// exceed the limits set in o. It only inspects fixed offsets and LSA header
+// lengths so that limits are enforced before any LSAs are allocated, leaving
+// the validation of everything else to the packet's unmarshal method.
+func (o ParseOptions) checkLimits(ptyp packetType, b []byte) error {
+	if o.MaxLSAs <= 0 && o.MaxLSALength <= 0 && o.MaxBodyLength <= 0 {
+		return nil
+	}
+
+	if o.MaxBodyLength > 0 && len(b) > o.MaxBodyLength {
+		return &LimitError{
+			Limit: "body length",
+			Value: len(b),
+			Max:   o.MaxBodyLength,
+		}
+	}
+
+	// The number of LSAs or LSA headers carried by the packet. Only
+	// LinkStateUpdate carries an explicit count, and the count may be bogus,
+	// so it is compared as an unsigned value.
+	var n uint64
+	switch ptyp {
+	case databaseDescription:
+		if len(b) > ddLen {
+			n = uint64((len(b) - ddLen) / lsaHeaderLen)
+		}
+	case linkStateRequest:
+		n = uint64(len(b) / lsaLen)
+	case linkStateUpdate:
+		if len(b) >= lsuLen {
+			n = uint64(binary.BigEndian.Uint32(b[0:4]))
+		}
+	case linkStateAcknowledgement:
+		n = uint64(len(b) / lsaHeaderLen)
+	}
+
+	if o.MaxLSAs > 0 && n > uint64(o.MaxLSAs) {
+		return &LimitError{
+			Limit: "LSAs",
+			Value: int(min64(n, uint64(maxInt))),
+			Max:   o.MaxLSAs,
+		}
+	}
+
+	if ptyp != linkStateUpdate || o.MaxLSALength <= 0 {
+		return nil
+	}
+
+	// Walk each LSA header's length field, stopping at the first malformed
+	// LSA so that the unmarshal method can report it.
+	off := lsuLen
+	for i := uint64(0); i < n && off+lsaHeaderLen <= len(b); i++ {
+		l := int(binary.BigEndian.Uint16(b[off+18 : off+20]))
+		if l > o.MaxLSALength {
+			return &LimitError{
+				Limit: "LSA length",
+				Value: l,
+				Max:   o.MaxLSALength,
+			}
+		}
+		if l < lsaHeaderLen {
+			break
+		}
+
+		off += l
+	}
+
+	return nil
+}
+
+// maxInt is the maximum value of an int.
+const maxInt = int(^uint(0) >> 1)
+
+// min64 returns the minimum of a and b.
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
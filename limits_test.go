@@ -0,0 +1,99 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOptionsLimits(t *testing.T) {
+	// A LinkStateUpdate which claims to carry far more LSAs than it does.
+	bogus := append([]byte(nil), bufLinkStateUpdate...)
+	copy(bogus[headerLen:headerLen+4], []byte{0xff, 0xff, 0xff, 0xff})
+
+	tests := []struct {
+		name string
+		b    []byte
+		o    ParseOptions
+		want *LimitError
+	}{
+		{
+			name: "DatabaseDescription",
+			b:    bufDatabaseDescription,
+			o:    ParseOptions{MaxLSAs: 1},
+			want: &LimitError{Limit: "LSAs", Value: 2, Max: 1},
+		},
+		{
+			name: "LinkStateRequest",
+			b:    bufLinkStateRequest,
+			o:    ParseOptions{MaxLSAs: 1},
+			want: &LimitError{Limit: "LSAs", Value: 2, Max: 1},
+		},
+		{
+			name: "LinkStateUpdate",
+			b:    bufLinkStateUpdate,
+			o:    ParseOptions{MaxLSAs: 1},
+			want: &LimitError{Limit: "LSAs", Value: 2, Max: 1},
+		},
+		{
+			name: "LinkStateUpdate bogus count",
+			b:    bogus,
+			o:    ParseOptions{MaxLSAs: 8},
+			want: &LimitError{Limit: "LSAs", Value: int(min64(0xffffffff, uint64(maxInt))), Max: 8},
+		},
+		{
+			name: "LinkStateUpdate LSA length",
+			b:    bufLinkStateUpdate,
+			o:    ParseOptions{MaxLSALength: 55},
+			want: &LimitError{Limit: "LSA length", Value: 56, Max: 55},
+		},
+		{
+			name: "body length",
+			b:    bufHello,
+			o:    ParseOptions{MaxBodyLength: helloLen},
+			want: &LimitError{Limit: "body length", Value: helloLen + 8, Max: helloLen},
+		},
+		{
+			name: "LinkStateAcknowledgement",
+			b:    bufLinkStateAcknowledgement,
+			o:    ParseOptions{MaxLSAs: 1},
+			want: &LimitError{Limit: "LSAs", Value: 2, Max: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.o.ParsePacket(tt.b)
+
+			var lerr *LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("expected *LimitError, but got: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, lerr); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseOptionsLimitsOK(t *testing.T) {
+	// Packets at the limits must be accepted.
+	o := ParseOptions{MaxLSAs: 2, MaxLSALength: 56}
+	for _, b := range [][]byte{
+		bufHello,
+		bufDatabaseDescription,
+		bufLinkStateRequest,
+		bufLinkStateUpdate,
+		bufLinkStateAcknowledgement,
+	} {
+		// Limit the body to exactly the length in the Header.
+		o.MaxBodyLength = int(binary.BigEndian.Uint16(b[2:4])) - headerLen
+
+		if _, err := o.ParsePacket(b); err != nil {
+			t.Fatalf("failed to parse: %v", err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// llsHeaderLen is the length of an LLS data block with no trailing TLVs.
+const llsHeaderLen = 4
+
+// LLS TLV types as described in RFC5613, section 2.3.
+const tlvExtendedOptions = 1
+
+// ExtendedOptions is a bitmask of options carried by the Extended Options and
+// Flags TLV of an LLS data block, as described in RFC5613, section 2.5.
+type ExtendedOptions uint32
+
+// Possible ExtendedOptions values.
+const (
+	LRBit ExtendedOptions = 1 << 0
+	RSBit ExtendedOptions = 1 << 1
+)
+
+// String returns the string representation of an ExtendedOptions bitmask.
+func (o ExtendedOptions) String() string {
+	return flagsString(uint(o), []string{
+		"LR-bit",
+		"RS-bit",
+	})
+}
+
+// An LLS is a Link-Local Signaling data block as described in RFC5613. An LLS
+// may follow a Hello or DatabaseDescription packet which sets LBit in its
+// Options. The LLS is not counted in the OSPFv3 packet length or checksum.
+type LLS struct {
+	// ExtendedOptions, if nonzero, is encoded as an Extended Options and
+	// Flags TLV.
+	ExtendedOptions ExtendedOptions
+
+	// TLVs contains any other TLVs in the order in which they appear, which
+	// are marshaled following the Extended Options and Flags TLV.
+	TLVs []TLV
+}
+
+// tlvs returns all of the TLVs for the LLS in marshaling order.
+func (l *LLS) tlvs() []TLV {
+	if l.ExtendedOptions == 0 {
+		return l.TLVs
+	}
+
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint32(v, uint32(l.ExtendedOptions))
+
+	tlvs := make([]TLV, 0, 1+len(l.TLVs))
+	tlvs = append(tlvs, TLV{Type: tlvExtendedOptions, Value: v})
+
+	return append(tlvs, l.TLVs...)
+}
+
+// len returns the length of an LLS in bytes, or 0 if l is nil.
+func (l *LLS) len() int {
+	if l == nil {
+		return 0
+	}
+
+	return llsHeaderLen + tlvsLen(l.tlvs())
+}
+
+// marshal stores the LLS bytes into b, which must be exactly the length of the
+// LLS.
+func (l *LLS) marshal(b []byte) error {
+	// The LLS data length is a count of 32-bit words.
+	if n := len(b) / 4; n > math.MaxUint16 {
+		return fmt.Errorf("LLS data block is too long: %d words: %w", n, errMarshal)
+	}
+
+	binary.BigEndian.PutUint16(b[0:2], 0)
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)/4))
+	if err := marshalTLVs(b[llsHeaderLen:], l.tlvs()); err != nil {
+		return fmt.Errorf("LLS %w", err)
+	}
+
+	// The checksum is the standard IP checksum of the entire LLS.
+	binary.BigEndian.PutUint16(b[0:2], ^fold(onesSum(0, b)))
+	return nil
+}
+
+// parseLLS parses an LLS data block from the bytes following a packet. Per
+// RFC5613, section 2.2, the packet must still be processed if the LLS is
+// malformed or its checksum is invalid, so parseLLS returns nil rather than an
+// error in those cases.
+func parseLLS(b []byte) *LLS {
+	if len(b) < llsHeaderLen {
+		return nil
+	}
+
+	n := 4 * int(binary.BigEndian.Uint16(b[2:4]))
+	if n < llsHeaderLen || n > len(b) {
+		return nil
+	}
+	b = b[:n]
+
+	if fold(onesSum(0, b)) != 0xffff {
+		return nil
+	}
+
+	var (
+		l   LLS
+		ext bool
+	)
+	err := parseTLVs(b[llsHeaderLen:], func(typ uint16, v []byte) error {
+		// Only the first Extended Options and Flags TLV is interpreted.
+		if typ == tlvExtendedOptions && !ext && len(v) == 4 {
+			l.ExtendedOptions = ExtendedOptions(binary.BigEndian.Uint32(v))
+			ext = true
+			return nil
+		}
+
+		l.TLVs = append(l.TLVs, TLV{
+			Type:  typ,
+			Value: append([]byte(nil), v...),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+
+	return &l
+}
+
+// packetLLS returns the LLS which follows p, if any.
+func packetLLS(p Packet) *LLS {
+	switch p := p.(type) {
+	case *Hello:
+		return p.LLS
+	case *DatabaseDescription:
+		return p.LLS
+	default:
+		return nil
+	}
+}
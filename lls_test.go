@@ -0,0 +1,115 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLLSRoundTrip(t *testing.T) {
+	lls := &LLS{
+		ExtendedOptions: LRBit,
+		TLVs:            []TLV{{Type: 255, Value: []byte{0xff}}},
+	}
+
+	bufLLS := []byte{
+		0xff, 0xf3, 0x00, 0x05, // Checksum, length in words
+		0x00, 0x01, 0x00, 0x04, // Extended Options and Flags TLV
+		0x00, 0x00, 0x00, 0x01, // LR-bit
+		0x00, 0xff, 0x00, 0x01, // Unknown TLV
+		0xff, 0x00, 0x00, 0x00, // Value, padding
+	}
+
+	tests := []struct {
+		name string
+		p    Packet
+	}{
+		{
+			name: "Hello",
+			p: &Hello{
+				Header:      Header{RouterID: ID{192, 0, 2, 1}},
+				Options:     V6Bit | LBit,
+				NeighborIDs: []ID{},
+				LLS:         lls,
+			},
+		},
+		{
+			name: "DatabaseDescription",
+			p: &DatabaseDescription{
+				Header:  Header{RouterID: ID{192, 0, 2, 1}},
+				Options: V6Bit | LBit,
+				LSAs:    []LSAHeader{},
+				LLS:     lls,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := MarshalPacket(tt.p)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			// The LLS follows the packet and is not counted in its length.
+			n := len(b) - len(bufLLS)
+			if diff := cmp.Diff(bufLLS, b[n:]); diff != "" {
+				t.Fatalf("unexpected LLS bytes (-want +got):\n%s", diff)
+			}
+
+			_, _, plen, err := parseHeader(b)
+			if err != nil {
+				t.Fatalf("failed to parse Header: %v", err)
+			}
+			if plen != n {
+				t.Fatalf("unexpected packet length: %d, want %d", plen, n)
+			}
+
+			p, err := ParsePacket(b)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.p, p); diff != "" {
+				t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+			}
+
+			// A corrupt LLS is discarded without affecting the packet.
+			b[len(b)-1] ^= 0xff
+			p, err = ParsePacket(b)
+			if err != nil {
+				t.Fatalf("failed to parse with corrupt LLS: %v", err)
+			}
+			if lls := packetLLS(p); lls != nil {
+				t.Fatalf("expected corrupt LLS to be discarded, but got: %+v", lls)
+			}
+		})
+	}
+}
+
+func Test_parseLLSMalformed(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x00, 0x00},
+		// Zero length.
+		{0xff, 0xff, 0x00, 0x00},
+		// Length exceeds bytes.
+		{0xff, 0xfd, 0x00, 0x02},
+		// Bad TLV, valid checksum.
+		{0xff, 0xf8, 0x00, 0x02, 0x00, 0x01, 0x00, 0x04},
+	} {
+		if lls := parseLLS(b); lls != nil {
+			t.Fatalf("expected nil LLS for %#v, but got: %+v", b, lls)
+		}
+	}
+}
+
+func TestLLSMarshalNoLBit(t *testing.T) {
+	for _, p := range []Packet{
+		&Hello{Options: V6Bit, LLS: &LLS{}},
+		&DatabaseDescription{Options: V6Bit, LLS: &LLS{}},
+	} {
+		if _, err := MarshalPacket(p); err == nil {
+			t.Fatalf("expected an error for %#v", p)
+		}
+	}
+}
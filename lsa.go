@@ -0,0 +1,1044 @@
+package ospf3
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+)
+
+// Fixed length LSA body structures. Note that some LSA bodies have trailing
+// variable length data.
+const (
+	routerLSALen  = 4  // No trailing array of RouterLinks.
+	routerLinkLen = 16 // Each RouterLink, no trailing array of MTMetrics.
+	mtMetricLen   = 4  // Each MTMetric.
+
+	networkLSALen         = 4  // No trailing array of attached routers.
+	interAreaPrefixLSALen = 4  // No trailing prefix.
+	interAreaRouterLSALen = 12 // Fixed.
+	externalLSALen        = 8  // No trailing prefix address or optional fields.
+	linkLSALen            = 24 // No trailing array of prefixes.
+	intraAreaPrefixLSALen = 12 // No trailing array of prefixes.
+)
+
+// maxMetric is the maximum value of a 24-bit LSA metric.
+const maxMetric = 0x00ffffff
+
+// An LSABody is the body of an OSPFv3 LSA which follows an LSAHeader in a
+// LinkStateAdvertisement. Use ParseLSABody to decode the appropriate LSABody
+// for a given LSType.
+//
+// This package implements LSABody for each of the LSAs described in RFC5340,
+// the Intra-Area-TE-LSA described in RFC5329, the Router Information LSA
+// described in RFC7770, and the SRv6 Locator LSA described in RFC9513.
+// Additional LSABody implementations for other LSTypes may be added using
+// RegisterLSAType.
+type LSABody interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// An lsaBody is an LSABody implemented by this package which can marshal
+// itself into a preallocated buffer.
+type lsaBody interface {
+	LSABody
+	len() int
+	marshal(b []byte) error
+	unmarshal(b []byte) error
+}
+
+// Compile-time lsaBody interface checks.
+var (
+	_ lsaBody = &RouterLSABody{}
+	_ lsaBody = &NetworkLSABody{}
+	_ lsaBody = &InterAreaPrefixLSABody{}
+	_ lsaBody = &InterAreaRouterLSABody{}
+	_ lsaBody = &ASExternalLSABody{}
+	_ lsaBody = &NSSALSABody{}
+	_ lsaBody = &LinkLSABody{}
+	_ lsaBody = &IntraAreaPrefixLSABody{}
+	_ lsaBody = &RouterInformationLSABody{}
+	_ lsaBody = &IntraAreaTELSABody{}
+	_ lsaBody = &SRv6LocatorLSABody{}
+	_ lsaBody = &RawLSABody{}
+)
+
+// ParseLSABody parses the LSABody of type t from b. If t has no known body
+// format and no LSABody has been registered for t using RegisterLSAType, a
+// *RawLSABody containing a copy of b is returned.
+func ParseLSABody(t LSType, b []byte) (LSABody, error) {
+	body := newLSABody(t)
+	if body == nil {
+		body = lookupLSAType(t)
+	}
+	if body == nil {
+		body = new(RawLSABody)
+	}
+
+	if err := body.UnmarshalBinary(b); err != nil {
+		return nil, parseError(0, t.String()+" body", err)
+	}
+
+	return body, nil
+}
+
+// newLSABody returns a new LSABody implemented by this package for t, or nil
+// if t has no known body format.
+func newLSABody(t LSType) LSABody {
+	switch t {
+	case RouterLSA:
+		return new(RouterLSABody)
+	case NetworkLSA:
+		return new(NetworkLSABody)
+	case InterAreaPrefixLSA:
+		return new(InterAreaPrefixLSABody)
+	case InterAreaRouterLSA:
+		return new(InterAreaRouterLSABody)
+	case ASExternalLSA:
+		return new(ASExternalLSABody)
+	case NSSALSA:
+		return new(NSSALSABody)
+	case LinkLSA:
+		return new(LinkLSABody)
+	case IntraAreaPrefixLSA:
+		return new(IntraAreaPrefixLSABody)
+	case IntraAreaTELSA:
+		return new(IntraAreaTELSABody)
+	case AreaSRv6LocatorLSA, ASSRv6LocatorLSA:
+		return new(SRv6LocatorLSABody)
+	case LinkRouterInformationLSA, AreaRouterInformationLSA, ASRouterInformationLSA:
+		return new(RouterInformationLSABody)
+	default:
+		return nil
+	}
+}
+
+// lsaRegistry stores LSABody implementations registered by RegisterLSAType.
+var lsaRegistry struct {
+	mu    sync.RWMutex
+	types map[LSType]registeredLSA
+}
+
+// A registeredLSA is an LSA registered by RegisterLSAType.
+type registeredLSA struct {
+	name string
+	fn   func() LSABody
+}
+
+// RegisterLSAType registers an LSABody implementation for an LSType which does
+// not have a body format implemented by this package, such as a proprietary or
+// experimental LSA. Once registered, ParseLSABody and ParsePacket use fn to
+// create an LSABody to decode LSAs of type t, and LSType.String reports name
+// for t.
+//
+// RegisterLSAType is typically called from an init function. It panics if fn
+// is nil, if name is empty, if t is implemented by this package, or if t has
+// already been registered.
+func RegisterLSAType(t LSType, name string, fn func() LSABody) {
+	if fn == nil {
+		panic("ospf3: RegisterLSAType function is nil")
+	}
+	if name == "" {
+		panic("ospf3: RegisterLSAType name is empty")
+	}
+	if newLSABody(t) != nil {
+		panic(fmt.Sprintf("ospf3: RegisterLSAType cannot replace built-in LSType %s", t))
+	}
+
+	lsaRegistry.mu.Lock()
+	defer lsaRegistry.mu.Unlock()
+
+	if _, ok := lsaRegistry.types[t]; ok {
+		panic(fmt.Sprintf("ospf3: RegisterLSAType called twice for LSType %#04x", uint16(t)))
+	}
+
+	if lsaRegistry.types == nil {
+		lsaRegistry.types = make(map[LSType]registeredLSA)
+	}
+	lsaRegistry.types[t] = registeredLSA{name: name, fn: fn}
+}
+
+// lookupLSAType returns a new LSABody registered for t, or nil if t has not
+// been registered.
+func lookupLSAType(t LSType) LSABody {
+	lsaRegistry.mu.RLock()
+	defer lsaRegistry.mu.RUnlock()
+
+	r, ok := lsaRegistry.types[t]
+	if !ok {
+		return nil
+	}
+
+	return r.fn()
+}
+
+// registeredLSAName returns the name of t if it has been registered.
+func registeredLSAName(t LSType) (string, bool) {
+	lsaRegistry.mu.RLock()
+	defer lsaRegistry.mu.RUnlock()
+
+	r, ok := lsaRegistry.types[t]
+	return r.name, ok
+}
+
+// lsaBodyLen returns the length of body in bytes. LSABody implementations
+// which are not provided by this package are marshaled to determine their
+// length, and report a length of zero if marshaling fails.
+func lsaBodyLen(body LSABody) int {
+	if b, ok := body.(lsaBody); ok {
+		return b.len()
+	}
+
+	bb, err := body.MarshalBinary()
+	if err != nil {
+		// Marshaling will fail again later and report this error.
+		return 0
+	}
+
+	return len(bb)
+}
+
+// marshalLSABody stores the bytes of body into b. It assumes b has allocated
+// lsaBodyLen(body) bytes to avoid a panic.
+func marshalLSABody(body LSABody, b []byte) error {
+	if bb, ok := body.(lsaBody); ok {
+		return bb.marshal(b)
+	}
+
+	bb, err := body.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if len(bb) != len(b) {
+		return fmt.Errorf("LSA body length changed from %d to %d bytes while marshaling: %w", len(b), len(bb), errMarshal)
+	}
+
+	copy(b, bb)
+	return nil
+}
+
+// A RawLSABody is an LSABody with no known format, such as the body of an LSA
+// with an unrecognized LSType. Its contents are stored verbatim so that the
+// LSA marshals back to exactly the same bytes, allowing LSAs which are not
+// understood to be stored or forwarded without corruption.
+type RawLSABody struct {
+	Data []byte
+}
+
+// MarshalBinary packs a RawLSABody into bytes.
+func (r *RawLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, r.len())
+	if err := r.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks a RawLSABody from bytes.
+func (r *RawLSABody) UnmarshalBinary(b []byte) error {
+	return r.unmarshal(b)
+}
+
+// len returns the length of a RawLSABody in bytes.
+func (r *RawLSABody) len() int { return len(r.Data) }
+
+// marshal stores the RawLSABody bytes into b. It assumes b has allocated
+// enough space for a RawLSABody to avoid a panic.
+func (r *RawLSABody) marshal(b []byte) error {
+	copy(b, r.Data)
+	return nil
+}
+
+// unmarshal stores a copy of b in the RawLSABody.
+func (r *RawLSABody) unmarshal(b []byte) error {
+	r.Data = make([]byte, len(b))
+	copy(r.Data, b)
+	return nil
+}
+
+// RouterFlags is a bitmask of flags which may appear in an OSPFv3 Router-LSA
+// as described in RFC5340, appendix A.4.3.
+type RouterFlags uint8
+
+// Possible RouterFlags values.
+const (
+	BFlag  RouterFlags = 1 << 0
+	EFlag  RouterFlags = 1 << 1
+	VFlag  RouterFlags = 1 << 2
+	xFlag  RouterFlags = 1 << 3
+	NtFlag RouterFlags = 1 << 4
+)
+
+// String returns the string representation of a RouterFlags bitmask.
+func (f RouterFlags) String() string {
+	return flagsString(uint(f), []string{
+		"B-bit",
+		"E-bit",
+		"V-bit",
+		"x-bit",
+		"Nt-bit",
+	})
+}
+
+// A RouterLSABody is the body of an OSPFv3 Router-LSA as described in RFC5340,
+// appendix A.4.3.
+type RouterLSABody struct {
+	Flags   RouterFlags
+	Options Options
+	Links   []RouterLink
+}
+
+// A RouterLinkType is the type of an interface described by a RouterLink.
+type RouterLinkType uint8
+
+// Possible RouterLinkType values.
+const (
+	PointToPointLink   RouterLinkType = 1
+	TransitNetworkLink RouterLinkType = 2
+	reservedLink       RouterLinkType = 3
+	VirtualLink        RouterLinkType = 4
+)
+
+// A RouterLink is a single interface description within a RouterLSABody.
+type RouterLink struct {
+	Type                RouterLinkType
+	Metric              uint16
+	InterfaceID         uint32
+	NeighborInterfaceID uint32
+	NeighborRouterID    ID
+
+	// MTMetrics are the metrics of the link in non-default topologies, as
+	// described in draft-ietf-ospf-mt-ospfv3, section 3.4. Metric is always
+	// the metric of the link in the default topology.
+	MTMetrics []MTMetric
+}
+
+// An MTID is a multi-topology identifier. MT-ID 0 identifies the default
+// topology.
+type MTID uint8
+
+// An MTMetric is the metric of a RouterLink in the topology identified by ID.
+type MTMetric struct {
+	ID     MTID
+	Metric uint16
+}
+
+// len returns the length of a RouterLink in bytes.
+func (l RouterLink) len() int {
+	return routerLinkLen + (mtMetricLen * len(l.MTMetrics))
+}
+
+// MarshalBinary packs a RouterLSABody into bytes.
+func (r *RouterLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, r.len())
+	if err := r.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks a RouterLSABody from bytes.
+func (r *RouterLSABody) UnmarshalBinary(b []byte) error {
+	return r.unmarshal(b)
+}
+
+// len returns the length of a RouterLSABody in bytes.
+func (r *RouterLSABody) len() int {
+	// Fixed RouterLSABody plus 16 bytes per link and 4 bytes per MT-ID metric.
+	n := routerLSALen
+	for _, l := range r.Links {
+		n += l.len()
+	}
+
+	return n
+}
+
+// marshal stores the RouterLSABody bytes into b. It assumes b has allocated
+// enough space for a RouterLSABody to avoid a panic.
+func (r *RouterLSABody) marshal(b []byte) error {
+	if !r.Options.valid() {
+		return fmt.Errorf("Router-LSA Options bitmask is not valid: %w", errMarshal)
+	}
+
+	// Flags is 8 bits, Options is 24 bits immediately following.
+	binary.BigEndian.PutUint32(b[0:4], uint32(r.Flags)<<24|uint32(r.Options))
+
+	// Each link is packed into 16 adjacent bytes, followed by 4 bytes for
+	// each MT-ID metric.
+	n := routerLSALen
+	for _, l := range r.Links {
+		if len(l.MTMetrics) > math.MaxUint8 {
+			return fmt.Errorf("Router-LSA link has too many MT-ID metrics: %d: %w", len(l.MTMetrics), errMarshal)
+		}
+
+		b[n] = byte(l.Type)
+		b[n+1] = byte(len(l.MTMetrics))
+		binary.BigEndian.PutUint16(b[n+2:n+4], l.Metric)
+		binary.BigEndian.PutUint32(b[n+4:n+8], l.InterfaceID)
+		binary.BigEndian.PutUint32(b[n+8:n+12], l.NeighborInterfaceID)
+		copy(b[n+12:n+16], l.NeighborRouterID[:])
+		n += routerLinkLen
+
+		for _, m := range l.MTMetrics {
+			b[n] = byte(m.ID)
+			// b[n+1] is reserved.
+			binary.BigEndian.PutUint16(b[n+2:n+4], m.Metric)
+			n += mtMetricLen
+		}
+	}
+
+	return nil
+}
+
+// unmarshal unpacks a RouterLSABody from b.
+func (r *RouterLSABody) unmarshal(b []byte) error {
+	if l := len(b); l < routerLSALen {
+		return fmt.Errorf("not enough bytes for Router-LSA: %d: %w", l, errParse)
+	}
+
+	r.Flags = RouterFlags(b[0])
+	// Options is 24 bits.
+	r.Options = options(b[0:4])
+
+	// Links without MT-ID metrics have a fixed size, so assume that is the
+	// common case when sizing the slice.
+	r.Links = make([]RouterLink, 0, len(b[routerLSALen:])/routerLinkLen)
+	for i := routerLSALen; i < len(b); {
+		if l := len(b[i:]); l < routerLinkLen {
+			return fmt.Errorf("not enough bytes for Router-LSA link: %d: %w", l, errParse)
+		}
+
+		l := RouterLink{
+			Type:                RouterLinkType(b[i]),
+			Metric:              binary.BigEndian.Uint16(b[i+2 : i+4]),
+			InterfaceID:         binary.BigEndian.Uint32(b[i+4 : i+8]),
+			NeighborInterfaceID: binary.BigEndian.Uint32(b[i+8 : i+12]),
+		}
+		copy(l.NeighborRouterID[:], b[i+12:i+16])
+
+		// The number of MT-ID metrics which follow the link.
+		nmt := int(b[i+1])
+		i += routerLinkLen
+
+		if l := len(b[i:]); l < nmt*mtMetricLen {
+			return fmt.Errorf("not enough bytes for %d Router-LSA MT-ID metrics: %d: %w", nmt, l, errParse)
+		}
+
+		if nmt > 0 {
+			l.MTMetrics = make([]MTMetric, 0, nmt)
+		}
+		for j := 0; j < nmt; j++ {
+			l.MTMetrics = append(l.MTMetrics, MTMetric{
+				ID: MTID(b[i]),
+				// b[i+1] is reserved.
+				Metric: binary.BigEndian.Uint16(b[i+2 : i+4]),
+			})
+			i += mtMetricLen
+		}
+
+		r.Links = append(r.Links, l)
+	}
+
+	return nil
+}
+
+// A NetworkLSABody is the body of an OSPFv3 Network-LSA as described in
+// RFC5340, appendix A.4.4.
+type NetworkLSABody struct {
+	Options         Options
+	AttachedRouters []ID
+}
+
+// MarshalBinary packs a NetworkLSABody into bytes.
+func (n *NetworkLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, n.len())
+	if err := n.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks a NetworkLSABody from bytes.
+func (n *NetworkLSABody) UnmarshalBinary(b []byte) error {
+	return n.unmarshal(b)
+}
+
+// len returns the length of a NetworkLSABody in bytes.
+func (n *NetworkLSABody) len() int {
+	// Fixed NetworkLSABody plus 4 bytes per attached router.
+	return networkLSALen + (4 * len(n.AttachedRouters))
+}
+
+// marshal stores the NetworkLSABody bytes into b. It assumes b has allocated
+// enough space for a NetworkLSABody to avoid a panic.
+func (n *NetworkLSABody) marshal(b []byte) error {
+	if !n.Options.valid() {
+		return fmt.Errorf("Network-LSA Options bitmask is not valid: %w", errMarshal)
+	}
+
+	// b[0] is reserved, with 24-bit Options following.
+	binary.BigEndian.PutUint32(b[0:4], uint32(n.Options))
+
+	// Each attached router ID is packed into 4 adjacent bytes.
+	nn := networkLSALen
+	for i := range n.AttachedRouters {
+		copy(b[nn:nn+4], n.AttachedRouters[i][:])
+		nn += 4
+	}
+
+	return nil
+}
+
+// unmarshal unpacks a NetworkLSABody from b.
+func (n *NetworkLSABody) unmarshal(b []byte) error {
+	if l := len(b); l < networkLSALen {
+		return fmt.Errorf("not enough bytes for Network-LSA: %d: %w", l, errParse)
+	}
+
+	// Network-LSA must end on a 4 byte boundary so we can parse any possible
+	// attached routers in the trailing array.
+	if l := len(b); l%4 != 0 {
+		return fmt.Errorf("Network-LSA must end on a 4 byte boundary, got %d bytes: %w", l, errParse)
+	}
+
+	// b[0] is reserved.
+	n.Options = options(b[0:4])
+
+	n.AttachedRouters = make([]ID, 0, len(b[networkLSALen:])/4)
+	for i := networkLSALen; i < len(b); i += 4 {
+		var id ID
+		copy(id[:], b[i:i+4])
+		n.AttachedRouters = append(n.AttachedRouters, id)
+	}
+
+	return nil
+}
+
+// An InterAreaPrefixLSABody is the body of an OSPFv3 Inter-Area-Prefix-LSA as
+// described in RFC5340, appendix A.4.5.
+type InterAreaPrefixLSABody struct {
+	Metric uint32
+	Prefix Prefix
+}
+
+// MarshalBinary packs an InterAreaPrefixLSABody into bytes.
+func (p *InterAreaPrefixLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, p.len())
+	if err := p.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an InterAreaPrefixLSABody from bytes.
+func (p *InterAreaPrefixLSABody) UnmarshalBinary(b []byte) error {
+	return p.unmarshal(b)
+}
+
+// len returns the length of an InterAreaPrefixLSABody in bytes.
+func (p *InterAreaPrefixLSABody) len() int { return interAreaPrefixLSALen + p.Prefix.len() }
+
+// marshal stores the InterAreaPrefixLSABody bytes into b. It assumes b has
+// allocated enough space for an InterAreaPrefixLSABody to avoid a panic.
+func (p *InterAreaPrefixLSABody) marshal(b []byte) error {
+	if p.Metric > maxMetric {
+		return fmt.Errorf("Inter-Area-Prefix-LSA Metric %d does not fit in 24 bits: %w", p.Metric, errMarshal)
+	}
+	if err := p.Prefix.validate(); err != nil {
+		return fmt.Errorf("Inter-Area-Prefix-LSA: %w", err)
+	}
+
+	// b[0] is reserved, with 24-bit Metric following. The 16 bits following
+	// the prefix options are also reserved.
+	binary.BigEndian.PutUint32(b[0:4], p.Metric)
+	p.Prefix.marshal(b[interAreaPrefixLSALen:], 0)
+
+	return nil
+}
+
+// unmarshal unpacks an InterAreaPrefixLSABody from b.
+func (p *InterAreaPrefixLSABody) unmarshal(b []byte) error {
+	if l := len(b); l < interAreaPrefixLSALen {
+		return fmt.Errorf("not enough bytes for Inter-Area-Prefix-LSA: %d: %w", l, errParse)
+	}
+
+	// b[0] is reserved.
+	p.Metric = binary.BigEndian.Uint32(b[0:4]) & maxMetric
+
+	prefix, _, n, err := parsePrefix(b[interAreaPrefixLSALen:])
+	if err != nil {
+		return fmt.Errorf("Inter-Area-Prefix-LSA: %w", err)
+	}
+	if l := len(b[interAreaPrefixLSALen:]); l != n {
+		return fmt.Errorf("Inter-Area-Prefix-LSA prefix must be exactly %d bytes, got %d bytes: %w", n, l, errParse)
+	}
+	p.Prefix = prefix
+
+	return nil
+}
+
+// An InterAreaRouterLSABody is the body of an OSPFv3 Inter-Area-Router-LSA as
+// described in RFC5340, appendix A.4.6.
+type InterAreaRouterLSABody struct {
+	Options             Options
+	Metric              uint32
+	DestinationRouterID ID
+}
+
+// MarshalBinary packs an InterAreaRouterLSABody into bytes.
+func (r *InterAreaRouterLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, r.len())
+	if err := r.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an InterAreaRouterLSABody from bytes.
+func (r *InterAreaRouterLSABody) UnmarshalBinary(b []byte) error {
+	return r.unmarshal(b)
+}
+
+// len returns the length of an InterAreaRouterLSABody in bytes.
+func (r *InterAreaRouterLSABody) len() int { return interAreaRouterLSALen }
+
+// marshal stores the InterAreaRouterLSABody bytes into b. It assumes b has
+// allocated enough space for an InterAreaRouterLSABody to avoid a panic.
+func (r *InterAreaRouterLSABody) marshal(b []byte) error {
+	if !r.Options.valid() {
+		return fmt.Errorf("Inter-Area-Router-LSA Options bitmask is not valid: %w", errMarshal)
+	}
+	if r.Metric > maxMetric {
+		return fmt.Errorf("Inter-Area-Router-LSA Metric %d does not fit in 24 bits: %w", r.Metric, errMarshal)
+	}
+
+	// b[0] and b[4] are reserved, with 24-bit Options and Metric following.
+	binary.BigEndian.PutUint32(b[0:4], uint32(r.Options))
+	binary.BigEndian.PutUint32(b[4:8], r.Metric)
+	copy(b[8:12], r.DestinationRouterID[:])
+
+	return nil
+}
+
+// unmarshal unpacks an InterAreaRouterLSABody from b.
+func (r *InterAreaRouterLSABody) unmarshal(b []byte) error {
+	if l := len(b); l != interAreaRouterLSALen {
+		return fmt.Errorf("Inter-Area-Router-LSA must be exactly %d bytes, got %d bytes: %w",
+			interAreaRouterLSALen, l, errParse)
+	}
+
+	// b[0] and b[4] are reserved.
+	r.Options = options(b[0:4])
+	r.Metric = binary.BigEndian.Uint32(b[4:8]) & maxMetric
+	copy(r.DestinationRouterID[:], b[8:12])
+
+	return nil
+}
+
+// ExternalFlags is a bitmask of flags which may appear in an OSPFv3
+// AS-External-LSA or NSSA-LSA as described in RFC5340, appendix A.4.7.
+type ExternalFlags uint8
+
+// Possible ExternalFlags values.
+const (
+	ExternalTFlag ExternalFlags = 1 << 0
+	ExternalFFlag ExternalFlags = 1 << 1
+	ExternalEFlag ExternalFlags = 1 << 2
+)
+
+// String returns the string representation of an ExternalFlags bitmask.
+func (f ExternalFlags) String() string {
+	return flagsString(uint(f), []string{
+		"T-bit",
+		"F-bit",
+		"E-bit",
+	})
+}
+
+// An ASExternalLSABody is the body of an OSPFv3 AS-External-LSA as described
+// in RFC5340, appendix A.4.7.
+//
+// The optional ForwardingAddress and ExternalRouteTag fields are only encoded
+// when ExternalFFlag and ExternalTFlag are set, respectively. The optional
+// ReferencedLinkStateID field is only encoded when ReferencedLSType is
+// non-zero.
+type ASExternalLSABody struct {
+	Flags                 ExternalFlags
+	Metric                uint32
+	Prefix                Prefix
+	ReferencedLSType      LSType
+	ForwardingAddress     net.IP
+	ExternalRouteTag      uint32
+	ReferencedLinkStateID ID
+}
+
+// MarshalBinary packs an ASExternalLSABody into bytes.
+func (e *ASExternalLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, e.len())
+	if err := e.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an ASExternalLSABody from bytes.
+func (e *ASExternalLSABody) UnmarshalBinary(b []byte) error {
+	return e.unmarshal(b)
+}
+
+// len returns the length of an ASExternalLSABody in bytes.
+func (e *ASExternalLSABody) len() int {
+	n := externalLSALen + e.Prefix.addrLen()
+	if e.Flags&ExternalFFlag != 0 {
+		n += net.IPv6len
+	}
+	if e.Flags&ExternalTFlag != 0 {
+		n += 4
+	}
+	if e.ReferencedLSType != 0 {
+		n += 4
+	}
+
+	return n
+}
+
+// marshal stores the ASExternalLSABody bytes into b. It assumes b has
+// allocated enough space for an ASExternalLSABody to avoid a panic.
+func (e *ASExternalLSABody) marshal(b []byte) error {
+	return marshalExternal("AS-External-LSA", e, b)
+}
+
+// unmarshal unpacks an ASExternalLSABody from b.
+func (e *ASExternalLSABody) unmarshal(b []byte) error {
+	return parseExternal("AS-External-LSA", e, b)
+}
+
+// An NSSALSABody is the body of an OSPFv3 NSSA-LSA (Type-7) as described in
+// RFC5340, appendix A.4.8. Its format is identical to that of an
+// ASExternalLSABody.
+//
+// When the PBit is set in the Prefix's Options, an NSSA border router should
+// translate the LSA into an AS-External-LSA for flooding outside of the NSSA,
+// as described in RFC3101, section 3.2.
+type NSSALSABody ASExternalLSABody
+
+// Propagate reports whether the P-bit is set in the NSSALSABody's Prefix,
+// indicating that the LSA should be translated into an AS-External-LSA by an
+// NSSA border router.
+func (n *NSSALSABody) Propagate() bool { return n.Prefix.Options&PBit != 0 }
+
+// MarshalBinary packs an NSSALSABody into bytes.
+func (n *NSSALSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, n.len())
+	if err := n.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an NSSALSABody from bytes.
+func (n *NSSALSABody) UnmarshalBinary(b []byte) error {
+	return n.unmarshal(b)
+}
+
+// len returns the length of an NSSALSABody in bytes.
+func (n *NSSALSABody) len() int { return (*ASExternalLSABody)(n).len() }
+
+// marshal stores the NSSALSABody bytes into b. It assumes b has allocated
+// enough space for an NSSALSABody to avoid a panic.
+func (n *NSSALSABody) marshal(b []byte) error {
+	return marshalExternal("NSSA-LSA", (*ASExternalLSABody)(n), b)
+}
+
+// unmarshal unpacks an NSSALSABody from b.
+func (n *NSSALSABody) unmarshal(b []byte) error {
+	return parseExternal("NSSA-LSA", (*ASExternalLSABody)(n), b)
+}
+
+// marshalExternal packs the shared AS-External-LSA and NSSA-LSA format into b,
+// using name to identify the LSA in errors.
+func marshalExternal(name string, e *ASExternalLSABody, b []byte) error {
+	if e.Metric > maxMetric {
+		return fmt.Errorf("%s Metric %d does not fit in 24 bits: %w", name, e.Metric, errMarshal)
+	}
+	if err := e.Prefix.validate(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if e.Flags&ExternalFFlag != 0 && len(e.ForwardingAddress) != net.IPv6len {
+		return fmt.Errorf("%s F-bit is set but forwarding address %v is not a 16 byte IPv6 address: %w",
+			name, e.ForwardingAddress, errMarshal)
+	}
+
+	// Flags is 8 bits, Metric is 24 bits immediately following.
+	binary.BigEndian.PutUint32(b[0:4], uint32(e.Flags)<<24|e.Metric)
+	// The 16 bits following the prefix options are the referenced LS type.
+	e.Prefix.marshal(b[4:], uint16(e.ReferencedLSType))
+	n := 4 + e.Prefix.len()
+
+	// Each of the remaining fields is optional.
+	if e.Flags&ExternalFFlag != 0 {
+		copy(b[n:n+net.IPv6len], e.ForwardingAddress)
+		n += net.IPv6len
+	}
+	if e.Flags&ExternalTFlag != 0 {
+		binary.BigEndian.PutUint32(b[n:n+4], e.ExternalRouteTag)
+		n += 4
+	}
+	if e.ReferencedLSType != 0 {
+		copy(b[n:n+4], e.ReferencedLinkStateID[:])
+	}
+
+	return nil
+}
+
+// parseExternal unpacks the shared AS-External-LSA and NSSA-LSA format from b,
+// using name to identify the LSA in errors.
+func parseExternal(name string, e *ASExternalLSABody, b []byte) error {
+	if l := len(b); l < externalLSALen {
+		return fmt.Errorf("not enough bytes for %s: %d: %w", name, l, errParse)
+	}
+
+	// Flags is 8 bits, Metric is 24 bits immediately following.
+	e.Flags = ExternalFlags(b[0])
+	e.Metric = binary.BigEndian.Uint32(b[0:4]) & maxMetric
+
+	// The 16 bits following the prefix options are the referenced LS type.
+	p, ref, n, err := parsePrefix(b[4:])
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	e.Prefix = p
+	e.ReferencedLSType = LSType(ref)
+	n += 4
+
+	// The flags and referenced LS type indicate which optional fields follow,
+	// so make sure exactly enough bytes remain for those fields.
+	want := n
+	if e.Flags&ExternalFFlag != 0 {
+		want += net.IPv6len
+	}
+	if e.Flags&ExternalTFlag != 0 {
+		want += 4
+	}
+	if e.ReferencedLSType != 0 {
+		want += 4
+	}
+	if l := len(b); l != want {
+		return fmt.Errorf("%s must be exactly %d bytes for its optional fields, got %d bytes: %w",
+			name, want, l, errParse)
+	}
+
+	e.ForwardingAddress = nil
+	if e.Flags&ExternalFFlag != 0 {
+		e.ForwardingAddress = make(net.IP, net.IPv6len)
+		copy(e.ForwardingAddress, b[n:n+net.IPv6len])
+		n += net.IPv6len
+	}
+
+	e.ExternalRouteTag = 0
+	if e.Flags&ExternalTFlag != 0 {
+		e.ExternalRouteTag = binary.BigEndian.Uint32(b[n : n+4])
+		n += 4
+	}
+
+	e.ReferencedLinkStateID = ID{}
+	if e.ReferencedLSType != 0 {
+		copy(e.ReferencedLinkStateID[:], b[n:n+4])
+	}
+
+	return nil
+}
+
+// A LinkLSABody is the body of an OSPFv3 Link-LSA as described in RFC5340,
+// appendix A.4.9.
+type LinkLSABody struct {
+	RouterPriority            uint8
+	Options                   Options
+	LinkLocalInterfaceAddress net.IP
+	Prefixes                  []Prefix
+}
+
+// MarshalBinary packs a LinkLSABody into bytes.
+func (l *LinkLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, l.len())
+	if err := l.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks a LinkLSABody from bytes.
+func (l *LinkLSABody) UnmarshalBinary(b []byte) error {
+	return l.unmarshal(b)
+}
+
+// len returns the length of a LinkLSABody in bytes.
+func (l *LinkLSABody) len() int {
+	// Fixed LinkLSABody plus the variable length of each prefix.
+	n := linkLSALen
+	for _, p := range l.Prefixes {
+		n += p.len()
+	}
+
+	return n
+}
+
+// marshal stores the LinkLSABody bytes into b. It assumes b has allocated
+// enough space for a LinkLSABody to avoid a panic.
+func (l *LinkLSABody) marshal(b []byte) error {
+	if !l.Options.valid() {
+		return fmt.Errorf("Link-LSA Options bitmask is not valid: %w", errMarshal)
+	}
+	if len(l.LinkLocalInterfaceAddress) != net.IPv6len {
+		return fmt.Errorf("Link-LSA link-local interface address %v must be a 16 byte IPv6 address: %w",
+			l.LinkLocalInterfaceAddress, errMarshal)
+	}
+
+	// Router priority is 8 bits, Options is 24 bits immediately following.
+	binary.BigEndian.PutUint32(b[0:4], uint32(l.RouterPriority)<<24|uint32(l.Options))
+	copy(b[4:20], l.LinkLocalInterfaceAddress)
+	binary.BigEndian.PutUint32(b[20:24], uint32(len(l.Prefixes)))
+
+	// Each prefix is packed into adjacent bytes with its 16 bits reserved.
+	n := linkLSALen
+	for _, p := range l.Prefixes {
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("Link-LSA: %w", err)
+		}
+
+		p.marshal(b[n:], 0)
+		n += p.len()
+	}
+
+	return nil
+}
+
+// unmarshal unpacks a LinkLSABody from b.
+func (l *LinkLSABody) unmarshal(b []byte) error {
+	if n := len(b); n < linkLSALen {
+		return fmt.Errorf("not enough bytes for Link-LSA: %d: %w", n, errParse)
+	}
+
+	l.RouterPriority = b[0]
+	// Options is 24 bits.
+	l.Options = options(b[0:4])
+	l.LinkLocalInterfaceAddress = make(net.IP, net.IPv6len)
+	copy(l.LinkLocalInterfaceAddress, b[4:20])
+
+	// The 16 bits following each prefix's options are reserved.
+	l.Prefixes = nil
+	err := parsePrefixes(b[linkLSALen:], binary.BigEndian.Uint32(b[20:24]), func(p Prefix, _ uint16) {
+		l.Prefixes = append(l.Prefixes, p)
+	})
+	if err != nil {
+		return fmt.Errorf("Link-LSA: %w", err)
+	}
+
+	return nil
+}
+
+// An IntraAreaPrefixLSABody is the body of an OSPFv3 Intra-Area-Prefix-LSA as
+// described in RFC5340, appendix A.4.10.
+type IntraAreaPrefixLSABody struct {
+	ReferencedLSType            LSType
+	ReferencedLinkStateID       ID
+	ReferencedAdvertisingRouter ID
+	Prefixes                    []IntraAreaPrefix
+}
+
+// An IntraAreaPrefix is a Prefix and its associated Metric within an
+// IntraAreaPrefixLSABody.
+type IntraAreaPrefix struct {
+	Metric uint16
+	Prefix Prefix
+}
+
+// MarshalBinary packs an IntraAreaPrefixLSABody into bytes.
+func (p *IntraAreaPrefixLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, p.len())
+	if err := p.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an IntraAreaPrefixLSABody from bytes.
+func (p *IntraAreaPrefixLSABody) UnmarshalBinary(b []byte) error {
+	return p.unmarshal(b)
+}
+
+// len returns the length of an IntraAreaPrefixLSABody in bytes.
+func (p *IntraAreaPrefixLSABody) len() int {
+	// Fixed IntraAreaPrefixLSABody plus the variable length of each prefix.
+	n := intraAreaPrefixLSALen
+	for _, pp := range p.Prefixes {
+		n += pp.Prefix.len()
+	}
+
+	return n
+}
+
+// marshal stores the IntraAreaPrefixLSABody bytes into b. It assumes b has
+// allocated enough space for an IntraAreaPrefixLSABody to avoid a panic.
+func (p *IntraAreaPrefixLSABody) marshal(b []byte) error {
+	if l := len(p.Prefixes); l > math.MaxUint16 {
+		return fmt.Errorf("Intra-Area-Prefix-LSA has too many prefixes: %d: %w", l, errMarshal)
+	}
+
+	binary.BigEndian.PutUint16(b[0:2], uint16(len(p.Prefixes)))
+	binary.BigEndian.PutUint16(b[2:4], uint16(p.ReferencedLSType))
+	copy(b[4:8], p.ReferencedLinkStateID[:])
+	copy(b[8:12], p.ReferencedAdvertisingRouter[:])
+
+	// Each prefix is packed into adjacent bytes with its metric occupying the
+	// 16 bits following the prefix options.
+	n := intraAreaPrefixLSALen
+	for _, pp := range p.Prefixes {
+		if err := pp.Prefix.validate(); err != nil {
+			return fmt.Errorf("Intra-Area-Prefix-LSA: %w", err)
+		}
+
+		pp.Prefix.marshal(b[n:], pp.Metric)
+		n += pp.Prefix.len()
+	}
+
+	return nil
+}
+
+// unmarshal unpacks an IntraAreaPrefixLSABody from b.
+func (p *IntraAreaPrefixLSABody) unmarshal(b []byte) error {
+	if l := len(b); l < intraAreaPrefixLSALen {
+		return fmt.Errorf("not enough bytes for Intra-Area-Prefix-LSA: %d: %w", l, errParse)
+	}
+
+	p.ReferencedLSType = LSType(binary.BigEndian.Uint16(b[2:4]))
+	copy(p.ReferencedLinkStateID[:], b[4:8])
+	copy(p.ReferencedAdvertisingRouter[:], b[8:12])
+
+	// The 16 bits following each prefix's options are its metric.
+	p.Prefixes = nil
+	n := uint32(binary.BigEndian.Uint16(b[0:2]))
+	err := parsePrefixes(b[intraAreaPrefixLSALen:], n, func(pp Prefix, metric uint16) {
+		p.Prefixes = append(p.Prefixes, IntraAreaPrefix{
+			Metric: metric,
+			Prefix: pp,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("Intra-Area-Prefix-LSA: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,711 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var (
+	bufRouterLSABody = []byte{
+		byte(EFlag) | byte(BFlag),            // Flags
+		0x00, 0x00, byte(V6Bit) | byte(RBit), // Options
+		// Links
+		byte(PointToPointLink), 0x00, // Type, reserved
+		0x00, 0x0a, // Metric
+		0x00, 0x00, 0x00, 0x01, // Interface ID
+		0x00, 0x00, 0x00, 0x02, // Neighbor interface ID
+		192, 0, 2, 2, // Neighbor router ID
+		byte(TransitNetworkLink), 0x00, // Type, reserved
+		0xff, 0xff, // Metric
+		0x00, 0x00, 0x00, 0x03, // Interface ID
+		0x00, 0x00, 0x00, 0x04, // Neighbor interface ID
+		192, 0, 2, 3, // Neighbor router ID
+	}
+
+	lsaRouterLSABody = &RouterLSABody{
+		Flags:   BFlag | EFlag,
+		Options: V6Bit | RBit,
+		Links: []RouterLink{
+			{
+				Type:                PointToPointLink,
+				Metric:              10,
+				InterfaceID:         1,
+				NeighborInterfaceID: 2,
+				NeighborRouterID:    ID{192, 0, 2, 2},
+			},
+			{
+				Type:                TransitNetworkLink,
+				Metric:              0xffff,
+				InterfaceID:         3,
+				NeighborInterfaceID: 4,
+				NeighborRouterID:    ID{192, 0, 2, 3},
+			},
+		},
+	}
+
+	bufInterAreaRouterLSABody = []byte{
+		0x00, 0x00, 0x00, byte(V6Bit) | byte(EBit) | byte(RBit), // Options
+		0x00, 0x01, 0x00, 0x00, // Metric
+		192, 0, 2, 9, // Destination router ID
+	}
+
+	lsaInterAreaRouterLSABody = &InterAreaRouterLSABody{
+		Options:             V6Bit | EBit | RBit,
+		Metric:              65536,
+		DestinationRouterID: ID{192, 0, 2, 9},
+	}
+
+	bufASExternalLSABody = []byte{
+		byte(ExternalEFlag), 0x00, 0x00, 0x14, // Flags, Metric
+		64, 0x00, // Prefix length, options
+		0x00, 0x00, // Referenced LS type
+		0x20, 0x01, 0x0d, 0xb8, // Address prefix
+		0x00, 0x00, 0x00, 0x00,
+	}
+
+	lsaASExternalLSABody = &ASExternalLSABody{
+		Flags:  ExternalEFlag,
+		Metric: 20,
+		Prefix: Prefix{
+			Length:  64,
+			Address: net.ParseIP("2001:db8::"),
+		},
+	}
+
+	bufNSSALSABody = merge(
+		[]byte{
+			byte(ExternalFFlag) | byte(ExternalTFlag), 0x00, 0x00, 0x0a, // Flags, Metric
+			48, byte(PBit), // Prefix length, options
+			byte(LinkLSA >> 8), byte(LinkLSA & 0x00ff), // Referenced LS type
+			0x20, 0x01, 0x0d, 0xb8, // Address prefix
+			0x00, 0x01, 0x00, 0x00,
+		},
+		net.ParseIP("fe80::1"), // Forwarding address
+		[]byte{
+			0x00, 0x00, 0x00, 0xff, // External route tag
+			0, 0, 0, 5, // Referenced link state ID
+		},
+	)
+
+	lsaNSSALSABody = &NSSALSABody{
+		Flags:  ExternalFFlag | ExternalTFlag,
+		Metric: 10,
+		Prefix: Prefix{
+			Length:  48,
+			Options: PBit,
+			Address: net.ParseIP("2001:db8:1::"),
+		},
+		ReferencedLSType:      LinkLSA,
+		ForwardingAddress:     net.ParseIP("fe80::1"),
+		ExternalRouteTag:      255,
+		ReferencedLinkStateID: ID{0, 0, 0, 5},
+	}
+)
+
+var (
+	bufNetworkLSABody = []byte{
+		0x00, 0x00, 0x00, byte(V6Bit) | byte(RBit), // Options
+		// Attached routers
+		192, 0, 2, 1,
+		192, 0, 2, 2,
+	}
+
+	lsaNetworkLSABody = &NetworkLSABody{
+		Options: V6Bit | RBit,
+		AttachedRouters: []ID{
+			{192, 0, 2, 1},
+			{192, 0, 2, 2},
+		},
+	}
+
+	bufInterAreaPrefixLSABody = []byte{
+		0x00, 0x00, 0x00, 0x0a, // Metric
+		0, 0x00, // Prefix length, options
+		0x00, 0x00, // Reserved
+	}
+
+	lsaInterAreaPrefixLSABody = &InterAreaPrefixLSABody{
+		Metric: 10,
+		Prefix: Prefix{Address: net.IPv6zero},
+	}
+
+	bufLinkLSABody = merge(
+		[]byte{
+			0x01,                                 // Router priority
+			0x00, 0x00, byte(V6Bit) | byte(EBit), // Options
+		},
+		net.ParseIP("fe80::1"), // Link-local interface address
+		[]byte{
+			0x00, 0x00, 0x00, 0x02, // # prefixes
+			// Prefixes
+			64, byte(LABit), // Prefix length, options
+			0x00, 0x00, // Reserved
+			0x20, 0x01, 0x0d, 0xb8,
+			0x00, 0x00, 0x00, 0x00,
+			128, 0x00, // Prefix length, options
+			0x00, 0x00, // Reserved
+		},
+		net.ParseIP("2001:db8::1"),
+	)
+
+	lsaLinkLSABody = &LinkLSABody{
+		RouterPriority:            1,
+		Options:                   V6Bit | EBit,
+		LinkLocalInterfaceAddress: net.ParseIP("fe80::1"),
+		Prefixes: []Prefix{
+			{
+				Length:  64,
+				Options: LABit,
+				Address: net.ParseIP("2001:db8::"),
+			},
+			{
+				Length:  128,
+				Address: net.ParseIP("2001:db8::1"),
+			},
+		},
+	}
+
+	bufIntraAreaPrefixLSABody = []byte{
+		0x00, 0x01, // # prefixes
+		byte(RouterLSA >> 8), byte(RouterLSA & 0x00ff), // Referenced LS type
+		0, 0, 0, 0, // Referenced link state ID
+		192, 0, 2, 1, // Referenced advertising router
+		// Prefixes
+		32, byte(NUBit), // Prefix length, options
+		0x00, 0x0a, // Metric
+		0x20, 0x01, 0x0d, 0xb8,
+	}
+
+	lsaIntraAreaPrefixLSABody = &IntraAreaPrefixLSABody{
+		ReferencedLSType:            RouterLSA,
+		ReferencedAdvertisingRouter: ID{192, 0, 2, 1},
+		Prefixes: []IntraAreaPrefix{{
+			Metric: 10,
+			Prefix: Prefix{
+				Length:  32,
+				Options: NUBit,
+				Address: net.ParseIP("2001:db8::"),
+			},
+		}},
+	}
+)
+
+func TestNSSALSABodyPropagate(t *testing.T) {
+	if !lsaNSSALSABody.Propagate() {
+		t.Fatal("expected P-bit NSSA-LSA to propagate")
+	}
+
+	if (&NSSALSABody{}).Propagate() {
+		t.Fatal("expected empty NSSA-LSA not to propagate")
+	}
+}
+
+func TestLSABodyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		t    LSType
+		b    []byte
+		body LSABody
+	}{
+		{
+			name: "router",
+			t:    RouterLSA,
+			b:    bufRouterLSABody,
+			body: lsaRouterLSABody,
+		},
+		{
+			name: "router MT-ID metrics",
+			t:    RouterLSA,
+			b: []byte{
+				0x00, 0x00, 0x00, byte(V6Bit), // Flags, Options
+				byte(PointToPointLink), 0x02, // Type, #MT-ID
+				0x00, 0x0a, // Metric
+				0x00, 0x00, 0x00, 0x01, // Interface ID
+				0x00, 0x00, 0x00, 0x02, // Neighbor interface ID
+				192, 0, 2, 2, // Neighbor router ID
+				0x02, 0x00, 0x00, 0x14, // MT-ID 2, reserved, metric
+				0x03, 0x00, 0x00, 0x1e, // MT-ID 3, reserved, metric
+				byte(VirtualLink), 0x00, // Type, #MT-ID
+				0x00, 0x01, // Metric
+				0x00, 0x00, 0x00, 0x03, // Interface ID
+				0x00, 0x00, 0x00, 0x04, // Neighbor interface ID
+				192, 0, 2, 3, // Neighbor router ID
+			},
+			body: &RouterLSABody{
+				Options: V6Bit,
+				Links: []RouterLink{
+					{
+						Type:                PointToPointLink,
+						Metric:              10,
+						InterfaceID:         1,
+						NeighborInterfaceID: 2,
+						NeighborRouterID:    ID{192, 0, 2, 2},
+						MTMetrics:           []MTMetric{{ID: 2, Metric: 20}, {ID: 3, Metric: 30}},
+					},
+					{
+						Type:                VirtualLink,
+						Metric:              1,
+						InterfaceID:         3,
+						NeighborInterfaceID: 4,
+						NeighborRouterID:    ID{192, 0, 2, 3},
+					},
+				},
+			},
+		},
+		{
+			name: "network",
+			t:    NetworkLSA,
+			b:    bufNetworkLSABody,
+			body: lsaNetworkLSABody,
+		},
+		{
+			name: "inter-area prefix",
+			t:    InterAreaPrefixLSA,
+			b:    bufInterAreaPrefixLSABody,
+			body: lsaInterAreaPrefixLSABody,
+		},
+		{
+			name: "inter-area router",
+			t:    InterAreaRouterLSA,
+			b:    bufInterAreaRouterLSABody,
+			body: lsaInterAreaRouterLSABody,
+		},
+		{
+			name: "AS-external",
+			t:    ASExternalLSA,
+			b:    bufASExternalLSABody,
+			body: lsaASExternalLSABody,
+		},
+		{
+			name: "NSSA",
+			t:    NSSALSA,
+			b:    bufNSSALSABody,
+			body: lsaNSSALSABody,
+		},
+		{
+			name: "link",
+			t:    LinkLSA,
+			b:    bufLinkLSABody,
+			body: lsaLinkLSABody,
+		},
+		{
+			name: "intra-area prefix",
+			t:    IntraAreaPrefixLSA,
+			b:    bufIntraAreaPrefixLSABody,
+			body: lsaIntraAreaPrefixLSABody,
+		},
+		{
+			name: "router information",
+			t:    AreaRouterInformationLSA,
+			b:    bufRouterInformationLSABody,
+			body: lsaRouterInformationLSABody,
+		},
+		{
+			name: "router information duplicate zero capabilities",
+			t:    AreaRouterInformationLSA,
+			b: []byte{
+				0x00, 0x02, 0x00, 0x04, // Functional capabilities
+				0x00, 0x00, 0x00, 0x00,
+				0x00, 0x02, 0x00, 0x00, // Empty duplicate
+			},
+			body: &RouterInformationLSABody{
+				TLVs: []TLV{{Type: tlvFunctionalCapabilities}},
+			},
+		},
+		{
+			name: "intra-area TE",
+			t:    IntraAreaTELSA,
+			b:    bufIntraAreaTELSABody,
+			body: lsaIntraAreaTELSABody,
+		},
+		{
+			name: "raw",
+			t:    0x2fff,
+			b:    []byte{0xde, 0xad, 0xbe, 0xef},
+			body: &RawLSABody{Data: []byte{0xde, 0xad, 0xbe, 0xef}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body1, err := ParseLSABody(tt.t, tt.b)
+			if err != nil {
+				t.Fatalf("failed to parse first body: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.body, body1); diff != "" {
+				t.Fatalf("unexpected initial body (-want +got):\n%s", diff)
+			}
+
+			b, err := body1.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.b, b); diff != "" {
+				t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+			}
+
+			body2, err := ParseLSABody(tt.t, b)
+			if err != nil {
+				t.Fatalf("failed to parse second body: %v", err)
+			}
+
+			if diff := cmp.Diff(body1, body2); diff != "" {
+				t.Fatalf("unexpected final body (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseLSABodyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		t    LSType
+		b    []byte
+	}{
+		{
+			name: "router short",
+			t:    RouterLSA,
+			b:    []byte{0x00, 0x00, 0x00},
+		},
+		{
+			name: "router bad links",
+			t:    RouterLSA,
+			b:    bufRouterLSABody[:len(bufRouterLSABody)-1],
+		},
+		{
+			name: "router bad MT-ID metrics",
+			t:    RouterLSA,
+			b: append(
+				append([]byte(nil), bufRouterLSABody[:routerLSALen+1]...),
+				append([]byte{0x01}, bufRouterLSABody[routerLSALen+2:]...)...,
+			),
+		},
+		{
+			name: "network short",
+			t:    NetworkLSA,
+			b:    []byte{0x00, 0x00, 0x00},
+		},
+		{
+			name: "network bad attached routers",
+			t:    NetworkLSA,
+			b:    bufNetworkLSABody[:len(bufNetworkLSABody)-1],
+		},
+		{
+			name: "inter-area prefix short",
+			t:    InterAreaPrefixLSA,
+			b:    []byte{0x00, 0x00, 0x00},
+		},
+		{
+			name: "inter-area prefix trailing",
+			t:    InterAreaPrefixLSA,
+			b:    append(bufInterAreaPrefixLSABody[:len(bufInterAreaPrefixLSABody):len(bufInterAreaPrefixLSABody)], 0xff),
+		},
+		{
+			name: "inter-area router short",
+			t:    InterAreaRouterLSA,
+			b:    bufInterAreaRouterLSABody[:len(bufInterAreaRouterLSABody)-1],
+		},
+		{
+			name: "inter-area router long",
+			t:    InterAreaRouterLSA,
+			b:    append(bufInterAreaRouterLSABody[:len(bufInterAreaRouterLSABody):len(bufInterAreaRouterLSABody)], 0xff),
+		},
+		{
+			name: "AS-external short",
+			t:    ASExternalLSA,
+			b:    bufASExternalLSABody[:4],
+		},
+		{
+			name: "AS-external bad prefix length",
+			t:    ASExternalLSA,
+			b: []byte{
+				0x00, 0x00, 0x00, 0x00, // Flags, Metric
+				129, 0x00, // Prefix length, options
+				0x00, 0x00, // Referenced LS type
+			},
+		},
+		{
+			name: "AS-external short prefix",
+			t:    ASExternalLSA,
+			b:    bufASExternalLSABody[:len(bufASExternalLSABody)-1],
+		},
+		{
+			name: "AS-external trailing",
+			t:    ASExternalLSA,
+			b:    append(bufASExternalLSABody[:len(bufASExternalLSABody):len(bufASExternalLSABody)], 0xff),
+		},
+		{
+			name: "NSSA short optional fields",
+			t:    NSSALSA,
+			b:    bufNSSALSABody[:len(bufNSSALSABody)-4],
+		},
+		{
+			name: "link short",
+			t:    LinkLSA,
+			b:    bufLinkLSABody[:linkLSALen-1],
+		},
+		{
+			name: "link bad prefix count",
+			t:    LinkLSA,
+			b: merge(
+				bufLinkLSABody[:linkLSALen-4],
+				[]byte{0xff, 0xff, 0xff, 0xff},
+			),
+		},
+		{
+			name: "link short prefix",
+			t:    LinkLSA,
+			b:    bufLinkLSABody[:len(bufLinkLSABody)-1],
+		},
+		{
+			name: "link trailing",
+			t:    LinkLSA,
+			b:    append(bufLinkLSABody[:len(bufLinkLSABody):len(bufLinkLSABody)], 0xff, 0xff, 0xff, 0xff),
+		},
+		{
+			name: "intra-area prefix short",
+			t:    IntraAreaPrefixLSA,
+			b:    bufIntraAreaPrefixLSABody[:intraAreaPrefixLSALen-1],
+		},
+		{
+			name: "intra-area prefix short prefix",
+			t:    IntraAreaPrefixLSA,
+			b:    bufIntraAreaPrefixLSABody[:len(bufIntraAreaPrefixLSABody)-1],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLSABody(tt.t, tt.b)
+			if diff := cmp.Diff(errParse, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+
+			t.Logf("err: %v", err)
+		})
+	}
+}
+
+func TestLSABodyMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body LSABody
+	}{
+		{
+			name: "router Options",
+			body: &RouterLSABody{Options: 0xf0000000 | V6Bit},
+		},
+		{
+			name: "router MT-ID metrics",
+			body: &RouterLSABody{Links: []RouterLink{{MTMetrics: make([]MTMetric, 256)}}},
+		},
+		{
+			name: "network Options",
+			body: &NetworkLSABody{Options: 0xf0000000 | V6Bit},
+		},
+		{
+			name: "inter-area prefix Metric",
+			body: &InterAreaPrefixLSABody{Metric: 0x01000000},
+		},
+		{
+			name: "inter-area prefix prefix",
+			body: &InterAreaPrefixLSABody{Prefix: Prefix{Length: 129}},
+		},
+		{
+			name: "inter-area router Options",
+			body: &InterAreaRouterLSABody{Options: 0xf0000000 | V6Bit},
+		},
+		{
+			name: "inter-area router Metric",
+			body: &InterAreaRouterLSABody{Metric: 0x01000000},
+		},
+		{
+			name: "AS-external Metric",
+			body: &ASExternalLSABody{Metric: 0x01000000},
+		},
+		{
+			name: "AS-external prefix length",
+			body: &ASExternalLSABody{
+				Prefix: Prefix{Length: 129, Address: net.IPv6loopback},
+			},
+		},
+		{
+			name: "AS-external prefix address",
+			body: &ASExternalLSABody{
+				Prefix: Prefix{Length: 64, Address: net.IPv4(192, 0, 2, 1).To4()},
+			},
+		},
+		{
+			name: "NSSA forwarding address",
+			body: &NSSALSABody{Flags: ExternalFFlag},
+		},
+		{
+			name: "link Options",
+			body: &LinkLSABody{
+				Options:                   0xf0000000 | V6Bit,
+				LinkLocalInterfaceAddress: net.ParseIP("fe80::1"),
+			},
+		},
+		{
+			name: "link address",
+			body: &LinkLSABody{},
+		},
+		{
+			name: "link prefix",
+			body: &LinkLSABody{
+				LinkLocalInterfaceAddress: net.ParseIP("fe80::1"),
+				Prefixes:                  []Prefix{{Length: 64}},
+			},
+		},
+		{
+			name: "intra-area prefix prefix",
+			body: &IntraAreaPrefixLSABody{
+				Prefixes: []IntraAreaPrefix{{Prefix: Prefix{Length: 129}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.body.MarshalBinary()
+			if diff := cmp.Diff(errMarshal, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+
+			t.Logf("err: %v", err)
+		})
+	}
+}
+
+// testLSAType is an LSType registered for tests with RegisterLSAType.
+const testLSAType LSType = 0x2fee
+
+func init() {
+	RegisterLSAType(testLSAType, "TestLSA", func() LSABody { return new(testLSABody) })
+}
+
+// A testLSABody is an LSABody implemented outside of this package's built-in
+// LSA types.
+type testLSABody struct {
+	Value uint32
+}
+
+func (b *testLSABody) MarshalBinary() ([]byte, error) {
+	if b.Value == 0 {
+		return nil, errors.New("zero value")
+	}
+
+	out := make([]byte, 4)
+	binary.BigEndian.PutUint32(out, b.Value)
+	return out, nil
+}
+
+func (b *testLSABody) UnmarshalBinary(bb []byte) error {
+	if len(bb) != 4 {
+		return fmt.Errorf("bad length: %d: %w", len(bb), errParse)
+	}
+
+	b.Value = binary.BigEndian.Uint32(bb)
+	return nil
+}
+
+func TestRegisterLSAType(t *testing.T) {
+	if diff := cmp.Diff("TestLSA", testLSAType.String()); diff != "" {
+		t.Fatalf("unexpected LSType string (-want +got):\n%s", diff)
+	}
+
+	lsu := &LinkStateUpdate{
+		Header: Header{RouterID: ID{192, 0, 2, 1}},
+		LSAs: []LinkStateAdvertisement{{
+			Header: LSAHeader{
+				LSA: LSA{
+					Type:              testLSAType,
+					AdvertisingRouter: ID{192, 0, 2, 1},
+				},
+				Length: lsaHeaderLen + 4,
+			},
+			Body: &testLSABody{Value: 0xdeadbeef},
+		}},
+	}
+
+	b, err := MarshalPacket(lsu)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	p, err := ParsePacket(b)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if diff := cmp.Diff(lsu, p); diff != "" {
+		t.Fatalf("unexpected LinkStateUpdate (-want +got):\n%s", diff)
+	}
+
+	// Errors from registered LSABody implementations must be propagated.
+	lsu.LSAs[0].Body = &testLSABody{}
+	if _, err := MarshalPacket(lsu); err == nil {
+		t.Fatal("expected an error marshaling registered LSA, but none occurred")
+	}
+
+	if _, err := ParseLSABody(testLSAType, []byte{0xff}); !errors.Is(err, errParse) {
+		t.Fatalf("expected parse error for registered LSA, but got: %v", err)
+	}
+}
+
+func TestRegisterLSATypePanics(t *testing.T) {
+	fn := func() LSABody { return new(testLSABody) }
+
+	tests := []struct {
+		name string
+		t    LSType
+		s    string
+		fn   func() LSABody
+	}{
+		{
+			name: "nil function",
+			t:    0x2fef,
+			s:    "foo",
+		},
+		{
+			name: "empty name",
+			t:    0x2fef,
+			fn:   fn,
+		},
+		{
+			name: "built-in",
+			t:    RouterLSA,
+			s:    "foo",
+			fn:   fn,
+		},
+		{
+			name: "duplicate",
+			t:    testLSAType,
+			s:    "foo",
+			fn:   fn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatal("expected a panic, but none occurred")
+				}
+
+				t.Logf("panic: %v", r)
+			}()
+
+			RegisterLSAType(tt.t, tt.s, tt.fn)
+		})
+	}
+}
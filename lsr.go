@@ -0,0 +1,156 @@
+package ospf3
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/ipv6"
+)
+
+// A RequestList is the Link State Request list for a single neighbor, as
+// described in RFC2328, sections 10 and 10.9. It holds the LSAs which the
+// neighbor described in DatabaseDescriptions as more recent than the local
+// copies, and produces the LinkStateRequests which fetch them during the
+// Exchange and Loading states.
+//
+// At most one LinkStateRequest is outstanding at a time. It is retransmitted
+// every RxmtInterval until each of its LSAs is received in a LinkStateUpdate,
+// and then the next LinkStateRequest is sent. A RequestList is not safe for
+// concurrent use.
+type RequestList struct {
+	h    Header
+	max  int
+	rxmt time.Duration
+
+	// lsas holds the instance of each LSA described by the neighbor, and
+	// order holds the LSAs in the order they were added. order may contain
+	// LSAs which have since been removed from lsas.
+	lsas  map[LSA]LSAHeader
+	order []LSA
+
+	// pending holds the LSAs of the outstanding LinkStateRequest, which was
+	// sent at time sent.
+	pending map[LSA]struct{}
+	sent    time.Time
+}
+
+// NewRequestList creates an empty RequestList which produces
+// LinkStateRequests with Header h. mtu is the interface MTU, or 0 on virtual
+// links, and limits the number of LSAs in each LinkStateRequest. rxmt is the
+// RxmtInterval between retransmissions and must be positive.
+func NewRequestList(h Header, mtu uint16, rxmt time.Duration) (*RequestList, error) {
+	if rxmt <= 0 {
+		return nil, fmt.Errorf("ospf3: RequestList RxmtInterval must be positive: %v", rxmt)
+	}
+
+	// Virtual links are assumed to support the minimum IPv6 MTU.
+	if mtu == 0 {
+		mtu = minIPv6MTU
+	}
+
+	n := (int(mtu) - ipv6.HeaderLen - headerLen) / lsaLen
+	if n < 1 {
+		return nil, fmt.Errorf("ospf3: MTU %d is too small for a LinkStateRequest", mtu)
+	}
+
+	return &RequestList{
+		h:       h,
+		max:     n,
+		rxmt:    rxmt,
+		lsas:    make(map[LSA]LSAHeader),
+		pending: make(map[LSA]struct{}),
+	}, nil
+}
+
+// Add adds the LSA described by h to the list, such as for an LSA header in a
+// DatabaseDescription which is more recent than the local copy. If the LSA is
+// already on the list, the requested instance is replaced only if h is more
+// recent.
+func (l *RequestList) Add(h LSAHeader) {
+	old, ok := l.lsas[h.LSA]
+	if !ok {
+		l.order = append(l.order, h.LSA)
+	} else if !h.Newer(old) {
+		return
+	}
+
+	l.lsas[h.LSA] = h
+}
+
+// Len returns the number of LSAs on the list.
+func (l *RequestList) Len() int { return len(l.lsas) }
+
+// Next returns the LinkStateRequest to send to the neighbor at time now, or
+// nil if no LinkStateRequest is due. A LinkStateRequest is due when the list
+// is not empty and either no LinkStateRequest is outstanding or the
+// outstanding one has not been answered within RxmtInterval.
+func (l *RequestList) Next(now time.Time) *LinkStateRequest {
+	if len(l.lsas) == 0 {
+		return nil
+	}
+	if len(l.pending) > 0 && now.Before(l.sent.Add(l.rxmt)) {
+		return nil
+	}
+
+	// Drop LSAs which have been received from order, and request the oldest
+	// remaining LSAs, which include any which were not answered by a previous
+	// request.
+	order := l.order[:0]
+	for _, lsa := range l.order {
+		if _, ok := l.lsas[lsa]; ok {
+			order = append(order, lsa)
+		}
+	}
+	l.order = order
+
+	n := l.max
+	if len(l.order) < n {
+		n = len(l.order)
+	}
+
+	lsr := &LinkStateRequest{
+		Header: l.h,
+		LSAs:   make([]LSA, 0, n),
+	}
+
+	l.pending = make(map[LSA]struct{}, n)
+	for _, lsa := range l.order[:n] {
+		lsr.LSAs = append(lsr.LSAs, lsa)
+		l.pending[lsa] = struct{}{}
+	}
+	l.sent = now
+
+	return lsr
+}
+
+// Deadline returns the time at which the outstanding LinkStateRequest must be
+// retransmitted, or the zero time if none is outstanding.
+func (l *RequestList) Deadline() time.Time {
+	if len(l.pending) == 0 {
+		return time.Time{}
+	}
+
+	return l.sent.Add(l.rxmt)
+}
+
+// Received removes each LSA in lsu from the list if it is the same as or more
+// recent than the requested instance. Received reports whether the list is
+// empty afterward, in which case the neighbor may transition from Loading to
+// Full, as described by the LoadingDone event in RFC2328, section 10.3.
+//
+// Once each LSA of the outstanding LinkStateRequest has been received, Next
+// returns the following LinkStateRequest immediately.
+func (l *RequestList) Received(lsu *LinkStateUpdate) bool {
+	for _, lsa := range lsu.LSAs {
+		h := lsa.Header
+		want, ok := l.lsas[h.LSA]
+		if !ok || want.Newer(h) {
+			continue
+		}
+
+		delete(l.lsas, h.LSA)
+		delete(l.pending, h.LSA)
+	}
+
+	return len(l.lsas) == 0
+}
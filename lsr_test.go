@@ -0,0 +1,205 @@
+package ospf3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/ipv6"
+)
+
+func TestRequestList(t *testing.T) {
+	var (
+		h     = Header{RouterID: ID{192, 0, 2, 1}}
+		start = time.Unix(0, 0)
+		rxmt  = 5 * time.Second
+	)
+
+	// Room for exactly two LSAs per LinkStateRequest.
+	l, err := NewRequestList(h, ipv6.HeaderLen+headerLen+2*lsaLen, rxmt)
+	if err != nil {
+		t.Fatalf("failed to create RequestList: %v", err)
+	}
+
+	if lsr := l.Next(start); lsr != nil {
+		t.Fatalf("expected no LinkStateRequest for an empty list, but got: %+v", lsr)
+	}
+
+	header := func(id byte, seq uint32) LSAHeader {
+		return LSAHeader{
+			LSA: LSA{
+				Type:              RouterLSA,
+				AdvertisingRouter: ID{192, 0, 2, id},
+			},
+			SequenceNumber: seq,
+		}
+	}
+
+	lsr := func(ids ...byte) *LinkStateRequest {
+		lsr := &LinkStateRequest{Header: h, LSAs: []LSA{}}
+		for _, id := range ids {
+			lsr.LSAs = append(lsr.LSAs, header(id, 0).LSA)
+		}
+		return lsr
+	}
+
+	lsu := func(hs ...LSAHeader) *LinkStateUpdate {
+		lsu := &LinkStateUpdate{Header: h}
+		for _, h := range hs {
+			lsu.LSAs = append(lsu.LSAs, LinkStateAdvertisement{Header: h})
+		}
+		return lsu
+	}
+
+	l.Add(header(2, 2))
+	l.Add(header(3, 2))
+	l.Add(header(4, 2))
+	// An older instance is ignored, and a newer one replaces the requested
+	// instance.
+	l.Add(header(2, 1))
+	l.Add(header(3, 3))
+
+	if diff := cmp.Diff(3, l.Len()); diff != "" {
+		t.Fatalf("unexpected list length (-want +got):\n%s", diff)
+	}
+
+	steps := []struct {
+		name     string
+		at       time.Duration
+		lsu      *LinkStateUpdate
+		done     bool
+		lsr      *LinkStateRequest
+		deadline time.Duration
+	}{
+		{
+			name:     "first request",
+			lsr:      lsr(2, 3),
+			deadline: rxmt,
+		},
+		{
+			name:     "outstanding",
+			at:       1 * time.Second,
+			deadline: rxmt,
+		},
+		{
+			name: "partial answer",
+			at:   2 * time.Second,
+			// The instance of 3 is older than requested.
+			lsu:      lsu(header(2, 2), header(3, 2)),
+			deadline: rxmt,
+		},
+		{
+			name:     "retransmit",
+			at:       rxmt,
+			lsr:      lsr(3, 4),
+			deadline: 2 * rxmt,
+		},
+		{
+			name: "unrelated LSA",
+			at:   6 * time.Second,
+			lsu:  lsu(header(5, 1), header(3, 3)),
+			// 4 is still outstanding.
+			deadline: 2 * rxmt,
+		},
+		{
+			name: "loading done",
+			at:   7 * time.Second,
+			lsu:  lsu(header(4, 3)),
+			done: true,
+		},
+		{
+			name: "empty",
+			at:   20 * time.Second,
+		},
+	}
+
+	for _, s := range steps {
+		now := start.Add(s.at)
+
+		if s.lsu != nil {
+			if diff := cmp.Diff(s.done, l.Received(s.lsu)); diff != "" {
+				t.Fatalf("%s: unexpected done (-want +got):\n%s", s.name, diff)
+			}
+		}
+
+		if diff := cmp.Diff(s.lsr, l.Next(now)); diff != "" {
+			t.Fatalf("%s: unexpected LinkStateRequest (-want +got):\n%s", s.name, diff)
+		}
+
+		var want time.Time
+		if s.deadline != 0 {
+			want = start.Add(s.deadline)
+		}
+		if diff := cmp.Diff(want, l.Deadline()); diff != "" {
+			t.Fatalf("%s: unexpected deadline (-want +got):\n%s", s.name, diff)
+		}
+	}
+
+	if diff := cmp.Diff(0, l.Len()); diff != "" {
+		t.Fatalf("unexpected list length (-want +got):\n%s", diff)
+	}
+}
+
+func TestRequestListNextAfterAnswer(t *testing.T) {
+	l, err := NewRequestList(Header{}, 0, 5*time.Second)
+	if err != nil {
+		t.Fatalf("failed to create RequestList: %v", err)
+	}
+
+	// A virtual link uses the minimum IPv6 MTU, so every LinkStateRequest
+	// must fit in a single packet.
+	const n = 200
+	for i := 0; i < n; i++ {
+		l.Add(LSAHeader{LSA: LSA{Type: RouterLSA, LinkStateID: ID{0, 0, byte(i >> 8), byte(i)}}})
+	}
+
+	var (
+		now   = time.Unix(0, 0)
+		total int
+	)
+	for l.Len() > 0 {
+		lsr := l.Next(now)
+		if lsr == nil {
+			t.Fatal("expected a LinkStateRequest once the previous one was answered")
+		}
+
+		b, err := MarshalPacket(lsr)
+		if err != nil {
+			t.Fatalf("failed to marshal LinkStateRequest: %v", err)
+		}
+		if size := ipv6.HeaderLen + len(b); size > minIPv6MTU {
+			t.Fatalf("LinkStateRequest of %d bytes exceeds the minimum IPv6 MTU", size)
+		}
+
+		// Answer the request immediately.
+		lsu := &LinkStateUpdate{}
+		for _, lsa := range lsr.LSAs {
+			lsu.LSAs = append(lsu.LSAs, LinkStateAdvertisement{Header: LSAHeader{LSA: lsa}})
+		}
+		l.Received(lsu)
+		total += len(lsr.LSAs)
+	}
+
+	if diff := cmp.Diff(n, total); diff != "" {
+		t.Fatalf("unexpected number of requested LSAs (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewRequestListErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		mtu  uint16
+		rxmt time.Duration
+	}{
+		{name: "RxmtInterval", mtu: 1500},
+		{name: "MTU", mtu: ipv6.HeaderLen + headerLen + lsaLen - 1, rxmt: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRequestList(Header{}, tt.mtu, tt.rxmt); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package ospf3
+
+import "sort"
+
+// An LSTypeInfo describes an LSType known to this package, either because its
+// body format is implemented by this package or because it was added by
+// RegisterLSAType.
+type LSTypeInfo struct {
+	// Type is the LSType, and Name is its name as reported by
+	// LSType.String.
+	Type LSType
+	Name string
+
+	// FloodingScope is the flooding scope encoded in Type.
+	FloodingScope FloodingScope
+
+	// Parsed reports whether LSA bodies of this type are decoded into a
+	// structured LSABody rather than a *RawLSABody.
+	Parsed bool
+
+	// Registered reports whether the LSType was added by RegisterLSAType.
+	Registered bool
+
+	// Reference is the specification which defines the LSType, such as
+	// "RFC5340, appendix A.4.3". It is empty for registered LSTypes.
+	Reference string
+}
+
+// builtinLSTypes describes the LSTypes implemented by this package.
+var builtinLSTypes = []LSTypeInfo{
+	{Type: RouterLSA, Name: "RouterLSA", Reference: "RFC5340, appendix A.4.3"},
+	{Type: NetworkLSA, Name: "NetworkLSA", Reference: "RFC5340, appendix A.4.4"},
+	{Type: InterAreaPrefixLSA, Name: "InterAreaPrefixLSA", Reference: "RFC5340, appendix A.4.5"},
+	{Type: InterAreaRouterLSA, Name: "InterAreaRouterLSA", Reference: "RFC5340, appendix A.4.6"},
+	{Type: ASExternalLSA, Name: "ASExternalLSA", Reference: "RFC5340, appendix A.4.7"},
+	{Type: deprecatedLSA, Name: "deprecatedLSA", Reference: "RFC5340, appendix A.4.2.1"},
+	{Type: NSSALSA, Name: "NSSALSA", Reference: "RFC5340, appendix A.4.8"},
+	{Type: LinkLSA, Name: "LinkLSA", Reference: "RFC5340, appendix A.4.9"},
+	{Type: IntraAreaPrefixLSA, Name: "IntraAreaPrefixLSA", Reference: "RFC5340, appendix A.4.10"},
+	{Type: IntraAreaTELSA, Name: "IntraAreaTELSA", Reference: "RFC5329, section 3"},
+	{Type: LinkRouterInformationLSA, Name: "LinkRouterInformationLSA", Reference: "RFC7770, section 2.2"},
+	{Type: AreaRouterInformationLSA, Name: "AreaRouterInformationLSA", Reference: "RFC7770, section 2.2"},
+	{Type: ASRouterInformationLSA, Name: "ASRouterInformationLSA", Reference: "RFC7770, section 2.2"},
+	{Type: AreaSRv6LocatorLSA, Name: "AreaSRv6LocatorLSA", Reference: "RFC9513, section 7"},
+	{Type: ASSRv6LocatorLSA, Name: "ASSRv6LocatorLSA", Reference: "RFC9513, section 7"},
+}
+
+// builtinLSTypeIndex maps each built-in LSType to its index in builtinLSTypes.
+var builtinLSTypeIndex = func() map[LSType]int {
+	m := make(map[LSType]int, len(builtinLSTypes))
+	for i := range builtinLSTypes {
+		info := &builtinLSTypes[i]
+		info.FloodingScope = info.Type.FloodingScope()
+		info.Parsed = newLSABody(info.Type) != nil
+		m[info.Type] = i
+	}
+
+	return m
+}()
+
+// LookupLSType returns information about the LSType t, or false if t is
+// neither implemented by this package nor added by RegisterLSAType.
+func LookupLSType(t LSType) (LSTypeInfo, bool) {
+	if i, ok := builtinLSTypeIndex[t]; ok {
+		return builtinLSTypes[i], true
+	}
+
+	name, ok := registeredLSAName(t)
+	if !ok {
+		return LSTypeInfo{}, false
+	}
+
+	return registeredLSTypeInfo(t, name), true
+}
+
+// LSTypes returns information about every LSType implemented by this package
+// or added by RegisterLSAType, sorted by LSType.
+func LSTypes() []LSTypeInfo {
+	infos := append([]LSTypeInfo(nil), builtinLSTypes...)
+
+	lsaRegistry.mu.RLock()
+	for t, r := range lsaRegistry.types {
+		infos = append(infos, registeredLSTypeInfo(t, r.name))
+	}
+	lsaRegistry.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Type < infos[j].Type })
+	return infos
+}
+
+// registeredLSTypeInfo returns the LSTypeInfo for an LSType t added by
+// RegisterLSAType with name.
+func registeredLSTypeInfo(t LSType, name string) LSTypeInfo {
+	return LSTypeInfo{
+		Type:          t,
+		Name:          name,
+		FloodingScope: t.FloodingScope(),
+		Parsed:        true,
+		Registered:    true,
+	}
+}
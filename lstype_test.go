@@ -0,0 +1,104 @@
+package ospf3
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLookupLSType(t *testing.T) {
+	tests := []struct {
+		name string
+		t    LSType
+		info LSTypeInfo
+		ok   bool
+	}{
+		{
+			name: "built-in",
+			t:    LinkLSA,
+			info: LSTypeInfo{
+				Type:          LinkLSA,
+				Name:          "LinkLSA",
+				FloodingScope: LinkLocalScoping,
+				Parsed:        true,
+				Reference:     "RFC5340, appendix A.4.9",
+			},
+			ok: true,
+		},
+		{
+			name: "built-in unparsed",
+			t:    deprecatedLSA,
+			info: LSTypeInfo{
+				Type:          deprecatedLSA,
+				Name:          "deprecatedLSA",
+				FloodingScope: AreaScoping,
+				Reference:     "RFC5340, appendix A.4.2.1",
+			},
+			ok: true,
+		},
+		{
+			name: "registered",
+			t:    testLSAType,
+			info: LSTypeInfo{
+				Type:          testLSAType,
+				Name:          "TestLSA",
+				FloodingScope: testLSAType.FloodingScope(),
+				Parsed:        true,
+				Registered:    true,
+			},
+			ok: true,
+		},
+		{
+			name: "unknown",
+			t:    0x2fff,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := LookupLSType(tt.t)
+			if diff := cmp.Diff(tt.ok, ok); diff != "" {
+				t.Fatalf("unexpected ok (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.info, info); diff != "" {
+				t.Fatalf("unexpected LSTypeInfo (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLSTypes(t *testing.T) {
+	infos := LSTypes()
+	if !sort.SliceIsSorted(infos, func(i, j int) bool { return infos[i].Type < infos[j].Type }) {
+		t.Fatal("LSTypes are not sorted")
+	}
+
+	var registered bool
+	for _, info := range infos {
+		// Every LSType must agree with its own methods and with LookupLSType.
+		if diff := cmp.Diff(info.Type.String(), info.Name); diff != "" {
+			t.Fatalf("unexpected name for %#04x (-want +got):\n%s", uint16(info.Type), diff)
+		}
+
+		got, ok := LookupLSType(info.Type)
+		if !ok {
+			t.Fatalf("failed to look up %s", info.Type)
+		}
+		if diff := cmp.Diff(info, got); diff != "" {
+			t.Fatalf("unexpected LSTypeInfo for %s (-want +got):\n%s", info.Type, diff)
+		}
+
+		if !info.Registered && info.Reference == "" {
+			t.Fatalf("built-in %s has no reference", info.Type)
+		}
+		if info.Type == testLSAType {
+			registered = true
+		}
+	}
+
+	if !registered {
+		t.Fatal("registered LSType was not returned")
+	}
+}
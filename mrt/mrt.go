@@ -0,0 +1,263 @@
+// Package mrt reads and writes OSPFv3 packets in the MRT routing information
+// export format described in RFC6396, such as to exchange link-state database
+// snapshots.
+package mrt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/mdlayher/ospf3"
+)
+
+// MRT record types and address families, per RFC6396, sections 4 and 5.
+const (
+	typeOSPFv3   = 48
+	typeOSPFv3ET = 49
+
+	afiIPv4 = 1
+	afiIPv6 = 2
+
+	headerLen   = 12
+	extendedLen = 4
+	maxLen      = 16 << 20
+)
+
+// A Record is an MRT record containing an OSPFv3 packet, as described in
+// RFC6396, section 5.
+type Record struct {
+	// Timestamp is the time at which the packet was sent or received. MRT
+	// timestamps have a precision of one microsecond.
+	Timestamp time.Time
+
+	// Remote and Local are the IP addresses of the remote and local routers.
+	// Both must be IPv4 or both must be IPv6 addresses.
+	Remote, Local net.IP
+
+	// Packet is the OSPFv3 packet.
+	Packet ospf3.Packet
+}
+
+// A Writer writes OSPFv3 MRT records to an io.Writer.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter creates a Writer which writes MRT records to w. Writes to w are
+// not buffered.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteRecord writes a single Record using the OSPFv3_ET record type, which
+// preserves microsecond timestamps.
+func (w *Writer) WriteRecord(r *Record) error {
+	afi, remote, local, err := addresses(r.Remote, r.Local)
+	if err != nil {
+		return err
+	}
+
+	p, err := ospf3.MarshalPacket(r.Packet)
+	if err != nil {
+		return err
+	}
+
+	n := extendedLen + 2 + len(remote) + len(local) + len(p)
+	b := make([]byte, headerLen, headerLen+n)
+	binary.BigEndian.PutUint32(b[0:4], uint32(r.Timestamp.Unix()))
+	binary.BigEndian.PutUint16(b[4:6], typeOSPFv3ET)
+	// Subtype is zero.
+	binary.BigEndian.PutUint32(b[8:12], uint32(n))
+
+	var x [6]byte
+	binary.BigEndian.PutUint32(x[0:4], uint32(r.Timestamp.Nanosecond()/1000))
+	binary.BigEndian.PutUint16(x[4:6], afi)
+	b = append(b, x[:]...)
+	b = append(b, remote...)
+	b = append(b, local...)
+	b = append(b, p...)
+
+	if _, err := w.w.Write(b); err != nil {
+		return fmt.Errorf("mrt: failed to write record: %w", err)
+	}
+
+	return nil
+}
+
+// WriteSnapshot writes a snapshot of a link-state database containing lsas as
+// one or more LinkStateUpdate records, each of which uses the Header h. The
+// LSAs are split across records as needed to fit within the maximum OSPFv3
+// packet length. The Timestamp, Remote, and Local fields of r are used for
+// each record, and its Packet field is ignored.
+func (w *Writer) WriteSnapshot(r Record, h ospf3.Header, lsas []ospf3.LinkStateAdvertisement) error {
+	// The packet header and LSA count.
+	const overhead = 16 + 4
+
+	var (
+		batch []ospf3.LinkStateAdvertisement
+		size  = overhead
+	)
+
+	flush := func() error {
+		r.Packet = &ospf3.LinkStateUpdate{Header: h, LSAs: batch}
+		batch, size = nil, overhead
+		return w.WriteRecord(&r)
+	}
+
+	for _, l := range lsas {
+		n, err := lsaLen(l)
+		if err != nil {
+			return err
+		}
+
+		if len(batch) > 0 && size+n > 0xffff {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		batch = append(batch, l)
+		size += n
+	}
+
+	// Always write at least one record, so that an empty database is
+	// represented in the snapshot.
+	return flush()
+}
+
+// lsaLen returns the marshaled length of l.
+func lsaLen(l ospf3.LinkStateAdvertisement) (int, error) {
+	b, err := ospf3.MarshalPacket(&ospf3.LinkStateUpdate{
+		LSAs: []ospf3.LinkStateAdvertisement{l},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b) - (16 + 4), nil
+}
+
+// addresses returns the MRT address family and the encoded remote and local
+// addresses.
+func addresses(remote, local net.IP) (uint16, net.IP, net.IP, error) {
+	if r4, l4 := remote.To4(), local.To4(); r4 != nil && l4 != nil {
+		return afiIPv4, r4, l4, nil
+	}
+
+	r16, l16 := remote.To16(), local.To16()
+	if r16 == nil || l16 == nil || remote.To4() != nil || local.To4() != nil {
+		return 0, nil, nil, fmt.Errorf("mrt: remote %s and local %s must be IP addresses of the same family", remote, local)
+	}
+
+	return afiIPv6, r16, l16, nil
+}
+
+// A Reader reads OSPFv3 MRT records from an io.Reader. Records of other types,
+// such as BGP records, are skipped.
+type Reader struct {
+	// Options modify the behavior of packet parsing as described in
+	// ospf3.ParseOptions.
+	Options ospf3.ParseOptions
+
+	r io.Reader
+}
+
+// NewReader creates a Reader which reads MRT records from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Next returns the next OSPFv3 Record. It returns io.EOF when no records
+// remain. If a record contains an OSPFv3 packet which cannot be parsed, Next
+// returns the parse error and the following call to Next continues with the
+// next record.
+func (r *Reader) Next() (*Record, error) {
+	for {
+		h := make([]byte, headerLen)
+		if _, err := io.ReadFull(r.r, h); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+
+			return nil, fmt.Errorf("mrt: failed to read record header: %w", unexpected(err))
+		}
+
+		var (
+			ts  = time.Unix(int64(binary.BigEndian.Uint32(h[0:4])), 0)
+			typ = binary.BigEndian.Uint16(h[4:6])
+			n   = binary.BigEndian.Uint32(h[8:12])
+		)
+		if n > maxLen {
+			return nil, fmt.Errorf("mrt: record length %d is too large", n)
+		}
+
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r.r, b); err != nil {
+			return nil, fmt.Errorf("mrt: failed to read record: %w", unexpected(err))
+		}
+
+		switch typ {
+		case typeOSPFv3:
+		case typeOSPFv3ET:
+			if len(b) < extendedLen {
+				return nil, errors.New("mrt: OSPFv3_ET record is too short")
+			}
+
+			us := binary.BigEndian.Uint32(b[0:4])
+			ts = ts.Add(time.Duration(us) * time.Microsecond)
+			b = b[extendedLen:]
+		default:
+			continue
+		}
+
+		return r.parse(ts, b)
+	}
+}
+
+// parse parses the body of an OSPFv3 record.
+func (r *Reader) parse(ts time.Time, b []byte) (*Record, error) {
+	if len(b) < 2 {
+		return nil, errors.New("mrt: OSPFv3 record is too short")
+	}
+
+	var l int
+	switch afi := binary.BigEndian.Uint16(b[0:2]); afi {
+	case afiIPv4:
+		l = net.IPv4len
+	case afiIPv6:
+		l = net.IPv6len
+	default:
+		return nil, fmt.Errorf("mrt: unknown address family %d", afi)
+	}
+
+	b = b[2:]
+	if len(b) < 2*l {
+		return nil, errors.New("mrt: OSPFv3 record is too short for addresses")
+	}
+
+	p, err := r.Options.ParsePacket(b[2*l:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Record{
+		Timestamp: ts,
+		Remote:    append(net.IP(nil), b[:l]...),
+		Local:     append(net.IP(nil), b[l:2*l]...),
+		Packet:    p,
+	}, nil
+}
+
+// unexpected converts io.EOF into io.ErrUnexpectedEOF, since the input ended
+// within a record.
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,190 @@
+package mrt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/mdlayher/ospf3"
+)
+
+var (
+	remote = net.ParseIP("fe80::1")
+	local  = net.ParseIP("fe80::2")
+
+	hello = &ospf3.Hello{
+		Header:             ospf3.Header{RouterID: ospf3.ID{192, 0, 2, 1}},
+		InterfaceID:        1,
+		Options:            ospf3.V6Bit | ospf3.EBit,
+		HelloInterval:      10 * time.Second,
+		RouterDeadInterval: 40 * time.Second,
+		NeighborIDs:        []ospf3.ID{{192, 0, 2, 2}},
+	}
+)
+
+func TestRoundTrip(t *testing.T) {
+	ts := time.Unix(1700000000, 123456000)
+
+	rs := []*Record{
+		{
+			Timestamp: ts,
+			Remote:    remote,
+			Local:     local,
+			Packet:    hello,
+		},
+		{
+			Timestamp: ts.Add(time.Second),
+			Remote:    net.IPv4(192, 0, 2, 1).To4(),
+			Local:     net.IPv4(192, 0, 2, 2).To4(),
+			Packet:    hello,
+		},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, r := range rs {
+		if err := w.WriteRecord(r); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+
+	// Records of other types are skipped.
+	writeRaw(&buf, 13, make([]byte, 8))
+	// Records without extended timestamps are also accepted.
+	p, err := ospf3.MarshalPacket(hello)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	writeRaw(&buf, typeOSPFv3, append(append(append([]byte{0, afiIPv6}, remote...), local...), p...))
+	rs = append(rs, &Record{
+		Timestamp: time.Unix(0, 0),
+		Remote:    remote,
+		Local:     local,
+		Packet:    hello,
+	})
+
+	if diff := cmp.Diff(rs, readAll(t, &buf), cmpopts.IgnoreFields(ospf3.Header{}, "Checksum")); diff != "" {
+		t.Fatalf("unexpected Records (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteSnapshot(t *testing.T) {
+	// Each LSA is 1024 bytes, so 64 of them require two packets.
+	lsas := make([]ospf3.LinkStateAdvertisement, 64)
+	for i := range lsas {
+		lsas[i] = ospf3.LinkStateAdvertisement{
+			Header: ospf3.LSAHeader{
+				LSA: ospf3.LSA{
+					Type:              0x4fff,
+					LinkStateID:       ospf3.ID{0, 0, 0, byte(i)},
+					AdvertisingRouter: ospf3.ID{192, 0, 2, 1},
+				},
+				SequenceNumber: 0x80000001,
+				Length:         1024,
+			},
+			Body: &ospf3.RawLSABody{Data: make([]byte, 1024-20)},
+		}
+	}
+
+	h := ospf3.Header{RouterID: ospf3.ID{192, 0, 2, 1}}
+	tests := []struct {
+		name string
+		lsas []ospf3.LinkStateAdvertisement
+		n    int
+	}{
+		{name: "empty", n: 1},
+		{name: "one", lsas: lsas[:1], n: 1},
+		{name: "split", lsas: lsas, n: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r := Record{Timestamp: time.Unix(1, 0), Remote: remote, Local: local}
+			if err := NewWriter(&buf).WriteSnapshot(r, h, tt.lsas); err != nil {
+				t.Fatalf("failed to write snapshot: %v", err)
+			}
+
+			rs := readAll(t, &buf)
+			if diff := cmp.Diff(tt.n, len(rs)); diff != "" {
+				t.Fatalf("unexpected number of records (-want +got):\n%s", diff)
+			}
+
+			var got []ospf3.LinkStateAdvertisement
+			for _, r := range rs {
+				got = append(got, r.Packet.(*ospf3.LinkStateUpdate).LSAs...)
+			}
+
+			if diff := cmp.Diff(tt.lsas, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("unexpected LSAs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	t.Run("write mixed families", func(t *testing.T) {
+		err := NewWriter(io.Discard).WriteRecord(&Record{
+			Remote: remote,
+			Local:  net.IPv4(192, 0, 2, 1),
+			Packet: hello,
+		})
+		if err == nil {
+			t.Fatal("expected an error, but none occurred")
+		}
+	})
+
+	t.Run("read", func(t *testing.T) {
+		var ok bytes.Buffer
+		if err := NewWriter(&ok).WriteRecord(&Record{Remote: remote, Local: local, Packet: hello}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+
+		var afi bytes.Buffer
+		writeRaw(&afi, typeOSPFv3, []byte{0, 3})
+
+		for _, b := range [][]byte{
+			ok.Bytes()[:headerLen-1],
+			ok.Bytes()[:ok.Len()-1],
+			afi.Bytes(),
+		} {
+			_, err := NewReader(bytes.NewReader(b)).Next()
+			if err == nil || errors.Is(err, io.EOF) {
+				t.Fatalf("expected a non-EOF error, but got: %v", err)
+			}
+		}
+	})
+}
+
+func readAll(t *testing.T, r io.Reader) []*Record {
+	t.Helper()
+
+	mr := NewReader(r)
+
+	var rs []*Record
+	for {
+		r, err := mr.Next()
+		if errors.Is(err, io.EOF) {
+			return rs
+		}
+		if err != nil {
+			t.Fatalf("failed to read record: %v", err)
+		}
+
+		rs = append(rs, r)
+	}
+}
+
+func writeRaw(w *bytes.Buffer, typ uint16, body []byte) {
+	b := make([]byte, headerLen)
+	binary.BigEndian.PutUint16(b[4:6], typ)
+	binary.BigEndian.PutUint32(b[8:12], uint32(len(body)))
+	w.Write(b)
+	w.Write(body)
+}
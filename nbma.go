@@ -0,0 +1,123 @@
+package ospf3
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// An NBMAPoller schedules the Hellos sent to each configured neighbor on an
+// NBMA interface, as described in RFC2328, section 9.5.1. Neighbors which are
+// up are sent a Hello every HelloInterval, while neighbors which are down are
+// polled every PollInterval. An NBMAPoller is safe for concurrent use.
+type NBMAPoller struct {
+	hello, poll time.Duration
+
+	mu        sync.Mutex
+	neighbors []*nbmaNeighbor
+}
+
+// An nbmaNeighbor is the Hello schedule for a single NBMA neighbor.
+type nbmaNeighbor struct {
+	addr *net.IPAddr
+	up   bool
+	last time.Time
+}
+
+// NewNBMAPoller creates an NBMAPoller for the neighbors configured on an NBMA
+// interface, which are initially down. t must be valid and must set
+// PollInterval.
+func NewNBMAPoller(neighbors []*net.IPAddr, t Timers) (*NBMAPoller, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	if t.PollInterval == 0 {
+		return nil, errors.New("ospf3: NBMAPoller Timers must set PollInterval")
+	}
+
+	ns := make([]*nbmaNeighbor, 0, len(neighbors))
+	for _, n := range neighbors {
+		if n == nil || n.IP.To16() == nil {
+			return nil, fmt.Errorf("ospf3: invalid NBMA neighbor address: %v", n)
+		}
+
+		nn := *n
+		ns = append(ns, &nbmaNeighbor{addr: &nn})
+	}
+
+	return &NBMAPoller{
+		hello:     t.HelloInterval,
+		poll:      t.PollInterval,
+		neighbors: ns,
+	}, nil
+}
+
+// SetUp records whether the neighbor at addr is up, meaning that a Hello has
+// been received from it within RouterDeadInterval. Its next Hello is
+// rescheduled using the new interval. SetUp reports false if addr is not a
+// configured neighbor.
+func (p *NBMAPoller) SetUp(addr *net.IPAddr, up bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, n := range p.neighbors {
+		if n.addr.IP.Equal(addr.IP) {
+			n.up = up
+			return true
+		}
+	}
+
+	return false
+}
+
+// Due returns the addresses of the neighbors which should be sent a Hello at
+// time now, and schedules their next Hello. Every neighbor is due on the first
+// call.
+func (p *NBMAPoller) Due(now time.Time) []*net.IPAddr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var due []*net.IPAddr
+	for _, n := range p.neighbors {
+		if !n.last.IsZero() && now.Before(p.next(n)) {
+			continue
+		}
+
+		n.last = now
+		addr := *n.addr
+		due = append(due, &addr)
+	}
+
+	return due
+}
+
+// Next returns the time at which the next Hello is due, or the zero time if
+// a Hello is due immediately or there are no neighbors.
+func (p *NBMAPoller) Next() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var next time.Time
+	for i, n := range p.neighbors {
+		if n.last.IsZero() {
+			return time.Time{}
+		}
+
+		if t := p.next(n); i == 0 || t.Before(next) {
+			next = t
+		}
+	}
+
+	return next
+}
+
+// next returns the time at which n's next Hello is due.
+func (p *NBMAPoller) next(n *nbmaNeighbor) time.Time {
+	if n.up {
+		return n.last.Add(p.hello)
+	}
+
+	return n.last.Add(p.poll)
+}
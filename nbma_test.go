@@ -0,0 +1,115 @@
+package ospf3
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNBMAPoller(t *testing.T) {
+	var (
+		a = &net.IPAddr{IP: net.ParseIP("2001:db8::1")}
+		b = &net.IPAddr{IP: net.ParseIP("2001:db8::2")}
+
+		start = time.Unix(0, 0)
+		tm    = DefaultTimers()
+	)
+
+	p, err := NewNBMAPoller([]*net.IPAddr{a, b}, tm)
+	if err != nil {
+		t.Fatalf("failed to create NBMAPoller: %v", err)
+	}
+
+	if !p.Next().IsZero() {
+		t.Fatalf("expected Hellos to be due immediately, but next is %v", p.Next())
+	}
+
+	if ok := p.SetUp(&net.IPAddr{IP: net.ParseIP("2001:db8::3")}, true); ok {
+		t.Fatal("expected unknown neighbor to be rejected")
+	}
+
+	tests := []struct {
+		name string
+		fn   func()
+		now  time.Duration
+		due  []*net.IPAddr
+		next time.Duration
+	}{
+		{
+			name: "initial",
+			due:  []*net.IPAddr{a, b},
+			next: tm.PollInterval,
+		},
+		{
+			name: "none due",
+			now:  tm.HelloInterval,
+			next: tm.PollInterval,
+		},
+		{
+			name: "up",
+			fn:   func() { p.SetUp(a, true) },
+			now:  tm.HelloInterval,
+			due:  []*net.IPAddr{a},
+			next: 2 * tm.HelloInterval,
+		},
+		{
+			name: "poll",
+			now:  tm.PollInterval,
+			due:  []*net.IPAddr{a, b},
+			next: tm.PollInterval + tm.HelloInterval,
+		},
+		{
+			name: "down",
+			fn:   func() { p.SetUp(a, false) },
+			now:  tm.PollInterval + tm.HelloInterval,
+			next: 2 * tm.PollInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.fn != nil {
+			tt.fn()
+		}
+
+		if diff := cmp.Diff(tt.due, p.Due(start.Add(tt.now))); diff != "" {
+			t.Fatalf("%s: unexpected due neighbors (-want +got):\n%s", tt.name, diff)
+		}
+		if diff := cmp.Diff(start.Add(tt.next), p.Next()); diff != "" {
+			t.Fatalf("%s: unexpected next Hello (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestNewNBMAPollerErrors(t *testing.T) {
+	noPoll := DefaultTimers()
+	noPoll.PollInterval = 0
+
+	tests := []struct {
+		name      string
+		neighbors []*net.IPAddr
+		t         Timers
+	}{
+		{
+			name: "invalid timers",
+		},
+		{
+			name: "no PollInterval",
+			t:    noPoll,
+		},
+		{
+			name:      "nil neighbor",
+			neighbors: []*net.IPAddr{nil},
+			t:         DefaultTimers(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewNBMAPoller(tt.neighbors, tt.t); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
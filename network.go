@@ -0,0 +1,40 @@
+package ospf3
+
+// A NetworkType is the type of network an OSPFv3 interface is attached to, as
+// described in RFC2328, section 1.2.
+type NetworkType uint8
+
+// Possible NetworkType values. The zero value selects BroadcastNetwork or
+// PointToPointNetwork according to the interface's flags.
+const (
+	BroadcastNetwork NetworkType = iota + 1
+	PointToPointNetwork
+	NBMANetwork
+	PointToMultipointNetwork
+)
+
+// ElectsDR reports whether a Designated Router and Backup Designated Router
+// are elected on a network of type t.
+func (t NetworkType) ElectsDR() bool {
+	return t == BroadcastNetwork || t == NBMANetwork
+}
+
+// PointToMultipointLinks returns the RouterLinks which describe a
+// point-to-multipoint interface in a Router-LSA, as described in RFC5340,
+// section 4.4.3.2. neighbors contains the most recent Hello from each fully
+// adjacent neighbor on the interface, and each is described by a
+// PointToPointLink with the specified metric.
+func PointToMultipointLinks(interfaceID uint32, metric uint16, neighbors []*Hello) []RouterLink {
+	links := make([]RouterLink, 0, len(neighbors))
+	for _, h := range neighbors {
+		links = append(links, RouterLink{
+			Type:                PointToPointLink,
+			Metric:              metric,
+			InterfaceID:         interfaceID,
+			NeighborInterfaceID: h.InterfaceID,
+			NeighborRouterID:    h.Header.RouterID,
+		})
+	}
+
+	return links
+}
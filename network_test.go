@@ -0,0 +1,55 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNetworkTypeElectsDR(t *testing.T) {
+	tests := []struct {
+		t  NetworkType
+		ok bool
+	}{
+		{t: BroadcastNetwork, ok: true},
+		{t: PointToPointNetwork},
+		{t: NBMANetwork, ok: true},
+		{t: PointToMultipointNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.t.String(), func(t *testing.T) {
+			if diff := cmp.Diff(tt.ok, tt.t.ElectsDR()); diff != "" {
+				t.Fatalf("unexpected ElectsDR (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPointToMultipointLinks(t *testing.T) {
+	neighbors := []*Hello{
+		{Header: Header{RouterID: ID{192, 0, 2, 2}}, InterfaceID: 2},
+		{Header: Header{RouterID: ID{192, 0, 2, 3}}, InterfaceID: 3},
+	}
+
+	want := []RouterLink{
+		{
+			Type:                PointToPointLink,
+			Metric:              10,
+			InterfaceID:         1,
+			NeighborInterfaceID: 2,
+			NeighborRouterID:    ID{192, 0, 2, 2},
+		},
+		{
+			Type:                PointToPointLink,
+			Metric:              10,
+			InterfaceID:         1,
+			NeighborInterfaceID: 3,
+			NeighborRouterID:    ID{192, 0, 2, 3},
+		},
+	}
+
+	if diff := cmp.Diff(want, PointToMultipointLinks(1, 10, neighbors)); diff != "" {
+		t.Fatalf("unexpected RouterLinks (-want +got):\n%s", diff)
+	}
+}
@@ -1,9 +1,12 @@
 package ospf3
 
 import (
+	"encoding"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"time"
 )
 
@@ -18,6 +21,7 @@ const (
 	lsaHeaderLen = 20
 	helloLen     = 20 // No trailing array of neighbor IDs.
 	ddLen        = 12 // No trailing array of LSA headers.
+	lsuLen       = 4  // No trailing array of LSAs.
 )
 
 // Sentinel errors used to differentiate various types of errors in tests.
@@ -29,6 +33,24 @@ var (
 // A packetType is the type of an OSPFv3 packet.
 type packetType uint8
 
+// String returns the name of the Packet type which implements a packetType.
+func (t packetType) String() string {
+	switch t {
+	case hello:
+		return "Hello"
+	case databaseDescription:
+		return "DatabaseDescription"
+	case linkStateRequest:
+		return "LinkStateRequest"
+	case linkStateUpdate:
+		return "LinkStateUpdate"
+	case linkStateAcknowledgement:
+		return "LinkStateAcknowledgement"
+	default:
+		return fmt.Sprintf("packetType(%d)", uint8(t))
+	}
+}
+
 // Possible OSPFv3 packet types.
 const (
 	hello                    packetType = 1
@@ -136,8 +158,6 @@ func parseHeader(b []byte) (Header, packetType, int, error) {
 	copy(h.RouterID[:], b[4:8])
 	copy(h.AreaID[:], b[8:12])
 
-	// TODO(mdlayher): inspect Checksum?
-
 	// Make sure the input buffer has enough data as indicated by the packet
 	// length field so we know how much to pass to Packet.unmarshal.
 	plen := int(binary.BigEndian.Uint16(b[2:4]))
@@ -154,58 +174,300 @@ func parseHeader(b []byte) (Header, packetType, int, error) {
 
 // A Packet is an OSPFv3 packet.
 type Packet interface {
+	// Validate verifies that the Packet's fields satisfy the semantic
+	// constraints of the OSPFv3 protocol, such as a RouterDeadInterval
+	// greater than the HelloInterval. It does not check wire format
+	// constraints, which are enforced when marshaling.
+	Validate() error
+
 	len() int
 	marshal(b []byte) error
 	unmarshal(b []byte) error
 }
 
+// Compile-time encoding interface checks. Each Packet marshals to and
+// unmarshals from complete OSPFv3 packet bytes, including its Header.
+var (
+	_ encoding.BinaryMarshaler   = &Hello{}
+	_ encoding.BinaryUnmarshaler = &Hello{}
+	_ encoding.BinaryMarshaler   = &DatabaseDescription{}
+	_ encoding.BinaryUnmarshaler = &DatabaseDescription{}
+	_ encoding.BinaryMarshaler   = &LinkStateRequest{}
+	_ encoding.BinaryUnmarshaler = &LinkStateRequest{}
+	_ encoding.BinaryMarshaler   = &LinkStateUpdate{}
+	_ encoding.BinaryUnmarshaler = &LinkStateUpdate{}
+	_ encoding.BinaryMarshaler   = &LinkStateAcknowledgement{}
+	_ encoding.BinaryUnmarshaler = &LinkStateAcknowledgement{}
+)
+
 // MarshalPacket turns a Packet into OSPFv3 packet bytes.
 func MarshalPacket(p Packet) ([]byte, error) {
+	return AppendPacket(nil, p)
+}
+
+// AppendPacket appends the OSPFv3 packet bytes of a Packet to dst and returns
+// the extended buffer. It allocates only if dst lacks the capacity for the
+// Packet, so callers can reuse a buffer across many calls. On error, dst is
+// returned unmodified.
+func AppendPacket(dst []byte, p Packet) ([]byte, error) {
 	if p == nil {
-		return nil, fmt.Errorf("ospf3: cannot marshal nil Packet: %w", errMarshal)
+		return dst, fmt.Errorf("ospf3: cannot marshal nil Packet: %w", errMarshal)
 	}
 
 	// Allocate enough space for the fixed length Header and then the
-	// appropriate number of bytes for the trailing packet.
-	b := make([]byte, p.len())
-	if err := p.marshal(b); err != nil {
-		return nil, fmt.Errorf("ospf3: failed to marshal Packet: %w", err)
+	// appropriate number of bytes for the trailing packet and LLS data block,
+	// which is not counted in the packet length.
+	lls := packetLLS(p)
+	n := p.len()
+	b, out := grow(dst, n+lls.len())
+	if err := p.marshal(b[:n]); err != nil {
+		return dst, fmt.Errorf("ospf3: failed to marshal Packet: %w", err)
+	}
+
+	if lls != nil {
+		if err := lls.marshal(b[n:]); err != nil {
+			return dst, fmt.Errorf("ospf3: failed to marshal Packet: %w", err)
+		}
 	}
 
-	return b, nil
+	return out, nil
 }
 
-// ParsePacket parses an OSPFv3 Header and trailing Packet from bytes.
-func ParsePacket(b []byte) (Packet, error) {
-	// The Header is added to each Packet and the parsed type and length are
-	// used to choose the appropriate Packet and its end offset.
-	h, ptyp, plen, err := parseHeader(b)
+// grow extends dst by n zeroed bytes, returning those n bytes and the extended
+// buffer. Zeroing is required because marshal methods skip reserved fields.
+func grow(dst []byte, n int) ([]byte, []byte) {
+	off := len(dst)
+	if cap(dst)-off < n {
+		out := make([]byte, off+n)
+		copy(out, dst)
+		return out[off:], out
+	}
+
+	out := dst[:off+n]
+	b := out[off:]
+	for i := range b {
+		b[i] = 0
+	}
+
+	return b, out
+}
+
+// MarshalOptions contains optional parameters which modify the behavior of
+// packet marshaling. The zero value of MarshalOptions is equivalent to the
+// behavior of the MarshalPacket function.
+type MarshalOptions struct {
+	// Source and Destination, if both set, are the IPv6 source and
+	// destination addresses used to compute the OSPFv3 packet checksum. The
+	// checksum is stored in the marshaled packet bytes in place of the
+	// Header's Checksum field, which is not modified.
+	Source, Destination net.IP
+
+	// Authenticator, if set, appends an Authentication Trailer for the
+	// security association SAID with the cryptographic sequence number
+	// SequenceNumber, as described in RFC7166. Source must also be set.
+	// Hello and DatabaseDescription packets must set ATBit in their Options.
+	// The authentication data is computed after the packet checksum.
+	Authenticator  Authenticator
+	SAID           uint16
+	SequenceNumber uint64
+}
+
+// MarshalPacket turns a Packet into OSPFv3 packet bytes using the
+// MarshalOptions.
+func (o MarshalOptions) MarshalPacket(p Packet) ([]byte, error) {
+	return o.AppendPacket(nil, p)
+}
+
+// AppendPacket appends the OSPFv3 packet bytes of a Packet to dst using the
+// MarshalOptions, as described by the AppendPacket function.
+func (o MarshalOptions) AppendPacket(dst []byte, p Packet) ([]byte, error) {
+	out, err := AppendPacket(dst, p)
 	if err != nil {
-		return nil, fmt.Errorf("ospf3: failed to parse Header: %w", err)
+		return dst, err
 	}
 
-	// Now that we've decoded the Header we can identify the rest of the
-	// payload as a known Packet type.
-	var p Packet
+	// Checksums and authentication apply only to the newly appended packet.
+	off := len(dst)
+	b := out[off:]
+	if o.Source != nil && o.Destination != nil {
+		c, err := PacketChecksum(b, o.Source, o.Destination)
+		if err != nil {
+			return dst, err
+		}
+		binary.BigEndian.PutUint16(b[12:14], c)
+	}
+
+	if o.Authenticator != nil {
+		b, err = appendAuthTrailer(b, o.Authenticator, o.SAID, o.SequenceNumber, o.Source)
+		if err != nil {
+			return dst, err
+		}
+
+		// Reattach dst in case the trailer required a new allocation.
+		return append(out[:off], b...), nil
+	}
+
+	return out, nil
+}
+
+// ParsePacket parses an OSPFv3 Header and trailing Packet from bytes. If the
+// bytes are malformed, the error is a *ParseError which reports where parsing
+// failed.
+func ParsePacket(b []byte) (Packet, error) {
+	return ParseOptions{}.ParsePacket(b)
+}
+
+// ParseOptions contains optional parameters which modify the behavior of
+// packet parsing. The zero value of ParseOptions is equivalent to the behavior
+// of the ParsePacket function.
+type ParseOptions struct {
+	// VerifyLSAChecksums enables verification of the Fletcher checksum of
+	// each LSA carried in a LinkStateUpdate. If any LSA has an invalid
+	// checksum, parsing fails.
+	VerifyLSAChecksums bool
+
+	// Source and Destination, if both set, are the IPv6 source and
+	// destination addresses used to verify the OSPFv3 packet checksum. If
+	// the checksum is invalid, parsing fails.
+	Source, Destination net.IP
+
+	// Strict enables rejection of packets which contain nonzero values in
+	// reserved fields or prefix padding, such as for conformance testing of
+	// other implementations. If any are found, parsing fails with a
+	// *ReservedFieldError.
+	//
+	// Strict also enables rejection of bytes which are not accounted for by
+	// a packet's length fields, such as trailing data following the packet
+	// other than an LLS data block and an Authentication Trailer. If any are
+	// found, parsing fails with a *LengthError. By default such bytes are
+	// ignored, as is appropriate for passive capture.
+	//
+	// By default, an LSA body of a known or registered LSType which cannot be
+	// parsed is kept as a *RawLSABody so that the other LSAs in the packet
+	// are not lost. Strict instead rejects the packet with a *ParseError.
+	Strict bool
+
+	// MaxLSAs and MaxLSALength, if set, limit the number of LSAs or LSA
+	// headers carried by a packet and the length in bytes of each LSA
+	// carried by a LinkStateUpdate, including its LSAHeader. MaxBodyLength,
+	// if set, limits the length in bytes of a packet following its Header,
+	// as specified by its packet length field. They are enforced before any
+	// LSAs are parsed. If a limit is exceeded, parsing fails with a
+	// *LimitError.
+	//
+	// The number and total size of the TLVs parsed from an LSA body are
+	// bounded by its length. TLV nesting depth is not configurable: each
+	// TLV format parsed by this package has a fixed structure at most two
+	// levels deep, and values are never parsed recursively based on their
+	// contents.
+	MaxLSAs, MaxLSALength, MaxBodyLength int
+
+	// Authenticator, if set, requires an Authentication Trailer following
+	// the packet and verifies its authentication data as described in
+	// RFC7166. Source must also be set. Hello and DatabaseDescription
+	// packets must set ATBit in their Options. Use ParseAuthTrailer to
+	// inspect the trailer's cryptographic sequence number.
+	Authenticator Authenticator
+}
+
+// ParsePacket parses an OSPFv3 Header and trailing Packet from bytes using the
+// ParseOptions.
+func (o ParseOptions) ParsePacket(b []byte) (Packet, error) {
+	return o.parsePacket(b, newPacket)
+}
+
+// newPacket allocates a Packet of type ptyp with Header h, or returns nil if
+// ptyp is not a known packet type.
+func newPacket(ptyp packetType, h Header) Packet {
 	switch ptyp {
 	case hello:
-		p = &Hello{Header: h}
+		return &Hello{Header: h}
 	case databaseDescription:
-		p = &DatabaseDescription{Header: h}
+		return &DatabaseDescription{Header: h}
 	case linkStateRequest:
-		p = &LinkStateRequest{Header: h}
+		return &LinkStateRequest{Header: h}
+	case linkStateUpdate:
+		return &LinkStateUpdate{Header: h}
 	case linkStateAcknowledgement:
-		p = &LinkStateAcknowledgement{Header: h}
+		return &LinkStateAcknowledgement{Header: h}
 	default:
+		return nil
+	}
+}
+
+// parsePacket implements ParsePacket, using newP to obtain the Packet which
+// the payload is parsed into.
+func (o ParseOptions) parsePacket(b []byte, newP func(ptyp packetType, h Header) Packet) (Packet, error) {
+	// The Header is added to each Packet and the parsed type and length are
+	// used to choose the appropriate Packet and its end offset.
+	h, ptyp, plen, err := parseHeader(b)
+	if err != nil {
+		return nil, parseError(0, "Header", err)
+	}
+
+	if o.Source != nil && o.Destination != nil {
+		if err := verifyPacketChecksum(b, o.Source, o.Destination, plen); err != nil {
+			return nil, fmt.Errorf("ospf3: failed to verify Packet: %w", err)
+		}
+	}
+
+	if err := o.checkLimits(ptyp, b[headerLen:plen]); err != nil {
+		return nil, err
+	}
+
+	if o.Authenticator != nil {
+		if err := verifyAuthTrailer(b, o.Authenticator, o.Source); err != nil {
+			return nil, fmt.Errorf("ospf3: failed to verify Packet: %w", err)
+		}
+	}
+
+	// Now that we've decoded the Header we can identify the rest of the
+	// payload as a known Packet type.
+	p := newP(ptyp, h)
+	if p == nil {
 		// TODO(mdlayher): implement more Packets!
-		return nil, fmt.Errorf("ospf3: parsing not implemented packet type: %d", ptyp)
+		return nil, parseError(1, "Header packet type",
+			fmt.Errorf("parsing not implemented packet type: %d: %w", ptyp, errParse))
 	}
 
 	// The unmarshal methods assume the header has already been processed so
 	// just pass the rest of the payload up to the max defined by
 	// Header.PacketLength.
 	if err := p.unmarshal(b[headerLen:plen]); err != nil {
-		return nil, fmt.Errorf("ospf3: failed to parse Packet: %w", err)
+		return nil, parseError(headerLen, ptyp.String(), err)
+	}
+
+	// Checksums are computed over the original LSA bytes because marshaling
+	// a parsed LSA may not reproduce any reserved bits.
+	if lsu, ok := p.(*LinkStateUpdate); ok && o.VerifyLSAChecksums {
+		if err := verifyLSAChecksums(lsu, b[headerLen+lsuLen:plen]); err != nil {
+			return nil, fmt.Errorf("ospf3: failed to verify Packet: %w", err)
+		}
+	}
+
+	if o.Strict {
+		if err := checkLSABodies(p); err != nil {
+			return nil, err
+		}
+		if err := checkReserved(p, b[:plen]); err != nil {
+			return nil, err
+		}
+		if err := checkLengths(p, b, plen); err != nil {
+			return nil, err
+		}
+	}
+
+	// An LLS data block may follow a Hello or DatabaseDescription packet
+	// which sets the L-bit.
+	switch p := p.(type) {
+	case *Hello:
+		if p.Options&LBit != 0 {
+			p.LLS = parseLLS(b[plen:])
+		}
+	case *DatabaseDescription:
+		if p.Options&LBit != 0 {
+			p.LLS = parseLLS(b[plen:])
+		}
 	}
 
 	return p, nil
@@ -224,6 +486,11 @@ type Hello struct {
 	DesignatedRouterID       ID
 	BackupDesignatedRouterID ID
 	NeighborIDs              []ID
+
+	// LLS, if set, is an LLS data block which follows the Hello. LBit must
+	// be set in Options to marshal an LLS. When parsing, LLS is nil if
+	// LBit is not set or the LLS is malformed.
+	LLS *LLS
 }
 
 // len implements Packet.
@@ -237,6 +504,9 @@ func (h *Hello) marshal(b []byte) error {
 	if !h.Options.valid() {
 		return fmt.Errorf("Hello Options bitmask is not valid: %w", errMarshal)
 	}
+	if h.LLS != nil && h.Options&LBit == 0 {
+		return fmt.Errorf("Hello with LLS must set the L-bit in Options: %w", errMarshal)
+	}
 
 	// Marshal the Header and then store the Hello bytes following it.
 	const n = headerLen
@@ -282,8 +552,13 @@ func (h *Hello) unmarshal(b []byte) error {
 	copy(h.BackupDesignatedRouterID[:], b[16:20])
 
 	// Allocate enough space for each trailing neighbor ID after the fixed
-	// length Hello and parse each one.
-	h.NeighborIDs = make([]ID, 0, len(b[helloLen:])/4)
+	// length Hello, unless a Decoder left enough capacity from a previous
+	// Hello, and parse each one.
+	if n := len(b[helloLen:]) / 4; h.NeighborIDs == nil || cap(h.NeighborIDs) < n {
+		h.NeighborIDs = make([]ID, 0, n)
+	} else {
+		h.NeighborIDs = h.NeighborIDs[:0]
+	}
 	for i := helloLen; i < len(b); i += 4 {
 		var id ID
 		copy(id[:], b[i:i+4])
@@ -293,6 +568,22 @@ func (h *Hello) unmarshal(b []byte) error {
 	return nil
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler and is equivalent to
+// calling MarshalPacket.
+func (h *Hello) MarshalBinary() ([]byte, error) { return MarshalPacket(h) }
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. b must contain
+// a Hello packet, including its Header, as accepted by ParsePacket.
+func (h *Hello) UnmarshalBinary(b []byte) error {
+	p, err := parsePacketType(b, hello)
+	if err != nil {
+		return err
+	}
+
+	*h = *p.(*Hello)
+	return nil
+}
+
 // DDFlags are flags which may appear in an OSPFv3 Database Description packet
 // as described in RFC5340, appendix A.3.3.
 type DDFlags uint16
@@ -313,6 +604,22 @@ func (f DDFlags) String() string {
 	})
 }
 
+// Valid reports whether f is a possible combination of DDFlags. The flags must
+// fit in the 8-bit field of a DatabaseDescription, and the I-bit, which marks
+// the first DatabaseDescription of an exchange, must be accompanied by the
+// M-bit and MS-bit as described in RFC2328, section 10.8.
+func (f DDFlags) Valid() bool {
+	if f > 0xff {
+		return false
+	}
+
+	if f&IBit != 0 {
+		return f&(MBit|MSBit) == MBit|MSBit
+	}
+
+	return true
+}
+
 var _ Packet = &DatabaseDescription{}
 
 // A DatabaseDescription is an OSPFv3 Database Description packet as described
@@ -324,6 +631,11 @@ type DatabaseDescription struct {
 	Flags          DDFlags
 	SequenceNumber uint32
 	LSAs           []LSAHeader
+
+	// LLS, if set, is an LLS data block which follows the
+	// DatabaseDescription. LBit must be set in Options to marshal an LLS.
+	// When parsing, LLS is nil if LBit is not set or the LLS is malformed.
+	LLS *LLS
 }
 
 // len implements Packet.
@@ -337,6 +649,12 @@ func (dd *DatabaseDescription) marshal(b []byte) error {
 	if !dd.Options.valid() {
 		return fmt.Errorf("Hello Options bitmask is not valid: %w", errMarshal)
 	}
+	if !dd.Flags.Valid() {
+		return fmt.Errorf("DatabaseDescription flags %s are not valid: %w", dd.Flags, errMarshal)
+	}
+	if dd.LLS != nil && dd.Options&LBit == 0 {
+		return fmt.Errorf("DatabaseDescription with LLS must set the L-bit in Options: %w", errMarshal)
+	}
 
 	// Marshal the Header and then store the Database Description bytes following it.
 	const n = headerLen
@@ -381,7 +699,11 @@ func (dd *DatabaseDescription) unmarshal(b []byte) error {
 
 	// We now know the number of LSA headers because they have a fixed size.
 	n := len(b[lsaOff:]) / lsaHeaderLen
-	dd.LSAs = make([]LSAHeader, 0, n)
+	if dd.LSAs == nil || cap(dd.LSAs) < n {
+		dd.LSAs = make([]LSAHeader, 0, n)
+	} else {
+		dd.LSAs = dd.LSAs[:0]
+	}
 	for i := 0; i < n; i++ {
 		// Parse each 20 byte LSA header from the slice.
 		var (
@@ -395,6 +717,22 @@ func (dd *DatabaseDescription) unmarshal(b []byte) error {
 	return nil
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler and is equivalent to
+// calling MarshalPacket.
+func (dd *DatabaseDescription) MarshalBinary() ([]byte, error) { return MarshalPacket(dd) }
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. b must contain
+// a DatabaseDescription packet, including its Header, as accepted by ParsePacket.
+func (dd *DatabaseDescription) UnmarshalBinary(b []byte) error {
+	p, err := parsePacketType(b, databaseDescription)
+	if err != nil {
+		return err
+	}
+
+	*dd = *p.(*DatabaseDescription)
+	return nil
+}
+
 var _ Packet = &LinkStateRequest{}
 
 // A LinkStateRequest is an OSPFv3 Link State Request packet as described
@@ -438,7 +776,11 @@ func (lsr *LinkStateRequest) unmarshal(b []byte) error {
 
 	// We now know the number of LSAs because they have a fixed size.
 	n := len(b) / lsaLen
-	lsr.LSAs = make([]LSA, 0, n)
+	if lsr.LSAs == nil || cap(lsr.LSAs) < n {
+		lsr.LSAs = make([]LSA, 0, n)
+	} else {
+		lsr.LSAs = lsr.LSAs[:0]
+	}
 	for i := 0; i < n; i++ {
 		// Parse each 12 byte LSA from the slice. Note that the first two bytes
 		// are reserved so start parsing LSA.Type at 2 bytes.
@@ -453,6 +795,118 @@ func (lsr *LinkStateRequest) unmarshal(b []byte) error {
 	return nil
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler and is equivalent to
+// calling MarshalPacket.
+func (lsr *LinkStateRequest) MarshalBinary() ([]byte, error) { return MarshalPacket(lsr) }
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. b must contain
+// a LinkStateRequest packet, including its Header, as accepted by ParsePacket.
+func (lsr *LinkStateRequest) UnmarshalBinary(b []byte) error {
+	p, err := parsePacketType(b, linkStateRequest)
+	if err != nil {
+		return err
+	}
+
+	*lsr = *p.(*LinkStateRequest)
+	return nil
+}
+
+var _ Packet = &LinkStateUpdate{}
+
+// A LinkStateUpdate is an OSPFv3 Link State Update packet as described in
+// RFC5340, appendix A.3.5.
+type LinkStateUpdate struct {
+	Header Header
+	LSAs   []LinkStateAdvertisement
+}
+
+// len implements Packet.
+func (lsu *LinkStateUpdate) len() int {
+	// Fixed Header and LinkStateUpdate, plus the variable length of each LSA.
+	n := headerLen + lsuLen
+	for i := range lsu.LSAs {
+		n += lsu.LSAs[i].len()
+	}
+
+	return n
+}
+
+// marshal implements Packet.
+func (lsu *LinkStateUpdate) marshal(b []byte) error {
+	// The packet length must fit in the Header's 16-bit length field.
+	if l := lsu.len(); l > math.MaxUint16 {
+		return fmt.Errorf("LinkStateUpdate is too long: %d bytes: %w", l, errMarshal)
+	}
+
+	// Marshal the Header and then store the LSA count and LSA bytes following
+	// it.
+	const n = headerLen
+	lsu.Header.marshal(b[:n], linkStateUpdate, uint16(lsu.len()))
+
+	binary.BigEndian.PutUint32(b[n:n+4], uint32(len(lsu.LSAs)))
+
+	// Each LSA is packed into adjacent bytes according to its own length.
+	nn := n + lsuLen
+	for i := range lsu.LSAs {
+		l := lsu.LSAs[i].len()
+		if err := lsu.LSAs[i].marshal(b[nn : nn+l]); err != nil {
+			return err
+		}
+		nn += l
+	}
+
+	return nil
+}
+
+// unmarshal implements Packet.
+func (lsu *LinkStateUpdate) unmarshal(b []byte) error {
+	if l := len(b); l < lsuLen {
+		return fmt.Errorf("not enough bytes for LinkStateUpdate: %d: %w", l, errParse)
+	}
+
+	// Sanity check the number of LSAs against the remaining bytes before
+	// allocating so a bogus count cannot trigger a huge allocation. Each LSA is
+	// at least as long as its LSAHeader.
+	n := binary.BigEndian.Uint32(b[0:4])
+	if l := len(b[lsuLen:]); uint64(n)*lsaHeaderLen > uint64(l) {
+		return fmt.Errorf("LinkStateUpdate indicates %d LSAs but only %d bytes are available: %w", n, l, errParse)
+	}
+
+	// Each LSA's length is determined by its LSAHeader, so walk the trailing
+	// bytes one LSA at a time.
+	if lsu.LSAs == nil || uint64(cap(lsu.LSAs)) < uint64(n) {
+		lsu.LSAs = make([]LinkStateAdvertisement, n)
+	} else {
+		lsu.LSAs = lsu.LSAs[:n]
+	}
+	off := lsuLen
+	for i := range lsu.LSAs {
+		l, err := lsu.LSAs[i].unmarshal(b[off:])
+		if err != nil {
+			return parseError(off, fmt.Sprintf("LSA %d", i), err)
+		}
+		off += l
+	}
+
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler and is equivalent to
+// calling MarshalPacket.
+func (lsu *LinkStateUpdate) MarshalBinary() ([]byte, error) { return MarshalPacket(lsu) }
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. b must contain
+// a LinkStateUpdate packet, including its Header, as accepted by ParsePacket.
+func (lsu *LinkStateUpdate) UnmarshalBinary(b []byte) error {
+	p, err := parsePacketType(b, linkStateUpdate)
+	if err != nil {
+		return err
+	}
+
+	*lsu = *p.(*LinkStateUpdate)
+	return nil
+}
+
 var _ Packet = &LinkStateAcknowledgement{}
 
 // A LinkStateAcknowledgement is an OSPFv3 Link State Acknowledgement packet as
@@ -495,7 +949,11 @@ func (lsa *LinkStateAcknowledgement) unmarshal(b []byte) error {
 
 	// We now know the number of LSA headers because they have a fixed size.
 	n := len(b) / lsaHeaderLen
-	lsa.LSAs = make([]LSAHeader, 0, n)
+	if lsa.LSAs == nil || cap(lsa.LSAs) < n {
+		lsa.LSAs = make([]LSAHeader, 0, n)
+	} else {
+		lsa.LSAs = lsa.LSAs[:0]
+	}
 	for i := 0; i < n; i++ {
 		// Parse each 20 byte LSA header from the slice.
 		var (
@@ -509,6 +967,22 @@ func (lsa *LinkStateAcknowledgement) unmarshal(b []byte) error {
 	return nil
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler and is equivalent to
+// calling MarshalPacket.
+func (lsa *LinkStateAcknowledgement) MarshalBinary() ([]byte, error) { return MarshalPacket(lsa) }
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. b must contain
+// a LinkStateAcknowledgement packet, including its Header, as accepted by ParsePacket.
+func (lsa *LinkStateAcknowledgement) UnmarshalBinary(b []byte) error {
+	p, err := parsePacketType(b, linkStateAcknowledgement)
+	if err != nil {
+		return err
+	}
+
+	*lsa = *p.(*LinkStateAcknowledgement)
+	return nil
+}
+
 // An LSType is the type of an OSPFv3 Link State Advertisement as described in
 // RFC5340, appendix A.4.2.1.
 type LSType uint16
@@ -524,8 +998,33 @@ const (
 	NSSALSA            LSType = 0x2007
 	LinkLSA            LSType = 0x0008
 	IntraAreaPrefixLSA LSType = 0x2009
+
+	// IntraAreaTELSA is the Intra-Area-TE-LSA described in RFC5329, section
+	// 3.
+	IntraAreaTELSA LSType = 0xa00a
+
+	// SRv6 Locator LSA types for each flooding scope, as described in
+	// RFC9513, section 7.
+	AreaSRv6LocatorLSA LSType = 0xa02a
+	ASSRv6LocatorLSA   LSType = 0xc02a
+
+	// Router Information LSA types for each flooding scope, as described in
+	// RFC7770, section 2.2.
+	LinkRouterInformationLSA LSType = 0x800c
+	AreaRouterInformationLSA LSType = 0xa00c
+	ASRouterInformationLSA   LSType = 0xc00c
 )
 
+// String returns the string representation of an LSType, including the names
+// of any LSTypes added by RegisterLSAType.
+func (t LSType) String() string {
+	if info, ok := LookupLSType(t); ok {
+		return info.Name
+	}
+
+	return fmt.Sprintf("LSType(%d)", uint16(t))
+}
+
 // LSAHandling returns the value of the U-bit in the LSType. False indicates the
 // LSA should be treated as if it had link-local flooding scope. True indicates
 // that a router should store and flood the LSA as if the type is understood.
@@ -606,6 +1105,137 @@ func parseLSAHeader(b []byte) LSAHeader {
 	}
 }
 
+// Compare determines which of two instances of the same LSA is more recent, as
+// described in RFC2328, section 13.1. Compare returns a positive number if h is
+// more recent than o, a negative number if o is more recent than h, or 0 if
+// they are considered to be the same instance.
+//
+// Compare only inspects the sequence number, checksum, and age fields and
+// assumes that h and o describe the same LSA.
+func (h LSAHeader) Compare(o LSAHeader) int {
+	// Sequence numbers are signed and increase linearly, per section 12.1.6.
+	if a, b := int32(h.SequenceNumber), int32(o.SequenceNumber); a != b {
+		return cmpBool(a > b)
+	}
+
+	if h.Checksum != o.Checksum {
+		return cmpBool(h.Checksum > o.Checksum)
+	}
+
+	// An instance which has reached MaxAge is being flushed and is considered
+	// more recent. The DoNotAge bit is ignored when comparing ages, per
+	// RFC1793, section 2.2.
+	hAge, oAge := lsAge(h.Age), lsAge(o.Age)
+	if ha, oa := hAge >= MaxAge, oAge >= MaxAge; ha != oa {
+		return cmpBool(ha)
+	}
+
+	// Ages which differ significantly indicate a more recent instance with
+	// the smaller age. Otherwise the instances are identical.
+	d := hAge - oAge
+	if d > MaxAgeDiff || d < -MaxAgeDiff {
+		return cmpBool(hAge < oAge)
+	}
+
+	return 0
+}
+
+// Newer reports whether h is a more recent instance of an LSA than o, as
+// determined by Compare.
+func (h LSAHeader) Newer(o LSAHeader) bool {
+	return h.Compare(o) > 0
+}
+
+// cmpBool returns 1 if b is true, or -1 otherwise.
+func cmpBool(b bool) int {
+	if b {
+		return 1
+	}
+
+	return -1
+}
+
+// A LinkStateAdvertisement is a complete OSPFv3 Link State Advertisement,
+// consisting of an LSAHeader and the LSABody which follows it, as carried in
+// Link State Update packets. The LSAHeader.Length field is computed
+// automatically when marshaling.
+type LinkStateAdvertisement struct {
+	Header LSAHeader
+	Body   LSABody
+}
+
+// len returns the length of the LSAHeader and body.
+func (l *LinkStateAdvertisement) len() int {
+	if l.Body == nil {
+		return lsaHeaderLen
+	}
+
+	return lsaHeaderLen + lsaBodyLen(l.Body)
+}
+
+// marshal stores the LinkStateAdvertisement bytes into b. It assumes b has
+// allocated enough space for the LinkStateAdvertisement to avoid a panic.
+func (l *LinkStateAdvertisement) marshal(b []byte) error {
+	if l.Body == nil {
+		return fmt.Errorf("LSA %s has no body: %w", l.Header.LSA.Type, errMarshal)
+	}
+
+	n := l.len()
+	if n > math.MaxUint16 {
+		return fmt.Errorf("LSA length %d is too large: %w", n, errMarshal)
+	}
+
+	h := l.Header
+	h.Length = uint16(n)
+	h.marshal(b[:lsaHeaderLen])
+
+	return marshalLSABody(l.Body, b[lsaHeaderLen:n])
+}
+
+// unmarshal unpacks a LinkStateAdvertisement from the beginning of b and
+// returns the number of bytes consumed.
+func (l *LinkStateAdvertisement) unmarshal(b []byte) (int, error) {
+	if n := len(b); n < lsaHeaderLen {
+		return 0, fmt.Errorf("not enough bytes for LSA header: %d: %w", n, errParse)
+	}
+
+	h := parseLSAHeader(b[:lsaHeaderLen])
+	n := int(h.Length)
+	if n < lsaHeaderLen {
+		return 0, fmt.Errorf("LSA length %d is too short for a valid LSA: %w", n, errParse)
+	}
+	if l := len(b); l < n {
+		return 0, fmt.Errorf("LSA length is %d bytes but only %d bytes are available: %w", n, l, errParse)
+	}
+
+	// Decode the body according to the LSA's type. A malformed body is kept
+	// verbatim rather than failing the entire packet, so that a single bad
+	// LSA cannot prevent the others from being flooded. Strict parsing
+	// rejects such bodies in checkLSABodies.
+	body, err := ParseLSABody(h.LSA.Type, b[lsaHeaderLen:n])
+	if err != nil {
+		raw := new(RawLSABody)
+		_ = raw.unmarshal(b[lsaHeaderLen:n])
+		body = raw
+	}
+
+	l.Header = h
+	l.Body = body
+
+	return n, nil
+}
+
+// parsePacketType parses b with ParsePacket, but first verifies that it
+// contains a packet of type want.
+func parsePacketType(b []byte, want packetType) (Packet, error) {
+	if len(b) >= 2 && packetType(b[1]) != want {
+		return nil, parseError(1, "Header packet type",
+			fmt.Errorf("expected %s, but got %s: %w", want, packetType(b[1]), errParse))
+	}
+
+	return ParsePacket(b)
+}
+
 // uint16Seconds interprets big endian uint16 bytes as a number of seconds.
 func uint16Seconds(b []byte) time.Duration {
 	return time.Duration(binary.BigEndian.Uint16(b)) * time.Second
@@ -618,12 +1248,12 @@ func putUint16Seconds(b []byte, d time.Duration) {
 }
 
 // flagsString generates a pretty-printed flags bitmask using the input value
-// and sequence of names.
+// and sequence of names. Bits with an empty name are treated as unknown.
 func flagsString(f uint, names []string) string {
 	var s string
 	left := f
 	for i, name := range names {
-		if f&(1<<uint(i)) != 0 {
+		if name != "" && f&(1<<uint(i)) != 0 {
 			if s != "" {
 				s += "|"
 			}
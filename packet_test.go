@@ -2,6 +2,10 @@ package ospf3
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"errors"
+	"net"
 	"testing"
 	"time"
 
@@ -111,9 +115,9 @@ var (
 		[]byte{
 			0x00, 0x00, byte(AFBit - 255), byte(V6Bit) | byte(EBit) | byte(RBit), // Options
 			0x05, 0xdc, // Interface MTU
-			0x00,                    // Reserved
-			byte(IBit) | byte(MBit), // Flags
-			0x00, 0x00, 0x00, 0x01,  // Sequence number
+			0x00,                     // Reserved
+			byte(MBit) | byte(MSBit), // Flags
+			0x00, 0x00, 0x00, 0x01,   // Sequence number
 		},
 		// LSA headers
 		bufRouterLSAHeader,
@@ -129,7 +133,7 @@ var (
 		},
 		Options:        V6Bit | EBit | RBit | AFBit,
 		InterfaceMTU:   1500,
-		Flags:          IBit | MBit,
+		Flags:          MBit | MSBit,
 		SequenceNumber: 1,
 		LSAs: []LSAHeader{
 			{
@@ -189,6 +193,78 @@ var (
 		},
 	}
 
+	bufLinkStateUpdate = merge(
+		// Header
+		[]byte{
+			version,                // OSPFv3
+			uint8(linkStateUpdate), // Link State Update
+			0x00, 100,              // PacketLength
+		},
+		bufHeaderCommon,
+		// LinkStateUpdate
+		[]byte{
+			0x00, 0x00, 0x00, 0x02, // # LSAs
+		},
+		// LSAs
+		[]byte{
+			0x00, 0x01, // Age
+		},
+		bufRouterLSA,
+		[]byte{
+			0x00, 0x00, 0x00, 0xff, // Sequence number
+			0x00, 0x00, // Checksum
+			0x00, lsaHeaderLen + 36, // Length
+		},
+		bufRouterLSABody,
+		[]byte{
+			0x00, 0x02, // Age
+			0x2f, 0xff, // Type
+			0, 0, 0, 5, // Link state ID
+			192, 0, 2, 1, // Advertising router
+			0x00, 0x00, 0x01, 0xff, // Sequence number
+			0x00, 0x00, // Checksum
+			0x00, lsaHeaderLen + 4, // Length
+		},
+		// Unknown LSA body
+		[]byte{0xde, 0xad, 0xbe, 0xef},
+		// Ignored.
+		bufTrailing,
+	)
+
+	pktLinkStateUpdate = &LinkStateUpdate{
+		Header: Header{
+			RouterID:   ID{192, 0, 2, 1},
+			InstanceID: 1,
+		},
+		LSAs: []LinkStateAdvertisement{
+			{
+				Header: LSAHeader{
+					Age: 1 * time.Second,
+					LSA: LSA{
+						Type:              RouterLSA,
+						AdvertisingRouter: ID{192, 0, 2, 1},
+					},
+					SequenceNumber: 255,
+					Length:         56,
+				},
+				Body: lsaRouterLSABody,
+			},
+			{
+				Header: LSAHeader{
+					Age: 2 * time.Second,
+					LSA: LSA{
+						Type:              0x2fff,
+						LinkStateID:       ID{0, 0, 0, 5},
+						AdvertisingRouter: ID{192, 0, 2, 1},
+					},
+					SequenceNumber: 511,
+					Length:         24,
+				},
+				Body: &RawLSABody{Data: []byte{0xde, 0xad, 0xbe, 0xef}},
+			},
+		},
+	}
+
 	bufLinkStateAcknowledgement = merge(
 		// Header
 		[]byte{
@@ -383,6 +459,84 @@ func TestParsePacketErrors(t *testing.T) {
 				0xff, // Truncated LSA
 			},
 		},
+		{
+			name: "short link state update",
+			b: []byte{
+				version,
+				uint8(linkStateUpdate),
+				0x00, 17, // Header + 1 trailing byte
+				0x00, 0x00,
+				192, 0, 2, 1,
+				0, 0, 0, 0,
+				0x01,
+				0x00,
+
+				0xff, // Truncated Link State Update
+			},
+		},
+		{
+			name: "bad link state update LSA count",
+			b: []byte{
+				version,
+				uint8(linkStateUpdate),
+				0x00, 20, // Header + LSA count
+				0x00, 0x00,
+				192, 0, 2, 1,
+				0, 0, 0, 0,
+				0x01,
+				0x00,
+
+				0xff, 0xff, 0xff, 0xff, // # LSAs, no LSAs follow
+			},
+		},
+		{
+			name: "bad link state update LSA length",
+			b: merge(
+				[]byte{
+					version,
+					uint8(linkStateUpdate),
+					0x00, 40, // Header + LSA count + LSA header
+					0x00, 0x00,
+					192, 0, 2, 1,
+					0, 0, 0, 0,
+					0x01,
+					0x00,
+
+					0x00, 0x00, 0x00, 0x01, // # LSAs
+					0x00, 0x01, // Age
+				},
+				bufRouterLSA,
+				[]byte{
+					0x00, 0x00, 0x00, 0xff, // Sequence number
+					0x00, 0x00, // Checksum
+					0x00, 0xff, // Length, exceeds packet
+				},
+			),
+		},
+		{
+			name: "short link state update LSA length",
+			b: merge(
+				[]byte{
+					version,
+					uint8(linkStateUpdate),
+					0x00, 40, // Header + LSA count + LSA header
+					0x00, 0x00,
+					192, 0, 2, 1,
+					0, 0, 0, 0,
+					0x01,
+					0x00,
+
+					0x00, 0x00, 0x00, 0x01, // # LSAs
+					0x00, 0x01, // Age
+				},
+				bufRouterLSA,
+				[]byte{
+					0x00, 0x00, 0x00, 0xff, // Sequence number
+					0x00, 0x00, // Checksum
+					0x00, 0x01, // Length, shorter than LSA header
+				},
+			),
+		},
 		{
 			name: "bad link state acknowledgement LSAs",
 			b: []byte{
@@ -412,6 +566,44 @@ func TestParsePacketErrors(t *testing.T) {
 	}
 }
 
+func TestParsePacketMalformedLSABody(t *testing.T) {
+	// A Router-LSA with an empty body is malformed, but must not prevent the
+	// following Network-LSA from being parsed.
+	want := &LinkStateUpdate{
+		Header: Header{RouterID: ID{192, 0, 2, 1}},
+		LSAs: []LinkStateAdvertisement{
+			{
+				Header: LSAHeader{LSA: LSA{Type: RouterLSA}, Length: lsaHeaderLen},
+				Body:   &RawLSABody{},
+			},
+			{
+				Header: LSAHeader{LSA: LSA{Type: NetworkLSA}},
+				Body:   lsaNetworkLSABody,
+			},
+		},
+	}
+
+	b, err := MarshalPacket(want)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	got, err := ParsePacket(b)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	want.LSAs[1].Header.Length = uint16(want.LSAs[1].len())
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("unexpected LinkStateUpdate (-want +got):\n%s", diff)
+	}
+
+	_, err = ParseOptions{Strict: true}.ParsePacket(b)
+	if diff := cmp.Diff(errParse, err, cmpopts.EquateErrors()); diff != "" {
+		t.Fatalf("unexpected strict error (-want +got):\n%s", diff)
+	}
+}
+
 func TestMarshalPacketErrors(t *testing.T) {
 	tests := []struct {
 		name string
@@ -432,6 +624,41 @@ func TestMarshalPacketErrors(t *testing.T) {
 				Options: 0xf0000000 | V6Bit,
 			},
 		},
+		{
+			name: "DatabaseDescription I-bit without M-bit",
+			p: &DatabaseDescription{
+				Flags: IBit | MSBit,
+			},
+		},
+		{
+			name: "DatabaseDescription Flags overflow",
+			p: &DatabaseDescription{
+				Flags: 0x100 | MBit,
+			},
+		},
+		{
+			name: "LinkStateUpdate nil LSA body",
+			p: &LinkStateUpdate{
+				LSAs: []LinkStateAdvertisement{{}},
+			},
+		},
+		{
+			name: "LinkStateUpdate too long",
+			p: &LinkStateUpdate{
+				LSAs: []LinkStateAdvertisement{
+					{Body: &RawLSABody{Data: make([]byte, 40000)}},
+					{Body: &RawLSABody{Data: make([]byte, 40000)}},
+				},
+			},
+		},
+		{
+			name: "LinkStateUpdate LSA body",
+			p: &LinkStateUpdate{
+				LSAs: []LinkStateAdvertisement{{
+					Body: &RouterLSABody{Options: 0xf0000000 | V6Bit},
+				}},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -447,29 +674,42 @@ func TestMarshalPacketErrors(t *testing.T) {
 }
 
 var roundTripTests = []struct {
-	name string
-	b    []byte
-	p    Packet
+	name   string
+	b      []byte
+	p      Packet
+	allocs int
 }{
 	{
-		name: "hello",
-		b:    bufHello,
-		p:    pktHello,
+		name:   "hello",
+		b:      bufHello,
+		p:      pktHello,
+		allocs: 2,
 	},
 	{
-		name: "database description",
-		b:    bufDatabaseDescription,
-		p:    pktDatabaseDescription,
+		name:   "database description",
+		b:      bufDatabaseDescription,
+		p:      pktDatabaseDescription,
+		allocs: 2,
 	},
 	{
-		name: "link state request",
-		b:    bufLinkStateRequest,
-		p:    pktLinkStateRequest,
+		name:   "link state request",
+		b:      bufLinkStateRequest,
+		p:      pktLinkStateRequest,
+		allocs: 2,
 	},
 	{
-		name: "link state acknowledgement",
-		b:    bufLinkStateAcknowledgement,
-		p:    pktLinkStateAcknowledgement,
+		name: "link state update",
+		b:    bufLinkStateUpdate,
+		p:    pktLinkStateUpdate,
+		// Two additional allocations for each LSA body and its trailing
+		// data.
+		allocs: 6,
+	},
+	{
+		name:   "link state acknowledgement",
+		b:      bufLinkStateAcknowledgement,
+		p:      pktLinkStateAcknowledgement,
+		allocs: 2,
 	},
 }
 
@@ -506,6 +746,232 @@ func TestPacketRoundTrip(t *testing.T) {
 	}
 }
 
+func TestPacketBinaryMarshaler(t *testing.T) {
+	type binaryPacket interface {
+		Packet
+		encoding.BinaryMarshaler
+		encoding.BinaryUnmarshaler
+	}
+
+	tests := []struct {
+		name string
+		p    binaryPacket
+		new  func() binaryPacket
+	}{
+		{
+			name: "hello",
+			p:    pktHello,
+			new:  func() binaryPacket { return new(Hello) },
+		},
+		{
+			name: "database description",
+			p:    pktDatabaseDescription,
+			new:  func() binaryPacket { return new(DatabaseDescription) },
+		},
+		{
+			name: "link state request",
+			p:    pktLinkStateRequest,
+			new:  func() binaryPacket { return new(LinkStateRequest) },
+		},
+		{
+			name: "link state update",
+			p:    pktLinkStateUpdate,
+			new:  func() binaryPacket { return new(LinkStateUpdate) },
+		},
+		{
+			name: "link state acknowledgement",
+			p:    pktLinkStateAcknowledgement,
+			new:  func() binaryPacket { return new(LinkStateAcknowledgement) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.p.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			want, err := MarshalPacket(tt.p)
+			if err != nil {
+				t.Fatalf("failed to marshal packet: %v", err)
+			}
+
+			if diff := cmp.Diff(want, b); diff != "" {
+				t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+			}
+
+			p := tt.new()
+			if err := p.UnmarshalBinary(b); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.p, p); diff != "" {
+				t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+			}
+
+			// Any other packet type must be rejected without modifying p.
+			other := bufHello
+			if _, ok := p.(*Hello); ok {
+				other = bufLinkStateAcknowledgement
+			}
+
+			if err := p.UnmarshalBinary(other); !errors.Is(err, errParse) {
+				t.Fatalf("expected parse error, but got: %v", err)
+			}
+			if diff := cmp.Diff(tt.p, p); diff != "" {
+				t.Fatalf("unexpected modified Packet (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAppendPacket(t *testing.T) {
+	var (
+		src = net.ParseIP("fe80::1")
+		dst = net.ParseIP("ff02::5")
+		a   = HMACAuthenticator{1: {Hash: sha256.New, Key: []byte("ospf3")}}
+
+		// All packets must be valid for authentication.
+		packets = []Packet{
+			&Hello{
+				Options:     V6Bit | ATBit | LBit,
+				NeighborIDs: []ID{{192, 0, 2, 2}},
+				LLS:         &LLS{ExtendedOptions: LRBit},
+			},
+			pktLinkStateRequest,
+			pktLinkStateUpdate,
+			pktLinkStateAcknowledgement,
+		}
+	)
+
+	tests := []struct {
+		name string
+		o    MarshalOptions
+	}{
+		{name: "plain"},
+		{
+			name: "checksum",
+			o:    MarshalOptions{Source: src, Destination: dst},
+		},
+		{
+			name: "authentication",
+			o: MarshalOptions{
+				Source:        src,
+				Destination:   dst,
+				Authenticator: a,
+				SAID:          1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, p := range packets {
+				want, err := tt.o.MarshalPacket(p)
+				if err != nil {
+					t.Fatalf("failed to marshal %T: %v", p, err)
+				}
+
+				// Reuse a dirty buffer with spare capacity, then a buffer too
+				// small to hold the packet, and verify that the prefix is kept
+				// and the packet bytes match MarshalPacket.
+				prefix := []byte{0xff, 0xff}
+				for _, buf := range [][]byte{
+					append(prefix, bytes.Repeat([]byte{0xff}, 1024)...)[:len(prefix)],
+					append([]byte(nil), prefix...),
+				} {
+					b, err := tt.o.AppendPacket(buf, p)
+					if err != nil {
+						t.Fatalf("failed to append %T: %v", p, err)
+					}
+
+					if diff := cmp.Diff(prefix, b[:len(prefix)]); diff != "" {
+						t.Fatalf("unexpected %T prefix (-want +got):\n%s", p, diff)
+					}
+					if diff := cmp.Diff(want, b[len(prefix):]); diff != "" {
+						t.Fatalf("unexpected %T bytes (-want +got):\n%s", p, diff)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAppendPacketError(t *testing.T) {
+	dst := []byte{0xff}
+	b, err := AppendPacket(dst, nil)
+	if err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+
+	if diff := cmp.Diff(dst, b); diff != "" {
+		t.Fatalf("unexpected buffer (-want +got):\n%s", diff)
+	}
+}
+
+func TestLinkStateUpdateUnknownLSAs(t *testing.T) {
+	// Unknown LSAs of varying flooding scopes, U-bit settings, and odd body
+	// lengths must survive a round trip byte-for-byte.
+	b := merge(
+		[]byte{
+			version,                // OSPFv3
+			uint8(linkStateUpdate), // Link State Update
+			0x00, 87,               // PacketLength
+		},
+		bufHeaderCommon,
+		[]byte{
+			0x00, 0x00, 0x00, 0x03, // # LSAs
+			// Link-local scope, U-bit clear.
+			0x00, 0x01, // Age
+			0x00, 0x7f, // Type
+			0, 0, 0, 1, // Link state ID
+			192, 0, 2, 1, // Advertising router
+			0x80, 0x00, 0x00, 0x01, // Sequence number
+			0xab, 0xcd, // Checksum
+			0x00, lsaHeaderLen + 1, // Length
+			0x01, // Body
+			// Area scope, U-bit set.
+			0x00, 0x02, // Age
+			0xa0, 0x7f, // Type
+			0, 0, 0, 2, // Link state ID
+			192, 0, 2, 1, // Advertising router
+			0x80, 0x00, 0x00, 0x02, // Sequence number
+			0x12, 0x34, // Checksum
+			0x00, lsaHeaderLen + 6, // Length
+			0x01, 0x02, 0x03, 0x04, 0x05, 0x06, // Body
+			// AS scope, U-bit set, empty body.
+			0x0e, 0x10, // Age
+			0xc0, 0x7f, // Type
+			0, 0, 0, 3, // Link state ID
+			192, 0, 2, 1, // Advertising router
+			0x80, 0x00, 0x00, 0x03, // Sequence number
+			0x56, 0x78, // Checksum
+			0x00, lsaHeaderLen, // Length
+		},
+	)
+
+	p, err := ParsePacket(b)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	for i, l := range p.(*LinkStateUpdate).LSAs {
+		if _, ok := l.Body.(*RawLSABody); !ok {
+			t.Fatalf("LSA %d: expected *RawLSABody, but got: %T", i, l.Body)
+		}
+	}
+
+	out, err := MarshalPacket(p)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if diff := cmp.Diff(b, out); diff != "" {
+		t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+	}
+}
+
 func TestPacketAllocations(t *testing.T) {
 	for _, tt := range roundTripTests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -514,8 +980,9 @@ func TestPacketAllocations(t *testing.T) {
 			}))
 
 			// Expect one allocation for the fixed length header/message and a
-			// second for the internal slice which carries trailing data.
-			if diff := cmp.Diff(2, nParse); diff != "" {
+			// second for the internal slice which carries trailing data, plus
+			// any allocations for variable length trailing data.
+			if diff := cmp.Diff(tt.allocs, nParse); diff != "" {
 				t.Fatalf("unexpected number of parsing allocations (-want +got):\n%s", diff)
 			}
 
@@ -531,6 +998,30 @@ func TestPacketAllocations(t *testing.T) {
 	}
 }
 
+func TestDDFlagsValid(t *testing.T) {
+	tests := []struct {
+		f  DDFlags
+		ok bool
+	}{
+		{f: 0, ok: true},
+		{f: MSBit, ok: true},
+		{f: MBit | MSBit, ok: true},
+		{f: IBit | MBit | MSBit, ok: true},
+		{f: IBit},
+		{f: IBit | MBit},
+		{f: IBit | MSBit},
+		{f: 0x100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.f.String(), func(t *testing.T) {
+			if diff := cmp.Diff(tt.ok, tt.f.Valid()); diff != "" {
+				t.Fatalf("unexpected validity (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func Test_flagsString(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -583,6 +1074,10 @@ func BenchmarkMarshalPacket(b *testing.B) {
 			name: "link state request",
 			p:    pktLinkStateRequest,
 		},
+		{
+			name: "link state update",
+			p:    pktLinkStateUpdate,
+		},
 		{
 			name: "link state acknowledgement",
 			p:    pktLinkStateAcknowledgement,
@@ -601,6 +1096,19 @@ func BenchmarkMarshalPacket(b *testing.B) {
 	}
 }
 
+func BenchmarkAppendPacket(b *testing.B) {
+	buf := make([]byte, 0, 1500)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		buf, err = AppendPacket(buf[:0], pktLinkStateUpdate)
+		if err != nil {
+			b.Fatalf("failed to append: %v", err)
+		}
+	}
+}
+
 func BenchmarkParsePacket(b *testing.B) {
 	tests := []struct {
 		name string
@@ -618,6 +1126,10 @@ func BenchmarkParsePacket(b *testing.B) {
 			name: "link state request",
 			b:    bufLinkStateRequest,
 		},
+		{
+			name: "link state update",
+			b:    bufLinkStateUpdate,
+		},
 		{
 			name: "link state acknowledgement",
 			b:    bufLinkStateAcknowledgement,
@@ -635,3 +1147,95 @@ func BenchmarkParsePacket(b *testing.B) {
 		})
 	}
 }
+
+func TestLSAHeaderCompare(t *testing.T) {
+	base := LSAHeader{
+		Age:            10 * time.Second,
+		SequenceNumber: 0x80000002,
+		Checksum:       0x1000,
+	}
+
+	tests := []struct {
+		name string
+		fn   func(h *LSAHeader)
+		want int
+	}{
+		{
+			name: "identical",
+			fn:   func(_ *LSAHeader) {},
+		},
+		{
+			name: "higher sequence number",
+			fn:   func(h *LSAHeader) { h.SequenceNumber++ },
+			want: 1,
+		},
+		{
+			name: "lower sequence number",
+			fn:   func(h *LSAHeader) { h.SequenceNumber-- },
+			want: -1,
+		},
+		{
+			name: "signed sequence number",
+			fn: func(h *LSAHeader) {
+				// InitialSequenceNumber is negative and must compare lower
+				// than a positive sequence number despite its larger unsigned
+				// value.
+				h.SequenceNumber = 0x00000001
+			},
+			want: 1,
+		},
+		{
+			name: "higher checksum",
+			fn:   func(h *LSAHeader) { h.Checksum++ },
+			want: 1,
+		},
+		{
+			name: "lower checksum",
+			fn:   func(h *LSAHeader) { h.Checksum-- },
+			want: -1,
+		},
+		{
+			name: "MaxAge",
+			fn:   func(h *LSAHeader) { h.Age = MaxAge },
+			want: 1,
+		},
+		{
+			name: "MaxAge DoNotAge",
+			fn:   func(h *LSAHeader) { h.Age = DoNotAge + MaxAge },
+			want: 1,
+		},
+		{
+			name: "DoNotAge ignored",
+			fn:   func(h *LSAHeader) { h.Age += DoNotAge },
+		},
+		{
+			name: "age within MaxAgeDiff",
+			fn:   func(h *LSAHeader) { h.Age += MaxAgeDiff },
+		},
+		{
+			name: "older beyond MaxAgeDiff",
+			fn:   func(h *LSAHeader) { h.Age += MaxAgeDiff + time.Second },
+			want: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := base
+			tt.fn(&h)
+
+			if diff := cmp.Diff(tt.want, h.Compare(base)); diff != "" {
+				t.Fatalf("unexpected comparison (-want +got):\n%s", diff)
+			}
+
+			// Comparison must be antisymmetric.
+			if diff := cmp.Diff(-tt.want, base.Compare(h)); diff != "" {
+				t.Fatalf("unexpected reverse comparison (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.want > 0, h.Newer(base)); diff != "" {
+				t.Fatalf("unexpected Newer result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// Link types, as registered at https://www.tcpdump.org/linktypes.html.
+const (
+	linkTypeNull      = 0
+	linkTypeEthernet  = 1
+	linkTypeRaw       = 101
+	linkTypeLinuxSLL  = 113
+	linkTypeIPv6      = 229
+	linkTypeLinuxSLL2 = 276
+)
+
+// EtherTypes and BSD address families which carry IPv6.
+const (
+	etherTypeIPv6   = 0x86dd
+	etherTypeVLAN   = 0x8100
+	etherTypeQinQ   = 0x88a8
+	afINET6BSD      = 24
+	afINET6FreeBSD  = 28
+	afINET6Darwin   = 30
+	ipv6HeaderLen   = 40
+	protocolOSPF    = 89
+	ipv6HopByHop    = 0
+	ipv6Routing     = 43
+	ipv6Fragment    = 44
+	ipv6AH          = 51
+	ipv6DestOptions = 60
+)
+
+// decodeFrame strips the link layer and IPv6 headers from a frame of the
+// specified link type, returning the IPv6 source and destination addresses
+// and the OSPFv3 packet. It reports false if the frame does not carry an
+// OSPFv3 packet in IPv6.
+func decodeFrame(linkType uint32, b []byte) (src, dst net.IP, ospf []byte, ok bool) {
+	b, ok = stripLinkLayer(linkType, b)
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	return decodeIPv6(b)
+}
+
+// stripLinkLayer returns the IPv6 packet carried by a frame of the specified
+// link type.
+func stripLinkLayer(linkType uint32, b []byte) ([]byte, bool) {
+	switch linkType {
+	case linkTypeRaw, linkTypeIPv6:
+		return b, true
+	case linkTypeNull:
+		if len(b) < 4 {
+			return nil, false
+		}
+
+		// The address family is in host byte order of the capturing
+		// machine, so check both.
+		for _, af := range []uint32{binary.LittleEndian.Uint32(b[0:4]), binary.BigEndian.Uint32(b[0:4])} {
+			switch af {
+			case afINET6BSD, afINET6FreeBSD, afINET6Darwin:
+				return b[4:], true
+			}
+		}
+
+		return nil, false
+	case linkTypeEthernet:
+		if len(b) < 14 {
+			return nil, false
+		}
+
+		et, b := binary.BigEndian.Uint16(b[12:14]), b[14:]
+		for et == etherTypeVLAN || et == etherTypeQinQ {
+			if len(b) < 4 {
+				return nil, false
+			}
+
+			et, b = binary.BigEndian.Uint16(b[2:4]), b[4:]
+		}
+
+		return b, et == etherTypeIPv6
+	case linkTypeLinuxSLL:
+		if len(b) < 16 {
+			return nil, false
+		}
+
+		return b[16:], binary.BigEndian.Uint16(b[14:16]) == etherTypeIPv6
+	case linkTypeLinuxSLL2:
+		if len(b) < 20 {
+			return nil, false
+		}
+
+		return b[20:], binary.BigEndian.Uint16(b[0:2]) == etherTypeIPv6
+	default:
+		return nil, false
+	}
+}
+
+// decodeIPv6 parses an IPv6 packet, skipping any extension headers, and
+// returns the addresses and payload of an OSPFv3 packet.
+func decodeIPv6(b []byte) (src, dst net.IP, ospf []byte, ok bool) {
+	if len(b) < ipv6HeaderLen || b[0]>>4 != 6 {
+		return nil, nil, nil, false
+	}
+
+	// Bound the payload by the payload length, since link layers may pad
+	// short frames.
+	n := int(binary.BigEndian.Uint16(b[4:6]))
+	if ipv6HeaderLen+n > len(b) {
+		return nil, nil, nil, false
+	}
+
+	src = append(net.IP(nil), b[8:24]...)
+	dst = append(net.IP(nil), b[24:40]...)
+	next, b := b[6], b[ipv6HeaderLen:ipv6HeaderLen+n]
+
+	for {
+		var l int
+		switch next {
+		case protocolOSPF:
+			return src, dst, b, true
+		case ipv6HopByHop, ipv6Routing, ipv6DestOptions:
+			if len(b) < 2 {
+				return nil, nil, nil, false
+			}
+			l = (int(b[1]) + 1) * 8
+		case ipv6Fragment:
+			// Only atomic fragments, with a zero offset and no more
+			// fragments, can be decoded without reassembly.
+			if len(b) < 8 || binary.BigEndian.Uint16(b[2:4])&0xfff9 != 0 {
+				return nil, nil, nil, false
+			}
+			l = 8
+		case ipv6AH:
+			if len(b) < 2 {
+				return nil, nil, nil, false
+			}
+			l = (int(b[1]) + 2) * 4
+		default:
+			return nil, nil, nil, false
+		}
+
+		if l > len(b) {
+			return nil, nil, nil, false
+		}
+
+		next, b = b[0], b[l:]
+	}
+}
@@ -0,0 +1,460 @@
+// Package pcap reads OSPFv3 packets from pcap and pcapng capture files.
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"time"
+
+	"github.com/mdlayher/ospf3"
+)
+
+// File format magic numbers.
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	magicNanoseconds  = 0xa1b23c4d
+	magicByteOrder    = 0x1a2b3c4d
+)
+
+// pcapng block and option types.
+const (
+	blockSectionHeader  = 0x0a0d0d0a
+	blockInterfaceDesc  = 0x00000001
+	blockSimplePacket   = 0x00000003
+	blockEnhancedPacket = 0x00000006
+
+	optionEndOfOpt         = 0
+	optionInterfaceTSResol = 9
+)
+
+// Structure lengths, and the maximum accepted length of a record or block.
+const (
+	fileHeaderLen     = 24
+	recordHeaderLen   = 16
+	blockHeaderLen    = 8
+	blockTrailerLen   = 4
+	enhancedPacketLen = 20
+	interfaceDescLen  = 8
+	simplePacketLen   = 4
+	maxBlockLen       = 16 << 20
+)
+
+// A Packet is an OSPFv3 packet read from a capture file.
+type Packet struct {
+	// Timestamp is the time at which the packet was captured. It is the zero
+	// value for pcapng Simple Packet Blocks, which carry no timestamp.
+	Timestamp time.Time
+
+	// Source and Destination are the addresses from the packet's IPv6
+	// header.
+	Source, Destination net.IP
+
+	// Packet is the parsed OSPFv3 packet.
+	Packet ospf3.Packet
+}
+
+// A Reader reads OSPFv3 packets from a pcap or pcapng capture file. Frames
+// which do not carry an OSPFv3 packet in IPv6 are skipped.
+//
+// Supported link types are Ethernet, with or without VLAN tags, raw IP,
+// IPv6, BSD loopback, and Linux cooked captures. Fragmented IPv6 packets
+// cannot be reassembled and are skipped.
+type Reader struct {
+	// Options modify the behavior of packet parsing as described in
+	// ospf3.ParseOptions. Source and Destination are ignored, and are
+	// replaced by the addresses from each packet's IPv6 header if
+	// VerifyChecksums is set.
+	Options ospf3.ParseOptions
+
+	// VerifyChecksums enables verification of each OSPFv3 packet checksum
+	// using the addresses from its IPv6 header.
+	VerifyChecksums bool
+
+	r     *bufio.Reader
+	order binary.ByteOrder
+	ng    bool
+
+	// pcap state.
+	linkType uint32
+	tsPerSec uint64
+
+	// pcapng state, reset by each Section Header Block.
+	ifaces []iface
+}
+
+// An iface is a pcapng interface described by an Interface Description Block.
+type iface struct {
+	linkType uint32
+	tsPerSec uint64
+}
+
+// NewReader creates a Reader which reads a pcap or pcapng capture file from r.
+// The file format is detected from its header.
+func NewReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{r: bufio.NewReader(r)}
+
+	magic, err := rd.r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("pcap: failed to read file header: %w", err)
+	}
+
+	if binary.BigEndian.Uint32(magic) == blockSectionHeader {
+		rd.ng = true
+		if err := rd.readSectionHeader(); err != nil {
+			return nil, err
+		}
+
+		return rd, nil
+	}
+
+	if err := rd.readFileHeader(); err != nil {
+		return nil, err
+	}
+
+	return rd, nil
+}
+
+// Next returns the next OSPFv3 packet in the capture file. It returns io.EOF
+// when no packets remain. If a frame carries an OSPFv3 packet which cannot be
+// parsed, Next returns the parse error and the following call to Next
+// continues with the next frame.
+func (r *Reader) Next() (*Packet, error) {
+	for {
+		f, err := r.nextFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		src, dst, b, ok := decodeFrame(f.linkType, f.data)
+		if !ok {
+			continue
+		}
+
+		o := r.Options
+		o.Source, o.Destination = nil, nil
+		if r.VerifyChecksums {
+			o.Source, o.Destination = src, dst
+		}
+
+		p, err := o.ParsePacket(b)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Packet{
+			Timestamp:   f.ts,
+			Source:      src,
+			Destination: dst,
+			Packet:      p,
+		}, nil
+	}
+}
+
+// A frame is a single captured link layer frame.
+type frame struct {
+	ts       time.Time
+	linkType uint32
+	data     []byte
+}
+
+// nextFrame reads the next captured frame from the file.
+func (r *Reader) nextFrame() (*frame, error) {
+	if r.ng {
+		return r.nextBlock()
+	}
+
+	return r.nextRecord()
+}
+
+// readFileHeader reads a pcap file header.
+func (r *Reader) readFileHeader() error {
+	b := make([]byte, fileHeaderLen)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return fmt.Errorf("pcap: failed to read file header: %w", err)
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(b[0:4]) {
+		case magicMicroseconds:
+			r.tsPerSec = 1e6
+		case magicNanoseconds:
+			r.tsPerSec = 1e9
+		default:
+			continue
+		}
+
+		r.order = order
+		r.linkType = order.Uint32(b[20:24])
+		return nil
+	}
+
+	return fmt.Errorf("pcap: unrecognized file magic %#08x", binary.BigEndian.Uint32(b[0:4]))
+}
+
+// nextRecord reads the next pcap packet record.
+func (r *Reader) nextRecord() (*frame, error) {
+	h := make([]byte, recordHeaderLen)
+	if _, err := io.ReadFull(r.r, h); err != nil {
+		if err == io.EOF {
+			// No more records.
+			return nil, io.EOF
+		}
+
+		return nil, eof(err, "packet record header")
+	}
+
+	var (
+		sec  = r.order.Uint32(h[0:4])
+		frac = r.order.Uint32(h[4:8])
+		n    = r.order.Uint32(h[8:12])
+	)
+	if n > maxBlockLen {
+		return nil, fmt.Errorf("pcap: packet record length %d is too large", n)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, eof(err, "packet record")
+	}
+
+	return &frame{
+		ts:       timestamp(uint64(sec)*r.tsPerSec+uint64(frac), r.tsPerSec),
+		linkType: r.linkType,
+		data:     data,
+	}, nil
+}
+
+// readSectionHeader reads a pcapng Section Header Block.
+func (r *Reader) readSectionHeader() error {
+	h, err := r.r.Peek(blockHeaderLen + 4)
+	if err != nil {
+		return eof(err, "Section Header Block")
+	}
+
+	// The byte order magic determines the byte order of the section,
+	// including the length of the Section Header Block itself.
+	switch {
+	case binary.BigEndian.Uint32(h[8:12]) == magicByteOrder:
+		r.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(h[8:12]) == magicByteOrder:
+		r.order = binary.LittleEndian
+	default:
+		return fmt.Errorf("pcap: unrecognized pcapng byte order magic %#08x", binary.BigEndian.Uint32(h[8:12]))
+	}
+
+	if _, _, err := r.readBlock(); err != nil {
+		return err
+	}
+
+	r.ifaces = r.ifaces[:0]
+	return nil
+}
+
+// readBlock reads a complete pcapng block, returning its type and body.
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	h := make([]byte, blockHeaderLen)
+	if _, err := io.ReadFull(r.r, h); err != nil {
+		return 0, nil, eof(err, "block header")
+	}
+
+	var (
+		typ = r.order.Uint32(h[0:4])
+		n   = r.order.Uint32(h[4:8])
+	)
+	if n%4 != 0 || n < blockHeaderLen+blockTrailerLen || n > maxBlockLen {
+		return 0, nil, fmt.Errorf("pcap: invalid pcapng block length %d", n)
+	}
+
+	b := make([]byte, n-blockHeaderLen)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return 0, nil, eof(err, "block")
+	}
+
+	return typ, b[:len(b)-blockTrailerLen], nil
+}
+
+// nextBlock reads pcapng blocks until one which contains a packet is found.
+func (r *Reader) nextBlock() (*frame, error) {
+	for {
+		typ, err := r.peekBlockType()
+		if err != nil {
+			return nil, err
+		}
+		if typ == blockSectionHeader {
+			if err := r.readSectionHeader(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		typ, b, err := r.readBlock()
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case blockInterfaceDesc:
+			ifi, err := r.parseInterface(b)
+			if err != nil {
+				return nil, err
+			}
+			r.ifaces = append(r.ifaces, ifi)
+		case blockEnhancedPacket:
+			return r.parseEnhancedPacket(b)
+		case blockSimplePacket:
+			return r.parseSimplePacket(b)
+		}
+
+		// All other blocks are skipped.
+	}
+}
+
+// peekBlockType returns the type of the next pcapng block without consuming
+// it.
+func (r *Reader) peekBlockType() (uint32, error) {
+	b, err := r.r.Peek(4)
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(b) == 0 {
+			return 0, io.EOF
+		}
+
+		return 0, eof(err, "block header")
+	}
+
+	// The Section Header Block type is a palindrome, so it is recognized
+	// regardless of the current byte order.
+	return r.order.Uint32(b), nil
+}
+
+// parseInterface parses the body of an Interface Description Block.
+func (r *Reader) parseInterface(b []byte) (iface, error) {
+	if len(b) < interfaceDescLen {
+		return iface{}, fmt.Errorf("pcap: Interface Description Block is too short: %d bytes", len(b))
+	}
+
+	ifi := iface{
+		linkType: uint32(r.order.Uint16(b[0:2])),
+		tsPerSec: 1e6,
+	}
+
+	// Walk the options to find the timestamp resolution.
+	opts := b[interfaceDescLen:]
+	for len(opts) >= 4 {
+		code := r.order.Uint16(opts[0:2])
+		n := int(r.order.Uint16(opts[2:4]))
+		if code == optionEndOfOpt || 4+n > len(opts) {
+			break
+		}
+
+		if code == optionInterfaceTSResol && n >= 1 {
+			per, ok := tsResolution(opts[4])
+			if !ok {
+				return iface{}, fmt.Errorf("pcap: unsupported timestamp resolution %#02x", opts[4])
+			}
+			ifi.tsPerSec = per
+		}
+
+		// Option values are padded to 32 bits.
+		opts = opts[4+(n+3)&^3:]
+	}
+
+	return ifi, nil
+}
+
+// parseEnhancedPacket parses the body of an Enhanced Packet Block.
+func (r *Reader) parseEnhancedPacket(b []byte) (*frame, error) {
+	if len(b) < enhancedPacketLen {
+		return nil, fmt.Errorf("pcap: Enhanced Packet Block is too short: %d bytes", len(b))
+	}
+
+	id := r.order.Uint32(b[0:4])
+	if int(id) >= len(r.ifaces) {
+		return nil, fmt.Errorf("pcap: Enhanced Packet Block references unknown interface %d", id)
+	}
+	ifi := r.ifaces[id]
+
+	ts := uint64(r.order.Uint32(b[4:8]))<<32 | uint64(r.order.Uint32(b[8:12]))
+	n := r.order.Uint32(b[12:16])
+	if int(n) > len(b[enhancedPacketLen:]) {
+		return nil, fmt.Errorf("pcap: Enhanced Packet Block captured length %d exceeds block", n)
+	}
+
+	return &frame{
+		ts:       timestamp(ts, ifi.tsPerSec),
+		linkType: ifi.linkType,
+		data:     b[enhancedPacketLen : enhancedPacketLen+int(n)],
+	}, nil
+}
+
+// parseSimplePacket parses the body of a Simple Packet Block, which is always
+// captured on the first interface.
+func (r *Reader) parseSimplePacket(b []byte) (*frame, error) {
+	if len(r.ifaces) == 0 {
+		return nil, errors.New("pcap: Simple Packet Block precedes any Interface Description Block")
+	}
+	if len(b) < simplePacketLen {
+		return nil, fmt.Errorf("pcap: Simple Packet Block is too short: %d bytes", len(b))
+	}
+
+	// The captured length is the lesser of the original length and the
+	// remaining block.
+	data := b[simplePacketLen:]
+	if n := r.order.Uint32(b[0:4]); int(n) < len(data) {
+		data = data[:n]
+	}
+
+	return &frame{
+		linkType: r.ifaces[0].linkType,
+		data:     data,
+	}, nil
+}
+
+// tsResolution decodes the if_tsresol option value into the number of
+// timestamp units per second, which is a power of 10 or, with the high bit
+// set, of 2. It reports false for resolutions which overflow 64 bits.
+func tsResolution(v uint8) (uint64, bool) {
+	if v&0x80 != 0 {
+		v &^= 0x80
+		if v > 63 {
+			return 0, false
+		}
+
+		return 1 << v, true
+	}
+
+	if v > 19 {
+		return 0, false
+	}
+
+	per := uint64(1)
+	for i := uint8(0); i < v; i++ {
+		per *= 10
+	}
+
+	return per, true
+}
+
+// timestamp converts a timestamp in units of 1/per seconds to a time.Time.
+func timestamp(ts, per uint64) time.Time {
+	// (ts % per) * 1e9 may overflow, so compute the nanoseconds using
+	// 128-bit arithmetic.
+	hi, lo := bits.Mul64(ts%per, uint64(time.Second))
+	ns, _ := bits.Div64(hi, lo, per)
+
+	return time.Unix(int64(ts/per), int64(ns))
+}
+
+// eof converts an io.EOF into io.ErrUnexpectedEOF, since the file ended within
+// a structure, and annotates err with the structure being read.
+func eof(err error, what string) error {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return fmt.Errorf("pcap: failed to read %s: %w", what, err)
+}
@@ -0,0 +1,447 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/ospf3"
+)
+
+var (
+	src = net.ParseIP("fe80::1")
+	dst = net.ParseIP("ff02::5")
+
+	hello = &ospf3.Hello{
+		Header: ospf3.Header{
+			RouterID:   ospf3.ID{192, 0, 2, 1},
+			InstanceID: 1,
+		},
+		InterfaceID:        1,
+		RouterPriority:     1,
+		Options:            ospf3.V6Bit | ospf3.EBit,
+		HelloInterval:      10 * time.Second,
+		RouterDeadInterval: 40 * time.Second,
+		NeighborIDs:        []ID{{192, 0, 2, 2}},
+	}
+
+	ack = &ospf3.LinkStateAcknowledgement{
+		Header: ospf3.Header{RouterID: ospf3.ID{192, 0, 2, 2}},
+		LSAs:   []ospf3.LSAHeader{},
+	}
+)
+
+// ID is shorthand for ospf3.ID.
+type ID = ospf3.ID
+
+func TestReaderPcap(t *testing.T) {
+	var (
+		t0 = time.Unix(1700000000, 0)
+		t1 = t0.Add(1500 * time.Microsecond)
+		t2 = t0.Add(2 * time.Second)
+	)
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			writeFileHeader(&buf, order, magicMicroseconds, linkTypeEthernet)
+			writeRecord(&buf, order, t0, time.Microsecond, ethernet(ipv6(marshal(t, hello), nil)))
+			// IPv4 frames are skipped.
+			writeRecord(&buf, order, t1, time.Microsecond, ethernetType(0x0800, make([]byte, 20)))
+			// VLAN tags and extension headers are stripped.
+			writeRecord(&buf, order, t2, time.Microsecond, vlan(ipv6(marshal(t, ack), hopByHop())))
+
+			want := []*Packet{
+				{Timestamp: t0, Source: src, Destination: dst, Packet: checksummed(t, hello)},
+				{Timestamp: t2, Source: src, Destination: dst, Packet: checksummed(t, ack)},
+			}
+
+			if diff := cmp.Diff(want, readAll(t, &buf, true)); diff != "" {
+				t.Fatalf("unexpected Packets (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReaderPcapNanoseconds(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+
+	var buf bytes.Buffer
+	writeFileHeader(&buf, binary.BigEndian, magicNanoseconds, linkTypeRaw)
+	writeRecord(&buf, binary.BigEndian, ts, time.Nanosecond, ipv6(marshal(t, hello), nil))
+
+	want := []*Packet{{
+		Timestamp:   ts,
+		Source:      src,
+		Destination: dst,
+		Packet:      checksummed(t, hello),
+	}}
+
+	if diff := cmp.Diff(want, readAll(t, &buf, false)); diff != "" {
+		t.Fatalf("unexpected Packets (-want +got):\n%s", diff)
+	}
+}
+
+func TestReaderPcapng(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			writeSectionHeader(&buf, order)
+			// Interface 0 uses the default microsecond resolution, and
+			// interface 1 uses nanoseconds.
+			writeInterface(&buf, order, linkTypeEthernet, 0)
+			writeInterface(&buf, order, linkTypeLinuxSLL, 9)
+			// Unknown blocks are skipped.
+			writeBlock(&buf, order, 0x00000005, make([]byte, 8))
+			writeEnhancedPacket(&buf, order, 1, uint64(ts.UnixNano()), sll(ipv6(marshal(t, hello), nil)))
+			writeSimplePacket(&buf, order, ethernet(ipv6(marshal(t, ack), nil)))
+
+			want := []*Packet{
+				{Timestamp: ts, Source: src, Destination: dst, Packet: checksummed(t, hello)},
+				{Source: src, Destination: dst, Packet: checksummed(t, ack)},
+			}
+
+			if diff := cmp.Diff(want, readAll(t, &buf, true)); diff != "" {
+				t.Fatalf("unexpected Packets (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReaderParseError(t *testing.T) {
+	bad := marshal(t, hello)
+	bad[len(bad)-1] ^= 0xff
+
+	var buf bytes.Buffer
+	writeFileHeader(&buf, binary.LittleEndian, magicMicroseconds, linkTypeIPv6)
+	writeRecord(&buf, binary.LittleEndian, time.Unix(0, 0), time.Microsecond, ipv6(bad, nil))
+	writeRecord(&buf, binary.LittleEndian, time.Unix(1, 0), time.Microsecond, ipv6(marshal(t, ack), nil))
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("failed to create Reader: %v", err)
+	}
+	r.VerifyChecksums = true
+
+	if _, err := r.Next(); err == nil {
+		t.Fatal("expected a checksum error, but none occurred")
+	}
+
+	// Reading continues with the following frame.
+	p, err := r.Next()
+	if err != nil {
+		t.Fatalf("failed to read packet: %v", err)
+	}
+	if diff := cmp.Diff(checksummed(t, ack), p.Packet); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected EOF, but got: %v", err)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	var pcap bytes.Buffer
+	writeFileHeader(&pcap, binary.LittleEndian, magicMicroseconds, linkTypeIPv6)
+	writeRecord(&pcap, binary.LittleEndian, time.Unix(0, 0), time.Microsecond, ipv6(marshal(t, hello), nil))
+
+	var ng bytes.Buffer
+	writeSectionHeader(&ng, binary.LittleEndian)
+	writeEnhancedPacket(&ng, binary.LittleEndian, 0, 0, ipv6(marshal(t, hello), nil))
+
+	tests := []struct {
+		name string
+		b    []byte
+		ok   bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "bad magic",
+			b:    make([]byte, fileHeaderLen),
+		},
+		{
+			name: "truncated file header",
+			b:    pcap.Bytes()[:fileHeaderLen-1],
+		},
+		{
+			name: "truncated record",
+			b:    pcap.Bytes()[:pcap.Len()-1],
+			ok:   true,
+		},
+		{
+			name: "unknown interface",
+			b:    ng.Bytes(),
+			ok:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(tt.b))
+			if !tt.ok {
+				if err == nil {
+					t.Fatal("expected an error, but none occurred")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to create Reader: %v", err)
+			}
+
+			_, err = r.Next()
+			if err == nil || errors.Is(err, io.EOF) {
+				t.Fatalf("expected a non-EOF error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func Test_decodeIPv6(t *testing.T) {
+	frag := func(offset uint16) []byte {
+		b := make([]byte, 8)
+		b[0] = protocolOSPF
+		binary.BigEndian.PutUint16(b[2:4], offset)
+		return b
+	}
+
+	tests := []struct {
+		name string
+		next uint8
+		ext  []byte
+		ok   bool
+	}{
+		{
+			name: "OSPF",
+			next: protocolOSPF,
+			ok:   true,
+		},
+		{
+			name: "UDP",
+			next: 17,
+		},
+		{
+			name: "atomic fragment",
+			next: ipv6Fragment,
+			ext:  frag(0),
+			ok:   true,
+		},
+		{
+			name: "first fragment",
+			next: ipv6Fragment,
+			ext:  frag(1),
+		},
+		{
+			name: "later fragment",
+			next: ipv6Fragment,
+			ext:  frag(8 << 3),
+		},
+		{
+			name: "AH",
+			next: ipv6AH,
+			// Payload length of 1 indicates a 12 byte header.
+			ext: []byte{protocolOSPF, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			ok:  true,
+		},
+		{
+			name: "truncated extension",
+			next: ipv6DestOptions,
+			ext:  []byte{protocolOSPF, 1},
+		},
+	}
+
+	payload := []byte{0xff, 0xff}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := ipv6Next(tt.next, append(tt.ext, payload...))
+
+			_, _, got, ok := decodeIPv6(b)
+			if ok != tt.ok {
+				t.Fatalf("unexpected ok: %v", ok)
+			}
+			if !ok {
+				return
+			}
+
+			if diff := cmp.Diff(payload, got); diff != "" {
+				t.Fatalf("unexpected payload (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func readAll(t *testing.T, r io.Reader, verify bool) []*Packet {
+	t.Helper()
+
+	pr, err := NewReader(r)
+	if err != nil {
+		t.Fatalf("failed to create Reader: %v", err)
+	}
+	pr.VerifyChecksums = verify
+
+	var ps []*Packet
+	for {
+		p, err := pr.Next()
+		if errors.Is(err, io.EOF) {
+			return ps
+		}
+		if err != nil {
+			t.Fatalf("failed to read packet: %v", err)
+		}
+
+		ps = append(ps, p)
+	}
+}
+
+func marshal(t *testing.T, p ospf3.Packet) []byte {
+	t.Helper()
+
+	b, err := ospf3.MarshalOptions{Source: src, Destination: dst}.MarshalPacket(p)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	return b
+}
+
+// checksummed returns p as parsed after marshaling, with its checksum set.
+func checksummed(t *testing.T, p ospf3.Packet) ospf3.Packet {
+	t.Helper()
+
+	pp, err := ospf3.ParsePacket(marshal(t, p))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	return pp
+}
+
+func hopByHop() []byte {
+	// PadN option filling the 8 byte header.
+	return []byte{protocolOSPF, 0, 1, 4, 0, 0, 0, 0}
+}
+
+func ipv6(payload, ext []byte) []byte {
+	if ext == nil {
+		return ipv6Next(protocolOSPF, payload)
+	}
+
+	return ipv6Next(ipv6HopByHop, append(ext, payload...))
+}
+
+func ipv6Next(next uint8, payload []byte) []byte {
+	b := make([]byte, ipv6HeaderLen, ipv6HeaderLen+len(payload))
+	b[0] = 6 << 4
+	binary.BigEndian.PutUint16(b[4:6], uint16(len(payload)))
+	b[6] = next
+	b[7] = 1
+	copy(b[8:24], src)
+	copy(b[24:40], dst)
+
+	return append(b, payload...)
+}
+
+func ethernet(b []byte) []byte { return ethernetType(etherTypeIPv6, b) }
+
+func ethernetType(et uint16, b []byte) []byte {
+	h := make([]byte, 14)
+	binary.BigEndian.PutUint16(h[12:14], et)
+	return append(h, b...)
+}
+
+func vlan(b []byte) []byte {
+	h := make([]byte, 18)
+	binary.BigEndian.PutUint16(h[12:14], etherTypeVLAN)
+	binary.BigEndian.PutUint16(h[14:16], 100)
+	binary.BigEndian.PutUint16(h[16:18], etherTypeIPv6)
+	return append(h, b...)
+}
+
+func sll(b []byte) []byte {
+	h := make([]byte, 16)
+	binary.BigEndian.PutUint16(h[14:16], etherTypeIPv6)
+	return append(h, b...)
+}
+
+func writeFileHeader(w *bytes.Buffer, order binary.ByteOrder, magic, linkType uint32) {
+	b := make([]byte, fileHeaderLen)
+	order.PutUint32(b[0:4], magic)
+	order.PutUint16(b[4:6], 2)
+	order.PutUint16(b[6:8], 4)
+	order.PutUint32(b[16:20], 65535)
+	order.PutUint32(b[20:24], linkType)
+	w.Write(b)
+}
+
+func writeRecord(w *bytes.Buffer, order binary.ByteOrder, ts time.Time, unit time.Duration, data []byte) {
+	b := make([]byte, recordHeaderLen)
+	order.PutUint32(b[0:4], uint32(ts.Unix()))
+	order.PutUint32(b[4:8], uint32(time.Duration(ts.Nanosecond())/unit))
+	order.PutUint32(b[8:12], uint32(len(data)))
+	order.PutUint32(b[12:16], uint32(len(data)))
+	w.Write(b)
+	w.Write(data)
+}
+
+func writeBlock(w *bytes.Buffer, order binary.ByteOrder, typ uint32, body []byte) {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+
+	n := uint32(blockHeaderLen + len(body) + blockTrailerLen)
+	b := make([]byte, blockHeaderLen)
+	order.PutUint32(b[0:4], typ)
+	order.PutUint32(b[4:8], n)
+	w.Write(b)
+	w.Write(body)
+	binary.Write(w, order, n)
+}
+
+func writeSectionHeader(w *bytes.Buffer, order binary.ByteOrder) {
+	b := make([]byte, 16)
+	order.PutUint32(b[0:4], magicByteOrder)
+	order.PutUint16(b[4:6], 1)
+	// Unspecified section length.
+	order.PutUint64(b[8:16], ^uint64(0))
+	writeBlock(w, order, blockSectionHeader, b)
+}
+
+func writeInterface(w *bytes.Buffer, order binary.ByteOrder, linkType uint16, tsresol uint8) {
+	b := make([]byte, interfaceDescLen)
+	order.PutUint16(b[0:2], linkType)
+	if tsresol != 0 {
+		opt := make([]byte, 8)
+		order.PutUint16(opt[0:2], optionInterfaceTSResol)
+		order.PutUint16(opt[2:4], 1)
+		opt[4] = tsresol
+		b = append(b, opt...)
+		// opt_endofopt.
+		b = append(b, 0, 0, 0, 0)
+	}
+	writeBlock(w, order, blockInterfaceDesc, b)
+}
+
+func writeEnhancedPacket(w *bytes.Buffer, order binary.ByteOrder, id uint32, ts uint64, data []byte) {
+	b := make([]byte, enhancedPacketLen)
+	order.PutUint32(b[0:4], id)
+	order.PutUint32(b[4:8], uint32(ts>>32))
+	order.PutUint32(b[8:12], uint32(ts))
+	order.PutUint32(b[12:16], uint32(len(data)))
+	order.PutUint32(b[16:20], uint32(len(data)))
+	writeBlock(w, order, blockEnhancedPacket, append(b, data...))
+}
+
+func writeSimplePacket(w *bytes.Buffer, order binary.ByteOrder, data []byte) {
+	b := make([]byte, simplePacketLen)
+	order.PutUint32(b[0:4], uint32(len(data)))
+	writeBlock(w, order, blockSimplePacket, append(b, data...))
+}
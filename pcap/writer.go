@@ -0,0 +1,209 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/mdlayher/ospf3"
+)
+
+// pcapng options written by a Writer.
+const (
+	optionInterfaceName  = 2
+	optionPacketFlags    = 2
+	flagInbound          = 1
+	flagOutbound         = 2
+	tsResolutionNanosecs = 9
+)
+
+var _ ospf3.Capturer = &Writer{}
+
+// A Writer writes OSPFv3 packets to a pcapng capture file, such as for later
+// analysis with Wireshark. Each packet is written with a synthesized IPv6
+// header using the IPv6 link type, and its direction is recorded when known.
+//
+// A Writer implements ospf3.Capturer, so it may be set in ospf3.Config or
+// passed to ospf3.Conn.SetCapturer to capture the traffic of a Conn. Its
+// methods are safe for concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	ifaces map[string]uint32
+	err    error
+}
+
+// NewWriter creates a Writer which writes a pcapng capture file to w. Writes
+// to w are not buffered.
+func NewWriter(w io.Writer) (*Writer, error) {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint32(b[0:4], magicByteOrder)
+	binary.BigEndian.PutUint16(b[4:6], 1)
+	// The section length is unspecified.
+	binary.BigEndian.PutUint64(b[8:16], ^uint64(0))
+
+	if _, err := w.Write(block(blockSectionHeader, b)); err != nil {
+		return nil, fmt.Errorf("pcap: failed to write Section Header Block: %w", err)
+	}
+
+	return &Writer{
+		w:      w,
+		ifaces: make(map[string]uint32),
+	}, nil
+}
+
+// WritePacket writes a single packet to the capture file. An Interface
+// Description Block is written the first time each interface name appears.
+func (w *Writer) WritePacket(p *ospf3.CapturedPacket) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id, ok := w.ifaces[p.Interface]
+	if !ok {
+		id = uint32(len(w.ifaces))
+		if _, err := w.w.Write(interfaceBlock(p.Interface)); err != nil {
+			return fmt.Errorf("pcap: failed to write Interface Description Block: %w", err)
+		}
+		w.ifaces[p.Interface] = id
+	}
+
+	if _, err := w.w.Write(packetBlock(id, p)); err != nil {
+		return fmt.Errorf("pcap: failed to write Enhanced Packet Block: %w", err)
+	}
+
+	return nil
+}
+
+// Capture implements ospf3.Capturer by calling WritePacket. The first error
+// encountered is retained and reported by Err, and subsequent packets are
+// discarded.
+func (w *Writer) Capture(p *ospf3.CapturedPacket) {
+	if w.Err() != nil {
+		return
+	}
+
+	if err := w.WritePacket(p); err != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.err == nil {
+			w.err = err
+		}
+	}
+}
+
+// Err returns the first error encountered by Capture, if any.
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// interfaceBlock builds an Interface Description Block for the IPv6 link type
+// with nanosecond timestamps.
+func interfaceBlock(name string) []byte {
+	b := make([]byte, interfaceDescLen)
+	binary.BigEndian.PutUint16(b[0:2], linkTypeIPv6)
+
+	b = appendOption(b, optionInterfaceTSResol, []byte{tsResolutionNanosecs})
+	if name != "" {
+		b = appendOption(b, optionInterfaceName, []byte(name))
+	}
+	b = appendOption(b, optionEndOfOpt, nil)
+
+	return block(blockInterfaceDesc, b)
+}
+
+// packetBlock builds an Enhanced Packet Block containing p with a synthesized
+// IPv6 header.
+func packetBlock(id uint32, p *ospf3.CapturedPacket) []byte {
+	ip := synthesizeIPv6(p)
+
+	b := make([]byte, enhancedPacketLen, enhancedPacketLen+len(ip)+16)
+	ts := uint64(p.Time.UnixNano())
+	binary.BigEndian.PutUint32(b[0:4], id)
+	binary.BigEndian.PutUint32(b[4:8], uint32(ts>>32))
+	binary.BigEndian.PutUint32(b[8:12], uint32(ts))
+	binary.BigEndian.PutUint32(b[12:16], uint32(len(ip)))
+	binary.BigEndian.PutUint32(b[16:20], uint32(len(ip)))
+	b = append(b, ip...)
+	b = pad(b)
+
+	flags := uint32(flagInbound)
+	if p.Outbound {
+		flags = flagOutbound
+	}
+	fb := make([]byte, 4)
+	binary.BigEndian.PutUint32(fb, flags)
+	b = appendOption(b, optionPacketFlags, fb)
+	b = appendOption(b, optionEndOfOpt, nil)
+
+	return block(blockEnhancedPacket, b)
+}
+
+// synthesizeIPv6 returns the OSPFv3 packet in p with an IPv6 header.
+func synthesizeIPv6(p *ospf3.CapturedPacket) []byte {
+	tclass, hops := p.TrafficClass, p.HopLimit
+	if tclass < 0 {
+		tclass = 0
+	}
+	if hops < 0 {
+		// OSPFv3 packets are sent with a hop limit of 1, except on virtual
+		// links.
+		hops = 1
+	}
+
+	b := make([]byte, ipv6HeaderLen, ipv6HeaderLen+len(p.Data))
+	binary.BigEndian.PutUint32(b[0:4], 6<<28|uint32(tclass&0xff)<<20)
+	binary.BigEndian.PutUint16(b[4:6], uint16(len(p.Data)))
+	b[6] = protocolOSPF
+	b[7] = uint8(hops)
+	copy(b[8:24], to16(p.Source))
+	copy(b[24:40], to16(p.Destination))
+
+	return append(b, p.Data...)
+}
+
+// to16 returns ip as a 16 byte address, or the unspecified address if ip is
+// not a valid IP address.
+func to16(ip net.IP) net.IP {
+	if ip16 := ip.To16(); ip16 != nil {
+		return ip16
+	}
+
+	return net.IPv6unspecified
+}
+
+// appendOption appends a pcapng option to b, padded to 32 bits.
+func appendOption(b []byte, code uint16, v []byte) []byte {
+	var h [4]byte
+	binary.BigEndian.PutUint16(h[0:2], code)
+	binary.BigEndian.PutUint16(h[2:4], uint16(len(v)))
+
+	return pad(append(append(b, h[:]...), v...))
+}
+
+// block builds a pcapng block of type typ with the specified body, which must
+// be padded to 32 bits.
+func block(typ uint32, body []byte) []byte {
+	n := uint32(blockHeaderLen + len(body) + blockTrailerLen)
+
+	b := make([]byte, blockHeaderLen, n)
+	binary.BigEndian.PutUint32(b[0:4], typ)
+	binary.BigEndian.PutUint32(b[4:8], n)
+	b = append(b, body...)
+
+	var t [4]byte
+	binary.BigEndian.PutUint32(t[:], n)
+	return append(b, t[:]...)
+}
+
+// pad pads b with zeros to a multiple of 32 bits.
+func pad(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+
+	return b
+}
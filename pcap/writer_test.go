@@ -0,0 +1,105 @@
+package pcap
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/ospf3"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	var (
+		t0 = time.Unix(1700000000, 123456789)
+		t1 = t0.Add(time.Second)
+		t2 = t0.Add(2 * time.Second)
+	)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create Writer: %v", err)
+	}
+
+	for _, p := range []*ospf3.CapturedPacket{
+		{
+			Time:         t0,
+			Outbound:     true,
+			Source:       src,
+			Destination:  dst,
+			TrafficClass: 0xc0,
+			HopLimit:     1,
+			Interface:    "eth0",
+			Data:         marshal(t, hello),
+		},
+		{
+			Time:         t1,
+			Source:       src,
+			Destination:  dst,
+			TrafficClass: -1,
+			HopLimit:     -1,
+			Interface:    "eth1",
+			Data:         marshal(t, ack),
+		},
+		{
+			Time:        t2,
+			Source:      src,
+			Destination: dst,
+			Interface:   "eth0",
+			Data:        marshal(t, hello),
+		},
+	} {
+		w.Capture(p)
+	}
+	if err := w.Err(); err != nil {
+		t.Fatalf("failed to capture: %v", err)
+	}
+
+	want := []*Packet{
+		{Timestamp: t0, Source: src, Destination: dst, Packet: checksummed(t, hello)},
+		{Timestamp: t1, Source: src, Destination: dst, Packet: checksummed(t, ack)},
+		{Timestamp: t2, Source: src, Destination: dst, Packet: checksummed(t, hello)},
+	}
+
+	if diff := cmp.Diff(want, readAll(t, &buf, true)); diff != "" {
+		t.Fatalf("unexpected Packets (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	fw := &failWriter{n: 1}
+	w, err := NewWriter(fw)
+	if err != nil {
+		t.Fatalf("failed to create Writer: %v", err)
+	}
+
+	p := &ospf3.CapturedPacket{Time: time.Unix(0, 0), Data: marshal(t, hello)}
+	w.Capture(p)
+	if err := w.Err(); !errors.Is(err, errWrite) {
+		t.Fatalf("expected write error, but got: %v", err)
+	}
+
+	// Packets are discarded after the first error.
+	w.Capture(p)
+	if fw.calls != 2 {
+		t.Fatalf("unexpected number of writes: %d", fw.calls)
+	}
+}
+
+var errWrite = errors.New("write failed")
+
+// A failWriter fails all writes after the first n.
+type failWriter struct {
+	n, calls int
+}
+
+func (w *failWriter) Write(b []byte) (int, error) {
+	w.calls++
+	if w.calls > w.n {
+		return 0, errWrite
+	}
+
+	return len(b), nil
+}
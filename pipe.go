@@ -0,0 +1,361 @@
+package ospf3
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv6"
+)
+
+// A PacketConn can send and receive OSPFv3 Packets. It is implemented by
+// *Conn, and by the in-memory connections returned by Pipe and NewPipe so that
+// code which uses a Conn can be tested without network interfaces or
+// privileges. HelloProtocol and Prober accept any PacketConn.
+type PacketConn interface {
+	ReadFrom() (Packet, *ipv6.ControlMessage, *net.IPAddr, error)
+	WriteTo(p Packet, dst *net.IPAddr) error
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
+
+var _ PacketConn = &Conn{}
+
+// pipeBuffer is the number of packets buffered by each end of a Pipe before
+// further packets are dropped, as a congested link would.
+const pipeBuffer = 64
+
+// Pipe creates a pair of in-memory PacketConns which behave like two routers
+// on a point-to-point link. Each Packet written to one end is delivered to the
+// other regardless of its destination address, with the link-local source
+// address fe80::1 or fe80::2 respectively.
+//
+// Packets are marshaled on write and parsed on read, so invalid Packets
+// produce errors as they would with a Conn. ReadFrom returns an error which
+// wraps os.ErrDeadlineExceeded when the read deadline passes, and
+// net.ErrClosed once either end is closed.
+func Pipe() (PacketConn, PacketConn) {
+	a, b, err := NewPipe(nil)
+	if err != nil {
+		panicf("ospf3: failed to create Pipe: %v", err)
+	}
+
+	return a, b
+}
+
+// A PipeConfig injects faults into the link created by NewPipe, so that
+// retransmission and convergence behavior can be tested under adverse
+// conditions. Faults are applied independently to each Packet written to
+// either end.
+type PipeConfig struct {
+	// Loss, Duplicate, and Reorder are the probabilities from 0 to 1 that a
+	// Packet is dropped, delivered twice, or held back and delivered after
+	// the next Packet written to the same end, respectively.
+	Loss, Duplicate, Reorder float64
+
+	// Latency delays the delivery of each Packet. Packets written to the
+	// same end are delivered in order.
+	Latency time.Duration
+
+	// Rand optionally sets the source of randomness for faults, such as to
+	// reproduce a test run. If nil, a source seeded from the clock is used.
+	Rand *rand.Rand
+}
+
+// NewPipe creates a pair of in-memory PacketConns like Pipe, with the faults
+// described by cfg. If cfg is nil, no faults are injected.
+func NewPipe(cfg *PipeConfig) (PacketConn, PacketConn, error) {
+	var f *pipeFaults
+	if cfg != nil {
+		for _, v := range []struct {
+			name string
+			p    float64
+		}{
+			{name: "Loss", p: cfg.Loss},
+			{name: "Duplicate", p: cfg.Duplicate},
+			{name: "Reorder", p: cfg.Reorder},
+		} {
+			if !(v.p >= 0 && v.p <= 1) {
+				return nil, nil, fmt.Errorf("ospf3: Pipe %s probability must be between 0 and 1: %v", v.name, v.p)
+			}
+		}
+		if cfg.Latency < 0 {
+			return nil, nil, fmt.Errorf("ospf3: Pipe Latency must not be negative: %v", cfg.Latency)
+		}
+
+		r := cfg.Rand
+		if r == nil {
+			r = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+
+		f = &pipeFaults{cfg: *cfg, rand: r}
+	}
+
+	var (
+		closed = make(chan struct{})
+		once   = &sync.Once{}
+
+		a = newPipeConn(net.ParseIP("fe80::1"), closed, once, f)
+		b = newPipeConn(net.ParseIP("fe80::2"), closed, once, f)
+	)
+
+	a.peer, b.peer = b, a
+	return a, b, nil
+}
+
+// pipeFaults is the fault injection state shared by both ends of a Pipe.
+type pipeFaults struct {
+	cfg PipeConfig
+
+	// mu guards rand and the held messages of each end.
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// chance reports whether an event with probability p occurs. The caller must
+// hold mu.
+func (f *pipeFaults) chance(p float64) bool {
+	return p > 0 && f.rand.Float64() < p
+}
+
+// A pipeDelivery is a set of messages delivered by a Pipe with Latency at
+// time at.
+type pipeDelivery struct {
+	at time.Time
+	ms []pipeMessage
+}
+
+// A pipeConn is one end of a Pipe.
+type pipeConn struct {
+	addr net.IP
+	peer *pipeConn
+	rx   chan pipeMessage
+
+	// closed is shared by both ends of the Pipe.
+	closed chan struct{}
+	once   *sync.Once
+
+	// faults, if set, is shared by both ends of the Pipe. held holds
+	// messages which are delivered after the next message written, guarded
+	// by faults.mu. delayed queues messages until the Latency has passed.
+	faults  *pipeFaults
+	held    []pipeMessage
+	delayed chan pipeDelivery
+
+	// changed is closed and replaced when the deadline is set, to wake up
+	// a pending read.
+	mu       sync.Mutex
+	deadline time.Time
+	changed  chan struct{}
+}
+
+// A pipeMessage is a packet in flight between the ends of a Pipe.
+type pipeMessage struct {
+	b   []byte
+	src net.IP
+	dst net.IP
+}
+
+// newPipeConn creates one end of a Pipe.
+func newPipeConn(addr net.IP, closed chan struct{}, once *sync.Once, f *pipeFaults) *pipeConn {
+	c := &pipeConn{
+		addr:    addr,
+		rx:      make(chan pipeMessage, pipeBuffer),
+		closed:  closed,
+		once:    once,
+		faults:  f,
+		changed: make(chan struct{}),
+	}
+
+	if f != nil && f.cfg.Latency > 0 {
+		c.delayed = make(chan pipeDelivery, pipeBuffer)
+		go c.deliverDelayed()
+	}
+
+	return c
+}
+
+// ReadFrom implements PacketConn.
+func (c *pipeConn) ReadFrom() (Packet, *ipv6.ControlMessage, *net.IPAddr, error) {
+	for {
+		m, err := c.next()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if m == nil {
+			// The deadline changed.
+			continue
+		}
+
+		p, err := ParsePacket(m.b)
+		if err != nil {
+			// Discard invalid packets as a Conn would.
+			continue
+		}
+
+		cm := &ipv6.ControlMessage{
+			TrafficClass: tclass,
+			HopLimit:     hopLimit,
+			Src:          m.src,
+			Dst:          m.dst,
+		}
+
+		return p, cm, &net.IPAddr{IP: m.src}, nil
+	}
+}
+
+// next waits for the next message until the read deadline. It returns a nil
+// message and error if the deadline is changed while waiting.
+func (c *pipeConn) next() (*pipeMessage, error) {
+	c.mu.Lock()
+	deadline, changed := c.deadline, c.changed
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return nil, c.opError("read", os.ErrDeadlineExceeded)
+		}
+
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case <-c.closed:
+		return nil, c.opError("read", net.ErrClosed)
+	case <-timeout:
+		return nil, c.opError("read", os.ErrDeadlineExceeded)
+	case <-changed:
+		return nil, nil
+	case m := <-c.rx:
+		return &m, nil
+	}
+}
+
+// WriteTo implements PacketConn.
+func (c *pipeConn) WriteTo(p Packet, dst *net.IPAddr) error {
+	select {
+	case <-c.closed:
+		return c.opError("write", net.ErrClosed)
+	default:
+	}
+
+	b, err := MarshalPacket(p)
+	if err != nil {
+		return err
+	}
+
+	ms := c.inject(pipeMessage{b: b, src: c.addr, dst: dst.IP})
+	if len(ms) == 0 {
+		return nil
+	}
+
+	if c.delayed == nil {
+		c.deliver(ms)
+		return nil
+	}
+
+	select {
+	case c.delayed <- pipeDelivery{at: time.Now().Add(c.faults.cfg.Latency), ms: ms}:
+	default:
+		// Too many packets are in flight, so drop them.
+	}
+
+	return nil
+}
+
+// inject applies the Pipe's faults to m and returns the messages to deliver.
+func (c *pipeConn) inject(m pipeMessage) []pipeMessage {
+	f := c.faults
+	if f == nil {
+		return []pipeMessage{m}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.chance(f.cfg.Loss) {
+		return nil
+	}
+
+	ms := []pipeMessage{m}
+	if f.chance(f.cfg.Duplicate) {
+		ms = append(ms, m)
+	}
+
+	if c.held == nil && f.chance(f.cfg.Reorder) {
+		c.held = ms
+		return nil
+	}
+
+	ms = append(ms, c.held...)
+	c.held = nil
+	return ms
+}
+
+// deliver sends ms to the peer.
+func (c *pipeConn) deliver(ms []pipeMessage) {
+	for _, m := range ms {
+		select {
+		case c.peer.rx <- m:
+		default:
+			// The peer's buffer is full, so drop the packet.
+		}
+	}
+}
+
+// deliverDelayed delivers messages once the Pipe's Latency has passed, until
+// the Pipe is closed.
+func (c *pipeConn) deliverDelayed() {
+	for {
+		var d pipeDelivery
+		select {
+		case <-c.closed:
+			return
+		case d = <-c.delayed:
+		}
+
+		t := time.NewTimer(time.Until(d.at))
+		select {
+		case <-c.closed:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		c.deliver(d.ms)
+	}
+}
+
+// SetReadDeadline implements PacketConn.
+func (c *pipeConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deadline = t
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return nil
+}
+
+// Close implements PacketConn. Closing either end closes the Pipe.
+func (c *pipeConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// opError wraps err in a *net.OpError for operation op.
+func (c *pipeConn) opError(op string, err error) error {
+	return &net.OpError{
+		Op:   op,
+		Net:  "ospf3-pipe",
+		Addr: &net.IPAddr{IP: c.addr},
+		Err:  err,
+	}
+}
@@ -0,0 +1,180 @@
+package ospf3
+
+import (
+	"errors"
+	"math"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPipe(t *testing.T) {
+	a, b := Pipe()
+	defer a.Close()
+
+	h := &Hello{
+		Header:      Header{RouterID: ID{192, 0, 2, 1}},
+		InterfaceID: 1,
+		NeighborIDs: []ID{},
+	}
+	if err := a.WriteTo(h, AllSPFRouters); err != nil {
+		t.Fatalf("failed to write Hello: %v", err)
+	}
+
+	p, cm, src, err := b.ReadFrom()
+	if err != nil {
+		t.Fatalf("failed to read Packet: %v", err)
+	}
+
+	if diff := cmp.Diff(h, p); diff != "" {
+		t.Fatalf("unexpected Packet (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("fe80::1", src.String()); diff != "" {
+		t.Fatalf("unexpected source address (-want +got):\n%s", diff)
+	}
+	if !cm.Dst.Equal(AllSPFRouters.IP) || cm.HopLimit != hopLimit {
+		t.Fatalf("unexpected control message: %+v", cm)
+	}
+
+	// Invalid Packets can't be written.
+	if err := a.WriteTo(&DatabaseDescription{Flags: IBit}, AllSPFRouters); err == nil {
+		t.Fatal("expected an error writing an invalid DatabaseDescription, but none occurred")
+	}
+}
+
+func TestPipeDeadline(t *testing.T) {
+	a, b := Pipe()
+	defer a.Close()
+
+	if err := b.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+	if _, _, _, err := b.ReadFrom(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, but got: %v", err)
+	}
+
+	// Setting a deadline in the past interrupts a pending read.
+	if err := b.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("failed to clear deadline: %v", err)
+	}
+
+	errC := make(chan error, 1)
+	go func() {
+		_, _, _, err := b.ReadFrom()
+		errC <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := b.SetReadDeadline(time.Now()); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+	if err := <-errC; !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, but got: %v", err)
+	}
+}
+
+func TestPipeClose(t *testing.T) {
+	a, b := Pipe()
+	if err := a.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("failed to close again: %v", err)
+	}
+
+	if _, _, _, err := b.ReadFrom(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("expected closed error reading, but got: %v", err)
+	}
+	if err := b.WriteTo(&Hello{}, AllSPFRouters); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("expected closed error writing, but got: %v", err)
+	}
+}
+
+func TestNewPipeFaults(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  PipeConfig
+		want []uint32
+	}{
+		{
+			name: "loss",
+			cfg:  PipeConfig{Loss: 1},
+		},
+		{
+			name: "duplicate",
+			cfg:  PipeConfig{Duplicate: 1},
+			want: []uint32{1, 1, 2, 2},
+		},
+		{
+			name: "reorder",
+			cfg:  PipeConfig{Reorder: 1},
+			want: []uint32{2, 1},
+		},
+		{
+			name: "latency",
+			cfg:  PipeConfig{Latency: 20 * time.Millisecond},
+			want: []uint32{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b, err := NewPipe(&tt.cfg)
+			if err != nil {
+				t.Fatalf("failed to create Pipe: %v", err)
+			}
+			defer a.Close()
+
+			start := time.Now()
+			for _, id := range []uint32{1, 2} {
+				h := &Hello{
+					Header:      Header{RouterID: ID{192, 0, 2, 1}},
+					InterfaceID: id,
+				}
+				if err := a.WriteTo(h, AllSPFRouters); err != nil {
+					t.Fatalf("failed to write Hello: %v", err)
+				}
+			}
+
+			if err := b.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+				t.Fatalf("failed to set deadline: %v", err)
+			}
+
+			var got []uint32
+			for {
+				p, _, _, err := b.ReadFrom()
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("failed to read Packet: %v", err)
+				}
+
+				got = append(got, p.(*Hello).InterfaceID)
+				if len(got) == 1 && time.Since(start) < tt.cfg.Latency {
+					t.Fatalf("Packet arrived before Latency %v", tt.cfg.Latency)
+				}
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("unexpected Hello interface IDs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewPipeErrors(t *testing.T) {
+	for _, cfg := range []PipeConfig{
+		{Loss: -0.1},
+		{Duplicate: 1.1},
+		{Reorder: math.NaN()},
+		{Latency: -1},
+	} {
+		if _, _, err := NewPipe(&cfg); err == nil {
+			t.Fatalf("expected an error for %+v, but none occurred", cfg)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// prefixLen is the length of a Prefix with no trailing address.
+const prefixLen = 4
+
+// PrefixOptions is a bitmask of options which may accompany a Prefix as
+// described in RFC5340, appendix A.4.1.1.
+type PrefixOptions uint8
+
+// Possible PrefixOptions values.
+const (
+	NUBit      PrefixOptions = 1 << 0
+	LABit      PrefixOptions = 1 << 1
+	xPrefixBit PrefixOptions = 1 << 2
+	PBit       PrefixOptions = 1 << 3
+	DNBit      PrefixOptions = 1 << 4
+)
+
+// String returns the string representation of a PrefixOptions bitmask.
+func (o PrefixOptions) String() string {
+	return flagsString(uint(o), []string{
+		"NU-bit",
+		"LA-bit",
+		"x-bit",
+		"P-bit",
+		"DN-bit",
+	})
+}
+
+// A Prefix is an IPv6 address prefix carried in an LSA body as described in
+// RFC5340, appendix A.4.1. Use PrefixFrom and Prefix.IPPrefix to convert
+// to and from a netip.Prefix.
+//
+// Instances of an IPv4 AddressFamily carry IPv4 prefixes of up to 32 bits
+// using the same encoding, as described in RFC5838. Address is
+// always 16 bytes, and an IPv4 address occupies its first 4 bytes. Use
+// PrefixFromIPv4 and Prefix.IPv4Prefix to convert to and from an IPv4
+// netip.Prefix.
+//
+// Within an LSA, the 16 bits following a Prefix's options are interpreted
+// differently depending on the LSA type. Prefix.MarshalBinary sets these bits
+// to zero and Prefix.UnmarshalBinary ignores them.
+type Prefix struct {
+	Length  uint8
+	Options PrefixOptions
+	Address net.IP
+}
+
+// PrefixFrom creates a Prefix from an IPv6 netip.Prefix and PrefixOptions. Any
+// host bits set in p are cleared.
+func PrefixFrom(p netip.Prefix, options PrefixOptions) (Prefix, error) {
+	if !p.IsValid() || !p.Addr().Is6() || p.Addr().Zone() != "" {
+		return Prefix{}, fmt.Errorf("ospf3: %v is not a valid IPv6 prefix", p)
+	}
+
+	a := p.Masked().Addr().As16()
+	return Prefix{
+		Length:  uint8(p.Bits()),
+		Options: options,
+		Address: net.IP(a[:]),
+	}, nil
+}
+
+// IPPrefix converts a Prefix to a netip.Prefix. Any host bits set in the
+// Prefix's Address are cleared.
+func (p Prefix) IPPrefix() (netip.Prefix, error) {
+	a, ok := netip.AddrFromSlice(p.Address)
+	if !ok || len(p.Address) != net.IPv6len {
+		return netip.Prefix{}, fmt.Errorf("ospf3: prefix address %v is not a 16 byte IPv6 address", p.Address)
+	}
+	if p.Length > 128 {
+		return netip.Prefix{}, fmt.Errorf("ospf3: prefix length %d is too long for an IPv6 prefix", p.Length)
+	}
+
+	return netip.PrefixFrom(a, int(p.Length)).Masked(), nil
+}
+
+// PrefixFromIPv4 creates a Prefix for an IPv4 AddressFamily from an IPv4
+// netip.Prefix and PrefixOptions. Any host bits set in p are cleared.
+func PrefixFromIPv4(p netip.Prefix, options PrefixOptions) (Prefix, error) {
+	if !p.IsValid() || !p.Addr().Is4() {
+		return Prefix{}, fmt.Errorf("ospf3: %v is not a valid IPv4 prefix", p)
+	}
+
+	a := p.Masked().Addr().As4()
+	addr := make(net.IP, net.IPv6len)
+	copy(addr, a[:])
+
+	return Prefix{
+		Length:  uint8(p.Bits()),
+		Options: options,
+		Address: addr,
+	}, nil
+}
+
+// IPv4Prefix converts a Prefix carried in the LSAs of an IPv4 AddressFamily
+// to an IPv4 netip.Prefix. Any host bits set in the Prefix's Address are
+// cleared.
+func (p Prefix) IPv4Prefix() (netip.Prefix, error) {
+	if len(p.Address) != net.IPv6len {
+		return netip.Prefix{}, fmt.Errorf("ospf3: prefix address %v is not a 16 byte address", p.Address)
+	}
+	if p.Length > 32 {
+		return netip.Prefix{}, fmt.Errorf("ospf3: prefix length %d is too long for an IPv4 prefix", p.Length)
+	}
+
+	var a [4]byte
+	copy(a[:], p.Address[:4])
+
+	return netip.PrefixFrom(netip.AddrFrom4(a), int(p.Length)).Masked(), nil
+}
+
+// HostPrefix creates a /128 Prefix for a router's own IPv6 address. The LA-bit
+// is set so that the address may be used to reach the router, as described
+// in RFC5340, appendix A.4.1.1, unless anycast is true. An anycast address is
+// shared by several routers and does not identify any one of them, so it is
+// advertised as an ordinary host route instead. The NU-bit is never set.
+func HostPrefix(addr netip.Addr, anycast bool) (Prefix, error) {
+	if !addr.Is6() || addr.Is4In6() {
+		return Prefix{}, fmt.Errorf("ospf3: %v is not a valid IPv6 address", addr)
+	}
+
+	var options PrefixOptions
+	if !anycast {
+		options = LABit
+	}
+
+	return PrefixFrom(netip.PrefixFrom(addr.WithZone(""), 128), options)
+}
+
+// Unicast reports whether a Prefix should be included in unicast routing
+// calculations, which is true unless the NU-bit is set.
+func (p Prefix) Unicast() bool { return p.Options&NUBit == 0 }
+
+// LocalAddress returns a router's own IPv6 address if the Prefix is a /128
+// with the LA-bit set. The LA-bit is ignored on any other Prefix, which
+// cannot identify a single address.
+func (p Prefix) LocalAddress() (netip.Addr, bool) {
+	if p.Options&LABit == 0 || p.Length != 128 {
+		return netip.Addr{}, false
+	}
+
+	a, ok := netip.AddrFromSlice(p.Address)
+	if !ok || !a.Is6() {
+		return netip.Addr{}, false
+	}
+
+	return a, true
+}
+
+// MarshalBinary packs a Prefix into bytes.
+func (p Prefix) MarshalBinary() ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("ospf3: %w", err)
+	}
+
+	b := make([]byte, p.len())
+	p.marshal(b, 0)
+	return b, nil
+}
+
+// UnmarshalBinary unpacks a Prefix from bytes. b must contain exactly one
+// Prefix.
+func (p *Prefix) UnmarshalBinary(b []byte) error {
+	pp, _, n, err := parsePrefix(b)
+	if err != nil {
+		return fmt.Errorf("ospf3: %w", err)
+	}
+	if l := len(b); l != n {
+		return fmt.Errorf("ospf3: prefix must be exactly %d bytes, got %d bytes: %w", n, l, errParse)
+	}
+
+	*p = pp
+	return nil
+}
+
+// addrLen returns the number of bytes used to encode the Prefix's address,
+// which is padded to a 32-bit word boundary.
+func (p Prefix) addrLen() int { return 4 * ((int(p.Length) + 31) / 32) }
+
+// validate checks if the Prefix can be marshaled.
+func (p Prefix) validate() error {
+	if p.Length > 128 {
+		return fmt.Errorf("prefix length %d is too long for an IPv6 prefix: %w", p.Length, errMarshal)
+	}
+	if p.Length > 0 && (len(p.Address) != net.IPv6len) {
+		return fmt.Errorf("prefix address %v must be a 16 byte IPv6 address: %w", p.Address, errMarshal)
+	}
+
+	return nil
+}
+
+// len returns the length of the encoded Prefix in bytes.
+func (p Prefix) len() int { return prefixLen + p.addrLen() }
+
+// marshal stores the Prefix bytes into b, with v occupying the 16 bits which
+// are interpreted differently per LSA. It assumes the Prefix has been
+// validated and b has allocated enough space to avoid a panic.
+func (p Prefix) marshal(b []byte, v uint16) {
+	b[0] = p.Length
+	b[1] = byte(p.Options)
+	binary.BigEndian.PutUint16(b[2:4], v)
+	copy(b[prefixLen:p.len()], p.Address)
+}
+
+// parsePrefix unpacks a Prefix from b, where the first 4 bytes contain the
+// fixed length prefix fields and the address follows after them. The middle 16
+// bits are interpreted differently per LSA and are returned to the caller
+// along with the total number of bytes consumed.
+func parsePrefix(b []byte) (Prefix, uint16, int, error) {
+	if l := len(b); l < prefixLen {
+		return Prefix{}, 0, 0, fmt.Errorf("not enough bytes for prefix: %d: %w", l, errParse)
+	}
+
+	p := Prefix{
+		Length:  b[0],
+		Options: PrefixOptions(b[1]),
+	}
+	if p.Length > 128 {
+		return Prefix{}, 0, 0, fmt.Errorf("prefix length %d is too long for an IPv6 prefix: %w", p.Length, errParse)
+	}
+
+	n := prefixLen + p.addrLen()
+	if l := len(b); l < n {
+		return Prefix{}, 0, 0, fmt.Errorf("prefix requires %d bytes but only %d bytes are available: %w", n, l, errParse)
+	}
+
+	p.Address = make(net.IP, net.IPv6len)
+	copy(p.Address, b[prefixLen:n])
+
+	return p, binary.BigEndian.Uint16(b[2:4]), n, nil
+}
+
+// parsePrefixes parses exactly n adjacent Prefixes which must consume all of
+// b, calling fn with each Prefix and the 16 bits following its options.
+func parsePrefixes(b []byte, n uint32, fn func(p Prefix, v uint16)) error {
+	// Each Prefix is at least 4 bytes, so sanity check n before parsing.
+	if l := len(b); uint64(n)*prefixLen > uint64(l) {
+		return fmt.Errorf("%d prefixes indicated but only %d bytes are available: %w", n, l, errParse)
+	}
+
+	off := 0
+	for i := uint32(0); i < n; i++ {
+		p, v, nn, err := parsePrefix(b[off:])
+		if err != nil {
+			return err
+		}
+
+		fn(p, v)
+		off += nn
+	}
+
+	if off != len(b) {
+		return fmt.Errorf("%d trailing bytes after %d prefixes: %w", len(b)-off, n, errParse)
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package ospf3
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestPrefixRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   netip.Prefix
+		opts PrefixOptions
+		b    []byte
+		p    Prefix
+	}{
+		{
+			name: "default",
+			ip:   netip.MustParsePrefix("::/0"),
+			b:    []byte{0, 0x00, 0x00, 0x00},
+			p: Prefix{
+				Address: net.IPv6zero,
+			},
+		},
+		{
+			name: "/48",
+			ip:   netip.MustParsePrefix("2001:db8:1::/48"),
+			opts: PBit,
+			b: []byte{
+				48, byte(PBit), 0x00, 0x00,
+				0x20, 0x01, 0x0d, 0xb8,
+				0x00, 0x01, 0x00, 0x00,
+			},
+			p: Prefix{
+				Length:  48,
+				Options: PBit,
+				Address: net.ParseIP("2001:db8:1::"),
+			},
+		},
+		{
+			name: "/128",
+			ip:   netip.MustParsePrefix("2001:db8::1/128"),
+			opts: LABit,
+			b: merge(
+				[]byte{128, byte(LABit), 0x00, 0x00},
+				net.ParseIP("2001:db8::1"),
+			),
+			p: Prefix{
+				Length:  128,
+				Options: LABit,
+				Address: net.ParseIP("2001:db8::1"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := PrefixFrom(tt.ip, tt.opts)
+			if err != nil {
+				t.Fatalf("failed to convert from netip.Prefix: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.p, p); diff != "" {
+				t.Fatalf("unexpected Prefix (-want +got):\n%s", diff)
+			}
+
+			b, err := p.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.b, b); diff != "" {
+				t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+			}
+
+			var p2 Prefix
+			if err := p2.UnmarshalBinary(b); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+
+			ip, err := p2.IPPrefix()
+			if err != nil {
+				t.Fatalf("failed to convert to netip.Prefix: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.ip.String(), ip.String()); diff != "" {
+				t.Fatalf("unexpected netip.Prefix (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrefixFromMasked(t *testing.T) {
+	p, err := PrefixFrom(netip.MustParsePrefix("2001:db8::1/64"), 0)
+	if err != nil {
+		t.Fatalf("failed to convert from netip.Prefix: %v", err)
+	}
+
+	if diff := cmp.Diff(net.ParseIP("2001:db8::"), p.Address); diff != "" {
+		t.Fatalf("unexpected address (-want +got):\n%s", diff)
+	}
+}
+
+func TestHostPrefix(t *testing.T) {
+	addr := netip.MustParseAddr("2001:db8::1")
+
+	tests := []struct {
+		name    string
+		anycast bool
+		want    Prefix
+		local   bool
+	}{
+		{
+			name:  "local",
+			want:  Prefix{Length: 128, Options: LABit, Address: net.ParseIP("2001:db8::1")},
+			local: true,
+		},
+		{
+			name:    "anycast",
+			anycast: true,
+			want:    Prefix{Length: 128, Address: net.ParseIP("2001:db8::1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := HostPrefix(addr, tt.anycast)
+			if err != nil {
+				t.Fatalf("failed to create host prefix: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, p); diff != "" {
+				t.Fatalf("unexpected Prefix (-want +got):\n%s", diff)
+			}
+			if !p.Unicast() {
+				t.Fatal("host prefix must be included in unicast routing")
+			}
+
+			a, ok := p.LocalAddress()
+			if diff := cmp.Diff(tt.local, ok); diff != "" {
+				t.Fatalf("unexpected local address result (-want +got):\n%s", diff)
+			}
+			if ok && a != addr {
+				t.Fatalf("unexpected local address: %v", a)
+			}
+		})
+	}
+
+	for _, a := range []netip.Addr{
+		{},
+		netip.MustParseAddr("192.0.2.1"),
+		netip.MustParseAddr("::ffff:192.0.2.1"),
+	} {
+		if _, err := HostPrefix(a, false); err == nil {
+			t.Fatalf("expected an error creating host prefix for %v, but none occurred", a)
+		}
+	}
+}
+
+func TestPrefixOptionsInterpretation(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Prefix
+		unicast bool
+		local   bool
+	}{
+		{
+			name:    "LA-bit host",
+			p:       Prefix{Length: 128, Options: LABit, Address: net.ParseIP("2001:db8::1")},
+			unicast: true,
+			local:   true,
+		},
+		{
+			name:    "LA-bit on subnet is ignored",
+			p:       Prefix{Length: 64, Options: LABit, Address: net.ParseIP("2001:db8::")},
+			unicast: true,
+		},
+		{
+			name:  "NU-bit",
+			p:     Prefix{Length: 128, Options: NUBit | LABit, Address: net.ParseIP("2001:db8::1")},
+			local: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.unicast, tt.p.Unicast()); diff != "" {
+				t.Fatalf("unexpected unicast result (-want +got):\n%s", diff)
+			}
+
+			_, ok := tt.p.LocalAddress()
+			if diff := cmp.Diff(tt.local, ok); diff != "" {
+				t.Fatalf("unexpected local address result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrefixConversionErrors(t *testing.T) {
+	for _, ip := range []netip.Prefix{
+		{},
+		netip.MustParsePrefix("192.0.2.0/24"),
+	} {
+		if _, err := PrefixFrom(ip, 0); err == nil {
+			t.Fatalf("expected an error converting %v, but none occurred", ip)
+		}
+	}
+
+	for _, p := range []Prefix{
+		{},
+		{Length: 129, Address: net.IPv6zero},
+		{Length: 24, Address: net.IPv4(192, 0, 2, 0).To4()},
+	} {
+		if _, err := p.IPPrefix(); err == nil {
+			t.Fatalf("expected an error converting %+v, but none occurred", p)
+		}
+	}
+}
+
+func TestPrefixUnmarshalBinaryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{
+			name: "short",
+			b:    []byte{0x00, 0x00, 0x00},
+		},
+		{
+			name: "bad length",
+			b:    []byte{129, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "short address",
+			b:    []byte{64, 0x00, 0x00, 0x00, 0x20, 0x01, 0x0d, 0xb8},
+		},
+		{
+			name: "trailing",
+			b:    []byte{0, 0x00, 0x00, 0x00, 0xff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Prefix
+			err := p.UnmarshalBinary(tt.b)
+			if diff := cmp.Diff(errParse, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrefixIPv4RoundTrip(t *testing.T) {
+	ip := netip.MustParsePrefix("192.0.2.1/24")
+
+	p, err := PrefixFromIPv4(ip, LABit)
+	if err != nil {
+		t.Fatalf("failed to convert from netip.Prefix: %v", err)
+	}
+
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	want := []byte{24, byte(LABit), 0x00, 0x00, 192, 0, 2, 0}
+	if diff := cmp.Diff(want, b); diff != "" {
+		t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+	}
+
+	var p2 Prefix
+	if err := p2.UnmarshalBinary(b); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	got, err := p2.IPv4Prefix()
+	if err != nil {
+		t.Fatalf("failed to convert to netip.Prefix: %v", err)
+	}
+
+	if diff := cmp.Diff(ip.Masked().String(), got.String()); diff != "" {
+		t.Fatalf("unexpected netip.Prefix (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrefixIPv4ConversionErrors(t *testing.T) {
+	for _, ip := range []netip.Prefix{
+		{},
+		netip.MustParsePrefix("2001:db8::/32"),
+	} {
+		if _, err := PrefixFromIPv4(ip, 0); err == nil {
+			t.Fatalf("expected an error converting %v, but none occurred", ip)
+		}
+	}
+
+	for _, p := range []Prefix{
+		{},
+		{Length: 33, Address: net.IPv6zero},
+		{Length: 24, Address: net.IPv4(192, 0, 2, 0).To4()},
+	} {
+		if _, err := p.IPv4Prefix(); err == nil {
+			t.Fatalf("expected an error converting %+v, but none occurred", p)
+		}
+	}
+}
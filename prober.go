@@ -0,0 +1,111 @@
+package ospf3
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// A Prober discovers OSPFv3 routers on a link by periodically sending Hello
+// packets and collecting the Hello packets sent by other routers.
+//
+// A Prober never lists any neighbor IDs in its own Hellos, so no neighbor will
+// ever consider the Prober's router ID to have bidirectional communication and
+// no adjacency will be formed.
+type Prober struct {
+	// Atomics must come first.
+	sendErrors uint64
+
+	c PacketConn
+	h Hello
+}
+
+// A ProbeResult is the most recent Hello received from a router during a
+// probe, along with its source address.
+type ProbeResult struct {
+	Address *net.IPAddr
+	Hello   *Hello
+}
+
+// NewProber creates a Prober which sends Hellos using the parameters in h over
+// c, with the HelloInterval and RouterDeadInterval of t. t must satisfy
+// Timers.Validate, and its HelloInterval sets the interval between sent
+// Hellos. h.NeighborIDs is ignored.
+func NewProber(c PacketConn, h *Hello, t Timers) (*Prober, error) {
+	if h == nil {
+		return nil, errors.New("ospf3: Prober Hello must not be nil")
+	}
+
+	// Copy h so the caller can't modify it during a probe.
+	hh := *h
+	hh.NeighborIDs = nil
+	if err := WithTimers(t)(&hh); err != nil {
+		return nil, err
+	}
+
+	return &Prober{
+		c: c,
+		h: hh,
+	}, nil
+}
+
+// Probe sends Hellos and collects Hellos from other routers until ctx is
+// canceled, and then returns the most recent Hello from each router, sorted
+// by router ID. Hellos which cannot be sent are counted by SendErrors and do
+// not stop the probe.
+func (p *Prober) Probe(ctx context.Context) ([]ProbeResult, error) {
+	seen := make(map[ID]ProbeResult)
+	l := &helloLoop{
+		c:          p.c,
+		interval:   p.h.HelloInterval,
+		now:        time.Now,
+		sendErrors: &p.sendErrors,
+		hello:      func() *Hello { return &p.h },
+		receive: func(h *Hello, src *net.IPAddr) {
+			// Ignore Hellos from this router.
+			if h.Header.RouterID == p.h.Header.RouterID {
+				return
+			}
+
+			seen[h.Header.RouterID] = ProbeResult{
+				Address: src,
+				Hello:   h,
+			}
+		},
+	}
+
+	if err := l.run(ctx); err != nil {
+		return nil, err
+	}
+
+	return probeResults(seen), nil
+}
+
+// SendErrors returns the number of Hellos which Probe could not send.
+func (p *Prober) SendErrors() uint64 {
+	return atomic.LoadUint64(&p.sendErrors)
+}
+
+// probeResults produces sorted ProbeResults from a map.
+func probeResults(m map[ID]ProbeResult) []ProbeResult {
+	rs := make([]ProbeResult, 0, len(m))
+	for _, r := range m {
+		rs = append(rs, r)
+	}
+
+	sort.Slice(rs, func(i, j int) bool {
+		a, b := rs[i].Hello.Header.RouterID, rs[j].Hello.Header.RouterID
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+
+		return false
+	})
+
+	return rs
+}
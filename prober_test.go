@@ -0,0 +1,95 @@
+package ospf3
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProber(t *testing.T) {
+	c1, c2 := testConns(t, nil)
+	testProber(t, c1, c2)
+}
+
+func TestProberPipe(t *testing.T) {
+	c1, c2 := Pipe()
+	defer c1.Close()
+
+	testProber(t, c1, c2)
+}
+
+// testProber runs a Prober on each of c1 and c2 and verifies that each
+// discovers the other.
+func testProber(t *testing.T, c1, c2 PacketConn) {
+	t.Helper()
+
+	var (
+		id1 = ID{192, 0, 2, 1}
+		id2 = ID{192, 0, 2, 2}
+	)
+
+	newProber := func(c PacketConn, id ID) *Prober {
+		p, err := NewProber(c, &Hello{
+			Header: Header{RouterID: id},
+			// Must be ignored.
+			NeighborIDs: []ID{{192, 0, 2, 255}},
+		}, testTimers())
+		if err != nil {
+			t.Fatalf("failed to create Prober: %v", err)
+		}
+
+		return p
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	// Probe concurrently from each side of the link so that each Prober
+	// discovers the other.
+	var (
+		wg      sync.WaitGroup
+		results [2][]ProbeResult
+		errs    [2]error
+	)
+	for i, p := range []*Prober{newProber(c1, id1), newProber(c2, id2)} {
+		wg.Add(1)
+		go func(i int, p *Prober) {
+			defer wg.Done()
+			results[i], errs[i] = p.Probe(ctx)
+		}(i, p)
+	}
+	wg.Wait()
+
+	for i, want := range []ID{id2, id1} {
+		if errs[i] != nil {
+			t.Fatalf("failed to probe: %v", errs[i])
+		}
+
+		if len(results[i]) != 1 {
+			t.Fatalf("expected 1 result, but got: %d", len(results[i]))
+		}
+
+		r := results[i][0]
+		if diff := cmp.Diff(want, r.Hello.Header.RouterID); diff != "" {
+			t.Fatalf("unexpected router ID (-want +got):\n%s", diff)
+		}
+		if len(r.Hello.NeighborIDs) != 0 {
+			t.Fatalf("Prober advertised neighbor IDs: %v", r.Hello.NeighborIDs)
+		}
+		if r.Address == nil || !r.Address.IP.IsLinkLocalUnicast() {
+			t.Fatalf("unexpected source address: %v", r.Address)
+		}
+	}
+}
+
+func TestNewProberErrors(t *testing.T) {
+	if _, err := NewProber(nil, nil, testTimers()); err == nil {
+		t.Fatal("expected an error for nil Hello, but none occurred")
+	}
+	if _, err := NewProber(nil, &Hello{}, Timers{}); err == nil {
+		t.Fatal("expected an error for invalid Timers, but none occurred")
+	}
+}
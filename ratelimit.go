@@ -0,0 +1,276 @@
+package ospf3
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRateLimited is returned by Conn.WriteTo and Conn.WriteBatch when a packet
+// is dropped because it exceeds Config.SendLimit or
+// Config.SendLimitPerDestination.
+var ErrRateLimited = errors.New("ospf3: packet dropped by send rate limit")
+
+// A RateLimit configures a token bucket which limits the rate of packets sent
+// by a Conn, such as to keep retransmissions or large floods from saturating a
+// slow link.
+type RateLimit struct {
+	// Rate is the sustained number of packets per second. It must be
+	// positive.
+	Rate float64
+
+	// Burst is the number of packets which may be sent at once before Rate
+	// applies. If zero, 1 is used.
+	Burst int
+
+	// MaxDelay is the longest a write will wait for the bucket to permit a
+	// packet. Packets which would wait longer are dropped, and
+	// ErrRateLimited is returned. If zero, packets are dropped rather than
+	// delayed.
+	MaxDelay time.Duration
+}
+
+// RateLimitStats contains the number of packets affected by a Conn's send
+// rate limits.
+type RateLimitStats struct {
+	// Delayed counts packets which were sent after waiting for a rate limit.
+	Delayed uint64
+
+	// Dropped counts packets which were not sent because they would have
+	// exceeded MaxDelay.
+	Dropped uint64
+}
+
+// maxBuckets is the number of per-destination buckets after which idle
+// buckets are removed.
+const maxBuckets = 256
+
+// A rateLimiter applies the overall and per-destination RateLimits of a Conn.
+type rateLimiter struct {
+	// Counters are accessed atomically and must be the first fields for
+	// 64-bit alignment.
+	stats RateLimitStats
+
+	now func() time.Time
+
+	mu      sync.Mutex
+	all     *tokenBucket
+	perDst  *RateLimit
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter creates a rateLimiter with the specified limits and time
+// source, or returns nil if neither limit is set.
+func newRateLimiter(all, perDst *RateLimit, now func() time.Time) (*rateLimiter, error) {
+	if all == nil && perDst == nil {
+		return nil, nil
+	}
+
+	for _, l := range []*RateLimit{all, perDst} {
+		if l == nil {
+			continue
+		}
+
+		if l.Rate <= 0 || math.IsInf(l.Rate, 0) || math.IsNaN(l.Rate) || l.Burst < 0 || l.MaxDelay < 0 {
+			return nil, fmt.Errorf("ospf3: invalid RateLimit: %+v", *l)
+		}
+	}
+
+	r := &rateLimiter{
+		now:     now,
+		perDst:  perDst,
+		buckets: make(map[string]*tokenBucket),
+	}
+	if all != nil {
+		r.all = newTokenBucket(*all, now())
+	}
+
+	return r, nil
+}
+
+// wait takes a token for a packet to each of dsts from each applicable
+// bucket, waiting until every packet is permitted. It returns ErrRateLimited
+// if the packets must be dropped instead, in which case no tokens are taken.
+func (r *rateLimiter) wait(dsts ...*net.IPAddr) error {
+	ds, err := r.reserveAll(dsts)
+	if err != nil {
+		atomic.AddUint64(&r.stats.Dropped, uint64(len(dsts)))
+		return err
+	}
+
+	var max time.Duration
+	for _, d := range ds {
+		if d > 0 {
+			atomic.AddUint64(&r.stats.Delayed, 1)
+		}
+		if d > max {
+			max = d
+		}
+	}
+
+	if max > 0 {
+		time.Sleep(max)
+	}
+
+	return nil
+}
+
+// reserve takes a token for a packet to dst from each applicable bucket, and
+// returns the delay before the packet may be sent. No tokens are taken if
+// ErrRateLimited is returned.
+func (r *rateLimiter) reserve(dst *net.IPAddr) (time.Duration, error) {
+	ds, err := r.reserveAll([]*net.IPAddr{dst})
+	if err != nil {
+		return 0, err
+	}
+
+	return ds[0], nil
+}
+
+// reserveAll takes a token for a packet to each of dsts in turn, and returns
+// the delay before each packet may be sent. If any packet is not permitted, no
+// tokens are taken and ErrRateLimited is returned.
+func (r *rateLimiter) reserveAll(dsts []*net.IPAddr) ([]time.Duration, error) {
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Record the state of each bucket before its first token is taken so
+	// that it can be restored if a later packet is not permitted.
+	type state struct {
+		tokens float64
+		last   time.Time
+	}
+	var (
+		saved   = make(map[*tokenBucket]state)
+		created []string
+	)
+
+	rollback := func() {
+		for b, s := range saved {
+			b.tokens, b.last = s.tokens, s.last
+		}
+		for _, k := range created {
+			delete(r.buckets, k)
+		}
+	}
+
+	ds := make([]time.Duration, 0, len(dsts))
+	for _, dst := range dsts {
+		var bs []*tokenBucket
+		if r.all != nil {
+			bs = append(bs, r.all)
+		}
+		if r.perDst != nil {
+			key := dst.String()
+			b, ok := r.buckets[key]
+			if !ok {
+				if len(r.buckets) >= maxBuckets {
+					r.prune(now)
+				}
+
+				b = newTokenBucket(*r.perDst, now)
+				r.buckets[key] = b
+				created = append(created, key)
+			}
+			bs = append(bs, b)
+		}
+
+		// The packet must be permitted by every bucket before any tokens
+		// are taken.
+		var delay time.Duration
+		for _, b := range bs {
+			d := b.delay(now)
+			if d > b.limit.MaxDelay {
+				rollback()
+				return nil, ErrRateLimited
+			}
+			if d > delay {
+				delay = d
+			}
+		}
+
+		for _, b := range bs {
+			if _, ok := saved[b]; !ok {
+				saved[b] = state{tokens: b.tokens, last: b.last}
+			}
+			b.take(now)
+		}
+
+		ds = append(ds, delay)
+	}
+
+	return ds, nil
+}
+
+// prune removes per-destination buckets which have refilled completely, as
+// they behave the same as a new bucket.
+func (r *rateLimiter) prune(now time.Time) {
+	for k, b := range r.buckets {
+		b.fill(now)
+		if b.tokens >= b.burst {
+			delete(r.buckets, k)
+		}
+	}
+}
+
+// snapshot returns the rateLimiter's statistics.
+func (r *rateLimiter) snapshot() RateLimitStats {
+	return RateLimitStats{
+		Delayed: atomic.LoadUint64(&r.stats.Delayed),
+		Dropped: atomic.LoadUint64(&r.stats.Dropped),
+	}
+}
+
+// A tokenBucket is a single token bucket. Its tokens may become negative when
+// packets are permitted to wait for a future token.
+type tokenBucket struct {
+	limit  RateLimit
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full tokenBucket for l at time now.
+func newTokenBucket(l RateLimit, now time.Time) *tokenBucket {
+	burst := float64(l.Burst)
+	if burst == 0 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		limit:  l,
+		burst:  burst,
+		tokens: burst,
+		last:   now,
+	}
+}
+
+// fill adds the tokens accumulated since the last fill.
+func (b *tokenBucket) fill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// delay returns the time until a token is available.
+func (b *tokenBucket) delay(now time.Time) time.Duration {
+	b.fill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// take removes a token.
+func (b *tokenBucket) take(now time.Time) {
+	b.fill(now)
+	b.tokens--
+}
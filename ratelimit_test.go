@@ -0,0 +1,172 @@
+package ospf3
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_rateLimiterReserve(t *testing.T) {
+	var (
+		a = &net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}
+		b = &net.IPAddr{IP: net.ParseIP("fe80::2"), Zone: "eth0"}
+	)
+
+	type reserve struct {
+		at    time.Duration
+		dst   *net.IPAddr
+		delay time.Duration
+		drop  bool
+	}
+
+	tests := []struct {
+		name        string
+		all, perDst *RateLimit
+		reserves    []reserve
+	}{
+		{
+			name: "burst then drop",
+			all:  &RateLimit{Rate: 10, Burst: 2},
+			reserves: []reserve{
+				{dst: a},
+				{dst: b},
+				{dst: a, drop: true},
+				{at: 100 * time.Millisecond, dst: a},
+				{at: 100 * time.Millisecond, dst: b, drop: true},
+			},
+		},
+		{
+			name: "delay",
+			all:  &RateLimit{Rate: 10, MaxDelay: 150 * time.Millisecond},
+			reserves: []reserve{
+				{dst: a},
+				{dst: a, delay: 100 * time.Millisecond},
+				// The second token is already reserved.
+				{dst: a, drop: true},
+				{at: 50 * time.Millisecond, dst: a, delay: 150 * time.Millisecond},
+			},
+		},
+		{
+			name:   "per destination",
+			perDst: &RateLimit{Rate: 1},
+			reserves: []reserve{
+				{dst: a},
+				{dst: b},
+				{dst: a, drop: true},
+				{at: time.Second, dst: a},
+			},
+		},
+		{
+			name:   "overall and per destination",
+			all:    &RateLimit{Rate: 1, Burst: 2},
+			perDst: &RateLimit{Rate: 1, MaxDelay: time.Second},
+			reserves: []reserve{
+				{dst: a},
+				// Waits for the per-destination bucket.
+				{dst: a, delay: time.Second},
+				// The overall bucket is empty, and a dropped packet takes
+				// no tokens from b's bucket.
+				{dst: b, drop: true},
+				{at: time.Second, dst: b},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(0, 0)
+			now := start
+
+			r, err := newRateLimiter(tt.all, tt.perDst, func() time.Time { return now })
+			if err != nil {
+				t.Fatalf("failed to create rate limiter: %v", err)
+			}
+
+			for i, rr := range tt.reserves {
+				now = start.Add(rr.at)
+
+				d, err := r.reserve(rr.dst)
+				if rr.drop {
+					if err != ErrRateLimited {
+						t.Fatalf("%d: expected ErrRateLimited, but got: %v", i, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%d: failed to reserve: %v", i, err)
+				}
+
+				if diff := cmp.Diff(rr.delay, d); diff != "" {
+					t.Fatalf("%d: unexpected delay (-want +got):\n%s", i, diff)
+				}
+			}
+		})
+	}
+}
+
+func Test_rateLimiterWait(t *testing.T) {
+	r, err := newRateLimiter(&RateLimit{Rate: 1000, MaxDelay: time.Second}, nil, time.Now)
+	if err != nil {
+		t.Fatalf("failed to create rate limiter: %v", err)
+	}
+
+	// The first packet is sent immediately and the second after a short
+	// delay. The third is dropped immediately by a stricter limiter.
+	for i := 0; i < 2; i++ {
+		if err := r.wait(AllSPFRouters); err != nil {
+			t.Fatalf("failed to wait: %v", err)
+		}
+	}
+
+	if diff := cmp.Diff(RateLimitStats{Delayed: 1}, r.snapshot()); diff != "" {
+		t.Fatalf("unexpected stats (-want +got):\n%s", diff)
+	}
+}
+
+func Test_newRateLimiterErrors(t *testing.T) {
+	for _, l := range []*RateLimit{
+		{},
+		{Rate: -1},
+		{Rate: 1, Burst: -1},
+		{Rate: 1, MaxDelay: -1},
+	} {
+		if _, err := newRateLimiter(l, nil, time.Now); err == nil {
+			t.Fatalf("expected an error for %+v, but none occurred", *l)
+		}
+		if _, err := newRateLimiter(nil, l, time.Now); err == nil {
+			t.Fatalf("expected an error for per-destination %+v, but none occurred", *l)
+		}
+	}
+
+	r, err := newRateLimiter(nil, nil, time.Now)
+	if err != nil || r != nil {
+		t.Fatalf("expected no rate limiter, but got: %v, %v", r, err)
+	}
+}
+
+func Test_rateLimiterPrune(t *testing.T) {
+	now := time.Unix(0, 0)
+	r, err := newRateLimiter(nil, &RateLimit{Rate: 1}, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("failed to create rate limiter: %v", err)
+	}
+
+	for i := 0; i < maxBuckets; i++ {
+		dst := &net.IPAddr{IP: net.IPv6loopback, Zone: string(rune('a' + i))}
+		if _, err := r.reserve(dst); err != nil {
+			t.Fatalf("failed to reserve: %v", err)
+		}
+	}
+
+	// Once every bucket has refilled, adding another prunes them all.
+	now = now.Add(time.Second)
+	if _, err := r.reserve(AllSPFRouters); err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+
+	if diff := cmp.Diff(1, len(r.buckets)); diff != "" {
+		t.Fatalf("unexpected number of buckets (-want +got):\n%s", diff)
+	}
+}
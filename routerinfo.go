@@ -0,0 +1,247 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Router Information LSA TLV types as described in RFC7770, section 2.
+const (
+	tlvInformationalCapabilities = 1
+	tlvFunctionalCapabilities    = 2
+
+	// Each capabilities TLV carries at least one 32-bit bitmask.
+	capabilitiesLen = 4
+)
+
+// InformationalCapabilities is a bitmask of optional router capabilities
+// advertised in the Router Informational Capabilities TLV of a Router
+// Information LSA, as described in RFC7770, section 2.4. Bits are numbered
+// from the most significant bit, as in the RFC.
+type InformationalCapabilities uint32
+
+// Possible InformationalCapabilities values.
+const (
+	GracefulRestartCapable InformationalCapabilities = 1 << (31 - iota)
+	GracefulRestartHelper
+	StubRouterSupport
+	TrafficEngineeringSupport
+	PointToPointOverLAN
+	ExperimentalTE
+)
+
+// String returns the string representation of an InformationalCapabilities
+// bitmask.
+func (c InformationalCapabilities) String() string {
+	names := []string{
+		"GracefulRestartCapable",
+		"GracefulRestartHelper",
+		"StubRouterSupport",
+		"TrafficEngineeringSupport",
+		"PointToPointOverLAN",
+		"ExperimentalTE",
+	}
+
+	var ss []string
+	left := c
+	for i, name := range names {
+		if f := InformationalCapabilities(1) << (31 - i); c&f != 0 {
+			ss = append(ss, name)
+			left &^= f
+		}
+	}
+
+	if left != 0 || len(ss) == 0 {
+		ss = append(ss, fmt.Sprintf("%#x", uint32(left)))
+	}
+
+	return strings.Join(ss, "|")
+}
+
+// FunctionalCapabilities is a bitmask of functional router capabilities
+// advertised in the Router Functional Capabilities TLV of a Router Information
+// LSA, as described in RFC7770, section 2.5. RFC7770 does not define any
+// values.
+type FunctionalCapabilities uint32
+
+// A RouterInformationLSABody is the body of an OSPFv3 Router Information LSA
+// as described in RFC7770, section 2.2. The Router Information LSA may be
+// originated with link-local, area, or AS flooding scope, using the
+// LinkRouterInformationLSA, AreaRouterInformationLSA, and
+// ASRouterInformationLSA LSTypes, respectively.
+//
+// The capabilities TLVs are only marshaled when the corresponding field is
+// nonzero or TLVs contains another instance of the TLV. Only the first 32 bits of each capabilities TLV are interpreted.
+type RouterInformationLSABody struct {
+	InformationalCapabilities InformationalCapabilities
+	FunctionalCapabilities    FunctionalCapabilities
+
+	// Segment Routing capabilities as described in RFC8666, section 3.
+	// SRAlgorithms, if set, is encoded as an SR-Algorithm TLV. Each
+	// SIDLabelRange and SRLocalBlock is encoded as a SID/Label Range TLV and
+	// SR Local Block TLV, respectively.
+	SRAlgorithms   []SRAlgorithm
+	SIDLabelRanges []SIDLabelRange
+	SRLocalBlocks  []SIDLabelRange
+
+	// TLVs contains any other TLVs in the order in which they appear, which
+	// are marshaled following all other TLVs.
+	TLVs []TLV
+}
+
+// MarshalBinary packs a RouterInformationLSABody into bytes.
+func (r *RouterInformationLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, r.len())
+	if err := r.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks a RouterInformationLSABody from bytes.
+func (r *RouterInformationLSABody) UnmarshalBinary(b []byte) error {
+	return r.unmarshal(b)
+}
+
+// tlvs returns all of the TLVs for the RouterInformationLSABody in marshaling
+// order.
+func (r *RouterInformationLSABody) tlvs() ([]TLV, error) {
+	tlvs := make([]TLV, 0, 3+len(r.SIDLabelRanges)+len(r.SRLocalBlocks)+len(r.TLVs))
+
+	for _, c := range []struct {
+		typ uint16
+		v   uint32
+	}{
+		{typ: tlvInformationalCapabilities, v: uint32(r.InformationalCapabilities)},
+		{typ: tlvFunctionalCapabilities, v: uint32(r.FunctionalCapabilities)},
+	} {
+		// A zero value is still marshaled if TLVs contains another
+		// instance of the TLV, which would otherwise be interpreted in its
+		// place when parsed.
+		if c.v == 0 && !r.hasTLV(c.typ) {
+			continue
+		}
+
+		v := make([]byte, capabilitiesLen)
+		binary.BigEndian.PutUint32(v, c.v)
+		tlvs = append(tlvs, TLV{Type: c.typ, Value: v})
+	}
+
+	if len(r.SRAlgorithms) > 0 {
+		v := make([]byte, 0, len(r.SRAlgorithms))
+		for _, a := range r.SRAlgorithms {
+			v = append(v, byte(a))
+		}
+
+		tlvs = append(tlvs, TLV{Type: tlvSRAlgorithm, Value: v})
+	}
+
+	for _, rs := range []struct {
+		typ    uint16
+		ranges []SIDLabelRange
+	}{
+		{typ: tlvSIDLabelRange, ranges: r.SIDLabelRanges},
+		{typ: tlvSRLocalBlock, ranges: r.SRLocalBlocks},
+	} {
+		for _, rr := range rs.ranges {
+			v, err := rr.marshal()
+			if err != nil {
+				return nil, fmt.Errorf("Router Information LSA %w", err)
+			}
+
+			tlvs = append(tlvs, TLV{Type: rs.typ, Value: v})
+		}
+	}
+
+	return append(tlvs, r.TLVs...), nil
+}
+
+// len returns the length of a RouterInformationLSABody in bytes.
+func (r *RouterInformationLSABody) len() int {
+	// Invalid bodies are reported by marshal.
+	tlvs, _ := r.tlvs()
+	return tlvsLen(tlvs)
+}
+
+// marshal stores the RouterInformationLSABody bytes into b. It assumes b has
+// allocated enough space for a RouterInformationLSABody to avoid a panic.
+func (r *RouterInformationLSABody) marshal(b []byte) error {
+	tlvs, err := r.tlvs()
+	if err != nil {
+		return err
+	}
+
+	if err := marshalTLVs(b, tlvs); err != nil {
+		return fmt.Errorf("Router Information LSA %w", err)
+	}
+
+	return nil
+}
+
+// hasTLV reports whether r.TLVs contains a TLV of type typ.
+func (r *RouterInformationLSABody) hasTLV(typ uint16) bool {
+	for _, t := range r.TLVs {
+		if t.Type == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unmarshal unpacks a RouterInformationLSABody from b.
+func (r *RouterInformationLSABody) unmarshal(b []byte) error {
+	*r = RouterInformationLSABody{}
+
+	var info, fn bool
+	return parseTLVs(b, func(typ uint16, v []byte) error {
+		// Only the first instance of each capabilities and SR-Algorithm TLV is
+		// interpreted.
+		switch {
+		case typ == tlvInformationalCapabilities && !info:
+			if l := len(v); l < capabilitiesLen {
+				return fmt.Errorf("not enough bytes for Router Informational Capabilities TLV: %d: %w", l, errParse)
+			}
+
+			r.InformationalCapabilities = InformationalCapabilities(binary.BigEndian.Uint32(v))
+			info = true
+		case typ == tlvFunctionalCapabilities && !fn:
+			if l := len(v); l < capabilitiesLen {
+				return fmt.Errorf("not enough bytes for Router Functional Capabilities TLV: %d: %w", l, errParse)
+			}
+
+			r.FunctionalCapabilities = FunctionalCapabilities(binary.BigEndian.Uint32(v))
+			fn = true
+		case typ == tlvSRAlgorithm && r.SRAlgorithms == nil:
+			// An empty SR-Algorithm TLV could not be marshaled again.
+			if len(v) == 0 {
+				return fmt.Errorf("SR-Algorithm TLV contains no algorithms: %w", errParse)
+			}
+
+			r.SRAlgorithms = make([]SRAlgorithm, 0, len(v))
+			for _, a := range v {
+				r.SRAlgorithms = append(r.SRAlgorithms, SRAlgorithm(a))
+			}
+		case typ == tlvSIDLabelRange, typ == tlvSRLocalBlock:
+			var rr SIDLabelRange
+			if err := rr.unmarshal(v); err != nil {
+				return err
+			}
+
+			if typ == tlvSIDLabelRange {
+				r.SIDLabelRanges = append(r.SIDLabelRanges, rr)
+			} else {
+				r.SRLocalBlocks = append(r.SRLocalBlocks, rr)
+			}
+		default:
+			r.TLVs = append(r.TLVs, TLV{
+				Type:  typ,
+				Value: append([]byte(nil), v...),
+			})
+		}
+
+		return nil
+	})
+}
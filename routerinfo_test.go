@@ -0,0 +1,137 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	bufRouterInformationLSABody = []byte{
+		0x00, 0x01, 0x00, 0x04, // Router Informational Capabilities TLV
+		0xc0, 0x00, 0x00, 0x00, // GR capable, GR helper
+		0x00, 0x02, 0x00, 0x04, // Router Functional Capabilities TLV
+		0x00, 0x00, 0x00, 0x01,
+		0x80, 0x00, 0x00, 0x03, // Unknown TLV
+		0xde, 0xad, 0xbe, 0x00, // Value and padding
+	}
+
+	lsaRouterInformationLSABody = &RouterInformationLSABody{
+		InformationalCapabilities: GracefulRestartCapable | GracefulRestartHelper,
+		FunctionalCapabilities:    1,
+		TLVs: []TLV{{
+			Type:  0x8000,
+			Value: []byte{0xde, 0xad, 0xbe},
+		}},
+	}
+)
+
+func TestRouterInformationLSABodyParse(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+		body *RouterInformationLSABody
+	}{
+		{
+			name: "empty",
+			b:    []byte{},
+			body: &RouterInformationLSABody{},
+		},
+		{
+			name: "long capabilities",
+			// Only the first 32 bits are interpreted.
+			b: []byte{
+				0x00, 0x01, 0x00, 0x08,
+				0x20, 0x00, 0x00, 0x00,
+				0xff, 0xff, 0xff, 0xff,
+			},
+			body: &RouterInformationLSABody{
+				InformationalCapabilities: StubRouterSupport,
+			},
+		},
+		{
+			name: "duplicate capabilities",
+			// Only the first instance is interpreted.
+			b: []byte{
+				0x00, 0x02, 0x00, 0x04,
+				0x00, 0x00, 0x00, 0x01,
+				0x00, 0x02, 0x00, 0x04,
+				0x00, 0x00, 0x00, 0x02,
+			},
+			body: &RouterInformationLSABody{
+				FunctionalCapabilities: 1,
+				TLVs: []TLV{{
+					Type:  tlvFunctionalCapabilities,
+					Value: []byte{0x00, 0x00, 0x00, 0x02},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, typ := range []LSType{LinkRouterInformationLSA, AreaRouterInformationLSA, ASRouterInformationLSA} {
+				body, err := ParseLSABody(typ, tt.b)
+				if err != nil {
+					t.Fatalf("failed to parse %s: %v", typ, err)
+				}
+
+				if diff := cmp.Diff(tt.body, body); diff != "" {
+					t.Fatalf("unexpected %s body (-want +got):\n%s", typ, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterInformationLSABodyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{
+			name: "short TLV header",
+			b:    []byte{0x00, 0x01, 0x00},
+		},
+		{
+			name: "short TLV value",
+			b:    []byte{0x80, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "short informational capabilities",
+			b:    []byte{0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "short functional capabilities",
+			b:    []byte{0x00, 0x02, 0x00, 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseLSABody(AreaRouterInformationLSA, tt.b); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+func TestInformationalCapabilitiesString(t *testing.T) {
+	tests := []struct {
+		c InformationalCapabilities
+		s string
+	}{
+		{s: "0x0"},
+		{c: GracefulRestartCapable, s: "GracefulRestartCapable"},
+		{
+			c: GracefulRestartHelper | ExperimentalTE | 1,
+			s: "GracefulRestartHelper|ExperimentalTE|0x1",
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.s, tt.c.String()); diff != "" {
+			t.Fatalf("unexpected string (-want +got):\n%s", diff)
+		}
+	}
+}
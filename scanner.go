@@ -0,0 +1,114 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A Scanner reads back-to-back OSPFv3 packets from a byte stream, such as a
+// file dump or a tunneled capture, and parses them one at a time. Packets are
+// framed using the packet length field of each Header, so an LLS data block or
+// Authentication Trailer following a packet cannot be represented in the
+// stream.
+//
+// Successive calls to Scan step through the packets in the stream. Scanning
+// stops at the end of the stream or at the first error. A malformed Header
+// makes the remainder of the stream impossible to frame, so the error is
+// returned by Err and no further packets are read.
+type Scanner struct {
+	// Options modify the behavior of packet parsing as described in
+	// ParseOptions.
+	Options ParseOptions
+
+	r   io.Reader
+	b   []byte
+	p   Packet
+	err error
+}
+
+// NewScanner creates a Scanner which reads OSPFv3 packets from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{r: r}
+}
+
+// Scan advances the Scanner to the next packet, which is then available
+// through the Packet and Bytes methods. It returns false when scanning stops,
+// either by reaching the end of the stream or an error. After Scan returns
+// false, Err returns any error which occurred, except that it returns nil if
+// the stream ended cleanly between packets.
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	s.p = nil
+	if cap(s.b) < headerLen {
+		s.b = make([]byte, headerLen)
+	}
+	s.b = s.b[:headerLen]
+
+	if _, err := io.ReadFull(s.r, s.b); err != nil {
+		// A clean EOF between packets ends the stream and is not reported by
+		// Err.
+		if err != io.EOF {
+			err = fmt.Errorf("ospf3: failed to read Header: %w", err)
+		}
+		s.err = err
+		return false
+	}
+
+	// Only the version and length are needed to frame the packet, and full
+	// validation is left to parsing.
+	if v := s.b[0]; v != version {
+		s.err = parseError(0, "Header", fmt.Errorf("unrecognized OSPF version: %d: %w", v, errParse))
+		return false
+	}
+	plen := int(binary.BigEndian.Uint16(s.b[2:4]))
+	if plen < headerLen {
+		s.err = parseError(0, "Header", fmt.Errorf("header packet length %d is too short for a valid packet: %w", plen, errParse))
+		return false
+	}
+
+	if cap(s.b) < plen {
+		b := make([]byte, plen)
+		copy(b, s.b)
+		s.b = b
+	}
+	s.b = s.b[:plen]
+
+	if _, err := io.ReadFull(s.r, s.b[headerLen:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		s.err = fmt.Errorf("ospf3: failed to read %d byte packet: %w", plen, err)
+		return false
+	}
+
+	p, err := s.Options.ParsePacket(s.b)
+	if err != nil {
+		s.err = err
+		return false
+	}
+
+	s.p = p
+	return true
+}
+
+// Packet returns the Packet parsed by the most recent call to Scan.
+func (s *Scanner) Packet() Packet { return s.p }
+
+// Bytes returns the bytes of the packet read by the most recent call to Scan.
+// The underlying array may be overwritten by a subsequent call to Scan.
+func (s *Scanner) Bytes() []byte { return s.b }
+
+// Err returns the first error encountered by the Scanner, or nil if the stream
+// ended cleanly between packets.
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+
+	return s.err
+}
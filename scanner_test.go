@@ -0,0 +1,123 @@
+package ospf3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScanner(t *testing.T) {
+	var (
+		stream bytes.Buffer
+		want   []Packet
+	)
+
+	for _, p := range []Packet{
+		pktHello,
+		pktDatabaseDescription,
+		pktLinkStateRequest,
+		pktLinkStateUpdate,
+		pktLinkStateAcknowledgement,
+	} {
+		b, err := MarshalPacket(p)
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+
+		stream.Write(b)
+		want = append(want, p)
+	}
+
+	// Read one byte at a time to verify that partial reads are handled.
+	s := NewScanner(iotest.OneByteReader(&stream))
+
+	var got []Packet
+	for s.Scan() {
+		got = append(got, s.Packet())
+
+		b, err := MarshalPacket(s.Packet())
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		if diff := cmp.Diff(b, s.Bytes()); diff != "" {
+			t.Fatalf("unexpected packet bytes (-want +got):\n%s", diff)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected Packets (-want +got):\n%s", diff)
+	}
+}
+
+func TestScannerErrors(t *testing.T) {
+	valid := trimmed(bufHello)
+
+	tests := []struct {
+		name string
+		b    []byte
+		ok   int
+		is   error
+	}{
+		{
+			name: "truncated Header",
+			b:    append(append([]byte(nil), valid...), valid[:headerLen-1]...),
+			ok:   1,
+			is:   io.ErrUnexpectedEOF,
+		},
+		{
+			name: "truncated packet",
+			b:    valid[:len(valid)-1],
+			is:   io.ErrUnexpectedEOF,
+		},
+		{
+			name: "bad version",
+			b:    append([]byte{0x02}, valid[1:]...),
+			is:   errParse,
+		},
+		{
+			name: "short length",
+			b:    merge([]byte{version, uint8(hello), 0x00, headerLen - 1}, bufHeaderCommon),
+			is:   errParse,
+		},
+		{
+			name: "malformed packet",
+			b: merge(
+				[]byte{version, uint8(hello), 0x00, headerLen + 4},
+				bufHeaderCommon,
+				[]byte{0x00, 0x00, 0x00, 0x00},
+				valid,
+			),
+			is: errParse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanner(bytes.NewReader(tt.b))
+
+			var n int
+			for s.Scan() {
+				n++
+			}
+
+			if diff := cmp.Diff(tt.ok, n); diff != "" {
+				t.Fatalf("unexpected number of packets (-want +got):\n%s", diff)
+			}
+			if err := s.Err(); !errors.Is(err, tt.is) {
+				t.Fatalf("expected %v, but got: %v", tt.is, err)
+			}
+
+			// Scanning must not resume after an error.
+			if s.Scan() {
+				t.Fatal("scanned a packet after an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package ospf3
+
+import (
+	"net"
+
+	"golang.org/x/net/bpf"
+	"golang.org/x/net/ipv6"
+)
+
+// checksumOffset is the offset of the checksum in the OSPFv3 header, used for
+// checksum offload.
+const checksumOffset = 12
+
+// A socketOptions can set the IPv6 socket options used by a Conn. It is
+// implemented by *ipv6.PacketConn.
+type socketOptions interface {
+	SetControlMessage(cf ipv6.ControlFlags, on bool) error
+	SetChecksum(on bool, offset int) error
+	SetTrafficClass(tclass int) error
+	SetHopLimit(hoplim int) error
+	SetMulticastHopLimit(hoplim int) error
+	SetMulticastInterface(ifi *net.Interface) error
+	JoinGroup(ifi *net.Interface, group net.Addr) error
+	SetMulticastLoopback(on bool) error
+	SetBPF(filter []bpf.RawInstruction) error
+}
+
+var _ socketOptions = &ipv6.PacketConn{}
+
+// A socketConfig contains the parameters for configureSocket.
+type socketConfig struct {
+	ifi     *net.Interface
+	nt      NetworkType
+	unicast bool
+
+	// auth indicates that checksums are computed in userspace along with
+	// the Authentication Trailer.
+	auth bool
+
+	filter []bpf.RawInstruction
+}
+
+// configureSocket applies the OSPFv3 socket options described by cfg to so. It
+// returns the multicast groups which were joined, and whether checksums must
+// be computed and verified in userspace.
+func configureSocket(so socketOptions, cfg socketConfig) ([]*net.IPAddr, bool, error) {
+	// Attach the filter before joining any groups so that unwanted packets
+	// are never queued.
+	if cfg.filter != nil {
+		if err := so.SetBPF(cfg.filter); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Return all possible control message information to the caller so they
+	// can make more informed choices.
+	if err := so.SetControlMessage(^ipv6.ControlFlags(0), true); err != nil {
+		return nil, false, err
+	}
+
+	// Process checksums in the OSPFv3 header, unless they are computed and
+	// verified with the Authentication Trailer. The kernel would otherwise
+	// compute the checksum over the trailer as well.
+	userCk := cfg.auth
+	if err := so.SetChecksum(!userCk, checksumOffset); err != nil {
+		if userCk {
+			return nil, false, err
+		}
+
+		// Some platforms don't support checksum offload for raw sockets, so
+		// compute and verify checksums in userspace instead.
+		userCk = true
+	}
+
+	// Set IPv6 header parameters per the RFC.
+	if err := so.SetTrafficClass(tclass); err != nil {
+		return nil, false, err
+	}
+
+	if cfg.unicast {
+		return nil, userCk, nil
+	}
+
+	if err := so.SetHopLimit(hopLimit); err != nil {
+		return nil, false, err
+	}
+	if err := so.SetMulticastHopLimit(hopLimit); err != nil {
+		return nil, false, err
+	}
+
+	// Join the appropriate multicast groups. Note that point-to-point and
+	// point-to-multipoint networks don't use DR/BDR and can skip joining that
+	// group.
+	if err := so.SetMulticastInterface(cfg.ifi); err != nil {
+		return nil, false, err
+	}
+
+	groups := []*net.IPAddr{AllSPFRouters}
+	if cfg.nt == BroadcastNetwork {
+		groups = append(groups, AllDRouters)
+	}
+
+	for _, g := range groups {
+		if err := so.JoinGroup(cfg.ifi, g); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Don't read our own multicast packets during concurrent read/write.
+	if err := so.SetMulticastLoopback(false); err != nil {
+		return nil, false, err
+	}
+
+	return groups, userCk, nil
+}
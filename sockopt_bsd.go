@@ -0,0 +1,49 @@
+//go:build freebsd || openbsd
+
+package ospf3
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenPacket opens a raw IPv6 PacketConn for network, bound to the VRF vrf
+// if set. On the BSDs, a VRF is identified by the number of a FIB or routing
+// table, set with soVRF.
+func listenPacket(network, vrf string) (net.PacketConn, error) {
+	var lc net.ListenConfig
+	if vrf != "" {
+		table, err := strconv.Atoi(vrf)
+		if err != nil || table < 0 {
+			return nil, fmt.Errorf("ospf3: VRF must be a routing table number on %s: %q", runtime.GOOS, vrf)
+		}
+
+		lc.Control = func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) {
+				serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, soVRF, table)
+			}); err != nil {
+				return err
+			}
+			if serr != nil {
+				return fmt.Errorf("ospf3: failed to bind to VRF %q: %w", vrf, serr)
+			}
+
+			return nil
+		}
+	}
+
+	return lc.ListenPacket(context.Background(), network, "::")
+}
+
+// inNamespace always returns an error as network namespaces are not supported
+// on this platform.
+func inNamespace(_ string, _ func() error) error {
+	return fmt.Errorf("ospf3: network namespaces are not supported on %s", runtime.GOOS)
+}
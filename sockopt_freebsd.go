@@ -0,0 +1,6 @@
+package ospf3
+
+import "golang.org/x/sys/unix"
+
+// soVRF selects a FIB for a socket.
+const soVRF = unix.SO_SETFIB
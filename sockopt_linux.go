@@ -0,0 +1,79 @@
+//go:build linux
+
+package ospf3
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenPacket opens a raw IPv6 PacketConn for network, bound to the VRF device
+// vrf if set.
+func listenPacket(network, vrf string) (net.PacketConn, error) {
+	var lc net.ListenConfig
+	if vrf != "" {
+		lc.Control = func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) {
+				serr = unix.BindToDevice(int(fd), vrf)
+			}); err != nil {
+				return err
+			}
+			if serr != nil {
+				return fmt.Errorf("ospf3: failed to bind to VRF %q: %w", vrf, serr)
+			}
+
+			return nil
+		}
+	}
+
+	return lc.ListenPacket(context.Background(), network, "::")
+}
+
+// inNamespace calls fn in the network namespace at path. Sockets opened by fn
+// remain in that namespace after it returns.
+func inNamespace(path string, fn func() error) error {
+	ns, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("ospf3: failed to open network namespace: %w", err)
+	}
+	defer ns.Close()
+
+	// Namespaces apply to a single thread, so fn runs on a new goroutine which
+	// is locked to its thread. If the original namespace can't be restored,
+	// the thread remains locked and exits with the goroutine rather than
+	// running other goroutines in the wrong namespace.
+	errC := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+
+		orig, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+		if err != nil {
+			runtime.UnlockOSThread()
+			errC <- err
+			return
+		}
+		defer orig.Close()
+
+		if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			errC <- fmt.Errorf("ospf3: failed to enter network namespace %q: %w", path, err)
+			return
+		}
+
+		err = fn()
+		if serr := unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET); serr == nil {
+			runtime.UnlockOSThread()
+		}
+
+		errC <- err
+	}()
+
+	return <-errC
+}
@@ -0,0 +1,51 @@
+//go:build linux
+
+package ospf3
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestListenPacketVRF(t *testing.T) {
+	c, err := listenPacket("ip6:89", "lo")
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			t.Skip("skipping, permission denied while trying to open raw socket")
+		}
+
+		t.Fatalf("failed to listen bound to device: %v", err)
+	}
+	_ = c.Close()
+
+	if _, err := listenPacket("ip6:89", "ospf3-nonexistent"); !errors.Is(err, unix.ENODEV) {
+		t.Fatalf("expected ENODEV for nonexistent device, but got: %v", err)
+	}
+}
+
+func TestInNamespace(t *testing.T) {
+	wantErr := errors.New("fn error")
+
+	// Entering the current namespace exercises the same code paths.
+	var called bool
+	err := inNamespace("/proc/self/ns/net", func() error {
+		called = true
+		return wantErr
+	})
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, unix.EPERM) {
+		t.Skipf("skipping, permission denied while trying to enter network namespace: %v", err)
+	}
+	if !called {
+		t.Fatalf("fn was not called: %v", err)
+	}
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("expected fn error, but got: %v", err)
+	}
+
+	if err := inNamespace("/ospf3-nonexistent", func() error { return nil }); err == nil {
+		t.Fatal("expected an error for nonexistent namespace, but none occurred")
+	}
+}
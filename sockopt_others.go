@@ -0,0 +1,25 @@
+//go:build !linux && !freebsd && !openbsd
+
+package ospf3
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// listenPacket opens a raw IPv6 PacketConn for network. VRFs are not
+// supported on this platform.
+func listenPacket(network, vrf string) (net.PacketConn, error) {
+	if vrf != "" {
+		return nil, fmt.Errorf("ospf3: VRF is not supported on %s", runtime.GOOS)
+	}
+
+	return net.ListenPacket(network, "::")
+}
+
+// inNamespace always returns an error as network namespaces are not supported
+// on this platform.
+func inNamespace(_ string, _ func() error) error {
+	return fmt.Errorf("ospf3: network namespaces are not supported on %s", runtime.GOOS)
+}
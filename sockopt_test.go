@@ -0,0 +1,174 @@
+package ospf3
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/bpf"
+	"golang.org/x/net/ipv6"
+)
+
+func Test_configureSocket(t *testing.T) {
+	ifi := &net.Interface{Index: 1, Name: "eth0"}
+
+	tests := []struct {
+		name       string
+		cfg        socketConfig
+		noChecksum bool
+		calls      []string
+		groups     []*net.IPAddr
+		userCk     bool
+	}{
+		{
+			name: "broadcast",
+			cfg:  socketConfig{ifi: ifi, nt: BroadcastNetwork},
+			calls: []string{
+				"control", "checksum true 12", "tclass 0xc0",
+				"hoplimit 1", "multicast hoplimit 1", "multicast interface eth0",
+				"join ff02::5", "join ff02::6", "loopback false",
+			},
+			groups: []*net.IPAddr{AllSPFRouters, AllDRouters},
+		},
+		{
+			name: "point-to-point",
+			cfg:  socketConfig{ifi: ifi, nt: PointToPointNetwork},
+			calls: []string{
+				"control", "checksum true 12", "tclass 0xc0",
+				"hoplimit 1", "multicast hoplimit 1", "multicast interface eth0",
+				"join ff02::5", "loopback false",
+			},
+			groups: []*net.IPAddr{AllSPFRouters},
+		},
+		{
+			name:   "unicast authenticated",
+			cfg:    socketConfig{ifi: ifi, unicast: true, auth: true},
+			calls:  []string{"control", "checksum false 12", "tclass 0xc0"},
+			userCk: true,
+		},
+		{
+			name:       "no checksum offload",
+			cfg:        socketConfig{ifi: ifi, unicast: true},
+			noChecksum: true,
+			calls:      []string{"control", "checksum true 12", "tclass 0xc0"},
+			userCk:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			so := &fakeSocket{noChecksum: tt.noChecksum}
+			groups, userCk, err := configureSocket(so, tt.cfg)
+			if err != nil {
+				t.Fatalf("failed to configure socket: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.calls, so.calls); diff != "" {
+				t.Fatalf("unexpected socket options (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.groups, groups); diff != "" {
+				t.Fatalf("unexpected groups (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.userCk, userCk); diff != "" {
+				t.Fatalf("unexpected userspace checksum (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_configureSocketErrors(t *testing.T) {
+	ifi := &net.Interface{Index: 1, Name: "eth0"}
+
+	tests := []struct {
+		name string
+		cfg  socketConfig
+		so   *fakeSocket
+	}{
+		{
+			name: "checksum with authentication",
+			cfg:  socketConfig{ifi: ifi, auth: true},
+			so:   &fakeSocket{noChecksum: true},
+		},
+		{
+			name: "join group",
+			cfg:  socketConfig{ifi: ifi, nt: BroadcastNetwork},
+			so:   &fakeSocket{failJoin: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := configureSocket(tt.so, tt.cfg); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+var errFakeSocket = errors.New("fake socket error")
+
+// A fakeSocket is a socketOptions which records the options that are set.
+type fakeSocket struct {
+	noChecksum, failJoin bool
+	calls                []string
+}
+
+func (s *fakeSocket) record(format string, v ...interface{}) {
+	s.calls = append(s.calls, fmt.Sprintf(format, v...))
+}
+
+func (s *fakeSocket) SetControlMessage(_ ipv6.ControlFlags, _ bool) error {
+	s.record("control")
+	return nil
+}
+
+func (s *fakeSocket) SetChecksum(on bool, offset int) error {
+	s.record("checksum %t %d", on, offset)
+	if s.noChecksum {
+		// Disabling checksum offload always fails as well.
+		return errFakeSocket
+	}
+
+	return nil
+}
+
+func (s *fakeSocket) SetTrafficClass(tclass int) error {
+	s.record("tclass %#x", tclass)
+	return nil
+}
+
+func (s *fakeSocket) SetHopLimit(hoplim int) error {
+	s.record("hoplimit %d", hoplim)
+	return nil
+}
+
+func (s *fakeSocket) SetMulticastHopLimit(hoplim int) error {
+	s.record("multicast hoplimit %d", hoplim)
+	return nil
+}
+
+func (s *fakeSocket) SetMulticastInterface(ifi *net.Interface) error {
+	s.record("multicast interface %s", ifi.Name)
+	return nil
+}
+
+func (s *fakeSocket) JoinGroup(_ *net.Interface, group net.Addr) error {
+	if s.failJoin {
+		return errFakeSocket
+	}
+
+	s.record("join %s", group)
+	return nil
+}
+
+func (s *fakeSocket) SetMulticastLoopback(on bool) error {
+	s.record("loopback %t", on)
+	return nil
+}
+
+func (s *fakeSocket) SetBPF(filter []bpf.RawInstruction) error {
+	s.record("bpf %d", len(filter))
+	return nil
+}
@@ -0,0 +1,352 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Segment Routing TLV types in the Router Information LSA as described in
+// RFC8666, section 3.
+const (
+	tlvSRAlgorithm   = 8
+	tlvSIDLabelRange = 9
+	tlvSRLocalBlock  = 14
+)
+
+// Segment Routing sub-TLV types of the OSPFv3 Extended-LSA Sub-TLVs registry,
+// as described in RFC8666, section 8. Each type may be used with the value
+// produced by the MarshalBinary method of the corresponding type.
+const (
+	PrefixSIDSubTLV       uint16 = 4
+	AdjacencySIDSubTLV    uint16 = 5
+	LANAdjacencySIDSubTLV uint16 = 6
+	subTLVSIDLabel        uint16 = 7
+)
+
+// Fixed length Segment Routing structures.
+const (
+	sidRangeLen  = 4 // No trailing SID/Label sub-TLV.
+	prefixSIDLen = 4 // No trailing SID.
+	adjSIDLen    = 4 // No trailing SID.
+	lanAdjSIDLen = 8 // No trailing SID.
+
+	labelLen = 3
+	indexLen = 4
+
+	// maxLabel is the maximum value of a 20-bit MPLS label.
+	maxLabel = 0x000fffff
+)
+
+// An SRAlgorithm is a Segment Routing algorithm as described in RFC8665,
+// section 3.1.
+type SRAlgorithm uint8
+
+// Possible SRAlgorithm values.
+const (
+	SPFAlgorithm       SRAlgorithm = 0
+	StrictSPFAlgorithm SRAlgorithm = 1
+)
+
+// A SIDLabel is a Segment Routing SID, encoded as either a 32-bit index or a
+// 20-bit MPLS label.
+type SIDLabel struct {
+	Value uint32
+	Label bool
+}
+
+// len returns the length of a SIDLabel in bytes.
+func (s SIDLabel) len() int { return sidLen(s.Label) }
+
+// marshal packs a SIDLabel into b. It assumes b has allocated enough space for
+// the SIDLabel to avoid a panic.
+func (s SIDLabel) marshal(b []byte) error {
+	return putSID(b, s.Value, s.Label)
+}
+
+// A SIDLabelRange is a range of SIDs advertised in a SID/Label Range TLV or SR
+// Local Block TLV of a Router Information LSA, as described in RFC8666,
+// section 3.2.
+type SIDLabelRange struct {
+	// Size is the 24-bit number of SIDs in the range.
+	Size uint32
+
+	// First is the first SID in the range.
+	First SIDLabel
+}
+
+// marshal packs a SIDLabelRange into the value of a TLV.
+func (r SIDLabelRange) marshal() ([]byte, error) {
+	if r.Size > maxMetric {
+		return nil, fmt.Errorf("SID/Label range size %d does not fit in 24 bits: %w", r.Size, errMarshal)
+	}
+
+	sub := TLV{
+		Type:  subTLVSIDLabel,
+		Value: make([]byte, r.First.len()),
+	}
+	if err := r.First.marshal(sub.Value); err != nil {
+		return nil, err
+	}
+
+	// The 24-bit range size is followed by a reserved byte.
+	b := make([]byte, sidRangeLen+sub.len())
+	binary.BigEndian.PutUint32(b[0:4], r.Size<<8)
+	sub.marshal(b[sidRangeLen:])
+
+	return b, nil
+}
+
+// unmarshal unpacks a SIDLabelRange from the value of a TLV.
+func (r *SIDLabelRange) unmarshal(b []byte) error {
+	if l := len(b); l < sidRangeLen {
+		return fmt.Errorf("not enough bytes for SID/Label range: %d: %w", l, errParse)
+	}
+
+	*r = SIDLabelRange{Size: binary.BigEndian.Uint32(b[0:4]) >> 8}
+
+	var sid bool
+	err := parseTLVs(b[sidRangeLen:], func(typ uint16, v []byte) error {
+		// Any other sub-TLVs are ignored.
+		if typ != subTLVSIDLabel || sid {
+			return nil
+		}
+
+		s, label, err := parseSID(v)
+		if err != nil {
+			return err
+		}
+
+		r.First = SIDLabel{Value: s, Label: label}
+		sid = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !sid {
+		return fmt.Errorf("SID/Label range must contain a SID/Label sub-TLV: %w", errParse)
+	}
+
+	return nil
+}
+
+// PrefixSIDFlags is a bitmask of flags in a Prefix-SID sub-TLV as described in
+// RFC8666, section 5.
+type PrefixSIDFlags uint8
+
+// Possible PrefixSIDFlags values.
+const (
+	PrefixSIDLFlag  PrefixSIDFlags = 1 << 2
+	PrefixSIDVFlag  PrefixSIDFlags = 1 << 3
+	PrefixSIDEFlag  PrefixSIDFlags = 1 << 4
+	PrefixSIDMFlag  PrefixSIDFlags = 1 << 5
+	PrefixSIDNPFlag PrefixSIDFlags = 1 << 6
+)
+
+// String returns the string representation of a PrefixSIDFlags bitmask.
+func (f PrefixSIDFlags) String() string {
+	return flagsString(uint(f), []string{
+		"",
+		"",
+		"L-flag",
+		"V-flag",
+		"E-flag",
+		"M-flag",
+		"NP-flag",
+	})
+}
+
+// A PrefixSID is the value of a Prefix-SID sub-TLV as described in RFC8666,
+// section 5. If PrefixSIDLFlag is set, SID is a 20-bit MPLS label. Otherwise it
+// is a 32-bit index.
+type PrefixSID struct {
+	Flags     PrefixSIDFlags
+	Algorithm SRAlgorithm
+	SID       uint32
+}
+
+// MarshalBinary packs a PrefixSID into the value of a Prefix-SID sub-TLV.
+func (p *PrefixSID) MarshalBinary() ([]byte, error) {
+	label := p.Flags&PrefixSIDLFlag != 0
+
+	b := make([]byte, prefixSIDLen+sidLen(label))
+	b[0] = byte(p.Flags)
+	b[1] = byte(p.Algorithm)
+	// b[2:4] are reserved.
+	if err := putSID(b[prefixSIDLen:], p.SID, label); err != nil {
+		return nil, fmt.Errorf("ospf3: Prefix-SID %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks a PrefixSID from the value of a Prefix-SID sub-TLV.
+func (p *PrefixSID) UnmarshalBinary(b []byte) error {
+	if l := len(b); l < prefixSIDLen {
+		return fmt.Errorf("ospf3: not enough bytes for Prefix-SID: %d: %w", l, errParse)
+	}
+
+	sid, _, err := parseSID(b[prefixSIDLen:])
+	if err != nil {
+		return fmt.Errorf("ospf3: Prefix-SID %w", err)
+	}
+
+	*p = PrefixSID{
+		Flags:     PrefixSIDFlags(b[0]),
+		Algorithm: SRAlgorithm(b[1]),
+		SID:       sid,
+	}
+
+	return nil
+}
+
+// AdjacencySIDFlags is a bitmask of flags in an Adj-SID or LAN Adj-SID
+// sub-TLV as described in RFC8666, section 6.
+type AdjacencySIDFlags uint8
+
+// Possible AdjacencySIDFlags values.
+const (
+	AdjacencySIDPFlag AdjacencySIDFlags = 1 << 3
+	AdjacencySIDGFlag AdjacencySIDFlags = 1 << 4
+	AdjacencySIDLFlag AdjacencySIDFlags = 1 << 5
+	AdjacencySIDVFlag AdjacencySIDFlags = 1 << 6
+	AdjacencySIDBFlag AdjacencySIDFlags = 1 << 7
+)
+
+// String returns the string representation of an AdjacencySIDFlags bitmask.
+func (f AdjacencySIDFlags) String() string {
+	return flagsString(uint(f), []string{
+		"",
+		"",
+		"",
+		"P-flag",
+		"G-flag",
+		"L-flag",
+		"V-flag",
+		"B-flag",
+	})
+}
+
+// An AdjacencySID is the value of an Adj-SID sub-TLV, or a LAN Adj-SID sub-TLV
+// when NeighborID is set, as described in RFC8666, section 6. If
+// AdjacencySIDLFlag is set, SID is a 20-bit MPLS label. Otherwise it is a
+// 32-bit index.
+type AdjacencySID struct {
+	Flags  AdjacencySIDFlags
+	Weight uint8
+	SID    uint32
+
+	// NeighborID is the router ID of the neighbor on a LAN. It is only used
+	// for LAN Adj-SID sub-TLVs and must be zero for Adj-SID sub-TLVs.
+	NeighborID ID
+}
+
+// LAN reports whether the AdjacencySID is encoded as a LAN Adj-SID sub-TLV.
+func (a *AdjacencySID) LAN() bool { return a.NeighborID != ID{} }
+
+// MarshalBinary packs an AdjacencySID into the value of an Adj-SID sub-TLV, or
+// a LAN Adj-SID sub-TLV if NeighborID is set.
+func (a *AdjacencySID) MarshalBinary() ([]byte, error) {
+	label := a.Flags&AdjacencySIDLFlag != 0
+
+	n := adjSIDLen
+	if a.LAN() {
+		n = lanAdjSIDLen
+	}
+
+	b := make([]byte, n+sidLen(label))
+	b[0] = byte(a.Flags)
+	b[1] = a.Weight
+	// b[2:4] are reserved.
+	if a.LAN() {
+		copy(b[4:8], a.NeighborID[:])
+	}
+
+	if err := putSID(b[n:], a.SID, label); err != nil {
+		return nil, fmt.Errorf("ospf3: Adj-SID %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an AdjacencySID from the value of an Adj-SID
+// sub-TLV. Use UnmarshalLAN for LAN Adj-SID sub-TLVs.
+func (a *AdjacencySID) UnmarshalBinary(b []byte) error {
+	return a.unmarshal(b, false)
+}
+
+// UnmarshalLAN unpacks an AdjacencySID from the value of a LAN Adj-SID
+// sub-TLV.
+func (a *AdjacencySID) UnmarshalLAN(b []byte) error {
+	return a.unmarshal(b, true)
+}
+
+// unmarshal unpacks an AdjacencySID from b, which contains a neighbor ID if
+// lan is true.
+func (a *AdjacencySID) unmarshal(b []byte, lan bool) error {
+	n := adjSIDLen
+	if lan {
+		n = lanAdjSIDLen
+	}
+
+	if l := len(b); l < n {
+		return fmt.Errorf("ospf3: not enough bytes for Adj-SID: %d: %w", l, errParse)
+	}
+
+	sid, _, err := parseSID(b[n:])
+	if err != nil {
+		return fmt.Errorf("ospf3: Adj-SID %w", err)
+	}
+
+	*a = AdjacencySID{
+		Flags:  AdjacencySIDFlags(b[0]),
+		Weight: b[1],
+		SID:    sid,
+	}
+	if lan {
+		copy(a.NeighborID[:], b[4:8])
+	}
+
+	return nil
+}
+
+// sidLen returns the length of a SID encoded as a label or index.
+func sidLen(label bool) int {
+	if label {
+		return labelLen
+	}
+
+	return indexLen
+}
+
+// putSID packs a SID into b as a 20-bit label in 3 bytes or a 32-bit index in
+// 4 bytes. It assumes b has allocated enough space to avoid a panic.
+func putSID(b []byte, sid uint32, label bool) error {
+	if !label {
+		binary.BigEndian.PutUint32(b[:indexLen], sid)
+		return nil
+	}
+
+	if sid > maxLabel {
+		return fmt.Errorf("label %d does not fit in 20 bits: %w", sid, errMarshal)
+	}
+
+	b[0] = byte(sid >> 16)
+	b[1] = byte(sid >> 8)
+	b[2] = byte(sid)
+	return nil
+}
+
+// parseSID unpacks a SID from b, which must contain exactly a 3 byte label or a
+// 4 byte index.
+func parseSID(b []byte) (uint32, bool, error) {
+	switch len(b) {
+	case labelLen:
+		return (uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])) & maxLabel, true, nil
+	case indexLen:
+		return binary.BigEndian.Uint32(b), false, nil
+	default:
+		return 0, false, fmt.Errorf("SID must be a 3 byte label or 4 byte index, got %d bytes: %w", len(b), errParse)
+	}
+}
@@ -0,0 +1,225 @@
+package ospf3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRouterInformationLSABodySegmentRouting(t *testing.T) {
+	b := []byte{
+		0x00, 0x08, 0x00, 0x02, // SR-Algorithm TLV
+		0x00, 0x01, 0x00, 0x00, // SPF, strict SPF, padding
+		0x00, 0x09, 0x00, 0x0c, // SID/Label Range TLV
+		0x00, 0x1f, 0x40, 0x00, // Range size 8000, reserved
+		0x00, 0x07, 0x00, 0x03, // SID/Label sub-TLV
+		0x00, 0x3e, 0x80, 0x00, // Label 16000, padding
+		0x00, 0x0e, 0x00, 0x0c, // SR Local Block TLV
+		0x00, 0x03, 0xe8, 0x00, // Range size 1000, reserved
+		0x00, 0x07, 0x00, 0x04, // SID/Label sub-TLV
+		0x00, 0x00, 0x3a, 0x98, // Index 15000
+	}
+
+	want := &RouterInformationLSABody{
+		SRAlgorithms: []SRAlgorithm{SPFAlgorithm, StrictSPFAlgorithm},
+		SIDLabelRanges: []SIDLabelRange{{
+			Size:  8000,
+			First: SIDLabel{Value: 16000, Label: true},
+		}},
+		SRLocalBlocks: []SIDLabelRange{{
+			Size:  1000,
+			First: SIDLabel{Value: 15000},
+		}},
+	}
+
+	got, err := ParseLSABody(AreaRouterInformationLSA, b)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected body (-want +got):\n%s", diff)
+	}
+
+	out, err := got.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if diff := cmp.Diff(b, out); diff != "" {
+		t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+	}
+}
+
+func TestRouterInformationLSABodySegmentRoutingErrors(t *testing.T) {
+	t.Run("parse", func(t *testing.T) {
+		for _, b := range [][]byte{
+			// Empty SR-Algorithm.
+			{0x00, 0x08, 0x00, 0x00},
+			// Short range.
+			{0x00, 0x09, 0x00, 0x03, 0x00, 0x00, 0x01, 0x00},
+			// No SID/Label sub-TLV.
+			{0x00, 0x09, 0x00, 0x04, 0x00, 0x00, 0x01, 0x00},
+			// Bad SID length.
+			{
+				0x00, 0x0e, 0x00, 0x0a,
+				0x00, 0x00, 0x01, 0x00,
+				0x00, 0x07, 0x00, 0x02,
+				0x00, 0x01, 0x00, 0x00,
+			},
+		} {
+			if _, err := ParseLSABody(AreaRouterInformationLSA, b); err == nil {
+				t.Fatalf("expected an error for %#v", b)
+			}
+		}
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		for _, body := range []*RouterInformationLSABody{
+			{SIDLabelRanges: []SIDLabelRange{{Size: 1 << 24}}},
+			{SRLocalBlocks: []SIDLabelRange{{First: SIDLabel{Value: 1 << 20, Label: true}}}},
+		} {
+			if _, err := body.MarshalBinary(); err == nil {
+				t.Fatalf("expected an error for %#v", body)
+			}
+		}
+	})
+}
+
+func TestPrefixSIDRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+		sid  *PrefixSID
+	}{
+		{
+			name: "index",
+			b:    []byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x64},
+			sid:  &PrefixSID{Flags: PrefixSIDNPFlag, SID: 100},
+		},
+		{
+			name: "label",
+			b:    []byte{0x0c, 0x01, 0x00, 0x00, 0x0f, 0xff, 0xff},
+			sid: &PrefixSID{
+				Flags:     PrefixSIDVFlag | PrefixSIDLFlag,
+				Algorithm: StrictSPFAlgorithm,
+				SID:       maxLabel,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sid PrefixSID
+			if err := sid.UnmarshalBinary(tt.b); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.sid, &sid); diff != "" {
+				t.Fatalf("unexpected Prefix-SID (-want +got):\n%s", diff)
+			}
+
+			b, err := sid.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.b, b); diff != "" {
+				t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAdjacencySIDRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		lan  bool
+		b    []byte
+		sid  *AdjacencySID
+	}{
+		{
+			name: "label",
+			b:    []byte{0x60, 0x0a, 0x00, 0x00, 0x00, 0x3a, 0x98},
+			sid: &AdjacencySID{
+				Flags:  AdjacencySIDVFlag | AdjacencySIDLFlag,
+				Weight: 10,
+				SID:    15000,
+			},
+		},
+		{
+			name: "LAN index",
+			lan:  true,
+			b: []byte{
+				0x80, 0x00, 0x00, 0x00,
+				192, 0, 2, 2,
+				0x00, 0x00, 0x00, 0x05,
+			},
+			sid: &AdjacencySID{
+				Flags:      AdjacencySIDBFlag,
+				SID:        5,
+				NeighborID: ID{192, 0, 2, 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				sid AdjacencySID
+				err error
+			)
+			if tt.lan {
+				err = sid.UnmarshalLAN(tt.b)
+			} else {
+				err = sid.UnmarshalBinary(tt.b)
+			}
+			if err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.sid, &sid); diff != "" {
+				t.Fatalf("unexpected Adj-SID (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.lan, sid.LAN()); diff != "" {
+				t.Fatalf("unexpected LAN (-want +got):\n%s", diff)
+			}
+
+			b, err := sid.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.b, b); diff != "" {
+				t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSegmentRoutingSIDErrors(t *testing.T) {
+	if _, err := (&PrefixSID{Flags: PrefixSIDLFlag, SID: maxLabel + 1}).MarshalBinary(); err == nil {
+		t.Fatal("expected a Prefix-SID label marshal error")
+	}
+
+	for _, b := range [][]byte{
+		{0x00, 0x00, 0x00},
+		{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	} {
+		if err := new(PrefixSID).UnmarshalBinary(b); err == nil {
+			t.Fatalf("expected a Prefix-SID unmarshal error for %#v", b)
+		}
+		if err := new(AdjacencySID).UnmarshalLAN(b); err == nil {
+			t.Fatalf("expected a LAN Adj-SID unmarshal error for %#v", b)
+		}
+	}
+}
+
+func TestSegmentRoutingFlagsString(t *testing.T) {
+	if diff := cmp.Diff("L-flag|NP-flag|0x1", (PrefixSIDLFlag | PrefixSIDNPFlag | 1).String()); diff != "" {
+		t.Fatalf("unexpected Prefix-SID flags (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("G-flag|B-flag", (AdjacencySIDGFlag | AdjacencySIDBFlag).String()); diff != "" {
+		t.Fatalf("unexpected Adj-SID flags (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,508 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// SRv6 TLV, sub-TLV, and sub-sub-TLV types as described in RFC9513, section
+// 14.
+const (
+	tlvSRv6Locator            = 1
+	subTLVSRv6EndSID          = 1
+	subSubTLVSRv6SIDStructure = 1
+)
+
+// SRv6 sub-TLV types of the OSPFv3 Extended-LSA Sub-TLVs registry, as
+// described in RFC9513, section 14. Each type may be used with the value
+// produced by SRv6EndXSID.MarshalBinary.
+const (
+	SRv6EndXSIDSubTLV    uint16 = 31
+	SRv6LANEndXSIDSubTLV uint16 = 32
+)
+
+// Fixed length SRv6 structures.
+const (
+	srv6LocatorLen    = 24 // No trailing sub-TLVs.
+	srv6EndSIDLen     = 20 // No trailing sub-sub-TLVs.
+	srv6EndXSIDLen    = 24 // No trailing sub-sub-TLVs.
+	srv6LANEndXSIDLen = 28 // No trailing sub-sub-TLVs.
+	srv6StructureLen  = 4
+)
+
+// An SRv6RouteType is the route type of an SRv6 Locator as described in
+// RFC9513, section 7.1.
+type SRv6RouteType uint8
+
+// Possible SRv6RouteType values.
+const (
+	SRv6IntraArea         SRv6RouteType = 1
+	SRv6InterArea         SRv6RouteType = 2
+	SRv6ASExternalType1   SRv6RouteType = 3
+	SRv6ASExternalType2   SRv6RouteType = 4
+	SRv6NSSAExternalType1 SRv6RouteType = 5
+	SRv6NSSAExternalType2 SRv6RouteType = 6
+)
+
+// SRv6LocatorFlags is a bitmask of flags in an SRv6 Locator TLV as described
+// in RFC9513, section 7.1.
+type SRv6LocatorFlags uint8
+
+// Possible SRv6LocatorFlags values.
+const (
+	SRv6LocatorAFlag SRv6LocatorFlags = 1 << 6
+	SRv6LocatorNFlag SRv6LocatorFlags = 1 << 7
+)
+
+// String returns the string representation of an SRv6LocatorFlags bitmask.
+func (f SRv6LocatorFlags) String() string {
+	return flagsString(uint(f), []string{
+		"",
+		"",
+		"",
+		"",
+		"",
+		"",
+		"A-flag",
+		"N-flag",
+	})
+}
+
+// An SRv6EndpointBehavior is an SRv6 endpoint behavior codepoint as described
+// in RFC8986, section 10.2.
+type SRv6EndpointBehavior uint16
+
+// An SRv6SIDStructure describes the structure of an SRv6 SID in bits, as
+// described in RFC9513, section 10.
+type SRv6SIDStructure struct {
+	LocatorBlockLength uint8
+	LocatorNodeLength  uint8
+	FunctionLength     uint8
+	ArgumentLength     uint8
+}
+
+// An SRv6LocatorLSABody is the body of an OSPFv3 SRv6 Locator LSA as described
+// in RFC9513, section 7. The SRv6 Locator LSA may be originated with area or AS
+// flooding scope, using the AreaSRv6LocatorLSA and ASSRv6LocatorLSA LSTypes,
+// respectively.
+type SRv6LocatorLSABody struct {
+	// Locators are encoded as SRv6 Locator TLVs.
+	Locators []SRv6Locator
+
+	// TLVs contains any other TLVs in the order in which they appear, which
+	// are marshaled following the SRv6 Locator TLVs.
+	TLVs []TLV
+}
+
+// An SRv6Locator is an SRv6 Locator TLV as described in RFC9513, section 7.1.
+type SRv6Locator struct {
+	RouteType SRv6RouteType
+	Algorithm SRAlgorithm
+	Flags     SRv6LocatorFlags
+	Metric    uint32
+
+	// Locator is an IPv6 prefix of Length bits.
+	Length  uint8
+	Locator net.IP
+
+	// EndSIDs are encoded as SRv6 End SID sub-TLVs.
+	EndSIDs []SRv6EndSID
+
+	// SubTLVs contains any other sub-TLVs in the order in which they appear,
+	// which are marshaled following the SRv6 End SID sub-TLVs.
+	SubTLVs []TLV
+}
+
+// An SRv6EndSID is an SRv6 End SID sub-TLV as described in RFC9513, section
+// 8.
+type SRv6EndSID struct {
+	Flags    uint8
+	Behavior SRv6EndpointBehavior
+	SID      net.IP
+
+	// Structure, if set, is encoded as an SRv6 SID Structure sub-sub-TLV.
+	Structure *SRv6SIDStructure
+
+	// SubTLVs contains any other sub-sub-TLVs in the order in which they
+	// appear, which are marshaled following the SRv6 SID Structure.
+	SubTLVs []TLV
+}
+
+// MarshalBinary packs an SRv6LocatorLSABody into bytes.
+func (s *SRv6LocatorLSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, s.len())
+	if err := s.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an SRv6LocatorLSABody from bytes.
+func (s *SRv6LocatorLSABody) UnmarshalBinary(b []byte) error {
+	return s.unmarshal(b)
+}
+
+// tlvs returns all of the TLVs for the SRv6LocatorLSABody in marshaling order.
+func (s *SRv6LocatorLSABody) tlvs() ([]TLV, error) {
+	tlvs := make([]TLV, 0, len(s.Locators)+len(s.TLVs))
+	for _, l := range s.Locators {
+		v, err := l.marshal()
+		if err != nil {
+			return nil, fmt.Errorf("SRv6 Locator LSA %w", err)
+		}
+
+		tlvs = append(tlvs, TLV{Type: tlvSRv6Locator, Value: v})
+	}
+
+	return append(tlvs, s.TLVs...), nil
+}
+
+// len returns the length of an SRv6LocatorLSABody in bytes.
+func (s *SRv6LocatorLSABody) len() int {
+	// Invalid bodies are reported by marshal.
+	tlvs, _ := s.tlvs()
+	return tlvsLen(tlvs)
+}
+
+// marshal stores the SRv6LocatorLSABody bytes into b. It assumes b has
+// allocated enough space for an SRv6LocatorLSABody to avoid a panic.
+func (s *SRv6LocatorLSABody) marshal(b []byte) error {
+	tlvs, err := s.tlvs()
+	if err != nil {
+		return err
+	}
+
+	if err := marshalTLVs(b, tlvs); err != nil {
+		return fmt.Errorf("SRv6 Locator LSA %w", err)
+	}
+
+	return nil
+}
+
+// unmarshal unpacks an SRv6LocatorLSABody from b.
+func (s *SRv6LocatorLSABody) unmarshal(b []byte) error {
+	*s = SRv6LocatorLSABody{}
+
+	return parseTLVs(b, func(typ uint16, v []byte) error {
+		if typ != tlvSRv6Locator {
+			s.TLVs = append(s.TLVs, TLV{
+				Type:  typ,
+				Value: append([]byte(nil), v...),
+			})
+			return nil
+		}
+
+		var l SRv6Locator
+		if err := l.unmarshal(v); err != nil {
+			return err
+		}
+
+		s.Locators = append(s.Locators, l)
+		return nil
+	})
+}
+
+// marshal packs an SRv6Locator into the value of an SRv6 Locator TLV.
+func (l SRv6Locator) marshal() ([]byte, error) {
+	if l.Length > 128 {
+		return nil, fmt.Errorf("SRv6 Locator length %d is too long for an IPv6 prefix: %w", l.Length, errMarshal)
+	}
+	if len(l.Locator) != net.IPv6len {
+		return nil, fmt.Errorf("SRv6 Locator %v must be a 16 byte IPv6 address: %w", l.Locator, errMarshal)
+	}
+
+	tlvs := make([]TLV, 0, len(l.EndSIDs)+len(l.SubTLVs))
+	for _, e := range l.EndSIDs {
+		v, err := e.marshal()
+		if err != nil {
+			return nil, err
+		}
+
+		tlvs = append(tlvs, TLV{Type: subTLVSRv6EndSID, Value: v})
+	}
+	tlvs = append(tlvs, l.SubTLVs...)
+
+	b := make([]byte, srv6LocatorLen+tlvsLen(tlvs))
+	b[0] = byte(l.RouteType)
+	b[1] = byte(l.Algorithm)
+	b[2] = l.Length
+	b[3] = byte(l.Flags)
+	binary.BigEndian.PutUint32(b[4:8], l.Metric)
+	copy(b[8:24], l.Locator)
+
+	if err := marshalTLVs(b[srv6LocatorLen:], tlvs); err != nil {
+		return nil, fmt.Errorf("SRv6 Locator %w", err)
+	}
+
+	return b, nil
+}
+
+// unmarshal unpacks an SRv6Locator from the value of an SRv6 Locator TLV.
+func (l *SRv6Locator) unmarshal(b []byte) error {
+	if n := len(b); n < srv6LocatorLen {
+		return fmt.Errorf("not enough bytes for SRv6 Locator TLV: %d: %w", n, errParse)
+	}
+
+	*l = SRv6Locator{
+		RouteType: SRv6RouteType(b[0]),
+		Algorithm: SRAlgorithm(b[1]),
+		Length:    b[2],
+		Flags:     SRv6LocatorFlags(b[3]),
+		Metric:    binary.BigEndian.Uint32(b[4:8]),
+		Locator:   make(net.IP, net.IPv6len),
+	}
+	copy(l.Locator, b[8:24])
+
+	if l.Length > 128 {
+		return fmt.Errorf("SRv6 Locator length %d is too long for an IPv6 prefix: %w", l.Length, errParse)
+	}
+
+	return parseTLVs(b[srv6LocatorLen:], func(typ uint16, v []byte) error {
+		if typ != subTLVSRv6EndSID {
+			l.SubTLVs = append(l.SubTLVs, TLV{
+				Type:  typ,
+				Value: append([]byte(nil), v...),
+			})
+			return nil
+		}
+
+		var e SRv6EndSID
+		if err := e.unmarshal(v); err != nil {
+			return err
+		}
+
+		l.EndSIDs = append(l.EndSIDs, e)
+		return nil
+	})
+}
+
+// marshal packs an SRv6EndSID into the value of an SRv6 End SID sub-TLV.
+func (e SRv6EndSID) marshal() ([]byte, error) {
+	if len(e.SID) != net.IPv6len {
+		return nil, fmt.Errorf("SRv6 End SID %v must be a 16 byte IPv6 address: %w", e.SID, errMarshal)
+	}
+
+	tlvs := srv6SubSubTLVs(e.Structure, e.SubTLVs)
+	b := make([]byte, srv6EndSIDLen+tlvsLen(tlvs))
+	b[0] = e.Flags
+	// b[1] is reserved.
+	binary.BigEndian.PutUint16(b[2:4], uint16(e.Behavior))
+	copy(b[4:20], e.SID)
+
+	if err := marshalTLVs(b[srv6EndSIDLen:], tlvs); err != nil {
+		return nil, fmt.Errorf("SRv6 End SID %w", err)
+	}
+
+	return b, nil
+}
+
+// unmarshal unpacks an SRv6EndSID from the value of an SRv6 End SID sub-TLV.
+func (e *SRv6EndSID) unmarshal(b []byte) error {
+	if l := len(b); l < srv6EndSIDLen {
+		return fmt.Errorf("not enough bytes for SRv6 End SID sub-TLV: %d: %w", l, errParse)
+	}
+
+	*e = SRv6EndSID{
+		Flags: b[0],
+		// b[1] is reserved.
+		Behavior: SRv6EndpointBehavior(binary.BigEndian.Uint16(b[2:4])),
+		SID:      make(net.IP, net.IPv6len),
+	}
+	copy(e.SID, b[4:20])
+
+	var err error
+	e.Structure, e.SubTLVs, err = parseSRv6SubSubTLVs(b[srv6EndSIDLen:])
+	return err
+}
+
+// SRv6EndXSIDFlags is a bitmask of flags in an SRv6 End.X SID or LAN End.X
+// SID sub-TLV as described in RFC9513, section 9.
+type SRv6EndXSIDFlags uint8
+
+// Possible SRv6EndXSIDFlags values.
+const (
+	SRv6EndXSIDPFlag SRv6EndXSIDFlags = 1 << 5
+	SRv6EndXSIDSFlag SRv6EndXSIDFlags = 1 << 6
+	SRv6EndXSIDBFlag SRv6EndXSIDFlags = 1 << 7
+)
+
+// String returns the string representation of an SRv6EndXSIDFlags bitmask.
+func (f SRv6EndXSIDFlags) String() string {
+	return flagsString(uint(f), []string{
+		"",
+		"",
+		"",
+		"",
+		"",
+		"P-flag",
+		"S-flag",
+		"B-flag",
+	})
+}
+
+// An SRv6EndXSID is the value of an SRv6 End.X SID sub-TLV, or an SRv6 LAN
+// End.X SID sub-TLV when NeighborID is set, as described in RFC9513, section
+// 9.
+type SRv6EndXSID struct {
+	Behavior  SRv6EndpointBehavior
+	Flags     SRv6EndXSIDFlags
+	Algorithm SRAlgorithm
+	Weight    uint8
+	SID       net.IP
+
+	// NeighborID is the router ID of the neighbor on a LAN. It is only used
+	// for LAN End.X SID sub-TLVs and must be zero for End.X SID sub-TLVs.
+	NeighborID ID
+
+	// Structure, if set, is encoded as an SRv6 SID Structure sub-sub-TLV.
+	Structure *SRv6SIDStructure
+
+	// SubTLVs contains any other sub-sub-TLVs in the order in which they
+	// appear, which are marshaled following the SRv6 SID Structure.
+	SubTLVs []TLV
+}
+
+// LAN reports whether the SRv6EndXSID is encoded as an SRv6 LAN End.X SID
+// sub-TLV.
+func (e *SRv6EndXSID) LAN() bool { return e.NeighborID != ID{} }
+
+// MarshalBinary packs an SRv6EndXSID into the value of an SRv6 End.X SID
+// sub-TLV, or an SRv6 LAN End.X SID sub-TLV if NeighborID is set.
+func (e *SRv6EndXSID) MarshalBinary() ([]byte, error) {
+	if len(e.SID) != net.IPv6len {
+		return nil, fmt.Errorf("ospf3: SRv6 End.X SID %v must be a 16 byte IPv6 address: %w", e.SID, errMarshal)
+	}
+
+	n := srv6EndXSIDLen
+	if e.LAN() {
+		n = srv6LANEndXSIDLen
+	}
+
+	tlvs := srv6SubSubTLVs(e.Structure, e.SubTLVs)
+	b := make([]byte, n+tlvsLen(tlvs))
+	binary.BigEndian.PutUint16(b[0:2], uint16(e.Behavior))
+	b[2] = byte(e.Flags)
+	// b[3] is reserved.
+	b[4] = byte(e.Algorithm)
+	b[5] = e.Weight
+	// b[6:8] are reserved.
+
+	off := 8
+	if e.LAN() {
+		copy(b[8:12], e.NeighborID[:])
+		off = 12
+	}
+	copy(b[off:off+net.IPv6len], e.SID)
+
+	if err := marshalTLVs(b[n:], tlvs); err != nil {
+		return nil, fmt.Errorf("ospf3: SRv6 End.X SID %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an SRv6EndXSID from the value of an SRv6 End.X SID
+// sub-TLV. Use UnmarshalLAN for SRv6 LAN End.X SID sub-TLVs.
+func (e *SRv6EndXSID) UnmarshalBinary(b []byte) error {
+	return e.unmarshal(b, false)
+}
+
+// UnmarshalLAN unpacks an SRv6EndXSID from the value of an SRv6 LAN End.X SID
+// sub-TLV.
+func (e *SRv6EndXSID) UnmarshalLAN(b []byte) error {
+	return e.unmarshal(b, true)
+}
+
+// unmarshal unpacks an SRv6EndXSID from b, which contains a neighbor ID if lan
+// is true.
+func (e *SRv6EndXSID) unmarshal(b []byte, lan bool) error {
+	n := srv6EndXSIDLen
+	if lan {
+		n = srv6LANEndXSIDLen
+	}
+
+	if l := len(b); l < n {
+		return fmt.Errorf("ospf3: not enough bytes for SRv6 End.X SID: %d: %w", l, errParse)
+	}
+
+	*e = SRv6EndXSID{
+		Behavior:  SRv6EndpointBehavior(binary.BigEndian.Uint16(b[0:2])),
+		Flags:     SRv6EndXSIDFlags(b[2]),
+		Algorithm: SRAlgorithm(b[4]),
+		Weight:    b[5],
+		SID:       make(net.IP, net.IPv6len),
+	}
+
+	off := 8
+	if lan {
+		copy(e.NeighborID[:], b[8:12])
+		off = 12
+	}
+	copy(e.SID, b[off:off+net.IPv6len])
+
+	var err error
+	e.Structure, e.SubTLVs, err = parseSRv6SubSubTLVs(b[n:])
+	if err != nil {
+		return fmt.Errorf("ospf3: SRv6 End.X SID %w", err)
+	}
+
+	return nil
+}
+
+// srv6SubSubTLVs returns the sub-sub-TLVs of an SRv6 SID in marshaling order.
+func srv6SubSubTLVs(s *SRv6SIDStructure, other []TLV) []TLV {
+	if s == nil {
+		return other
+	}
+
+	tlvs := make([]TLV, 0, 1+len(other))
+	tlvs = append(tlvs, TLV{
+		Type: subSubTLVSRv6SIDStructure,
+		Value: []byte{
+			s.LocatorBlockLength,
+			s.LocatorNodeLength,
+			s.FunctionLength,
+			s.ArgumentLength,
+		},
+	})
+
+	return append(tlvs, other...)
+}
+
+// parseSRv6SubSubTLVs parses the sub-sub-TLVs of an SRv6 SID.
+func parseSRv6SubSubTLVs(b []byte) (*SRv6SIDStructure, []TLV, error) {
+	var (
+		s     *SRv6SIDStructure
+		other []TLV
+	)
+
+	err := parseTLVs(b, func(typ uint16, v []byte) error {
+		if typ != subSubTLVSRv6SIDStructure || s != nil {
+			other = append(other, TLV{
+				Type:  typ,
+				Value: append([]byte(nil), v...),
+			})
+			return nil
+		}
+
+		if l := len(v); l != srv6StructureLen {
+			return fmt.Errorf("SRv6 SID Structure must be exactly %d bytes, got %d bytes: %w",
+				srv6StructureLen, l, errParse)
+		}
+
+		s = &SRv6SIDStructure{
+			LocatorBlockLength: v[0],
+			LocatorNodeLength:  v[1],
+			FunctionLength:     v[2],
+			ArgumentLength:     v[3],
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s, other, nil
+}
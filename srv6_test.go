@@ -0,0 +1,210 @@
+package ospf3
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSRv6LocatorLSABody(t *testing.T) {
+	b := []byte{
+		0x00, 0x01, 0x00, 0x40, // SRv6 Locator TLV
+		0x01, 0x00, 0x30, 0x80, // Intra-area, SPF, /48, N-flag
+		0x00, 0x00, 0x00, 0x0a, // Metric 10
+		0x20, 0x01, 0x0d, 0xb8, // Locator 2001:db8:1::
+		0x00, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x01, 0x00, 0x1c, // SRv6 End SID sub-TLV
+		0x00, 0x00, 0x00, 0x01, // Flags, reserved, End behavior
+		0x20, 0x01, 0x0d, 0xb8, // SID 2001:db8:1::1
+		0x00, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x01, 0x00, 0x04, // SRv6 SID Structure sub-sub-TLV
+		0x20, 0x10, 0x10, 0x00, // LB 32, LN 16, Fun 16, Arg 0
+		0x00, 0x02, 0x00, 0x01, // Unknown sub-TLV
+		0xff, 0x00, 0x00, 0x00, // Value, padding
+		0x00, 0x09, 0x00, 0x00, // Unknown TLV
+	}
+
+	want := &SRv6LocatorLSABody{
+		Locators: []SRv6Locator{{
+			RouteType: SRv6IntraArea,
+			Algorithm: SPFAlgorithm,
+			Flags:     SRv6LocatorNFlag,
+			Metric:    10,
+			Length:    48,
+			Locator:   net.ParseIP("2001:db8:1::"),
+			EndSIDs: []SRv6EndSID{{
+				Behavior: 1,
+				SID:      net.ParseIP("2001:db8:1::1"),
+				Structure: &SRv6SIDStructure{
+					LocatorBlockLength: 32,
+					LocatorNodeLength:  16,
+					FunctionLength:     16,
+				},
+			}},
+			SubTLVs: []TLV{{Type: 2, Value: []byte{0xff}}},
+		}},
+		TLVs: []TLV{{Type: 9}},
+	}
+
+	got, err := ParseLSABody(AreaSRv6LocatorLSA, b)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected body (-want +got):\n%s", diff)
+	}
+
+	out, err := got.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if diff := cmp.Diff(b, out); diff != "" {
+		t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+	}
+}
+
+func TestSRv6LocatorLSABodyErrors(t *testing.T) {
+	t.Run("parse", func(t *testing.T) {
+		for _, b := range [][]byte{
+			// Short locator.
+			{0x00, 0x01, 0x00, 0x04, 0x01, 0x00, 0x30, 0x00},
+			// Locator length too long.
+			append([]byte{
+				0x00, 0x01, 0x00, 0x18,
+				0x01, 0x00, 0x81, 0x00,
+			}, make([]byte, 20)...),
+			// Short End SID.
+			append(append([]byte{
+				0x00, 0x01, 0x00, 0x1c,
+				0x01, 0x00, 0x30, 0x00,
+			}, make([]byte, 20)...), 0x00, 0x01, 0x00, 0x00),
+		} {
+			if _, err := ParseLSABody(ASSRv6LocatorLSA, b); err == nil {
+				t.Fatalf("expected an error for %#v", b)
+			}
+		}
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		for _, body := range []*SRv6LocatorLSABody{
+			{Locators: []SRv6Locator{{Length: 129, Locator: net.IPv6zero}}},
+			{Locators: []SRv6Locator{{Locator: net.IPv4(192, 0, 2, 1).To4()}}},
+			{Locators: []SRv6Locator{{
+				Locator: net.IPv6zero,
+				EndSIDs: []SRv6EndSID{{SID: nil}},
+			}}},
+		} {
+			if _, err := body.MarshalBinary(); err == nil {
+				t.Fatalf("expected an error for %#v", body)
+			}
+		}
+	})
+}
+
+func TestSRv6EndXSIDRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		lan  bool
+		b    []byte
+		sid  *SRv6EndXSID
+	}{
+		{
+			name: "End.X",
+			b: []byte{
+				0x00, 0x05, 0x40, 0x00, // End.X behavior, S-flag, reserved
+				0x00, 0x0a, 0x00, 0x00, // SPF, weight 10, reserved
+				0x20, 0x01, 0x0d, 0xb8, // SID 2001:db8:1:0:e000::
+				0x00, 0x01, 0x00, 0x00,
+				0xe0, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+			},
+			sid: &SRv6EndXSID{
+				Behavior: 5,
+				Flags:    SRv6EndXSIDSFlag,
+				Weight:   10,
+				SID:      net.ParseIP("2001:db8:1:0:e000::"),
+			},
+		},
+		{
+			name: "LAN End.X",
+			lan:  true,
+			b: []byte{
+				0x00, 0x05, 0xa0, 0x00, // End.X behavior, B/P-flags, reserved
+				0x01, 0x00, 0x00, 0x00, // Strict SPF, weight 0, reserved
+				0xc0, 0x00, 0x02, 0x02, // Neighbor ID
+				0x20, 0x01, 0x0d, 0xb8, // SID 2001:db8:1:0:e001::
+				0x00, 0x01, 0x00, 0x00,
+				0xe0, 0x01, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+				0x00, 0x01, 0x00, 0x04, // SRv6 SID Structure sub-sub-TLV
+				0x20, 0x10, 0x10, 0x00, // LB 32, LN 16, Fun 16, Arg 0
+			},
+			sid: &SRv6EndXSID{
+				Behavior:   5,
+				Flags:      SRv6EndXSIDBFlag | SRv6EndXSIDPFlag,
+				Algorithm:  StrictSPFAlgorithm,
+				SID:        net.ParseIP("2001:db8:1:0:e001::"),
+				NeighborID: ID{192, 0, 2, 2},
+				Structure: &SRv6SIDStructure{
+					LocatorBlockLength: 32,
+					LocatorNodeLength:  16,
+					FunctionLength:     16,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sid SRv6EndXSID
+			unmarshal := sid.UnmarshalBinary
+			if tt.lan {
+				unmarshal = sid.UnmarshalLAN
+			}
+
+			if err := unmarshal(tt.b); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.sid, &sid); diff != "" {
+				t.Fatalf("unexpected End.X SID (-want +got):\n%s", diff)
+			}
+
+			b, err := sid.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.b, b); diff != "" {
+				t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSRv6EndXSIDErrors(t *testing.T) {
+	var sid SRv6EndXSID
+	if err := sid.UnmarshalBinary(make([]byte, srv6EndXSIDLen-1)); err == nil {
+		t.Fatal("expected an error for short End.X SID")
+	}
+	if err := sid.UnmarshalLAN(make([]byte, srv6EndXSIDLen)); err == nil {
+		t.Fatal("expected an error for short LAN End.X SID")
+	}
+
+	// Malformed SID Structure sub-sub-TLV.
+	b := append(make([]byte, srv6EndXSIDLen), 0x00, 0x01, 0x00, 0x02, 0x20, 0x10, 0x00, 0x00)
+	if err := sid.UnmarshalBinary(b); err == nil {
+		t.Fatal("expected an error for malformed SID Structure")
+	}
+
+	if _, err := (&SRv6EndXSID{SID: net.IPv4(192, 0, 2, 1).To4()}).MarshalBinary(); err == nil {
+		t.Fatal("expected an error for IPv4 SID")
+	}
+}
@@ -0,0 +1,290 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Store persists small amounts of protocol state, such as the boot count
+// used to derive cryptographic sequence numbers and the LS sequence numbers of
+// self-originated LSAs, so that the state survives a restart. Values are grouped by bucket and key. Implementations must be safe
+// for concurrent use.
+//
+// Embedders may implement Store using their own storage, such as a replicated
+// key/value store for highly available deployments.
+type Store interface {
+	// Get returns the value stored for key in bucket, or false if none
+	// exists.
+	Get(bucket, key string) ([]byte, bool, error)
+
+	// Put stores value for key in bucket, replacing any existing value.
+	Put(bucket, key string, value []byte) error
+
+	// Delete removes the value stored for key in bucket. It is not an error
+	// if no such value exists.
+	Delete(bucket, key string) error
+}
+
+// Compile-time Store interface checks.
+var (
+	_ Store = &MemoryStore{}
+	_ Store = &FileStore{}
+)
+
+// A MemoryStore is a Store which holds values in memory, such as for tests or
+// for state which need not survive a restart of the process.
+type MemoryStore struct {
+	mu sync.Mutex
+	m  map[string]map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{m: make(map[string]map[string][]byte)}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(bucket, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.m[bucket][key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	return append([]byte(nil), v...), true, nil
+}
+
+// Put implements Store.
+func (s *MemoryStore) Put(bucket, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.m[bucket] == nil {
+		s.m[bucket] = make(map[string][]byte)
+	}
+
+	s.m[bucket][key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.m[bucket], key)
+	return nil
+}
+
+// A FileStore is a Store which holds each value in a file named by its key,
+// within a directory named by its bucket. Values are replaced atomically so
+// that a crash cannot leave a partially written value.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating dir if it does not
+// exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+// Get implements Store.
+func (s *FileStore) Get(bucket, key string) ([]byte, bool, error) {
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	b, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil, false, nil
+	case err != nil:
+		return nil, false, err
+	}
+
+	return b, true, nil
+}
+
+// Put implements Store.
+func (s *FileStore) Put(bucket, key string, value []byte) error {
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// Write the value to a temporary file and rename it over any existing
+	// value once it is safely on disk.
+	f, err := os.CreateTemp(dir, "."+key+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(value); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(bucket, key string) error {
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// path returns the file path for key in bucket, verifying that neither can
+// refer to a file outside of the FileStore's directory.
+func (s *FileStore) path(bucket, key string) (string, error) {
+	for _, name := range []string{bucket, key} {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`+"\x00") {
+			return "", fmt.Errorf("ospf3: invalid FileStore bucket or key name %q", name)
+		}
+	}
+
+	return filepath.Join(s.dir, bucket, key), nil
+}
+
+// bootCountBucket is the Store bucket which holds a boot count per interface.
+const bootCountBucket = "boot-count"
+
+// errBootCountWrapped is returned by nextBootCount when the boot count cannot
+// be incremented further.
+var errBootCountWrapped = errors.New("ospf3: boot count has wrapped, Keychain keys must be changed and the boot count deleted")
+
+// nextBootCount increments and returns the boot count stored in s for key. The
+// boot count is at least the number of seconds since the Unix epoch at time
+// now, so that enabling a Store does not lower the cryptographic sequence
+// numbers sent by a Conn which previously used the clock alone. Per RFC7166,
+// section 4.1, the boot count must not wrap without a change of keys.
+func nextBootCount(s Store, key string, now time.Time) (uint32, error) {
+	n, ok, err := getUint32(s, bootCountBucket, key)
+	if err != nil {
+		return 0, fmt.Errorf("ospf3: failed to get boot count: %w", err)
+	}
+	if ok && n == math.MaxUint32 {
+		return 0, errBootCountWrapped
+	}
+
+	n++
+	if clock := uint32(now.Unix()); n < clock {
+		n = clock
+	}
+
+	if err := putUint32(s, bootCountBucket, key, n); err != nil {
+		return 0, fmt.Errorf("ospf3: failed to put boot count: %w", err)
+	}
+
+	return n, nil
+}
+
+// LS sequence numbers for self-originated LSAs, as described in RFC2328,
+// section 12.1.6. Sequence numbers are compared as signed 32-bit integers.
+const (
+	InitialSequenceNumber uint32 = 0x80000001
+	MaxSequenceNumber     uint32 = 0x7fffffff
+)
+
+// lsaSequenceBucket is the Store bucket which holds the last LS sequence
+// number used for each self-originated LSA.
+const lsaSequenceBucket = "lsa-sequence-number"
+
+// NextLSASequenceNumber returns the LS sequence number for the next instance
+// of the self-originated LSA lsa and stores it in s, so that a restarted router
+// continues to originate lsa with increasing sequence numbers. The first
+// instance of an LSA uses InitialSequenceNumber.
+//
+// Once MaxSequenceNumber has been returned, NextLSASequenceNumber returns an
+// error. The LSA must then be flushed from the routing domain by premature
+// aging, and its sequence number reset using ResetLSASequenceNumber.
+func NextLSASequenceNumber(s Store, lsa LSA) (uint32, error) {
+	key := lsaKey(lsa)
+	n, ok, err := getUint32(s, lsaSequenceBucket, key)
+	if err != nil {
+		return 0, fmt.Errorf("ospf3: failed to get LS sequence number: %w", err)
+	}
+
+	switch {
+	case !ok:
+		n = InitialSequenceNumber
+	case n == MaxSequenceNumber:
+		return 0, fmt.Errorf("ospf3: LS sequence number of %s LSA %s from %s has reached MaxSequenceNumber",
+			lsa.Type, lsa.LinkStateID, lsa.AdvertisingRouter)
+	default:
+		n++
+	}
+
+	if err := putUint32(s, lsaSequenceBucket, key, n); err != nil {
+		return 0, fmt.Errorf("ospf3: failed to put LS sequence number: %w", err)
+	}
+
+	return n, nil
+}
+
+// ResetLSASequenceNumber removes the LS sequence number stored in s for lsa,
+// so that the next instance of lsa uses InitialSequenceNumber.
+func ResetLSASequenceNumber(s Store, lsa LSA) error {
+	return s.Delete(lsaSequenceBucket, lsaKey(lsa))
+}
+
+// lsaKey returns the Store key for lsa.
+func lsaKey(lsa LSA) string {
+	return fmt.Sprintf("%04x_%s_%s", uint16(lsa.Type), lsa.LinkStateID, lsa.AdvertisingRouter)
+}
+
+// getUint32 returns the 32-bit value stored in s for key in bucket, or false
+// if none exists.
+func getUint32(s Store, bucket, key string) (uint32, bool, error) {
+	b, ok, err := s.Get(bucket, key)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if len(b) != 4 {
+		return 0, false, fmt.Errorf("invalid value length %d: %w", len(b), errParse)
+	}
+
+	return binary.BigEndian.Uint32(b), true, nil
+}
+
+// putUint32 stores the 32-bit value v in s for key in bucket.
+func putUint32(s Store, bucket, key string, v uint32) error {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return s.Put(bucket, key, b)
+}
@@ -0,0 +1,198 @@
+package ospf3
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStore(t *testing.T) {
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create FileStore: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		s    Store
+	}{
+		{
+			name: "memory",
+			s:    NewMemoryStore(),
+		},
+		{
+			name: "file",
+			s:    fs,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get := func(bucket, key string) ([]byte, bool) {
+				t.Helper()
+
+				v, ok, err := tt.s.Get(bucket, key)
+				if err != nil {
+					t.Fatalf("failed to get: %v", err)
+				}
+
+				return v, ok
+			}
+
+			if _, ok := get("a", "key"); ok {
+				t.Fatal("value exists before Put")
+			}
+
+			for _, v := range [][]byte{{0x01}, {0x02, 0x03}} {
+				if err := tt.s.Put("a", "key", v); err != nil {
+					t.Fatalf("failed to put: %v", err)
+				}
+
+				got, ok := get("a", "key")
+				if !ok {
+					t.Fatal("value does not exist after Put")
+				}
+				if diff := cmp.Diff(v, got); diff != "" {
+					t.Fatalf("unexpected value (-want +got):\n%s", diff)
+				}
+			}
+
+			// Buckets are independent.
+			if _, ok := get("b", "key"); ok {
+				t.Fatal("value exists in other bucket")
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := tt.s.Delete("a", "key"); err != nil {
+					t.Fatalf("failed to delete: %v", err)
+				}
+			}
+
+			if _, ok := get("a", "key"); ok {
+				t.Fatal("value exists after Delete")
+			}
+		})
+	}
+}
+
+func TestFileStoreInvalidNames(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create FileStore: %v", err)
+	}
+
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`} {
+		if err := s.Put(name, "key", nil); err == nil {
+			t.Fatalf("expected an error for bucket %q, but none occurred", name)
+		}
+		if err := s.Put("bucket", name, nil); err == nil {
+			t.Fatalf("expected an error for key %q, but none occurred", name)
+		}
+	}
+}
+
+func Test_nextBootCount(t *testing.T) {
+	var (
+		past   = time.Unix(10, 0)
+		future = time.Unix(100, 0)
+	)
+
+	s := NewMemoryStore()
+	tests := []struct {
+		now  time.Time
+		want uint32
+	}{
+		// A missing boot count is seeded from the clock, which is then used
+		// until the boot count passes it.
+		{now: future, want: 100},
+		{now: future, want: 101},
+		{now: past, want: 102},
+		{now: time.Unix(200, 0), want: 200},
+	}
+
+	for _, tt := range tests {
+		got, err := nextBootCount(s, "eth0", tt.now)
+		if err != nil {
+			t.Fatalf("failed to get boot count: %v", err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Fatalf("unexpected boot count (-want +got):\n%s", diff)
+		}
+	}
+
+	if err := putUint32(s, bootCountBucket, "eth0", math.MaxUint32); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if _, err := nextBootCount(s, "eth0", past); !errors.Is(err, errBootCountWrapped) {
+		t.Fatalf("expected wrapped error, but got: %v", err)
+	}
+
+	if err := s.Put(bootCountBucket, "eth0", []byte{0x01}); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if _, err := nextBootCount(s, "eth0", past); !errors.Is(err, errParse) {
+		t.Fatalf("expected parse error, but got: %v", err)
+	}
+}
+
+func TestNextLSASequenceNumber(t *testing.T) {
+	var (
+		s   = NewMemoryStore()
+		lsa = LSA{
+			Type:              RouterLSA,
+			AdvertisingRouter: ID{192, 0, 2, 1},
+		}
+		other = LSA{
+			Type:              NetworkLSA,
+			LinkStateID:       ID{0, 0, 0, 1},
+			AdvertisingRouter: ID{192, 0, 2, 1},
+		}
+	)
+
+	next := func(lsa LSA) uint32 {
+		t.Helper()
+
+		n, err := NextLSASequenceNumber(s, lsa)
+		if err != nil {
+			t.Fatalf("failed to get LS sequence number: %v", err)
+		}
+
+		return n
+	}
+
+	for i, want := range []uint32{InitialSequenceNumber, InitialSequenceNumber + 1} {
+		if diff := cmp.Diff(want, next(lsa)); diff != "" {
+			t.Fatalf("unexpected LS sequence number %d (-want +got):\n%s", i, diff)
+		}
+	}
+
+	// Each LSA has its own sequence number.
+	if diff := cmp.Diff(InitialSequenceNumber, next(other)); diff != "" {
+		t.Fatalf("unexpected other LS sequence number (-want +got):\n%s", diff)
+	}
+
+	// Sequence numbers pass through zero, but stop at MaxSequenceNumber.
+	if err := putUint32(s, lsaSequenceBucket, lsaKey(lsa), math.MaxUint32); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if diff := cmp.Diff(uint32(0), next(lsa)); diff != "" {
+		t.Fatalf("unexpected wrapped LS sequence number (-want +got):\n%s", diff)
+	}
+
+	if err := putUint32(s, lsaSequenceBucket, lsaKey(lsa), MaxSequenceNumber); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if _, err := NextLSASequenceNumber(s, lsa); err == nil {
+		t.Fatal("expected an error after MaxSequenceNumber, but none occurred")
+	}
+
+	if err := ResetLSASequenceNumber(s, lsa); err != nil {
+		t.Fatalf("failed to reset LS sequence number: %v", err)
+	}
+	if diff := cmp.Diff(InitialSequenceNumber, next(lsa)); diff != "" {
+		t.Fatalf("unexpected reset LS sequence number (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,227 @@
+package ospf3
+
+import "fmt"
+
+// Masks for the reserved bits of flag fields.
+const (
+	ddFlagsReserved       = 0xf8
+	routerFlagsReserved   = 0xe0
+	externalFlagsReserved = 0xf8
+)
+
+// A ReservedFieldError is returned by ParseOptions.ParsePacket when strict
+// parsing is enabled and a packet contains a reserved field or padding with a
+// nonzero value.
+type ReservedFieldError struct {
+	// Field describes the reserved field, such as "Header reserved" or
+	// "Router-LSA flags".
+	Field string
+
+	// Offset is the offset in bytes of the field from the start of the
+	// OSPFv3 packet.
+	Offset int
+
+	// Value is the value of the reserved bits of the byte at Offset.
+	Value uint8
+}
+
+// Error implements error.
+func (e *ReservedFieldError) Error() string {
+	return fmt.Sprintf("ospf3: %s at offset %d has nonzero reserved bits %#02x", e.Field, e.Offset, e.Value)
+}
+
+// A LengthError is returned by ParseOptions.ParsePacket when strict parsing is
+// enabled and a packet contains bytes which are not accounted for by its
+// length fields, such as data following the last LSA of a LinkStateUpdate or
+// following the packet itself.
+type LengthError struct {
+	// Field describes the structure which the unexpected bytes follow, such
+	// as "LinkStateUpdate LSAs" or "packet".
+	Field string
+
+	// Offset is the offset in bytes of the first unexpected byte from the
+	// start of the OSPFv3 packet, and Length is the number of unexpected
+	// bytes.
+	Offset, Length int
+}
+
+// Error implements error.
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("ospf3: %d unexpected bytes following %s at offset %d", e.Length, e.Field, e.Offset)
+}
+
+// checkLSABodies verifies that each LSA body in a LinkStateUpdate p which was
+// kept as a *RawLSABody has no known format, rather than a known format which
+// could not be parsed.
+func checkLSABodies(p Packet) error {
+	lsu, ok := p.(*LinkStateUpdate)
+	if !ok {
+		return nil
+	}
+
+	off := lsuLen
+	for i, l := range lsu.LSAs {
+		if raw, ok := l.Body.(*RawLSABody); ok {
+			if _, err := ParseLSABody(l.Header.LSA.Type, raw.Data); err != nil {
+				return parseError(headerLen, linkStateUpdate.String(),
+					parseError(off, fmt.Sprintf("LSA %d", i), parseError(lsaHeaderLen, "", err)))
+			}
+		}
+
+		off += int(l.Header.Length)
+	}
+
+	return nil
+}
+
+// checkLengths verifies that every byte of b, which contains an OSPFv3 packet
+// of length plen and anything following it, is accounted for by the
+// already-parsed Packet p. Only an LLS data block when the L-bit is set and
+// a single Authentication Trailer may follow the packet.
+func checkLengths(p Packet, b []byte, plen int) error {
+	if lsu, ok := p.(*LinkStateUpdate); ok {
+		off := headerLen + lsuLen
+		for _, l := range lsu.LSAs {
+			off += int(l.Header.Length)
+		}
+		if off != plen {
+			return &LengthError{Field: "LinkStateUpdate LSAs", Offset: off, Length: plen - off}
+		}
+	}
+
+	// authTrailerOffset skips the LLS data block, if any.
+	end, err := authTrailerOffset(b)
+	if err != nil {
+		return &LengthError{Field: "packet", Offset: plen, Length: len(b) - plen}
+	}
+	if end == len(b) {
+		return nil
+	}
+
+	if _, n, err := parseAuthTrailer(b[end:]); err != nil || end+n != len(b) {
+		field := "packet"
+		if end != plen {
+			field = "LLS data block"
+		}
+		if err == nil {
+			// Report only the bytes following a valid trailer.
+			field = "Authentication Trailer"
+			end += n
+		}
+
+		return &LengthError{Field: field, Offset: end, Length: len(b) - end}
+	}
+
+	return nil
+}
+
+// checkReserved verifies that the reserved fields of the OSPFv3 packet bytes b
+// are zero, using the already-parsed Packet p to determine their offsets.
+func checkReserved(p Packet, b []byte) error {
+	c := reservedChecker{b: b}
+	c.zero("Header reserved", 15, 0xff)
+
+	switch p := p.(type) {
+	case *DatabaseDescription:
+		c.zero("DatabaseDescription reserved", headerLen, 0xff)
+		c.zero("DatabaseDescription reserved", headerLen+6, 0xff)
+		c.zero("DatabaseDescription flags", headerLen+7, ddFlagsReserved)
+	case *LinkStateRequest:
+		for i := range p.LSAs {
+			off := headerLen + i*lsaLen
+			c.zero("LinkStateRequest reserved", off, 0xff)
+			c.zero("LinkStateRequest reserved", off+1, 0xff)
+		}
+	case *LinkStateUpdate:
+		off := headerLen + lsuLen
+		for _, l := range p.LSAs {
+			c.lsa(l, off+lsaHeaderLen)
+			off += int(l.Header.Length)
+		}
+	}
+
+	return c.err
+}
+
+// A reservedChecker checks reserved fields in packet bytes, retaining the first
+// error encountered.
+type reservedChecker struct {
+	b   []byte
+	err error
+}
+
+// zero checks that the bits in mask of the byte at off are zero.
+func (c *reservedChecker) zero(field string, off int, mask byte) {
+	if c.err != nil {
+		return
+	}
+
+	if v := c.b[off] & mask; v != 0 {
+		c.err = &ReservedFieldError{
+			Field:  field,
+			Offset: off,
+			Value:  v,
+		}
+	}
+}
+
+// lsa checks the reserved fields of the body of l, which begins at off.
+func (c *reservedChecker) lsa(l LinkStateAdvertisement, off int) {
+	switch body := l.Body.(type) {
+	case *RouterLSABody:
+		c.zero("Router-LSA flags", off, routerFlagsReserved)
+		off += routerLSALen
+		for _, l := range body.Links {
+			off += routerLinkLen
+			for range l.MTMetrics {
+				c.zero("Router-LSA MT-ID metric reserved", off+1, 0xff)
+				off += mtMetricLen
+			}
+		}
+	case *NetworkLSABody:
+		c.zero("Network-LSA reserved", off, 0xff)
+	case *InterAreaPrefixLSABody:
+		c.zero("Inter-Area-Prefix-LSA reserved", off, 0xff)
+		c.prefixes("Inter-Area-Prefix-LSA", off+interAreaPrefixLSALen, 1, true)
+	case *InterAreaRouterLSABody:
+		c.zero("Inter-Area-Router-LSA reserved", off, 0xff)
+		c.zero("Inter-Area-Router-LSA reserved", off+4, 0xff)
+	case *ASExternalLSABody:
+		c.zero("AS-External-LSA flags", off, externalFlagsReserved)
+		c.prefixes("AS-External-LSA", off+externalLSALen-prefixLen, 1, false)
+	case *NSSALSABody:
+		c.zero("NSSA-LSA flags", off, externalFlagsReserved)
+		c.prefixes("NSSA-LSA", off+externalLSALen-prefixLen, 1, false)
+	case *LinkLSABody:
+		c.prefixes("Link-LSA", off+linkLSALen, len(body.Prefixes), true)
+	case *IntraAreaPrefixLSABody:
+		c.prefixes("Intra-Area-Prefix-LSA", off+intraAreaPrefixLSALen, len(body.Prefixes), false)
+	}
+}
+
+// prefixes checks n adjacent prefixes beginning at off. If reserved is true,
+// the 16 bits following each prefix's options are reserved. The padding bits
+// following each prefix's address are always checked.
+func (c *reservedChecker) prefixes(lsa string, off, n int, reserved bool) {
+	for i := 0; i < n; i++ {
+		if reserved {
+			c.zero(lsa+" prefix reserved", off+2, 0xff)
+			c.zero(lsa+" prefix reserved", off+3, 0xff)
+		}
+
+		// The address is padded to a 32-bit boundary, and any bits beyond the
+		// prefix length must be zero.
+		plen := int(c.b[off])
+		addr := off + prefixLen
+		for j := plen / 8; j < (Prefix{Length: uint8(plen)}).addrLen(); j++ {
+			mask := byte(0xff)
+			if j == plen/8 {
+				mask >>= plen % 8
+			}
+
+			c.zero(lsa+" prefix padding", addr+j, mask)
+		}
+
+		off = addr + (Prefix{Length: uint8(plen)}).addrLen()
+	}
+}
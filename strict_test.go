@@ -0,0 +1,295 @@
+package ospf3
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOptionsStrict(t *testing.T) {
+	// Offset of the first LSA body in a LinkStateUpdate.
+	const body = headerLen + lsuLen + lsaHeaderLen
+
+	lsu := func(typ LSType, b LSABody) []byte {
+		t.Helper()
+
+		buf, err := MarshalPacket(&LinkStateUpdate{
+			LSAs: []LinkStateAdvertisement{{
+				Header: LSAHeader{LSA: LSA{Type: typ}},
+				Body:   b,
+			}},
+		})
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+
+		return buf
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+		off  int
+		v    uint8
+		want *ReservedFieldError
+	}{
+		{
+			name: "Header",
+			b:    trimmed(bufHello),
+			off:  15,
+			v:    0x01,
+			want: &ReservedFieldError{Field: "Header reserved"},
+		},
+		{
+			name: "DatabaseDescription reserved",
+			b:    trimmed(bufDatabaseDescription),
+			off:  headerLen + 6,
+			v:    0x80,
+			want: &ReservedFieldError{Field: "DatabaseDescription reserved"},
+		},
+		{
+			name: "DatabaseDescription flags",
+			b:    trimmed(bufDatabaseDescription),
+			off:  headerLen + 7,
+			v:    0x10,
+			want: &ReservedFieldError{Field: "DatabaseDescription flags"},
+		},
+		{
+			name: "LinkStateRequest",
+			b:    trimmed(bufLinkStateRequest),
+			off:  headerLen + lsaLen + 1,
+			v:    0x01,
+			want: &ReservedFieldError{Field: "LinkStateRequest reserved"},
+		},
+		{
+			name: "Router-LSA flags",
+			b:    lsu(RouterLSA, lsaRouterLSABody),
+			off:  body,
+			v:    0x80,
+			want: &ReservedFieldError{Field: "Router-LSA flags"},
+		},
+		{
+			name: "Router-LSA MT-ID metric",
+			b: lsu(RouterLSA, &RouterLSABody{
+				Links: []RouterLink{
+					{Type: PointToPointLink},
+					{
+						Type:      TransitNetworkLink,
+						MTMetrics: []MTMetric{{ID: 2, Metric: 10}, {ID: 3, Metric: 20}},
+					},
+				},
+			}),
+			off:  body + routerLSALen + 2*routerLinkLen + mtMetricLen + 1,
+			v:    0x01,
+			want: &ReservedFieldError{Field: "Router-LSA MT-ID metric reserved"},
+		},
+		{
+			name: "Network-LSA",
+			b:    lsu(NetworkLSA, lsaNetworkLSABody),
+			off:  body,
+			v:    0x01,
+			want: &ReservedFieldError{Field: "Network-LSA reserved"},
+		},
+		{
+			name: "Inter-Area-Prefix-LSA prefix",
+			b:    lsu(InterAreaPrefixLSA, lsaInterAreaPrefixLSABody),
+			off:  body + 6,
+			v:    0x01,
+			want: &ReservedFieldError{Field: "Inter-Area-Prefix-LSA prefix reserved"},
+		},
+		{
+			name: "Inter-Area-Router-LSA",
+			b:    lsu(InterAreaRouterLSA, lsaInterAreaRouterLSABody),
+			off:  body + 4,
+			v:    0x01,
+			want: &ReservedFieldError{Field: "Inter-Area-Router-LSA reserved"},
+		},
+		{
+			name: "AS-External-LSA flags",
+			b:    lsu(ASExternalLSA, lsaASExternalLSABody),
+			off:  body,
+			v:    0x08,
+			want: &ReservedFieldError{Field: "AS-External-LSA flags"},
+		},
+		{
+			name: "NSSA-LSA prefix padding",
+			// 48 bit prefix, so the last 16 bits of the second word are
+			// padding.
+			b:    lsu(NSSALSA, lsaNSSALSABody),
+			off:  body + externalLSALen + 7,
+			v:    0x01,
+			want: &ReservedFieldError{Field: "NSSA-LSA prefix padding"},
+		},
+		{
+			name: "Link-LSA second prefix",
+			b:    lsu(LinkLSA, lsaLinkLSABody),
+			off:  body + linkLSALen + prefixLen + 8 + 2,
+			v:    0x01,
+			want: &ReservedFieldError{Field: "Link-LSA prefix reserved"},
+		},
+		{
+			name: "Intra-Area-Prefix-LSA prefix padding",
+			b: lsu(IntraAreaPrefixLSA, &IntraAreaPrefixLSABody{
+				Prefixes: []IntraAreaPrefix{{
+					Prefix: Prefix{Length: 29, Address: lsaIntraAreaPrefixLSABody.Prefixes[0].Prefix.Address},
+				}},
+			}),
+			// Only the low 3 bits of the last byte are padding.
+			off:  body + intraAreaPrefixLSALen + prefixLen + 3,
+			v:    0x04,
+			want: &ReservedFieldError{Field: "Intra-Area-Prefix-LSA prefix padding"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The unmodified packet must be accepted.
+			b := append([]byte(nil), tt.b...)
+			strict := ParseOptions{Strict: true}
+			if _, err := strict.ParsePacket(b); err != nil {
+				t.Fatalf("failed to parse valid Packet: %v", err)
+			}
+
+			b[tt.off] |= tt.v
+
+			// Reserved fields are ignored by default.
+			if _, err := ParsePacket(b); err != nil {
+				t.Fatalf("failed to parse permissively: %v", err)
+			}
+
+			_, err := strict.ParsePacket(b)
+
+			var rerr *ReservedFieldError
+			if !errors.As(err, &rerr) {
+				t.Fatalf("expected *ReservedFieldError, but got: %v", err)
+			}
+
+			tt.want.Offset = tt.off
+			tt.want.Value = tt.v
+			if diff := cmp.Diff(tt.want, rerr); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseOptionsStrictRoundTrip(t *testing.T) {
+	// Packets produced by MarshalPacket must always pass strict parsing.
+	strict := ParseOptions{Strict: true}
+	for _, tt := range roundTripTests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := MarshalPacket(tt.p)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if _, err := strict.ParsePacket(b); err != nil {
+				t.Fatalf("failed to parse strictly: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseOptionsStrictLengths(t *testing.T) {
+	marshal := func(p Packet, o MarshalOptions) []byte {
+		t.Helper()
+
+		b, err := o.MarshalPacket(p)
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+
+		return b
+	}
+
+	var (
+		src = net.ParseIP("fe80::1")
+		a   = HMACAuthenticator{1: {Hash: sha256.New, Key: []byte("ospf3")}}
+		ao  = MarshalOptions{Source: src, Authenticator: a, SAID: 1}
+
+		lls = &Hello{
+			Options:     V6Bit | LBit | ATBit,
+			NeighborIDs: []ID{},
+			LLS:         &LLS{ExtendedOptions: LRBit},
+		}
+
+		lsu = marshal(pktLinkStateUpdate, MarshalOptions{})
+	)
+
+	// Add bytes inside the LinkStateUpdate's packet length which do not
+	// belong to any LSA.
+	lsuExtra := append(append([]byte(nil), lsu...), 0x00, 0x00, 0x00, 0x00)
+	binary.BigEndian.PutUint16(lsuExtra[2:4], uint16(len(lsuExtra)))
+
+	tests := []struct {
+		name string
+		b    []byte
+		want *LengthError
+	}{
+		{
+			name: "exact",
+			b:    trimmed(bufHello),
+		},
+		{
+			name: "LLS and Authentication Trailer",
+			b:    marshal(lls, ao),
+		},
+		{
+			name: "trailing packet",
+			b:    bufHello,
+			want: &LengthError{Field: "packet", Offset: 44, Length: 4},
+		},
+		{
+			name: "trailing LinkStateUpdate LSAs",
+			b:    lsuExtra,
+			want: &LengthError{Field: "LinkStateUpdate LSAs", Offset: len(lsu), Length: 4},
+		},
+		{
+			name: "trailing LLS",
+			b:    append(marshal(lls, MarshalOptions{}), 0xff),
+			want: &LengthError{Field: "LLS data block", Offset: lls.len() + lls.LLS.len(), Length: 1},
+		},
+		{
+			name: "trailing Authentication Trailer",
+			b:    append(marshal(pktLinkStateAcknowledgement, ao), 0xff),
+			want: &LengthError{
+				Field:  "Authentication Trailer",
+				Offset: len(marshal(pktLinkStateAcknowledgement, ao)),
+				Length: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Unexpected bytes are ignored by default.
+			if _, err := ParsePacket(tt.b); err != nil {
+				t.Fatalf("failed to parse permissively: %v", err)
+			}
+
+			_, err := ParseOptions{Strict: true}.ParsePacket(tt.b)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("failed to parse strictly: %v", err)
+				}
+				return
+			}
+
+			var lerr *LengthError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("expected *LengthError, but got: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, lerr); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// trimmed returns b without bufTrailing, which is rejected by strict parsing.
+func trimmed(b []byte) []byte { return b[:len(b)-len(bufTrailing)] }
@@ -0,0 +1,384 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+)
+
+// Intra-Area-TE-LSA TLV and sub-TLV types as described in RFC3630, section 2.4
+// and RFC5329, sections 3 and 4.
+const (
+	tlvTELink          = 2
+	tlvTERouterAddress = 3
+
+	subTLVLinkType               = 1
+	subTLVLinkID                 = 2
+	subTLVTEMetric               = 5
+	subTLVMaxBandwidth           = 6
+	subTLVMaxReservableBandwidth = 7
+	subTLVUnreservedBandwidth    = 8
+	subTLVAdminGroup             = 9
+	subTLVNeighborID             = 18
+	subTLVLocalAddresses         = 19
+	subTLVRemoteAddresses        = 20
+
+	// unreservedPriorities is the number of priority levels in the
+	// Unreserved Bandwidth sub-TLV.
+	unreservedPriorities = 8
+)
+
+// A TELinkType is the type of a traffic engineering link as described in
+// RFC3630, section 2.5.1.
+type TELinkType uint8
+
+// Possible TELinkType values.
+const (
+	PointToPointTELink TELinkType = 1
+	MultiAccessTELink  TELinkType = 2
+)
+
+// An IntraAreaTELSABody is the body of an OSPFv3 Intra-Area-TE-LSA as described
+// in RFC5329, section 3.
+type IntraAreaTELSABody struct {
+	// RouterAddress, if set, is encoded as a Router IPv6 Address TLV.
+	RouterAddress net.IP
+
+	// Links are encoded as Link TLVs.
+	Links []TELink
+
+	// TLVs contains any other TLVs in the order in which they appear, which
+	// are marshaled following the Router IPv6 Address and Link TLVs.
+	TLVs []TLV
+}
+
+// A TELink is a Link TLV in an IntraAreaTELSABody as described in RFC5329,
+// section 4. Optional sub-TLVs are only marshaled when the corresponding field
+// is set.
+type TELink struct {
+	Type TELinkType
+	ID   ID
+
+	// Neighbor, if set, is encoded as a Neighbor ID sub-TLV.
+	Neighbor *TENeighbor
+
+	// Local and remote IPv6 addresses for the link.
+	LocalAddresses, RemoteAddresses []net.IP
+
+	// Optional traffic engineering parameters. Bandwidths are in bytes per
+	// second, and UnreservedBandwidth is indexed by priority.
+	Metric                 *uint32
+	MaxBandwidth           *float32
+	MaxReservableBandwidth *float32
+	UnreservedBandwidth    *[unreservedPriorities]float32
+	AdminGroup             *uint32
+
+	// SubTLVs contains any other sub-TLVs in the order in which they appear,
+	// which are marshaled following all other sub-TLVs.
+	SubTLVs []TLV
+}
+
+// A TENeighbor identifies the neighbor of a TELink as described in RFC5329,
+// section 4.3.
+type TENeighbor struct {
+	InterfaceID uint32
+	RouterID    ID
+}
+
+// MarshalBinary packs an IntraAreaTELSABody into bytes.
+func (te *IntraAreaTELSABody) MarshalBinary() ([]byte, error) {
+	b := make([]byte, te.len())
+	if err := te.marshal(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks an IntraAreaTELSABody from bytes.
+func (te *IntraAreaTELSABody) UnmarshalBinary(b []byte) error {
+	return te.unmarshal(b)
+}
+
+// tlvs returns all of the TLVs for the IntraAreaTELSABody in marshaling order.
+func (te *IntraAreaTELSABody) tlvs() ([]TLV, error) {
+	tlvs := make([]TLV, 0, 1+len(te.Links)+len(te.TLVs))
+
+	if te.RouterAddress != nil {
+		if len(te.RouterAddress) != net.IPv6len {
+			return nil, fmt.Errorf("Intra-Area-TE-LSA router address %v must be a 16 byte IPv6 address: %w",
+				te.RouterAddress, errMarshal)
+		}
+
+		tlvs = append(tlvs, TLV{Type: tlvTERouterAddress, Value: te.RouterAddress})
+	}
+
+	for _, l := range te.Links {
+		v, err := l.marshal()
+		if err != nil {
+			return nil, fmt.Errorf("Intra-Area-TE-LSA %w", err)
+		}
+
+		tlvs = append(tlvs, TLV{Type: tlvTELink, Value: v})
+	}
+
+	return append(tlvs, te.TLVs...), nil
+}
+
+// len returns the length of an IntraAreaTELSABody in bytes.
+func (te *IntraAreaTELSABody) len() int {
+	// Invalid bodies are reported by marshal.
+	tlvs, _ := te.tlvs()
+	return tlvsLen(tlvs)
+}
+
+// marshal stores the IntraAreaTELSABody bytes into b. It assumes b has
+// allocated enough space for an IntraAreaTELSABody to avoid a panic.
+func (te *IntraAreaTELSABody) marshal(b []byte) error {
+	tlvs, err := te.tlvs()
+	if err != nil {
+		return err
+	}
+
+	if err := marshalTLVs(b, tlvs); err != nil {
+		return fmt.Errorf("Intra-Area-TE-LSA %w", err)
+	}
+
+	return nil
+}
+
+// unmarshal unpacks an IntraAreaTELSABody from b.
+func (te *IntraAreaTELSABody) unmarshal(b []byte) error {
+	*te = IntraAreaTELSABody{}
+
+	var addr bool
+	return parseTLVs(b, func(typ uint16, v []byte) error {
+		switch {
+		case typ == tlvTERouterAddress && !addr:
+			if l := len(v); l != net.IPv6len {
+				return fmt.Errorf("Router IPv6 Address TLV must be exactly %d bytes, got %d bytes: %w",
+					net.IPv6len, l, errParse)
+			}
+
+			te.RouterAddress = make(net.IP, net.IPv6len)
+			copy(te.RouterAddress, v)
+			addr = true
+		case typ == tlvTELink:
+			var l TELink
+			if err := l.unmarshal(v); err != nil {
+				return err
+			}
+
+			te.Links = append(te.Links, l)
+		default:
+			te.TLVs = append(te.TLVs, TLV{
+				Type:  typ,
+				Value: append([]byte(nil), v...),
+			})
+		}
+
+		return nil
+	})
+}
+
+// marshal packs the sub-TLVs of a TELink into the value of a Link TLV.
+func (l TELink) marshal() ([]byte, error) {
+	u32 := func(v uint32) []byte {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, v)
+		return b
+	}
+
+	// The Link Type and Link ID sub-TLVs are mandatory.
+	tlvs := []TLV{
+		{Type: subTLVLinkType, Value: []byte{byte(l.Type)}},
+		{Type: subTLVLinkID, Value: l.ID[:]},
+	}
+
+	if l.Neighbor != nil {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint32(b[0:4], l.Neighbor.InterfaceID)
+		copy(b[4:8], l.Neighbor.RouterID[:])
+		tlvs = append(tlvs, TLV{Type: subTLVNeighborID, Value: b})
+	}
+
+	for _, a := range []struct {
+		typ   uint16
+		addrs []net.IP
+	}{
+		{typ: subTLVLocalAddresses, addrs: l.LocalAddresses},
+		{typ: subTLVRemoteAddresses, addrs: l.RemoteAddresses},
+	} {
+		if len(a.addrs) == 0 {
+			continue
+		}
+
+		b := make([]byte, 0, len(a.addrs)*net.IPv6len)
+		for _, ip := range a.addrs {
+			if len(ip) != net.IPv6len {
+				return nil, fmt.Errorf("TE link address %v must be a 16 byte IPv6 address: %w", ip, errMarshal)
+			}
+
+			b = append(b, ip...)
+		}
+
+		tlvs = append(tlvs, TLV{Type: a.typ, Value: b})
+	}
+
+	if l.Metric != nil {
+		tlvs = append(tlvs, TLV{Type: subTLVTEMetric, Value: u32(*l.Metric)})
+	}
+	if l.MaxBandwidth != nil {
+		tlvs = append(tlvs, TLV{Type: subTLVMaxBandwidth, Value: u32(math.Float32bits(*l.MaxBandwidth))})
+	}
+	if l.MaxReservableBandwidth != nil {
+		tlvs = append(tlvs, TLV{Type: subTLVMaxReservableBandwidth, Value: u32(math.Float32bits(*l.MaxReservableBandwidth))})
+	}
+	if l.UnreservedBandwidth != nil {
+		b := make([]byte, 0, 4*unreservedPriorities)
+		for _, bw := range l.UnreservedBandwidth {
+			b = append(b, u32(math.Float32bits(bw))...)
+		}
+
+		tlvs = append(tlvs, TLV{Type: subTLVUnreservedBandwidth, Value: b})
+	}
+	if l.AdminGroup != nil {
+		tlvs = append(tlvs, TLV{Type: subTLVAdminGroup, Value: u32(*l.AdminGroup)})
+	}
+
+	tlvs = append(tlvs, l.SubTLVs...)
+
+	b := make([]byte, tlvsLen(tlvs))
+	if err := marshalTLVs(b, tlvs); err != nil {
+		return nil, fmt.Errorf("Link %w", err)
+	}
+
+	return b, nil
+}
+
+// unmarshal unpacks a TELink from the value of a Link TLV.
+func (l *TELink) unmarshal(b []byte) error {
+	// exact verifies the length of a fixed length sub-TLV.
+	exact := func(name string, v []byte, n int) error {
+		if l := len(v); l != n {
+			return fmt.Errorf("%s sub-TLV must be exactly %d bytes, got %d bytes: %w", name, n, l, errParse)
+		}
+
+		return nil
+	}
+
+	// addrs parses an array of IPv6 addresses from a sub-TLV.
+	addrs := func(name string, v []byte) ([]net.IP, error) {
+		if l := len(v); l == 0 || l%net.IPv6len != 0 {
+			return nil, fmt.Errorf("%s sub-TLV must contain 16 byte IPv6 addresses, got %d bytes: %w", name, l, errParse)
+		}
+
+		ips := make([]net.IP, 0, len(v)/net.IPv6len)
+		for i := 0; i < len(v); i += net.IPv6len {
+			ip := make(net.IP, net.IPv6len)
+			copy(ip, v[i:i+net.IPv6len])
+			ips = append(ips, ip)
+		}
+
+		return ips, nil
+	}
+
+	f32 := func(v []byte) float32 { return math.Float32frombits(binary.BigEndian.Uint32(v)) }
+
+	var typ, id bool
+	err := parseTLVs(b, func(t uint16, v []byte) error {
+		switch t {
+		case subTLVLinkType:
+			if err := exact("Link Type", v, 1); err != nil {
+				return err
+			}
+
+			l.Type = TELinkType(v[0])
+			typ = true
+		case subTLVLinkID:
+			if err := exact("Link ID", v, 4); err != nil {
+				return err
+			}
+
+			copy(l.ID[:], v)
+			id = true
+		case subTLVNeighborID:
+			if err := exact("Neighbor ID", v, 8); err != nil {
+				return err
+			}
+
+			l.Neighbor = &TENeighbor{InterfaceID: binary.BigEndian.Uint32(v[0:4])}
+			copy(l.Neighbor.RouterID[:], v[4:8])
+		case subTLVLocalAddresses:
+			ips, err := addrs("Local Interface IPv6 Address", v)
+			if err != nil {
+				return err
+			}
+
+			l.LocalAddresses = ips
+		case subTLVRemoteAddresses:
+			ips, err := addrs("Remote Interface IPv6 Address", v)
+			if err != nil {
+				return err
+			}
+
+			l.RemoteAddresses = ips
+		case subTLVTEMetric:
+			if err := exact("Traffic Engineering Metric", v, 4); err != nil {
+				return err
+			}
+
+			m := binary.BigEndian.Uint32(v)
+			l.Metric = &m
+		case subTLVMaxBandwidth:
+			if err := exact("Maximum Bandwidth", v, 4); err != nil {
+				return err
+			}
+
+			bw := f32(v)
+			l.MaxBandwidth = &bw
+		case subTLVMaxReservableBandwidth:
+			if err := exact("Maximum Reservable Bandwidth", v, 4); err != nil {
+				return err
+			}
+
+			bw := f32(v)
+			l.MaxReservableBandwidth = &bw
+		case subTLVUnreservedBandwidth:
+			if err := exact("Unreserved Bandwidth", v, 4*unreservedPriorities); err != nil {
+				return err
+			}
+
+			var bws [unreservedPriorities]float32
+			for i := range bws {
+				bws[i] = f32(v[i*4:])
+			}
+
+			l.UnreservedBandwidth = &bws
+		case subTLVAdminGroup:
+			if err := exact("Administrative Group", v, 4); err != nil {
+				return err
+			}
+
+			g := binary.BigEndian.Uint32(v)
+			l.AdminGroup = &g
+		default:
+			l.SubTLVs = append(l.SubTLVs, TLV{
+				Type:  t,
+				Value: append([]byte(nil), v...),
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Link TLV: %w", err)
+	}
+
+	if !typ || !id {
+		return fmt.Errorf("Link TLV must contain Link Type and Link ID sub-TLVs: %w", errParse)
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package ospf3
+
+import (
+	"math"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	bufIntraAreaTELSABody = merge(
+		[]byte{0x00, 0x03, 0x00, 0x10}, // Router IPv6 Address TLV
+		net.ParseIP("2001:db8::1"),
+		[]byte{
+			0x00, 0x02, 0x00, 0x6c, // Link TLV
+			0x00, 0x01, 0x00, 0x01, // Link Type
+			0x01, 0x00, 0x00, 0x00, // Point-to-point, padding
+			0x00, 0x02, 0x00, 0x04, // Link ID
+			192, 0, 2, 2,
+			0x00, 0x12, 0x00, 0x08, // Neighbor ID
+			0x00, 0x00, 0x00, 0x05, // Neighbor interface ID
+			192, 0, 2, 2, // Neighbor router ID
+			0x00, 0x13, 0x00, 0x10, // Local Interface IPv6 Address
+		},
+		net.ParseIP("2001:db8::1"),
+		[]byte{
+			0x00, 0x05, 0x00, 0x04, // Traffic Engineering Metric
+			0x00, 0x00, 0x00, 0x0a,
+			0x00, 0x06, 0x00, 0x04, // Maximum Bandwidth
+			0x4e, 0xee, 0x6b, 0x28, // 2e9 bytes/s
+			0x00, 0x08, 0x00, 0x20, // Unreserved Bandwidth
+			0x4e, 0xee, 0x6b, 0x28,
+			0x4e, 0xee, 0x6b, 0x28,
+			0x4e, 0xee, 0x6b, 0x28,
+			0x4e, 0xee, 0x6b, 0x28,
+			0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00,
+			0x80, 0x01, 0x00, 0x01, // Unknown sub-TLV
+			0xff, 0x00, 0x00, 0x00,
+		},
+	)
+
+	lsaIntraAreaTELSABody = &IntraAreaTELSABody{
+		RouterAddress: net.ParseIP("2001:db8::1"),
+		Links: []TELink{{
+			Type: PointToPointTELink,
+			ID:   ID{192, 0, 2, 2},
+			Neighbor: &TENeighbor{
+				InterfaceID: 5,
+				RouterID:    ID{192, 0, 2, 2},
+			},
+			LocalAddresses: []net.IP{net.ParseIP("2001:db8::1")},
+			Metric:         uint32p(10),
+			MaxBandwidth:   float32p(2e9),
+			UnreservedBandwidth: &[8]float32{
+				2e9, 2e9, 2e9, 2e9,
+			},
+			SubTLVs: []TLV{{Type: 0x8001, Value: []byte{0xff}}},
+		}},
+	}
+)
+
+func TestIntraAreaTELSABodyParseErrors(t *testing.T) {
+	link := func(sub ...byte) []byte {
+		return merge([]byte{0x00, 0x02, 0x00, byte(len(sub))}, sub)
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{
+			name: "short router address",
+			b:    []byte{0x00, 0x03, 0x00, 0x04, 0x20, 0x01, 0x0d, 0xb8},
+		},
+		{
+			name: "no link type",
+			b: link(
+				0x00, 0x02, 0x00, 0x04, 192, 0, 2, 2,
+			),
+		},
+		{
+			name: "no link ID",
+			b: link(
+				0x00, 0x01, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00,
+			),
+		},
+		{
+			name: "bad metric",
+			b: link(
+				0x00, 0x01, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00,
+				0x00, 0x02, 0x00, 0x04, 192, 0, 2, 2,
+				0x00, 0x05, 0x00, 0x02, 0x00, 0x0a, 0x00, 0x00,
+			),
+		},
+		{
+			name: "bad addresses",
+			b: link(
+				0x00, 0x01, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00,
+				0x00, 0x02, 0x00, 0x04, 192, 0, 2, 2,
+				0x00, 0x14, 0x00, 0x04, 0x20, 0x01, 0x0d, 0xb8,
+			),
+		},
+		{
+			name: "truncated sub-TLV",
+			b: link(
+				0x00, 0x01, 0x00, 0x08, 0x01, 0x00, 0x00, 0x00,
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseLSABody(IntraAreaTELSA, tt.b); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+func TestIntraAreaTELSABodyMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body *IntraAreaTELSABody
+	}{
+		{
+			name: "router address",
+			body: &IntraAreaTELSABody{RouterAddress: net.IPv4(192, 0, 2, 1).To4()},
+		},
+		{
+			name: "link address",
+			body: &IntraAreaTELSABody{
+				Links: []TELink{{RemoteAddresses: []net.IP{net.IPv4(192, 0, 2, 1).To4()}}},
+			},
+		},
+		{
+			name: "sub-TLV too long",
+			body: &IntraAreaTELSABody{
+				Links: []TELink{{SubTLVs: []TLV{{Value: make([]byte, math.MaxUint16+1)}}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.body.MarshalBinary(); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+func TestIntraAreaTELSABodyEmptyLink(t *testing.T) {
+	// A Link with only the mandatory sub-TLVs must round trip without any
+	// optional fields being set.
+	want := &IntraAreaTELSABody{
+		Links: []TELink{{Type: MultiAccessTELink, ID: ID{192, 0, 2, 1}}},
+	}
+
+	b, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	got, err := ParseLSABody(IntraAreaTELSA, b)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected body (-want +got):\n%s", diff)
+	}
+}
+
+func uint32p(v uint32) *uint32    { return &v }
+func float32p(v float32) *float32 { return &v }
@@ -0,0 +1,109 @@
+package ospf3
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Timers contains the OSPFv3 protocol timer values for an interface as
+// described in RFC2328, appendices B and C.3. Use DefaultTimers to obtain a
+// Timers value populated with the RFC defaults.
+//
+// MaxAge is an architectural constant which must be identical on all routers,
+// and is therefore not configurable.
+type Timers struct {
+	// HelloInterval is the interval between Hello packets sent on the
+	// interface.
+	HelloInterval time.Duration
+
+	// RouterDeadInterval is the interval after which a neighbor is declared
+	// down if no Hello packets have been received. It must be greater than
+	// HelloInterval.
+	RouterDeadInterval time.Duration
+
+	// RxmtInterval is the interval between LSA retransmissions for
+	// adjacencies on the interface.
+	RxmtInterval time.Duration
+
+	// InfTransDelay is the estimated time required to transmit a Link State
+	// Update packet over the interface, which is added to the age of each
+	// transmitted LSA.
+	InfTransDelay time.Duration
+
+	// Wait is the interval a router waits to observe an existing Designated
+	// Router before electing one. It is typically equal to RouterDeadInterval.
+	Wait time.Duration
+
+	// LSRefreshTime is the maximum age of a self-originated LSA before it is
+	// refreshed. It must be less than MaxAge.
+	LSRefreshTime time.Duration
+
+	// PollInterval is the interval between Hello packets sent to neighbors
+	// on an NBMA interface which are down, as described in RFC2328, section
+	// 9.5.1. It should be much larger than HelloInterval. It is only used on
+	// NBMA interfaces, and is ignored by Validate if zero.
+	PollInterval time.Duration
+}
+
+// DefaultTimers returns Timers populated with the default values suggested by
+// RFC2328, appendices B and C.3.
+func DefaultTimers() Timers {
+	return Timers{
+		HelloInterval:      10 * time.Second,
+		RouterDeadInterval: 40 * time.Second,
+		RxmtInterval:       5 * time.Second,
+		InfTransDelay:      1 * time.Second,
+		Wait:               40 * time.Second,
+		LSRefreshTime:      LSRefreshTime,
+		PollInterval:       120 * time.Second,
+	}
+}
+
+// Validate verifies that the Timers values are usable and consistent with
+// one another.
+func (t Timers) Validate() error {
+	// These values are transmitted on the wire as 16-bit seconds.
+	for _, v := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{name: "HelloInterval", d: t.HelloInterval},
+		{name: "RouterDeadInterval", d: t.RouterDeadInterval},
+		{name: "InfTransDelay", d: t.InfTransDelay},
+	} {
+		if v.d < time.Second || v.d > math.MaxUint16*time.Second {
+			return fmt.Errorf("ospf3: %s must be between 1 and %d seconds: %v", v.name, math.MaxUint16, v.d)
+		}
+	}
+
+	// InfTransDelay is added to LSA ages, which cannot exceed MaxAge.
+	if t.InfTransDelay > MaxAge {
+		return fmt.Errorf("ospf3: InfTransDelay must not exceed MaxAge: %v", t.InfTransDelay)
+	}
+
+	if t.RouterDeadInterval <= t.HelloInterval {
+		return fmt.Errorf("ospf3: RouterDeadInterval %v must be greater than HelloInterval %v",
+			t.RouterDeadInterval, t.HelloInterval)
+	}
+
+	if t.RxmtInterval <= 0 {
+		return fmt.Errorf("ospf3: RxmtInterval must be positive: %v", t.RxmtInterval)
+	}
+
+	if t.Wait <= 0 {
+		return fmt.Errorf("ospf3: Wait must be positive: %v", t.Wait)
+	}
+
+	// RFC2328, appendix B fixes MaxAge at 1 hour.
+	if t.LSRefreshTime <= 0 || t.LSRefreshTime >= MaxAge {
+		return fmt.Errorf("ospf3: LSRefreshTime must be positive and less than MaxAge: %v", t.LSRefreshTime)
+	}
+
+	if t.PollInterval != 0 && t.PollInterval < t.HelloInterval {
+		return fmt.Errorf("ospf3: PollInterval %v must not be less than HelloInterval %v",
+			t.PollInterval, t.HelloInterval)
+	}
+
+	return nil
+}
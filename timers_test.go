@@ -0,0 +1,91 @@
+package ospf3
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimersValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *Timers)
+		ok   bool
+	}{
+		{
+			name: "OK defaults",
+			fn:   func(_ *Timers) {},
+			ok:   true,
+		},
+		{
+			name: "OK fast hellos",
+			fn: func(t *Timers) {
+				t.HelloInterval = 1 * time.Second
+				t.RouterDeadInterval = 3 * time.Second
+				t.Wait = 3 * time.Second
+			},
+			ok: true,
+		},
+		{
+			name: "zero HelloInterval",
+			fn:   func(t *Timers) { t.HelloInterval = 0 },
+		},
+		{
+			name: "sub-second HelloInterval",
+			fn:   func(t *Timers) { t.HelloInterval = 500 * time.Millisecond },
+		},
+		{
+			name: "large RouterDeadInterval",
+			fn:   func(t *Timers) { t.RouterDeadInterval = 65536 * time.Second },
+		},
+		{
+			name: "RouterDeadInterval equals HelloInterval",
+			fn:   func(t *Timers) { t.RouterDeadInterval = t.HelloInterval },
+		},
+		{
+			name: "zero RxmtInterval",
+			fn:   func(t *Timers) { t.RxmtInterval = 0 },
+		},
+		{
+			name: "zero InfTransDelay",
+			fn:   func(t *Timers) { t.InfTransDelay = 0 },
+		},
+		{
+			name: "InfTransDelay exceeds MaxAge",
+			fn:   func(t *Timers) { t.InfTransDelay = MaxAge + time.Second },
+		},
+		{
+			name: "zero Wait",
+			fn:   func(t *Timers) { t.Wait = 0 },
+		},
+		{
+			name: "OK zero PollInterval",
+			fn:   func(t *Timers) { t.PollInterval = 0 },
+			ok:   true,
+		},
+		{
+			name: "PollInterval less than HelloInterval",
+			fn:   func(t *Timers) { t.PollInterval = 5 * time.Second },
+		},
+		{
+			name: "LSRefreshTime MaxAge",
+			fn:   func(t *Timers) { t.LSRefreshTime = time.Hour },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timers := DefaultTimers()
+			tt.fn(&timers)
+
+			err := timers.Validate()
+			if tt.ok && err != nil {
+				t.Fatalf("failed to validate: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+
+			t.Logf("err: %v", err)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package ospf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// tlvHeaderLen is the length of the type and length fields of a TLV.
+const tlvHeaderLen = 4
+
+// A TLV is a generic type/length/value structure carried in the body of
+// TLV-based LSAs, as described in RFC7770, section 2.3. On the wire, Value is
+// padded with zeros to a 32-bit boundary. The padding is not included in Value.
+type TLV struct {
+	Type  uint16
+	Value []byte
+}
+
+// MarshalTLVs packs adjacent TLVs into bytes, padding each value with zeros to
+// a 32-bit boundary. It may be used to implement an LSABody for a TLV-based LSA
+// registered using RegisterLSAType.
+func MarshalTLVs(tlvs []TLV) ([]byte, error) {
+	b := make([]byte, tlvsLen(tlvs))
+	if err := marshalTLVs(b, tlvs); err != nil {
+		return nil, fmt.Errorf("ospf3: %w", err)
+	}
+
+	return b, nil
+}
+
+// ParseTLVs parses adjacent TLVs which must consume all of b, including the
+// padding following each value. Each TLV's Value is a copy of the bytes in b
+// and excludes padding.
+func ParseTLVs(b []byte) ([]TLV, error) {
+	var tlvs []TLV
+	err := parseTLVs(b, func(typ uint16, v []byte) error {
+		tlvs = append(tlvs, TLV{
+			Type:  typ,
+			Value: append([]byte(nil), v...),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ospf3: %w", err)
+	}
+
+	return tlvs, nil
+}
+
+// len returns the length of a TLV in bytes, including padding.
+func (t TLV) len() int { return tlvHeaderLen + pad32(len(t.Value)) }
+
+// validate checks if the TLV can be marshaled.
+func (t TLV) validate() error {
+	if l := len(t.Value); l > math.MaxUint16 {
+		return fmt.Errorf("TLV type %d value is too long: %d bytes: %w", t.Type, l, errMarshal)
+	}
+
+	return nil
+}
+
+// marshal packs a TLV's bytes into b. It assumes b has allocated enough space
+// for the TLV and that the TLV has been validated.
+func (t TLV) marshal(b []byte) {
+	binary.BigEndian.PutUint16(b[0:2], t.Type)
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(t.Value)))
+	n := copy(b[tlvHeaderLen:], t.Value)
+
+	// Zero any padding in case b was reused.
+	for i := tlvHeaderLen + n; i < t.len(); i++ {
+		b[i] = 0
+	}
+}
+
+// tlvsLen returns the total length of tlvs in bytes, including padding.
+func tlvsLen(tlvs []TLV) int {
+	var n int
+	for _, t := range tlvs {
+		n += t.len()
+	}
+
+	return n
+}
+
+// marshalTLVs validates and packs adjacent tlvs into b. It assumes b has
+// allocated enough space for tlvs to avoid a panic.
+func marshalTLVs(b []byte, tlvs []TLV) error {
+	var n int
+	for _, t := range tlvs {
+		if err := t.validate(); err != nil {
+			return err
+		}
+
+		t.marshal(b[n:])
+		n += t.len()
+	}
+
+	return nil
+}
+
+// parseTLVs parses adjacent TLVs which must consume all of b, calling fn with
+// the type and value of each. The value aliases b and excludes padding.
+func parseTLVs(b []byte, fn func(typ uint16, v []byte) error) error {
+	for len(b) > 0 {
+		if l := len(b); l < tlvHeaderLen {
+			return fmt.Errorf("not enough bytes for TLV: %d: %w", l, errParse)
+		}
+
+		var (
+			typ = binary.BigEndian.Uint16(b[0:2])
+			vl  = int(binary.BigEndian.Uint16(b[2:4]))
+			n   = tlvHeaderLen + pad32(vl)
+		)
+
+		if l := len(b); l < n {
+			return fmt.Errorf("TLV type %d requires %d bytes but only %d bytes are available: %w",
+				typ, n, l, errParse)
+		}
+
+		if err := fn(typ, b[tlvHeaderLen:tlvHeaderLen+vl]); err != nil {
+			return err
+		}
+
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// pad32 rounds n up to a 32-bit boundary.
+func pad32(n int) int { return (n + 3) &^ 3 }
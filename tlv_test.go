@@ -0,0 +1,99 @@
+package ospf3
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestTLVsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+		tlvs []TLV
+	}{
+		{
+			name: "empty",
+			b:    []byte{},
+		},
+		{
+			name: "empty value",
+			b:    []byte{0x00, 0x01, 0x00, 0x00},
+			tlvs: []TLV{{Type: 1, Value: []byte{}}},
+		},
+		{
+			name: "padding",
+			b: []byte{
+				0x00, 0x01, 0x00, 0x01,
+				0xff, 0x00, 0x00, 0x00,
+				0x80, 0x00, 0x00, 0x04,
+				0xde, 0xad, 0xbe, 0xef,
+				0xff, 0xff, 0x00, 0x05,
+				0x01, 0x02, 0x03, 0x04,
+				0x05, 0x00, 0x00, 0x00,
+			},
+			tlvs: []TLV{
+				{Type: 1, Value: []byte{0xff}},
+				{Type: 0x8000, Value: []byte{0xde, 0xad, 0xbe, 0xef}},
+				{Type: 0xffff, Value: []byte{0x01, 0x02, 0x03, 0x04, 0x05}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlvs, err := ParseTLVs(tt.b)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.tlvs, tlvs, cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("unexpected TLVs (-want +got):\n%s", diff)
+			}
+
+			b, err := MarshalTLVs(tlvs)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.b, b); diff != "" {
+				t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseTLVsCopies(t *testing.T) {
+	b := []byte{0x00, 0x01, 0x00, 0x01, 0xff, 0x00, 0x00, 0x00}
+
+	tlvs, err := ParseTLVs(b)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	b[4] = 0x00
+	if diff := cmp.Diff([]byte{0xff}, tlvs[0].Value); diff != "" {
+		t.Fatalf("TLV value aliases input (-want +got):\n%s", diff)
+	}
+}
+
+func TestTLVsErrors(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x00},
+		{0x00, 0x01, 0x00, 0x04, 0xff},
+		// Value fits, but padding does not.
+		{0x00, 0x01, 0x00, 0x01, 0xff},
+	} {
+		_, err := ParseTLVs(b)
+		if diff := cmp.Diff(errParse, err, cmpopts.EquateErrors()); diff != "" {
+			t.Fatalf("unexpected parse error for %#v (-want +got):\n%s", b, diff)
+		}
+	}
+
+	_, err := MarshalTLVs([]TLV{{Value: make([]byte, math.MaxUint16+1)}})
+	if diff := cmp.Diff(errMarshal, err, cmpopts.EquateErrors()); diff != "" {
+		t.Fatalf("unexpected marshal error (-want +got):\n%s", diff)
+	}
+}
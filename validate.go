@@ -0,0 +1,167 @@
+package ospf3
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Constants used to validate packet fields.
+const (
+	// minIPv6MTU is the minimum link MTU for IPv6 as described in RFC8200,
+	// section 5.
+	minIPv6MTU = 1280
+
+	// reservedSequenceNumber is the LS sequence number which is reserved and
+	// unused, as described in RFC2328, section 12.1.6.
+	reservedSequenceNumber = 0x80000000
+)
+
+// Validate verifies that the Hello's fields satisfy the semantic constraints
+// of RFC5340 and RFC2328, independent of its wire format.
+func (h *Hello) Validate() error {
+	if err := h.Header.validate(); err != nil {
+		return err
+	}
+
+	// These values are transmitted on the wire as 16-bit seconds.
+	for _, v := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{name: "HelloInterval", d: h.HelloInterval},
+		{name: "RouterDeadInterval", d: h.RouterDeadInterval},
+	} {
+		if v.d < time.Second || v.d > math.MaxUint16*time.Second {
+			return fmt.Errorf("ospf3: Hello %s must be between 1 and %d seconds: %v", v.name, math.MaxUint16, v.d)
+		}
+	}
+
+	if h.RouterDeadInterval <= h.HelloInterval {
+		return fmt.Errorf("ospf3: Hello RouterDeadInterval %v must be greater than HelloInterval %v",
+			h.RouterDeadInterval, h.HelloInterval)
+	}
+
+	seen := make(map[ID]struct{}, len(h.NeighborIDs))
+	for _, id := range h.NeighborIDs {
+		if _, ok := seen[id]; ok {
+			return fmt.Errorf("ospf3: Hello lists neighbor ID %s more than once", id)
+		}
+		seen[id] = struct{}{}
+	}
+
+	return nil
+}
+
+// Validate verifies that the DatabaseDescription's fields satisfy the semantic
+// constraints of RFC5340 and RFC2328, independent of its wire format.
+func (dd *DatabaseDescription) Validate() error {
+	if err := dd.Header.validate(); err != nil {
+		return err
+	}
+
+	// Virtual links advertise an MTU of 0, as described in RFC5340, appendix
+	// A.3.3.
+	if dd.InterfaceMTU != 0 && dd.InterfaceMTU < minIPv6MTU {
+		return fmt.Errorf("ospf3: DatabaseDescription InterfaceMTU %d is less than the IPv6 minimum MTU %d",
+			dd.InterfaceMTU, minIPv6MTU)
+	}
+
+	// The first DatabaseDescription of an exchange sets the I-, M-, and
+	// MS-bits and carries no LSA headers, as described in RFC2328, section
+	// 10.8.
+	if !dd.Flags.Valid() {
+		return fmt.Errorf("ospf3: DatabaseDescription flags are not valid: %s", dd.Flags)
+	}
+	if dd.Flags&IBit != 0 && len(dd.LSAs) > 0 {
+		return fmt.Errorf("ospf3: DatabaseDescription with I-bit must not carry LSA headers, got %d", len(dd.LSAs))
+	}
+
+	for _, h := range dd.LSAs {
+		if err := h.validate(); err != nil {
+			return fmt.Errorf("ospf3: DatabaseDescription %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate verifies that the LinkStateRequest's fields satisfy the semantic
+// constraints of RFC5340 and RFC2328, independent of its wire format.
+func (lsr *LinkStateRequest) Validate() error {
+	if err := lsr.Header.validate(); err != nil {
+		return err
+	}
+
+	seen := make(map[LSA]struct{}, len(lsr.LSAs))
+	for _, l := range lsr.LSAs {
+		if _, ok := seen[l]; ok {
+			return fmt.Errorf("ospf3: LinkStateRequest requests %s %s from %s more than once",
+				l.Type, l.LinkStateID, l.AdvertisingRouter)
+		}
+		seen[l] = struct{}{}
+	}
+
+	return nil
+}
+
+// Validate verifies that the LinkStateUpdate's fields satisfy the semantic
+// constraints of RFC5340 and RFC2328, independent of its wire format.
+func (lsu *LinkStateUpdate) Validate() error {
+	if err := lsu.Header.validate(); err != nil {
+		return err
+	}
+
+	for _, l := range lsu.LSAs {
+		if err := l.Header.validate(); err != nil {
+			return fmt.Errorf("ospf3: LinkStateUpdate %w", err)
+		}
+		if l.Body == nil {
+			return fmt.Errorf("ospf3: LinkStateUpdate %s from %s has no body",
+				l.Header.LSA.Type, l.Header.LSA.AdvertisingRouter)
+		}
+	}
+
+	return nil
+}
+
+// Validate verifies that the LinkStateAcknowledgement's fields satisfy the
+// semantic constraints of RFC5340 and RFC2328, independent of its wire format.
+func (lsa *LinkStateAcknowledgement) Validate() error {
+	if err := lsa.Header.validate(); err != nil {
+		return err
+	}
+
+	for _, h := range lsa.LSAs {
+		if err := h.validate(); err != nil {
+			return fmt.Errorf("ospf3: LinkStateAcknowledgement %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validate verifies the semantic constraints of a Header.
+func (h Header) validate() error {
+	// A Router ID of 0.0.0.0 indicates that no Router ID was configured.
+	if h.RouterID == (ID{}) {
+		return fmt.Errorf("ospf3: Header RouterID must not be %s", h.RouterID)
+	}
+
+	return nil
+}
+
+// validate verifies the semantic constraints of an LSAHeader.
+func (h LSAHeader) validate() error {
+	if age := lsAge(h.Age); age < 0 || age > MaxAge {
+		return fmt.Errorf("%s from %s has age %v outside of 0 to MaxAge",
+			h.LSA.Type, h.LSA.AdvertisingRouter, age)
+	}
+
+	if h.SequenceNumber == reservedSequenceNumber {
+		return fmt.Errorf("%s from %s uses reserved sequence number %#08x",
+			h.LSA.Type, h.LSA.AdvertisingRouter, h.SequenceNumber)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package ospf3
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacketValidate(t *testing.T) {
+	var (
+		h = Header{RouterID: ID{192, 0, 2, 1}}
+
+		hello = func(fn func(h *Hello)) *Hello {
+			p := &Hello{
+				Header:             h,
+				HelloInterval:      10 * time.Second,
+				RouterDeadInterval: 40 * time.Second,
+				NeighborIDs:        []ID{{192, 0, 2, 2}},
+			}
+			fn(p)
+			return p
+		}
+
+		dd = func(fn func(dd *DatabaseDescription)) *DatabaseDescription {
+			p := &DatabaseDescription{
+				Header:       h,
+				InterfaceMTU: 1500,
+				Flags:        IBit | MBit | MSBit,
+			}
+			fn(p)
+			return p
+		}
+
+		lsaHeader = LSAHeader{
+			Age:            10 * time.Second,
+			LSA:            LSA{Type: RouterLSA, AdvertisingRouter: ID{192, 0, 2, 1}},
+			SequenceNumber: 0x80000001,
+		}
+	)
+
+	tests := []struct {
+		name string
+		p    Packet
+		ok   bool
+	}{
+		{
+			name: "OK Hello",
+			p:    hello(func(_ *Hello) {}),
+			ok:   true,
+		},
+		{
+			name: "Hello zero Router ID",
+			p:    hello(func(h *Hello) { h.Header.RouterID = ID{} }),
+		},
+		{
+			name: "Hello zero HelloInterval",
+			p:    hello(func(h *Hello) { h.HelloInterval = 0 }),
+		},
+		{
+			name: "Hello large RouterDeadInterval",
+			p:    hello(func(h *Hello) { h.RouterDeadInterval = 65536 * time.Second }),
+		},
+		{
+			name: "Hello RouterDeadInterval equals HelloInterval",
+			p:    hello(func(h *Hello) { h.RouterDeadInterval = h.HelloInterval }),
+		},
+		{
+			name: "Hello duplicate neighbor",
+			p:    hello(func(h *Hello) { h.NeighborIDs = append(h.NeighborIDs, h.NeighborIDs[0]) }),
+		},
+		{
+			name: "OK DatabaseDescription initial",
+			p:    dd(func(_ *DatabaseDescription) {}),
+			ok:   true,
+		},
+		{
+			name: "OK DatabaseDescription virtual link",
+			p: dd(func(dd *DatabaseDescription) {
+				dd.InterfaceMTU = 0
+				dd.Flags = MBit
+				dd.LSAs = []LSAHeader{lsaHeader}
+			}),
+			ok: true,
+		},
+		{
+			name: "DatabaseDescription small MTU",
+			p:    dd(func(dd *DatabaseDescription) { dd.InterfaceMTU = 1279 }),
+		},
+		{
+			name: "DatabaseDescription I-bit without MS-bit",
+			p:    dd(func(dd *DatabaseDescription) { dd.Flags = IBit | MBit }),
+		},
+		{
+			name: "DatabaseDescription I-bit with LSA headers",
+			p:    dd(func(dd *DatabaseDescription) { dd.LSAs = []LSAHeader{lsaHeader} }),
+		},
+		{
+			name: "DatabaseDescription reserved sequence number",
+			p: dd(func(dd *DatabaseDescription) {
+				dd.Flags = 0
+				dd.LSAs = []LSAHeader{{SequenceNumber: 0x80000000}}
+			}),
+		},
+		{
+			name: "OK LinkStateRequest",
+			p:    &LinkStateRequest{Header: h, LSAs: []LSA{{Type: RouterLSA}, {Type: LinkLSA}}},
+			ok:   true,
+		},
+		{
+			name: "LinkStateRequest duplicate LSA",
+			p:    &LinkStateRequest{Header: h, LSAs: []LSA{{Type: RouterLSA}, {Type: RouterLSA}}},
+		},
+		{
+			name: "OK LinkStateUpdate",
+			p: &LinkStateUpdate{
+				Header: h,
+				LSAs:   []LinkStateAdvertisement{{Header: lsaHeader, Body: lsaRouterLSABody}},
+			},
+			ok: true,
+		},
+		{
+			name: "LinkStateUpdate no body",
+			p: &LinkStateUpdate{
+				Header: h,
+				LSAs:   []LinkStateAdvertisement{{Header: lsaHeader}},
+			},
+		},
+		{
+			name: "OK LinkStateAcknowledgement DoNotAge MaxAge",
+			p: &LinkStateAcknowledgement{
+				Header: h,
+				LSAs:   []LSAHeader{{Age: DoNotAge + MaxAge, SequenceNumber: 0x80000001}},
+			},
+			ok: true,
+		},
+		{
+			name: "LinkStateAcknowledgement age exceeds MaxAge",
+			p: &LinkStateAcknowledgement{
+				Header: h,
+				LSAs:   []LSAHeader{{Age: MaxAge + time.Second, SequenceNumber: 0x80000001}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.p.Validate()
+			if tt.ok && err != nil {
+				t.Fatalf("failed to validate: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+
+			t.Logf("err: %v", err)
+		})
+	}
+}